@@ -0,0 +1,220 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/isaacjstriker/gatorapp/internal/database"
+)
+
+// The roles a user can have. Admins manage other users and run the
+// commands that affect everyone's data; the first user to register is one.
+const (
+	roleAdmin = "admin"
+	roleUser  = "user"
+)
+
+// isAdmin reports whether user is an admin. Only their role counts.
+func isAdmin(user database.User) bool {
+	return user.Role == roleAdmin
+}
+
+// findUser looks up a user by name, returning a NotFoundError if there is
+// none.
+func findUser(s *State, name string) (database.User, error) {
+	user, err := s.Queries.GetUser(context.Background(), name)
+	if errors.Is(err, sql.ErrNoRows) {
+		return user, &NotFoundError{Kind: "user", Name: name}
+	}
+	if err != nil {
+		return user, fmt.Errorf("could not find user %s: %w", name, err)
+	}
+	return user, nil
+}
+
+// checkNotLastAdmin refuses to demote, disable or delete the only active
+// admin, which would leave nobody able to manage users.
+func checkNotLastAdmin(ctx context.Context, s *State, user database.User) error {
+	if user.Role != roleAdmin || user.DisabledAt.Valid {
+		return nil
+	}
+	admins, err := s.Queries.CountActiveAdmins(ctx)
+	if err != nil {
+		return fmt.Errorf("could not count admins: %w", err)
+	}
+	if admins <= 1 {
+		return &ConflictError{Message: fmt.Sprintf("%s is the only admin; make someone else an admin first", user.Name)}
+	}
+	return nil
+}
+
+// handlerAdminRename renames a user. Their feeds, follows and settings go
+// with them.
+func handlerAdminRename(s *State, admin database.User, cmd Command) error {
+	ctx := context.Background()
+	oldName, newName := cmd.args[0], cmd.args[1]
+	if newName == "" {
+		return &UsageError{Usage: "admin rename <username> <new_name>", Reason: "not a valid name"}
+	}
+
+	user, err := findUser(s, oldName)
+	if err != nil {
+		return err
+	}
+	_, err = s.Queries.RenameUser(ctx, database.RenameUserParams{ID: user.ID, Name: newName, UpdatedAt: time.Now().UTC()})
+	if isUniqueViolation(err) {
+		return &ConflictError{Message: fmt.Sprintf("user %q already exists", newName)}
+	}
+	if err != nil {
+		return fmt.Errorf("could not rename user: %w", err)
+	}
+
+	if s.Config.CurrentUsername == oldName {
+		if err := s.Config.SetUser(newName); err != nil {
+			return fmt.Errorf("failed to write config: %w", err)
+		}
+	}
+	fmt.Printf("Renamed %s to %s\n", oldName, newName)
+	return nil
+}
+
+// handlerAdminDisable stops a user from logging in or running commands,
+// and from getting notifications and digests. Their data is kept.
+func handlerAdminDisable(s *State, admin database.User, cmd Command) error {
+	ctx := context.Background()
+
+	user, err := findUser(s, cmd.args[0])
+	if err != nil {
+		return err
+	}
+	if user.ID == admin.ID {
+		return &ConflictError{Message: "you can't disable yourself"}
+	}
+	if user.DisabledAt.Valid {
+		fmt.Printf("%s is already disabled\n", user.Name)
+		return nil
+	}
+	if err := checkNotLastAdmin(ctx, s, user); err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	if _, err := s.Queries.SetUserDisabled(ctx, database.SetUserDisabledParams{
+		ID:         user.ID,
+		DisabledAt: sql.NullTime{Time: now, Valid: true},
+		UpdatedAt:  now,
+	}); err != nil {
+		return fmt.Errorf("could not disable user: %w", err)
+	}
+	fmt.Printf("Disabled %s\n", user.Name)
+	return nil
+}
+
+func handlerAdminEnable(s *State, admin database.User, cmd Command) error {
+	ctx := context.Background()
+
+	user, err := findUser(s, cmd.args[0])
+	if err != nil {
+		return err
+	}
+	if !user.DisabledAt.Valid {
+		fmt.Printf("%s is not disabled\n", user.Name)
+		return nil
+	}
+
+	if _, err := s.Queries.SetUserDisabled(ctx, database.SetUserDisabledParams{ID: user.ID, UpdatedAt: time.Now().UTC()}); err != nil {
+		return fmt.Errorf("could not enable user: %w", err)
+	}
+	fmt.Printf("Enabled %s\n", user.Name)
+	return nil
+}
+
+// handlerAdminRole makes a user an admin or an ordinary user.
+func handlerAdminRole(s *State, admin database.User, cmd Command) error {
+	ctx := context.Background()
+	name, role := cmd.args[0], cmd.args[1]
+	if role != roleAdmin && role != roleUser {
+		return &UsageError{Usage: "admin role <username> <admin|user>", Reason: fmt.Sprintf("unknown role %q", role)}
+	}
+
+	user, err := findUser(s, name)
+	if err != nil {
+		return err
+	}
+	if user.Role == role {
+		fmt.Printf("%s is already %s\n", user.Name, withArticle(role))
+		return nil
+	}
+	if role == roleUser {
+		if err := checkNotLastAdmin(ctx, s, user); err != nil {
+			return err
+		}
+	}
+
+	if _, err := s.Queries.SetUserRole(ctx, database.SetUserRoleParams{ID: user.ID, Role: role, UpdatedAt: time.Now().UTC()}); err != nil {
+		return fmt.Errorf("could not change role: %w", err)
+	}
+	fmt.Printf("%s is now %s\n", user.Name, withArticle(role))
+	return nil
+}
+
+func withArticle(role string) string {
+	if role == roleAdmin {
+		return "an admin"
+	}
+	return "a user"
+}
+
+// handlerAdminTransfer gives a feed to another user, who follows it if they
+// didn't already. The previous owner keeps following it.
+func handlerAdminTransfer(s *State, admin database.User, cmd Command) error {
+	ctx := context.Background()
+
+	feed, err := findFeed(s, cmd.args[0])
+	if err != nil {
+		return err
+	}
+	owner, err := findUser(s, cmd.args[1])
+	if err != nil {
+		return err
+	}
+	if feed.UserID == owner.ID {
+		fmt.Printf("%s already owns feed %q\n", owner.Name, feed.Name)
+		return nil
+	}
+
+	err = s.Queries.InTx(ctx, func(qtx database.Querier) error {
+		if err := qtx.SetFeedOwner(ctx, database.SetFeedOwnerParams{ID: feed.ID, UserID: owner.ID, UpdatedAt: time.Now().UTC()}); err != nil {
+			return fmt.Errorf("could not transfer feed: %w", err)
+		}
+		_, err := qtx.GetFeedFollow(ctx, database.GetFeedFollowParams{UserID: owner.ID, FeedID: feed.ID})
+		if err == nil {
+			return nil
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("could not check follow: %w", err)
+		}
+		now := time.Now().UTC()
+		if _, err := qtx.CreateFeedFollow(ctx, database.CreateFeedFollowParams{
+			ID:        uuid.New(),
+			CreatedAt: now,
+			UpdatedAt: now,
+			UserID:    owner.ID,
+			FeedID:    feed.ID,
+		}); err != nil {
+			return fmt.Errorf("could not follow feed: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Gave feed %q (%s) to %s\n", feed.Name, feed.Url, owner.Name)
+	return nil
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestRegisterOneFirstAdmin registers users at once and checks that only
+// one of them became the first user's admin.
+func TestRegisterOneFirstAdmin(t *testing.T) {
+	ctx := context.Background()
+	for _, ts := range testStores(t) {
+		t.Run(ts.name, func(t *testing.T) {
+			before, err := ts.store.CountAllData(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			const n = 8
+			users := make(chan string, n)
+			var wg sync.WaitGroup
+			for i := range n {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					user, err := registerUser(ctx, ts.store, fmt.Sprintf("test-%d-%d", time.Now().UnixNano(), i), time.Now().UTC())
+					if err != nil {
+						t.Errorf("registerUser: %v", err)
+						return
+					}
+					cleanupUser(t, ts.store, user.ID)
+					users <- user.Role
+				}()
+			}
+			wg.Wait()
+			close(users)
+
+			admins := 0
+			for role := range users {
+				if role == roleAdmin {
+					admins++
+				}
+			}
+			want := 0
+			if before.Users == 0 {
+				want = 1
+			}
+			if admins != want {
+				t.Errorf("%d of %d new users are admins, want %d", admins, n, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,1117 @@
+package main
+
+import (
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/isaacjstriker/gatorapp/internal/database"
+)
+
+// A backup is a gzipped stream of JSON records, one per line. The first is
+// a header naming the format and its version; the rest each hold a type and
+// its data, in an order where every record comes after those it refers to.
+// Bump backupVersion whenever a record changes in a way an older gatorapp
+// couldn't restore.
+const (
+	backupFormat  = "gatorapp-backup"
+	backupVersion = 1
+)
+
+// backupPostPage is how many posts backup reads from the database at once.
+const backupPostPage = 500
+
+// The record types, in the order backup writes them.
+const (
+	recordHeader           = "header"
+	recordUser             = "user"
+	recordFeed             = "feed"
+	recordFolder           = "folder"
+	recordFollow           = "follow"
+	recordPost             = "post"
+	recordPostState        = "post_state"
+	recordPostTag          = "post_tag"
+	recordFilterRule       = "filter_rule"
+	recordNotificationRule = "notification_rule"
+	recordDigest           = "digest"
+)
+
+var backupRecordTypes = []string{
+	recordUser, recordFeed, recordFolder, recordFollow, recordPost, recordPostState,
+	recordPostTag, recordFilterRule, recordNotificationRule, recordDigest,
+}
+
+type backupRecord struct {
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data"`
+}
+
+type backupHeader struct {
+	Format    string    `json:"format"`
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Records refer to each other by the IDs they had in the backed up
+// database. Restore maps those to its own: users by name, feeds and posts by
+// URL.
+
+type backupUser struct {
+	ID         uuid.UUID  `json:"id"`
+	Name       string     `json:"name"`
+	Role       string     `json:"role"`
+	DisabledAt *time.Time `json:"disabled_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+type backupFeed struct {
+	ID                     uuid.UUID `json:"id"`
+	UserID                 uuid.UUID `json:"user_id"`
+	Name                   string    `json:"name"`
+	URL                    string    `json:"url"`
+	CreatedAt              time.Time `json:"created_at"`
+	RetentionMaxAgeSeconds *int64    `json:"retention_max_age_seconds,omitempty"`
+	RetentionMaxPosts      *int32    `json:"retention_max_posts,omitempty"`
+	// PreviousURLs are URLs the feed has moved from, so that following one
+	// still finds the feed.
+	PreviousURLs []backupFeedURL `json:"previous_urls,omitempty"`
+}
+
+type backupFeedURL struct {
+	URL       string    `json:"url"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type backupFolder struct {
+	UserID    uuid.UUID `json:"user_id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type backupFollow struct {
+	UserID    uuid.UUID `json:"user_id"`
+	FeedID    uuid.UUID `json:"feed_id"`
+	CreatedAt time.Time `json:"created_at"`
+	Folder    string    `json:"folder,omitempty"`
+	Tags      []string  `json:"tags,omitempty"`
+	Title     *string   `json:"title,omitempty"`
+	Muted     bool      `json:"muted"`
+	Priority  int32     `json:"priority"`
+	Notify    bool      `json:"notify"`
+}
+
+type backupPost struct {
+	ID          uuid.UUID  `json:"id"`
+	FeedID      uuid.UUID  `json:"feed_id"`
+	Title       string     `json:"title"`
+	URL         string     `json:"url"`
+	Description *string    `json:"description,omitempty"`
+	PublishedAt *time.Time `json:"published_at,omitempty"`
+	Author      string     `json:"author,omitempty"`
+	Categories  []string   `json:"categories,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+type backupPostState struct {
+	UserID    uuid.UUID  `json:"user_id"`
+	PostID    uuid.UUID  `json:"post_id"`
+	ReadAt    *time.Time `json:"read_at,omitempty"`
+	StarredAt *time.Time `json:"starred_at,omitempty"`
+	HiddenAt  *time.Time `json:"hidden_at,omitempty"`
+}
+
+type backupPostTag struct {
+	UserID uuid.UUID `json:"user_id"`
+	PostID uuid.UUID `json:"post_id"`
+	Tag    string    `json:"tag"`
+}
+
+type backupFilterRule struct {
+	UserID    uuid.UUID  `json:"user_id"`
+	FeedID    *uuid.UUID `json:"feed_id,omitempty"`
+	Field     string     `json:"field"`
+	MatchType string     `json:"match_type"`
+	Pattern   string     `json:"pattern"`
+	Action    string     `json:"action"`
+	Tag       string     `json:"tag,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+type backupNotificationRule struct {
+	UserID    uuid.UUID  `json:"user_id"`
+	FeedID    *uuid.UUID `json:"feed_id,omitempty"`
+	Field     string     `json:"field"`
+	MatchType string     `json:"match_type"`
+	Pattern   string     `json:"pattern"`
+	Sink      string     `json:"sink"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+type backupDigest struct {
+	UserID uuid.UUID `json:"user_id"`
+	Email  string    `json:"email"`
+	Folder *string   `json:"folder,omitempty"`
+	Tag    *string   `json:"tag,omitempty"`
+}
+
+func stringPtr(s sql.NullString) *string {
+	if !s.Valid {
+		return nil
+	}
+	return &s.String
+}
+
+func timePtr(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
+
+func uuidPtr(id uuid.NullUUID) *uuid.UUID {
+	if !id.Valid {
+		return nil
+	}
+	return &id.UUID
+}
+
+func ptrString(s *string) sql.NullString {
+	if s == nil {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: *s, Valid: true}
+}
+
+func ptrTime(t *time.Time) sql.NullTime {
+	if t == nil {
+		return sql.NullTime{}
+	}
+	return sql.NullTime{Time: *t, Valid: true}
+}
+
+// backupWriter writes records to an archive, counting them by type. It
+// remembers which users, feeds and posts it has written so that records
+// referring to ones added while the backup ran can be left out rather than
+// written dangling.
+type backupWriter struct {
+	enc    *json.Encoder
+	counts map[string]int64
+	users  map[uuid.UUID]bool
+	feeds  map[uuid.UUID]bool
+	posts  map[uuid.UUID]bool
+}
+
+func (w *backupWriter) write(kind string, data any) error {
+	if err := w.enc.Encode(struct {
+		Type string `json:"type"`
+		Data any    `json:"data"`
+	}{kind, data}); err != nil {
+		return fmt.Errorf("could not write backup: %w", err)
+	}
+	w.counts[kind]++
+	return nil
+}
+
+// writeBackup writes everything in the database to out as an archive.
+func writeBackup(ctx context.Context, q database.Querier, out io.Writer) (map[string]int64, error) {
+	zw := gzip.NewWriter(out)
+	w := &backupWriter{
+		enc:    json.NewEncoder(zw),
+		counts: make(map[string]int64),
+		users:  make(map[uuid.UUID]bool),
+		feeds:  make(map[uuid.UUID]bool),
+		posts:  make(map[uuid.UUID]bool),
+	}
+	w.enc.SetEscapeHTML(false)
+
+	header := backupHeader{Format: backupFormat, Version: backupVersion, CreatedAt: time.Now().UTC()}
+	if err := w.write(recordHeader, header); err != nil {
+		return nil, err
+	}
+	delete(w.counts, recordHeader)
+
+	users, err := q.GetUsers(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch users: %w", err)
+	}
+	for _, user := range users {
+		w.users[user.ID] = true
+		if err := w.write(recordUser, backupUser{
+			ID:         user.ID,
+			Name:       user.Name,
+			Role:       user.Role,
+			DisabledAt: timePtr(user.DisabledAt),
+			CreatedAt:  user.CreatedAt,
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	feeds, err := q.GetFeeds(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch feeds: %w", err)
+	}
+	for _, feed := range feeds {
+		if !w.users[feed.UserID] {
+			continue
+		}
+		w.feeds[feed.ID] = true
+		record := backupFeed{
+			ID:        feed.ID,
+			UserID:    feed.UserID,
+			Name:      feed.Name,
+			URL:       feed.Url,
+			CreatedAt: feed.CreatedAt,
+		}
+		if feed.RetentionMaxAgeSeconds.Valid {
+			record.RetentionMaxAgeSeconds = &feed.RetentionMaxAgeSeconds.Int64
+		}
+		if feed.RetentionMaxPosts.Valid {
+			record.RetentionMaxPosts = &feed.RetentionMaxPosts.Int32
+		}
+		history, err := q.GetFeedURLHistory(ctx, feed.ID)
+		if err != nil {
+			return nil, fmt.Errorf("could not fetch previous urls of %s: %w", feed.Url, err)
+		}
+		for _, h := range history {
+			record.PreviousURLs = append(record.PreviousURLs, backupFeedURL{URL: h.Url, CreatedAt: h.CreatedAt})
+		}
+		if err := w.write(recordFeed, record); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, user := range users {
+		if err := backupUserFollows(ctx, q, w, user); err != nil {
+			return nil, err
+		}
+	}
+
+	after := uuid.Nil
+	for {
+		posts, err := q.GetPostsAfter(ctx, database.GetPostsAfterParams{After: after, MaxPosts: backupPostPage})
+		if err != nil {
+			return nil, fmt.Errorf("could not fetch posts: %w", err)
+		}
+		for _, post := range posts {
+			if !w.feeds[post.FeedID] {
+				continue
+			}
+			w.posts[post.ID] = true
+			if err := w.write(recordPost, backupPost{
+				ID:          post.ID,
+				FeedID:      post.FeedID,
+				Title:       post.Title,
+				URL:         post.Url,
+				Description: stringPtr(post.Description),
+				PublishedAt: timePtr(post.PublishedAt),
+				Author:      post.Author,
+				Categories:  post.Categories,
+				CreatedAt:   post.CreatedAt,
+			}); err != nil {
+				return nil, err
+			}
+		}
+		if len(posts) < backupPostPage {
+			break
+		}
+		after = posts[len(posts)-1].ID
+	}
+
+	states, err := q.GetAllPostStates(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch post states: %w", err)
+	}
+	for _, state := range states {
+		if !w.users[state.UserID] || !w.posts[state.PostID] {
+			continue
+		}
+		if err := w.write(recordPostState, backupPostState{
+			UserID:    state.UserID,
+			PostID:    state.PostID,
+			ReadAt:    timePtr(state.ReadAt),
+			StarredAt: timePtr(state.StarredAt),
+			HiddenAt:  timePtr(state.HiddenAt),
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	tags, err := q.GetAllPostTags(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch post tags: %w", err)
+	}
+	for _, tag := range tags {
+		if !w.users[tag.UserID] || !w.posts[tag.PostID] {
+			continue
+		}
+		if err := w.write(recordPostTag, backupPostTag(tag)); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, user := range users {
+		if err := backupUserRules(ctx, q, w, user); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("could not write backup: %w", err)
+	}
+	return w.counts, nil
+}
+
+// backupUserFollows writes a user's folders and the feeds they follow.
+func backupUserFollows(ctx context.Context, q database.Querier, w *backupWriter, user database.User) error {
+	folders, err := q.GetFoldersForUser(ctx, user.ID)
+	if err != nil {
+		return fmt.Errorf("could not fetch folders for %s: %w", user.Name, err)
+	}
+	for _, folder := range folders {
+		if err := w.write(recordFolder, backupFolder{UserID: user.ID, Name: folder.Name, CreatedAt: folder.CreatedAt}); err != nil {
+			return err
+		}
+	}
+
+	follows, err := q.GetFeedFollowsForUser(ctx, database.GetFeedFollowsForUserParams{UserID: user.ID})
+	if err != nil {
+		return fmt.Errorf("could not fetch follows for %s: %w", user.Name, err)
+	}
+	for _, follow := range follows {
+		if !w.feeds[follow.FeedID] {
+			continue
+		}
+		if err := w.write(recordFollow, backupFollow{
+			UserID:    user.ID,
+			FeedID:    follow.FeedID,
+			CreatedAt: follow.CreatedAt,
+			Folder:    follow.FolderName,
+			Tags:      follow.Tags,
+			Title:     stringPtr(follow.Title),
+			Muted:     follow.Muted,
+			Priority:  follow.Priority,
+			Notify:    follow.Notify,
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// backupUserRules writes a user's filter and notification rules and their
+// digest settings.
+func backupUserRules(ctx context.Context, q database.Querier, w *backupWriter, user database.User) error {
+	filters, err := q.GetFilterRulesForUser(ctx, user.ID)
+	if err != nil {
+		return fmt.Errorf("could not fetch filter rules for %s: %w", user.Name, err)
+	}
+	for _, rule := range filters {
+		if rule.FeedID.Valid && !w.feeds[rule.FeedID.UUID] {
+			continue
+		}
+		if err := w.write(recordFilterRule, backupFilterRule{
+			UserID:    user.ID,
+			FeedID:    uuidPtr(rule.FeedID),
+			Field:     rule.Field,
+			MatchType: rule.MatchType,
+			Pattern:   rule.Pattern,
+			Action:    rule.Action,
+			Tag:       rule.Tag,
+			CreatedAt: rule.CreatedAt,
+		}); err != nil {
+			return err
+		}
+	}
+
+	notifications, err := q.GetNotificationRulesForUser(ctx, user.ID)
+	if err != nil {
+		return fmt.Errorf("could not fetch notification rules for %s: %w", user.Name, err)
+	}
+	for _, rule := range notifications {
+		if rule.FeedID.Valid && !w.feeds[rule.FeedID.UUID] {
+			continue
+		}
+		if err := w.write(recordNotificationRule, backupNotificationRule{
+			UserID:    user.ID,
+			FeedID:    uuidPtr(rule.FeedID),
+			Field:     rule.Field,
+			MatchType: rule.MatchType,
+			Pattern:   rule.Pattern,
+			Sink:      rule.Sink,
+			CreatedAt: rule.CreatedAt,
+		}); err != nil {
+			return err
+		}
+	}
+
+	digest, err := q.GetDigestSettings(ctx, user.ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("could not fetch digest settings for %s: %w", user.Name, err)
+	}
+	return w.write(recordDigest, backupDigest{
+		UserID: user.ID,
+		Email:  digest.Email,
+		Folder: stringPtr(digest.Folder),
+		Tag:    stringPtr(digest.Tag),
+	})
+}
+
+// handlerBackup writes every user's data to an archive at the given path,
+// or to stdout for "-". A file is written under a temporary name and only
+// renamed into place once the backup is complete.
+func handlerBackup(s *State, _ database.User, cmd Command) error {
+	ctx := context.Background()
+	path := cmd.args[0]
+
+	if path == "-" {
+		var counts map[string]int64
+		err := s.Queries.InTx(ctx, func(q database.Querier) error {
+			var err error
+			counts, err = writeBackup(ctx, q, os.Stdout)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		printBackupCounts(os.Stderr, "Backed up", counts)
+		return nil
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("could not create backup file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	var counts map[string]int64
+	err = s.Queries.InTx(ctx, func(q database.Querier) error {
+		var err error
+		counts, err = writeBackup(ctx, q, tmp)
+		return err
+	})
+	if err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("could not write backup: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("could not write backup: %w", err)
+	}
+	printBackupCounts(os.Stdout, "Backed up", counts)
+	fmt.Printf("Wrote %s\n", path)
+	return nil
+}
+
+func printBackupCounts(out io.Writer, verb string, counts map[string]int64) {
+	fmt.Fprintf(out, "%s:\n", verb)
+	for _, kind := range backupRecordTypes {
+		fmt.Fprintf(out, "  %-18s %d\n", kind+"s", counts[kind])
+	}
+}
+
+func restoreFlags(fs *flag.FlagSet) {
+	fs.Bool("dry-run", false, "report what would be restored without changing anything")
+}
+
+// errDryRun rolls back a dry run's transaction.
+var errDryRun = errors.New("dry run")
+
+// handlerRestore adds the data in an archive to the database, in one
+// transaction. Restoring is idempotent: users that already exist by name,
+// and feeds and posts that already exist by URL, are reused rather than
+// duplicated, and anything already present is left as it is.
+func handlerRestore(s *State, _ database.User, cmd Command) error {
+	ctx := context.Background()
+	path := cmd.args[0]
+
+	in := io.Reader(os.Stdin)
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return fmt.Errorf("could not open backup: %w", err)
+		}
+		defer f.Close()
+		in = f
+	}
+
+	var r *restorer
+	err := s.Queries.InTx(ctx, func(q database.Querier) error {
+		r = newRestorer(q)
+		if err := r.restore(ctx, in); err != nil {
+			return err
+		}
+		if cmd.flagBool("dry-run") {
+			return errDryRun
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errDryRun) {
+		return err
+	}
+
+	if cmd.flagBool("dry-run") {
+		fmt.Println("Dry run: would restore")
+	} else {
+		fmt.Println("Restored:")
+	}
+	for _, kind := range backupRecordTypes {
+		fmt.Printf("  %-18s %d added, %d already present\n", kind+"s", r.added[kind], r.existing[kind])
+	}
+	return nil
+}
+
+// restorer adds an archive's records to the database, mapping the IDs they
+// refer to onto the database's own.
+type restorer struct {
+	q        database.Querier
+	now      time.Time
+	users    map[uuid.UUID]uuid.UUID
+	feeds    map[uuid.UUID]uuid.UUID
+	posts    map[uuid.UUID]uuid.UUID
+	added    map[string]int64
+	existing map[string]int64
+}
+
+func newRestorer(q database.Querier) *restorer {
+	return &restorer{
+		q:        q,
+		now:      time.Now().UTC(),
+		users:    make(map[uuid.UUID]uuid.UUID),
+		feeds:    make(map[uuid.UUID]uuid.UUID),
+		posts:    make(map[uuid.UUID]uuid.UUID),
+		added:    make(map[string]int64),
+		existing: make(map[string]int64),
+	}
+}
+
+// count records whether a record was added or already present.
+func (r *restorer) count(kind string, added bool) {
+	if added {
+		r.added[kind]++
+	} else {
+		r.existing[kind]++
+	}
+}
+
+func (r *restorer) restore(ctx context.Context, in io.Reader) error {
+	zr, err := gzip.NewReader(in)
+	if err != nil {
+		return fmt.Errorf("not a gatorapp backup: %w", err)
+	}
+	defer zr.Close()
+	dec := json.NewDecoder(zr)
+
+	var record backupRecord
+	if err := dec.Decode(&record); err != nil || record.Type != recordHeader {
+		return errors.New("not a gatorapp backup: missing header")
+	}
+	var header backupHeader
+	if err := json.Unmarshal(record.Data, &header); err != nil || header.Format != backupFormat {
+		return errors.New("not a gatorapp backup: unrecognized header")
+	}
+	if header.Version < 1 {
+		return fmt.Errorf("invalid backup version %d", header.Version)
+	}
+	if header.Version > backupVersion {
+		return fmt.Errorf("backup is version %d, but this gatorapp reads versions up to %d; upgrade gatorapp to restore it",
+			header.Version, backupVersion)
+	}
+
+	for n := 2; ; n++ {
+		record = backupRecord{}
+		err := dec.Decode(&record)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("could not read backup record %d: %w", n, err)
+		}
+		if err := r.restoreRecord(ctx, record); err != nil {
+			return fmt.Errorf("backup record %d (%s): %w", n, record.Type, err)
+		}
+	}
+}
+
+func (r *restorer) restoreRecord(ctx context.Context, record backupRecord) error {
+	var data any
+	switch record.Type {
+	case recordUser:
+		data = &backupUser{}
+	case recordFeed:
+		data = &backupFeed{}
+	case recordFolder:
+		data = &backupFolder{}
+	case recordFollow:
+		data = &backupFollow{}
+	case recordPost:
+		data = &backupPost{}
+	case recordPostState:
+		data = &backupPostState{}
+	case recordPostTag:
+		data = &backupPostTag{}
+	case recordFilterRule:
+		data = &backupFilterRule{}
+	case recordNotificationRule:
+		data = &backupNotificationRule{}
+	case recordDigest:
+		data = &backupDigest{}
+	default:
+		return errors.New("unknown record type")
+	}
+	if err := json.Unmarshal(record.Data, data); err != nil {
+		return fmt.Errorf("invalid record: %w", err)
+	}
+
+	switch data := data.(type) {
+	case *backupUser:
+		return r.restoreUser(ctx, data)
+	case *backupFeed:
+		return r.restoreFeed(ctx, data)
+	case *backupFolder:
+		return r.restoreFolder(ctx, data)
+	case *backupFollow:
+		return r.restoreFollow(ctx, data)
+	case *backupPost:
+		return r.restorePost(ctx, data)
+	case *backupPostState:
+		return r.restorePostState(ctx, data)
+	case *backupPostTag:
+		return r.restorePostTag(ctx, data)
+	case *backupFilterRule:
+		return r.restoreFilterRule(ctx, data)
+	case *backupNotificationRule:
+		return r.restoreNotificationRule(ctx, data)
+	case *backupDigest:
+		return r.restoreDigest(ctx, data)
+	}
+	return nil
+}
+
+// user, feed and post look up what an archived ID was restored as.
+
+func (r *restorer) user(id uuid.UUID) (uuid.UUID, error) {
+	if local, ok := r.users[id]; ok {
+		return local, nil
+	}
+	return uuid.Nil, fmt.Errorf("refers to user %s, which is not in the backup", id)
+}
+
+func (r *restorer) feed(id uuid.UUID) (uuid.UUID, error) {
+	if local, ok := r.feeds[id]; ok {
+		return local, nil
+	}
+	return uuid.Nil, fmt.Errorf("refers to feed %s, which is not in the backup", id)
+}
+
+func (r *restorer) post(id uuid.UUID) (uuid.UUID, error) {
+	if local, ok := r.posts[id]; ok {
+		return local, nil
+	}
+	return uuid.Nil, fmt.Errorf("refers to post %s, which is not in the backup", id)
+}
+
+// optionalFeed maps a rule's feed, which is nil for rules on every feed.
+func (r *restorer) optionalFeed(id *uuid.UUID) (uuid.NullUUID, error) {
+	if id == nil {
+		return uuid.NullUUID{}, nil
+	}
+	local, err := r.feed(*id)
+	return uuid.NullUUID{UUID: local, Valid: err == nil}, err
+}
+
+// Everything restore creates gets a new ID, so that an archived ID which
+// happens to belong to something else here can't clash.
+
+func (r *restorer) restoreUser(ctx context.Context, u *backupUser) error {
+	user, err := r.q.GetUser(ctx, u.Name)
+	if err == nil {
+		r.users[u.ID] = user.ID
+		r.count(recordUser, false)
+		return nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("could not look up user %s: %w", u.Name, err)
+	}
+	if u.Role != roleAdmin && u.Role != roleUser {
+		return fmt.Errorf("user %s has unknown role %q", u.Name, u.Role)
+	}
+	user, err = r.q.CreateUser(ctx, database.CreateUserParams{
+		ID:        uuid.New(),
+		CreatedAt: u.CreatedAt,
+		UpdatedAt: r.now,
+		Name:      u.Name,
+		Role:      u.Role,
+	})
+	if err != nil {
+		return fmt.Errorf("could not create user %s: %w", u.Name, err)
+	}
+	if u.DisabledAt != nil {
+		if _, err := r.q.SetUserDisabled(ctx, database.SetUserDisabledParams{ID: user.ID, DisabledAt: ptrTime(u.DisabledAt), UpdatedAt: r.now}); err != nil {
+			return fmt.Errorf("could not disable user %s: %w", u.Name, err)
+		}
+	}
+	r.users[u.ID] = user.ID
+	r.count(recordUser, true)
+	return nil
+}
+
+// restoreFeed reuses a feed with the same URL, or one that has moved from
+// it, rather than adding a second copy.
+func (r *restorer) restoreFeed(ctx context.Context, f *backupFeed) error {
+	feed, err := r.q.GetFeedByURL(ctx, f.URL)
+	if errors.Is(err, sql.ErrNoRows) {
+		feed, err = r.q.GetFeedByPreviousURL(ctx, f.URL)
+	}
+	if err == nil {
+		r.feeds[f.ID] = feed.ID
+		r.count(recordFeed, false)
+		return r.restorePreviousURLs(ctx, feed, f)
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("could not look up feed %s: %w", f.URL, err)
+	}
+
+	owner, err := r.user(f.UserID)
+	if err != nil {
+		return err
+	}
+	feed, err = r.q.CreateFeed(ctx, database.CreateFeedParams{
+		ID:        uuid.New(),
+		CreatedAt: f.CreatedAt,
+		UpdatedAt: r.now,
+		Name:      f.Name,
+		Url:       f.URL,
+		UserID:    owner,
+	})
+	if err != nil {
+		return fmt.Errorf("could not create feed %s: %w", f.URL, err)
+	}
+	if f.RetentionMaxAgeSeconds != nil || f.RetentionMaxPosts != nil {
+		params := database.SetFeedRetentionParams{ID: feed.ID, UpdatedAt: r.now}
+		if f.RetentionMaxAgeSeconds != nil {
+			params.RetentionMaxAgeSeconds = sql.NullInt64{Int64: *f.RetentionMaxAgeSeconds, Valid: true}
+		}
+		if f.RetentionMaxPosts != nil {
+			params.RetentionMaxPosts = sql.NullInt32{Int32: *f.RetentionMaxPosts, Valid: true}
+		}
+		if err := r.q.SetFeedRetention(ctx, params); err != nil {
+			return fmt.Errorf("could not set retention for %s: %w", f.URL, err)
+		}
+	}
+	r.feeds[f.ID] = feed.ID
+	r.count(recordFeed, true)
+	return r.restorePreviousURLs(ctx, feed, f)
+}
+
+// restorePreviousURLs remembers the backed up feed's previous URLs as
+// previous URLs of feed, skipping any that already lead to a feed here.
+func (r *restorer) restorePreviousURLs(ctx context.Context, feed database.Feed, f *backupFeed) error {
+	for _, previous := range f.PreviousURLs {
+		_, err := r.q.GetFeedByURL(ctx, previous.URL)
+		if errors.Is(err, sql.ErrNoRows) {
+			_, err = r.q.GetFeedByPreviousURL(ctx, previous.URL)
+		}
+		if err == nil {
+			continue
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("could not look up feed %s: %w", previous.URL, err)
+		}
+		if err := r.q.AddFeedURLHistory(ctx, database.AddFeedURLHistoryParams{
+			ID:        uuid.New(),
+			CreatedAt: previous.CreatedAt,
+			Url:       previous.URL,
+			FeedID:    feed.ID,
+		}); err != nil {
+			return fmt.Errorf("could not record previous url %s: %w", previous.URL, err)
+		}
+	}
+	return nil
+}
+
+func (r *restorer) restoreFolder(ctx context.Context, f *backupFolder) error {
+	userID, err := r.user(f.UserID)
+	if err != nil {
+		return err
+	}
+	_, err = r.q.GetFolderByName(ctx, database.GetFolderByNameParams{UserID: userID, Name: f.Name})
+	if err == nil {
+		r.count(recordFolder, false)
+		return nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("could not look up folder %s: %w", f.Name, err)
+	}
+	if _, err := r.q.CreateFolder(ctx, database.CreateFolderParams{
+		ID:        uuid.New(),
+		CreatedAt: f.CreatedAt,
+		UpdatedAt: r.now,
+		UserID:    userID,
+		Name:      f.Name,
+	}); err != nil {
+		return fmt.Errorf("could not create folder %s: %w", f.Name, err)
+	}
+	r.count(recordFolder, true)
+	return nil
+}
+
+// restoreFollow leaves a follow that already exists, and its settings,
+// untouched.
+func (r *restorer) restoreFollow(ctx context.Context, f *backupFollow) error {
+	userID, err := r.user(f.UserID)
+	if err != nil {
+		return err
+	}
+	feedID, err := r.feed(f.FeedID)
+	if err != nil {
+		return err
+	}
+	_, err = r.q.GetFeedFollow(ctx, database.GetFeedFollowParams{UserID: userID, FeedID: feedID})
+	if err == nil {
+		r.count(recordFollow, false)
+		return nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("could not look up follow: %w", err)
+	}
+
+	if _, err := r.q.CreateFeedFollow(ctx, database.CreateFeedFollowParams{
+		ID:        uuid.New(),
+		CreatedAt: f.CreatedAt,
+		UpdatedAt: r.now,
+		UserID:    userID,
+		FeedID:    feedID,
+	}); err != nil {
+		return fmt.Errorf("could not create follow: %w", err)
+	}
+	if _, err := r.q.UpdateFeedFollowSettings(ctx, database.UpdateFeedFollowSettingsParams{
+		UserID:    userID,
+		FeedID:    feedID,
+		Title:     ptrString(f.Title),
+		Muted:     f.Muted,
+		Priority:  f.Priority,
+		Notify:    f.Notify,
+		UpdatedAt: r.now,
+	}); err != nil {
+		return fmt.Errorf("could not update follow settings: %w", err)
+	}
+	if f.Folder != "" {
+		folder, err := r.q.GetFolderByName(ctx, database.GetFolderByNameParams{UserID: userID, Name: f.Folder})
+		if err != nil {
+			return fmt.Errorf("could not look up folder %s: %w", f.Folder, err)
+		}
+		if _, err := r.q.SetFeedFollowFolder(ctx, database.SetFeedFollowFolderParams{
+			UserID:    userID,
+			FeedID:    feedID,
+			FolderID:  uuid.NullUUID{UUID: folder.ID, Valid: true},
+			UpdatedAt: r.now,
+		}); err != nil {
+			return fmt.Errorf("could not move follow to %s: %w", f.Folder, err)
+		}
+	}
+	for _, tag := range f.Tags {
+		if _, err := r.q.AddFeedFollowTag(ctx, database.AddFeedFollowTagParams{Tag: tag, UserID: userID, FeedID: feedID}); err != nil {
+			return fmt.Errorf("could not tag follow: %w", err)
+		}
+	}
+	r.count(recordFollow, true)
+	return nil
+}
+
+func (r *restorer) restorePost(ctx context.Context, p *backupPost) error {
+	post, err := r.q.GetPostByURL(ctx, p.URL)
+	if err == nil {
+		r.posts[p.ID] = post.ID
+		r.count(recordPost, false)
+		return nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("could not look up post %s: %w", p.URL, err)
+	}
+
+	feedID, err := r.feed(p.FeedID)
+	if err != nil {
+		return err
+	}
+	categories := p.Categories
+	if categories == nil {
+		categories = []string{}
+	}
+	post, err = r.q.CreatePost(ctx, database.CreatePostParams{
+		ID:          uuid.New(),
+		CreatedAt:   p.CreatedAt,
+		UpdatedAt:   r.now,
+		Title:       p.Title,
+		Url:         p.URL,
+		Description: ptrString(p.Description),
+		PublishedAt: ptrTime(p.PublishedAt),
+		FeedID:      feedID,
+		Author:      p.Author,
+		Categories:  categories,
+	})
+	if err != nil {
+		return fmt.Errorf("could not create post %s: %w", p.URL, err)
+	}
+	r.posts[p.ID] = post.ID
+	r.count(recordPost, true)
+	return nil
+}
+
+// restorePostState adds a user's marks on a post unless they already have
+// some, in which case theirs win.
+func (r *restorer) restorePostState(ctx context.Context, s *backupPostState) error {
+	userID, err := r.user(s.UserID)
+	if err != nil {
+		return err
+	}
+	postID, err := r.post(s.PostID)
+	if err != nil {
+		return err
+	}
+	n, err := r.q.RestorePostState(ctx, database.RestorePostStateParams{
+		UserID:    userID,
+		PostID:    postID,
+		ReadAt:    ptrTime(s.ReadAt),
+		StarredAt: ptrTime(s.StarredAt),
+		HiddenAt:  ptrTime(s.HiddenAt),
+	})
+	if err != nil {
+		return fmt.Errorf("could not restore post state: %w", err)
+	}
+	r.count(recordPostState, n > 0)
+	return nil
+}
+
+func (r *restorer) restorePostTag(ctx context.Context, t *backupPostTag) error {
+	userID, err := r.user(t.UserID)
+	if err != nil {
+		return err
+	}
+	postID, err := r.post(t.PostID)
+	if err != nil {
+		return err
+	}
+	n, err := r.q.AddPostTag(ctx, database.AddPostTagParams{UserID: userID, PostID: postID, Tag: t.Tag})
+	if err != nil {
+		return fmt.Errorf("could not tag post: %w", err)
+	}
+	r.count(recordPostTag, n > 0)
+	return nil
+}
+
+// Rules have no natural key, so a rule counts as already present when the
+// user has one that does the same thing.
+
+func (r *restorer) restoreFilterRule(ctx context.Context, f *backupFilterRule) error {
+	userID, err := r.user(f.UserID)
+	if err != nil {
+		return err
+	}
+	feedID, err := r.optionalFeed(f.FeedID)
+	if err != nil {
+		return err
+	}
+	rules, err := r.q.GetFilterRulesForUser(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("could not fetch filter rules: %w", err)
+	}
+	for _, rule := range rules {
+		if rule.FeedID == feedID && rule.Field == f.Field && rule.MatchType == f.MatchType &&
+			rule.Pattern == f.Pattern && rule.Action == f.Action && rule.Tag == f.Tag {
+			r.count(recordFilterRule, false)
+			return nil
+		}
+	}
+	if _, err := r.q.CreateFilterRule(ctx, database.CreateFilterRuleParams{
+		ID:        uuid.New(),
+		CreatedAt: f.CreatedAt,
+		UserID:    userID,
+		FeedID:    feedID,
+		Field:     f.Field,
+		MatchType: f.MatchType,
+		Pattern:   f.Pattern,
+		Action:    f.Action,
+		Tag:       f.Tag,
+	}); err != nil {
+		return fmt.Errorf("could not create filter rule: %w", err)
+	}
+	r.count(recordFilterRule, true)
+	return nil
+}
+
+func (r *restorer) restoreNotificationRule(ctx context.Context, n *backupNotificationRule) error {
+	userID, err := r.user(n.UserID)
+	if err != nil {
+		return err
+	}
+	feedID, err := r.optionalFeed(n.FeedID)
+	if err != nil {
+		return err
+	}
+	rules, err := r.q.GetNotificationRulesForUser(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("could not fetch notification rules: %w", err)
+	}
+	for _, rule := range rules {
+		if rule.FeedID == feedID && rule.Field == n.Field && rule.MatchType == n.MatchType &&
+			rule.Pattern == n.Pattern && rule.Sink == n.Sink {
+			r.count(recordNotificationRule, false)
+			return nil
+		}
+	}
+	if _, err := r.q.CreateNotificationRule(ctx, database.CreateNotificationRuleParams{
+		ID:        uuid.New(),
+		CreatedAt: n.CreatedAt,
+		UserID:    userID,
+		FeedID:    feedID,
+		Field:     n.Field,
+		MatchType: n.MatchType,
+		Pattern:   n.Pattern,
+		Sink:      n.Sink,
+	}); err != nil {
+		return fmt.Errorf("could not create notification rule: %w", err)
+	}
+	r.count(recordNotificationRule, true)
+	return nil
+}
+
+// restoreDigest leaves digest settings the user already has untouched.
+func (r *restorer) restoreDigest(ctx context.Context, d *backupDigest) error {
+	userID, err := r.user(d.UserID)
+	if err != nil {
+		return err
+	}
+	_, err = r.q.GetDigestSettings(ctx, userID)
+	if err == nil {
+		r.count(recordDigest, false)
+		return nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("could not fetch digest settings: %w", err)
+	}
+	if _, err := r.q.UpsertDigestSettings(ctx, database.UpsertDigestSettingsParams{
+		UserID:    userID,
+		CreatedAt: r.now,
+		Email:     d.Email,
+		Folder:    ptrString(d.Folder),
+		Tag:       ptrString(d.Tag),
+	}); err != nil {
+		return fmt.Errorf("could not restore digest settings: %w", err)
+	}
+	r.count(recordDigest, true)
+	return nil
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/isaacjstriker/gatorapp/internal/database"
+)
+
+func TestBackupRoundTrip(t *testing.T) {
+	for _, b := range testBackends(t) {
+		t.Run(b.name, func(t *testing.T) {
+			const previousURL = "https://old.example.com/feed.xml"
+			archive := filepath.Join(t.TempDir(), "backup.jsonl.gz")
+			ctx := context.Background()
+
+			s := newTestState(t, b)
+			mustRun(t, s,
+				[]string{"register", "alice"},
+				[]string{"addfeed", "Example", testFeedURL},
+				[]string{"register", "bob"},
+			)
+			feed, err := s.Queries.GetFeedByURL(ctx, testFeedURL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := s.Queries.AddFeedURLHistory(ctx, database.AddFeedURLHistoryParams{
+				ID:        uuid.New(),
+				CreatedAt: time.Now().UTC(),
+				Url:       previousURL,
+				FeedID:    feed.ID,
+			}); err != nil {
+				t.Fatal(err)
+			}
+			mustRun(t, s, []string{"login", "alice"}, []string{"backup", archive})
+
+			restored := newTestState(t, b)
+			mustRun(t, restored, []string{"restore", archive})
+
+			for name, role := range map[string]string{"alice": roleAdmin, "bob": roleUser} {
+				user, err := restored.Queries.GetUser(ctx, name)
+				if err != nil {
+					t.Fatalf("%s wasn't restored: %v", name, err)
+				}
+				if user.Role != role {
+					t.Errorf("%s restored as %s, want %s", name, user.Role, role)
+				}
+			}
+			moved, err := restored.Queries.GetFeedByPreviousURL(ctx, previousURL)
+			if err != nil {
+				t.Fatalf("previous url wasn't restored: %v", err)
+			}
+			if moved.Url != testFeedURL {
+				t.Errorf("previous url leads to %s, want %s", moved.Url, testFeedURL)
+			}
+		})
+	}
+}
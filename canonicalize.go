@@ -0,0 +1,205 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/isaacjstriker/gatorapp/internal/canonurl"
+	"github.com/isaacjstriker/gatorapp/internal/database"
+)
+
+// handlerCanonicalize rewrites every stored feed and post URL into its
+// canonical form, merging feeds that turn out to be the same feed. The
+// oldest feed in each group survives and inherits the others' follows and
+// posts.
+func handlerCanonicalize(s *State, _ database.User, cmd Command) error {
+	ctx := context.Background()
+
+	var mergedFeeds, rewrittenFeeds, droppedPosts, rewrittenPosts int
+	err := s.Queries.InTx(ctx, func(qtx database.Querier) error {
+		var err error
+		mergedFeeds, rewrittenFeeds, err = canonicalizeFeeds(ctx, qtx)
+		if err != nil {
+			return err
+		}
+		droppedPosts, rewrittenPosts, err = canonicalizePosts(ctx, qtx)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Feeds: %d merged, %d rewritten\n", mergedFeeds, rewrittenFeeds)
+	fmt.Printf("Posts: %d duplicates removed, %d rewritten\n", droppedPosts, rewrittenPosts)
+	return nil
+}
+
+func canonicalizeFeeds(ctx context.Context, q database.Querier) (merged, rewritten int, err error) {
+	feeds, err := q.GetFeeds(ctx)
+	if err != nil {
+		return 0, 0, fmt.Errorf("could not fetch feeds: %w", err)
+	}
+
+	// Feeds come back oldest first, so the first feed seen for a key is the
+	// one that survives.
+	survivors := make(map[string]database.Feed)
+	var order []string
+	for _, feed := range feeds {
+		key, err := canonurl.Key(feed.Url)
+		if err != nil {
+			feedLog(feed).Warn("skipping feed with invalid url", "error", err)
+			continue
+		}
+		survivor, ok := survivors[key]
+		if !ok {
+			survivors[key] = feed
+			order = append(order, key)
+			continue
+		}
+		if err := mergeFeed(ctx, q, feed, survivor); err != nil {
+			return 0, 0, fmt.Errorf("could not merge feed %s into %s: %w", feed.Url, survivor.Url, err)
+		}
+		merged++
+	}
+
+	// Duplicates are gone, so rewriting the survivors can no longer collide
+	// with the unique constraint on feeds.url.
+	for _, key := range order {
+		feed := survivors[key]
+		canonical, err := canonurl.Canonicalize(feed.Url)
+		if err != nil || canonical == feed.Url {
+			continue
+		}
+		if err := q.UpdateFeedURL(ctx, database.UpdateFeedURLParams{
+			ID:        feed.ID,
+			Url:       canonical,
+			UpdatedAt: time.Now().UTC(),
+		}); err != nil {
+			return 0, 0, fmt.Errorf("could not rewrite feed url %s: %w", feed.Url, err)
+		}
+		rewritten++
+	}
+	return merged, rewritten, nil
+}
+
+// mergeFeed folds from into to and deletes from. Follows, posts, URL
+// history, filter rules and notification rules move over. Where a user
+// follows both feeds, their follow of from fills in the settings and tags
+// their follow of to lacks, and so do from's retention limits. from's URL is
+// remembered as a previous URL of to.
+func mergeFeed(ctx context.Context, q database.Querier, from, to database.Feed) error {
+	now := time.Now().UTC()
+	if err := q.MergeFeedFollowSettings(ctx, database.MergeFeedFollowSettingsParams{
+		UpdatedAt:  now,
+		ToFeedID:   to.ID,
+		FromFeedID: from.ID,
+	}); err != nil {
+		return err
+	}
+	if err := q.MergeFeedFollowTags(ctx, database.MergeFeedFollowTagsParams{
+		FromFeedID: from.ID,
+		ToFeedID:   to.ID,
+	}); err != nil {
+		return err
+	}
+	if err := q.MoveFeedFollows(ctx, database.MoveFeedFollowsParams{
+		UpdatedAt:  now,
+		FromFeedID: from.ID,
+		ToFeedID:   to.ID,
+	}); err != nil {
+		return err
+	}
+	if err := q.MovePosts(ctx, database.MovePostsParams{
+		UpdatedAt:  now,
+		FromFeedID: from.ID,
+		ToFeedID:   to.ID,
+	}); err != nil {
+		return err
+	}
+	if err := q.MoveFeedURLHistory(ctx, database.MoveFeedURLHistoryParams{
+		FromFeedID: from.ID,
+		ToFeedID:   to.ID,
+	}); err != nil {
+		return err
+	}
+	if err := q.MergeFeedRetention(ctx, database.MergeFeedRetentionParams{
+		UpdatedAt:  now,
+		ToFeedID:   to.ID,
+		FromFeedID: from.ID,
+	}); err != nil {
+		return err
+	}
+	if err := q.MoveFilterRules(ctx, database.MoveFilterRulesParams{
+		FromFeedID: uuid.NullUUID{UUID: from.ID, Valid: true},
+		ToFeedID:   uuid.NullUUID{UUID: to.ID, Valid: true},
+	}); err != nil {
+		return err
+	}
+	if err := q.MoveNotificationRules(ctx, database.MoveNotificationRulesParams{
+		FromFeedID: uuid.NullUUID{UUID: from.ID, Valid: true},
+		ToFeedID:   uuid.NullUUID{UUID: to.ID, Valid: true},
+	}); err != nil {
+		return err
+	}
+	// Follows left behind belong to users already following the survivor,
+	// whose settings and tags were merged above, and go away with the feed.
+	if err := q.DeleteFeed(ctx, from.ID); err != nil {
+		return err
+	}
+
+	previousURL, err := canonurl.Canonicalize(from.Url)
+	if err != nil {
+		previousURL = from.Url
+	}
+	return q.AddFeedURLHistory(ctx, database.AddFeedURLHistoryParams{
+		ID:        uuid.New(),
+		CreatedAt: now,
+		Url:       previousURL,
+		FeedID:    to.ID,
+	})
+}
+
+func canonicalizePosts(ctx context.Context, q database.Querier) (dropped, rewritten int, err error) {
+	posts, err := q.GetPostURLs(ctx)
+	if err != nil {
+		return 0, 0, fmt.Errorf("could not fetch posts: %w", err)
+	}
+
+	type pending struct {
+		id  uuid.UUID
+		url string
+	}
+	seen := make(map[string]bool)
+	var updates []pending
+	for _, post := range posts {
+		canonical, err := canonurl.Canonicalize(post.Url)
+		if err != nil {
+			continue
+		}
+		if seen[canonical] {
+			if err := q.DeletePost(ctx, post.ID); err != nil {
+				return 0, 0, fmt.Errorf("could not remove duplicate post %s: %w", post.Url, err)
+			}
+			dropped++
+			continue
+		}
+		seen[canonical] = true
+		if canonical != post.Url {
+			updates = append(updates, pending{id: post.ID, url: canonical})
+		}
+	}
+
+	for _, update := range updates {
+		if err := q.UpdatePostURL(ctx, database.UpdatePostURLParams{
+			ID:        update.id,
+			Url:       update.url,
+			UpdatedAt: time.Now().UTC(),
+		}); err != nil {
+			return 0, 0, fmt.Errorf("could not rewrite post url %s: %w", update.url, err)
+		}
+		rewritten++
+	}
+	return dropped, rewritten, nil
+}
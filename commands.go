@@ -4,200 +4,598 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"flag"
 	"fmt"
-	"log"
+	"io"
+	"log/slog"
 	"os"
-	"time"
-	"strings"
 	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/isaacjstriker/gatorapp/internal/canonurl"
 	"github.com/isaacjstriker/gatorapp/internal/config"
 	"github.com/isaacjstriker/gatorapp/internal/database"
+	"github.com/isaacjstriker/gatorapp/internal/filter"
+	"github.com/isaacjstriker/gatorapp/internal/metrics"
+	"github.com/isaacjstriker/gatorapp/internal/schedule"
 )
 
 type State struct {
 	Config  *config.Config
-	DB      *sql.DB
-	Queries *database.Queries
+	Queries database.Store
+	Fetcher *Fetcher
+	Metrics *metrics.Metrics
 }
 
 type Command struct {
-	name string
-	args []string
+	name  string
+	args  []string
+	flags *flag.FlagSet
+}
+
+// commandSpec describes a command: how it is documented, which arguments and
+// flags it accepts, and the handler that runs it.
+type commandSpec struct {
+	name        string
+	description string
+	// usage describes the positional arguments, e.g. "<name> <url>".
+	usage   string
+	minArgs int
+	// maxArgs is the most positional arguments accepted, or -1 for no limit.
+	maxArgs int
+	// flags defines the command's flags on its flag set. Optional.
+	flags   func(fs *flag.FlagSet)
+	handler func(*State, Command) error
+	// completeArgs lists values shell completion offers for positional
+	// arguments. Optional; completion falls back to file names.
+	completeArgs func() []string
+	// offline commands run without a config file or database connection.
+	offline bool
+	// configOnly commands get the config but no database connection.
+	configOnly bool
+	// subcommands, when present, are selected by the first argument, as in
+	// "folder add". Each has its own arguments, flags and handler.
+	subcommands []commandSpec
+}
+
+// usageLine is the one-line synopsis shown in usage errors and help.
+func (spec *commandSpec) usageLine() string {
+	line := spec.name
+	if spec.flags != nil {
+		line += " [flags]"
+	}
+	switch {
+	case spec.usage != "":
+		line += " " + spec.usage
+	case len(spec.subcommands) > 0:
+		line += " <" + strings.Join(spec.completions(), "|") + "> [arguments...]"
+	}
+	return line
+}
+
+// subcommand returns the named subcommand, or nil if there is none. Its name
+// is prefixed with the parent's so usage lines read in full.
+func (spec *commandSpec) subcommand(name string) *commandSpec {
+	for _, sub := range spec.subcommands {
+		if sub.name == name {
+			sub.name = spec.name + " " + sub.name
+			sub.offline = spec.offline
+			sub.configOnly = spec.configOnly
+			return &sub
+		}
+	}
+	return nil
+}
+
+// completions lists the words shell completion offers for the first
+// positional argument.
+func (spec *commandSpec) completions() []string {
+	if spec.completeArgs != nil {
+		return spec.completeArgs()
+	}
+	var names []string
+	for _, sub := range spec.subcommands {
+		names = append(names, sub.name)
+	}
+	return names
+}
+
+// flagSet returns a fresh flag set with the command's flags defined.
+func (spec *commandSpec) flagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet(spec.name, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	if spec.flags != nil {
+		spec.flags(fs)
+	}
+	return fs
 }
 
 type Commands struct {
-	handlers map[string]func(*State, Command) error
+	specs map[string]*commandSpec
+	// order keeps commands in registration order for help output.
+	order []string
+	// global holds the flags accepted before the command name.
+	global *flag.FlagSet
+}
+
+func newCommands(global *flag.FlagSet) *Commands {
+	return &Commands{
+		specs:  make(map[string]*commandSpec),
+		global: global,
+	}
+}
+
+// flagValue returns the parsed value of one of the command's flags.
+func (cmd Command) flagValue(name string) any {
+	f := cmd.flags.Lookup(name)
+	if f == nil {
+		panic("undefined flag " + name + " for command " + cmd.name)
+	}
+	return f.Value.(flag.Getter).Get()
+}
+
+func (cmd Command) flagString(name string) string {
+	return cmd.flagValue(name).(string)
+}
+
+func (cmd Command) flagBool(name string) bool {
+	return cmd.flagValue(name).(bool)
+}
+
+func (cmd Command) flagInt(name string) int {
+	return cmd.flagValue(name).(int)
+}
+
+func (cmd Command) flagDuration(name string) time.Duration {
+	return cmd.flagValue(name).(time.Duration)
+}
+
+// flagGiven reports whether a flag was set on the command line.
+func (cmd Command) flagGiven(name string) bool {
+	given := false
+	cmd.flags.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			given = true
+		}
+	})
+	return given
 }
 
 type UserHandler func(s *State, user database.User, cmd Command) error
 
 // Middleware fucntion, allowing us to skip verification in each function
+// It records the user as active once the handler has run.
 func requireLogin(handler UserHandler) func(s *State, cmd Command) error {
 	return func(s *State, cmd Command) error {
-		user, err := s.Queries.GetUser(context.Background(), s.Config.CurrentUsername)
+		user, err := currentUser(s)
 		if err != nil {
-			fmt.Printf("could not find current username: %s\n", err)
-			os.Exit(1)
+			return err
+		}
+		err = handler(s, user, cmd)
+		recordActivity(s, user)
+		return err
+	}
+}
+
+// requireAdmin is requireLogin for commands that only admins may run.
+func requireAdmin(handler UserHandler) func(s *State, cmd Command) error {
+	return requireLogin(func(s *State, user database.User, cmd Command) error {
+		if !isAdmin(user) {
+			return &ForbiddenError{Reason: "only admins may run " + cmd.name}
 		}
 		return handler(s, user, cmd)
+	})
+}
+
+// requireAdminUnlessEmpty is requireAdmin, except that anyone may run the
+// command on a database with no users yet, where there is nobody to be an
+// admin. The handler then gets the zero User.
+func requireAdminUnlessEmpty(handler UserHandler) func(s *State, cmd Command) error {
+	asAdmin := requireAdmin(handler)
+	return func(s *State, cmd Command) error {
+		counts, err := s.Queries.CountAllData(context.Background())
+		if err != nil {
+			return fmt.Errorf("could not count users: %w", err)
+		}
+		if counts.Users == 0 {
+			return handler(s, database.User{}, cmd)
+		}
+		return asAdmin(s, cmd)
 	}
 }
 
-func handlerLogin(s *State, cmd Command) error {
-	if len(cmd.args) == 0 {
-		fmt.Println("No username provided")
-		os.Exit(1)
+// currentUser looks up the logged in user, who must still exist and not be
+// disabled.
+func currentUser(s *State) (database.User, error) {
+	if s.Config.CurrentUsername == "" {
+		return database.User{}, &UnauthenticatedError{Reason: "run login or register first"}
+	}
+	user, err := s.Queries.GetUser(context.Background(), s.Config.CurrentUsername)
+	if errors.Is(err, sql.ErrNoRows) {
+		return user, &UnauthenticatedError{Reason: fmt.Sprintf("current user %q no longer exists", s.Config.CurrentUsername)}
+	}
+	if err != nil {
+		return user, fmt.Errorf("could not find current user: %w", err)
 	}
+	if user.DisabledAt.Valid {
+		return user, &ForbiddenError{Reason: fmt.Sprintf("user %q is disabled", user.Name)}
+	}
+	return user, nil
+}
 
+func handlerLogin(s *State, cmd Command) error {
 	username := cmd.args[0]
-	_, err := s.Queries.GetUser(context.Background(), username)
+	user, err := s.Queries.GetUser(context.Background(), username)
+	if errors.Is(err, sql.ErrNoRows) {
+		return &NotFoundError{Kind: "user", Name: username}
+	}
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			fmt.Printf("User %s does not exist\n", username)
-			os.Exit(1)
-		}
-		fmt.Printf("Error checking user: %s\n", username)
-		os.Exit(1)
+		return fmt.Errorf("could not check user %s: %w", username, err)
 	}
-	s.Config.CurrentUsername = username
-
-	if err := config.Write(*s.Config); err != nil {
+	if user.DisabledAt.Valid {
+		return &ForbiddenError{Reason: fmt.Sprintf("user %q is disabled", username)}
+	}
+	if err := s.Config.SetUser(username); err != nil {
 		return fmt.Errorf("failed to write config: %w", err)
 	}
+	recordActivity(s, user)
 
 	fmt.Println("You have been logged in")
 	return nil
 }
 
-func (c *Commands) run(s *State, cmd Command) error {
-	handler, exists := c.handlers[cmd.name]
+// lookup returns the named command, or a UsageError if there is none.
+func (c *Commands) lookup(name string) (*commandSpec, error) {
+	spec, exists := c.specs[name]
 	if !exists {
-		return errors.New("unknown command: " + cmd.name)
+		return nil, &UsageError{Usage: "gatorapp <command> [arguments...]", Reason: "unknown command: " + name + " (see gatorapp help)"}
 	}
-	return handler(s, cmd)
+	return spec, nil
 }
 
-func (c *Commands) register(name string, f func(*State, Command) error) {
-	c.handlers[name] = f
+// parse resolves the command, parses its flags and checks its arguments.
+// -h and --help print the command's help and return flag.ErrHelp.
+func (c *Commands) parse(cmd Command) (*commandSpec, Command, error) {
+	spec, err := c.lookup(cmd.name)
+	if err != nil {
+		return nil, cmd, err
+	}
+	return c.parseArgs(spec, cmd)
 }
 
-func handlerRegister(s *State, cmd Command) error {
-	if len(cmd.args) < 1 || cmd.args[0] == "" {
-		return fmt.Errorf("not a valid name")
+func (c *Commands) parseArgs(spec *commandSpec, cmd Command) (*commandSpec, Command, error) {
+	if len(spec.subcommands) > 0 {
+		if len(cmd.args) == 0 {
+			return nil, cmd, &UsageError{Usage: spec.usageLine(), Reason: "missing subcommand"}
+		}
+		switch cmd.args[0] {
+		case "-h", "-help", "--help":
+			c.printCommandHelp(os.Stdout, spec)
+			return nil, cmd, flag.ErrHelp
+		}
+		sub := spec.subcommand(cmd.args[0])
+		if sub == nil {
+			return nil, cmd, &UsageError{Usage: spec.usageLine(), Reason: "unknown subcommand: " + cmd.args[0]}
+		}
+		cmd.args = cmd.args[1:]
+		return c.parseArgs(sub, cmd)
+	}
+
+	fs := spec.flagSet()
+	if err := fs.Parse(cmd.args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			c.printCommandHelp(os.Stdout, spec)
+			return nil, cmd, err
+		}
+		return nil, cmd, &UsageError{Usage: spec.usageLine(), Reason: err.Error()}
+	}
+	cmd.flags = fs
+	cmd.args = fs.Args()
+
+	switch {
+	case len(cmd.args) < spec.minArgs:
+		return nil, cmd, &UsageError{Usage: spec.usageLine(), Reason: "not enough arguments"}
+	case spec.maxArgs >= 0 && len(cmd.args) > spec.maxArgs:
+		return nil, cmd, &UsageError{Usage: spec.usageLine(), Reason: "too many arguments"}
+	}
+	return spec, cmd, nil
+}
+
+func (c *Commands) register(spec commandSpec) {
+	if _, exists := c.specs[spec.name]; !exists {
+		c.order = append(c.order, spec.name)
 	}
+	c.specs[spec.name] = &spec
+}
 
+func handlerRegister(s *State, cmd Command) error {
 	name := cmd.args[0]
+	if name == "" {
+		return &UsageError{Usage: "register <username>", Reason: "not a valid name"}
+	}
 
 	// Check if a user exists
 	_, err := s.Queries.GetUser(context.Background(), name)
 	if err == nil {
-		fmt.Printf("User '%s' already exists", name)
-		os.Exit(1)
+		return &ConflictError{Message: fmt.Sprintf("user %q already exists", name)}
 	}
-	if err != nil && !errors.Is(err, sql.ErrNoRows) {
-		fmt.Printf("Error checking user: %s", err)
-		os.Exit(1)
+	if !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("could not check user %s: %w", name, err)
 	}
 
-	id := uuid.New()
-	now := time.Now()
-
-	_, err = s.Queries.CreateUser(context.Background(), database.CreateUserParams{
-		ID:        id,
-		CreatedAt: now,
-		UpdatedAt: now,
-		Name:      name,
-	})
+	now := time.Now().UTC()
+	user, err := registerUser(context.Background(), s.Queries, name, now)
+	if isUniqueViolation(err) {
+		return &ConflictError{Message: fmt.Sprintf("user %q already exists", name)}
+	}
 	if err != nil {
 		return fmt.Errorf("failed to register user: %w", err)
 	}
 
-	s.Config.CurrentUsername = name
-	if err := config.Write(*s.Config); err != nil {
+	if err := s.Config.SetUser(name); err != nil {
 		return fmt.Errorf("failed to write config: %w", err)
 	}
-	fmt.Printf("User '%s' successfully registered!", name)
+	recordActivity(s, user)
+	fmt.Printf("User '%s' successfully registered!\n", name)
+	if user.Role == roleAdmin {
+		fmt.Println("As the first user, you are an admin.")
+	}
 
-	log.Printf("[DEBUG] Registered user: ID=%s, Name=%s, CreatedAt=%s, UpdatedAt=%s\n",
-		id.String(), name, now.Format(time.RFC3339), now.Format(time.RFC3339))
+	slog.Debug("registered user", "user_id", user.ID, "user_name", name, "created_at", now)
 
 	return nil
 }
 
-func handlerReset(s *State, cmd Command) error {
-
-	err := s.Queries.DelUsers(context.Background())
-	if err != nil {
-		fmt.Printf("failed to delete users: %s", err)
-		os.Exit(1)
-	}
-	fmt.Println("Users deleted successfully")
-	return nil
+// registerUser adds a user, as an admin if they are the first. Users are
+// locked while it does, so only one of two people registering at once can be
+// first.
+func registerUser(ctx context.Context, store database.Store, name string, now time.Time) (database.User, error) {
+	var user database.User
+	err := store.InTx(ctx, func(qtx database.Querier) error {
+		if err := qtx.LockUsers(ctx); err != nil {
+			return err
+		}
+		var err error
+		user, err = qtx.RegisterUser(ctx, database.RegisterUserParams{
+			ID:        uuid.New(),
+			CreatedAt: now,
+			UpdatedAt: now,
+			Name:      name,
+		})
+		return err
+	})
+	return user, err
 }
 
-func handlerUsers(s *State, cmd Command) error {
-	users, err := s.Queries.GetUsers(context.Background())
-	if err != nil {
-		fmt.Printf("failed to fetch users: %s", err)
-		os.Exit(1)
+// maxAggSleep caps how long agg sleeps between rounds, so feeds added while
+// it runs are picked up reasonably quickly.
+const maxAggSleep = 5 * time.Minute
+
+// minAggRetry is how long agg waits after a round the database failed,
+// doubling while it keeps failing. Without it, feeds that couldn't be
+// marked fetched stay due and agg would retry them at once, forever.
+const minAggRetry = 5 * time.Second
+
+// recentPostWindow is how many recent posts the scheduler looks at to
+// estimate a feed's posting frequency.
+const recentPostWindow = 20
+
+func handlerAgg(s *State, cmd Command) error {
+	if len(cmd.args) == 1 {
+		minInterval, err := time.ParseDuration(cmd.args[0])
+		if err != nil {
+			return &UsageError{Usage: cmd.name + " [min_time_between_reqs]", Reason: "invalid duration: " + err.Error()}
+		}
+		s.Config.Schedule.MinInterval.Duration = minInterval
 	}
-	if len(users) == 0 {
-		fmt.Println("No users found")
-		return nil
+
+	lo, hi := schedulePolicy(s).Bounds()
+	slog.Info("collecting feeds", "min_interval", lo, "max_interval", hi)
+
+	// The HTTP server, and with it /metrics, only runs when asked for:
+	// without server.listen_addr or WebSub, agg serves nothing.
+	if s.Config.Server.ListenAddr != "" || s.Config.WebSub.Enabled() {
+		go serveAgg(s)
 	}
-	fmt.Println("Users:")
-	for _, user := range users {
-		if user.Name == s.Config.CurrentUsername {
-			fmt.Printf("* %s (current)\n", user.Name)
+
+	var lastPrune time.Time
+	failedRounds := 0
+	for {
+		renewWebSubSubscriptions(s)
+		dbFailed := !scrapeFeeds(s)
+		deliverNotifications(s)
+		sendDueDigests(s)
+		lastPrune = pruneIfDue(s, lastPrune)
+
+		wait, err := untilNextFetch(s)
+		if err != nil {
+			slog.Error("couldn't get next fetch time", "error", err)
+			wait = maxAggSleep
+		}
+		if dbFailed {
+			failedRounds++
+			wait = max(wait, aggRetryDelay(failedRounds))
+		} else {
+			failedRounds = 0
+		}
+		if wait > 0 {
+			time.Sleep(wait)
 		}
-		fmt.Printf("* %s\n", user.Name)
 	}
-	return nil
 }
 
-func handlerAgg(s *State, cmd Command) error {
-	if len(cmd.args) < 1 || len(cmd.args) > 2 {
-		return fmt.Errorf("usage: %v <time_between_reqs>", cmd.name)
+func schedulePolicy(s *State) schedule.Policy {
+	return schedule.Policy{
+		MinInterval: s.Config.Schedule.MinInterval.Duration,
+		MaxInterval: s.Config.Schedule.MaxInterval.Duration,
+	}
+}
+
+// aggRetryDelay is how long agg waits after the database has failed
+// failedRounds rounds in a row.
+func aggRetryDelay(failedRounds int) time.Duration {
+	delay := minAggRetry
+	for i := 1; i < failedRounds && delay < maxAggSleep; i++ {
+		delay *= 2
 	}
+	return min(delay, maxAggSleep)
+}
 
-	timeBetweenRequests, err := time.ParseDuration(cmd.args[0])
+// untilNextFetch returns how long agg can sleep before a feed is due.
+func untilNextFetch(s *State) (time.Duration, error) {
+	next, err := s.Queries.GetEarliestNextFetch(context.Background())
+	if errors.Is(err, sql.ErrNoRows) {
+		return maxAggSleep, nil
+	}
 	if err != nil {
-		return fmt.Errorf("invalid duration: %w", err)
+		return 0, err
 	}
+	if !next.Valid {
+		return 0, nil
+	}
+	return min(time.Until(next.Time), maxAggSleep), nil
+}
 
-	log.Printf("Collecting feeds every %s...", timeBetweenRequests)
+// scrapeFeeds fetches a batch of due feeds concurrently. The fetcher's host
+// limiter keeps feeds that share a host from being fetched all at once. It
+// reports false if the database failed before a feed could be fetched.
+func scrapeFeeds(s *State) bool {
+	feeds, err := s.Queries.GetNextFeedsToFetch(context.Background(), database.GetNextFeedsToFetchParams{
+		Now:      time.Now().UTC(),
+		MaxFeeds: int32(s.Config.Fetcher.ConcurrencyOrDefault()),
+	})
+	if err != nil {
+		slog.Error("couldn't get next feeds to fetch", "error", err)
+		return false
+	}
+	if len(feeds) == 0 {
+		return true
+	}
+	slog.Debug("found feeds to fetch", "count", len(feeds))
 
-	ticker := time.NewTicker(timeBetweenRequests)
+	var wg sync.WaitGroup
+	var failed atomic.Bool
+	for _, feed := range feeds {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if !scrapeFeed(s, feed) {
+				failed.Store(true)
+			}
+		}()
+	}
+	wg.Wait()
+	return !failed.Load()
+}
 
-	for ; ; <-ticker.C {
-		scrapeFeeds(s)
+// scheduleFeed works out when feed should next be fetched from its posting
+// history, its recent fetch failures and the hints it publishes.
+func scheduleFeed(s *State, feed database.Feed, failures int, hints schedule.Hints) {
+	postTimes, err := s.Queries.GetRecentPostTimes(context.Background(), database.GetRecentPostTimesParams{
+		FeedID: feed.ID,
+		Limit:  recentPostWindow,
+	})
+	if err != nil {
+		feedLog(feed).Error("couldn't get recent posts", "error", err)
 	}
+	next := schedulePolicy(s).Next(time.Now().UTC(), postTimes, failures, hints)
+	feedLog(feed).Debug("scheduled next fetch", "next_fetch_at", next, "failures", failures)
+	scheduleNextFetch(s, feed, next)
 }
 
-func scrapeFeeds(s *State) {
-	feed, err := s.Queries.GetNextFeedToFetch(context.Background())
+// recordFetchFailure counts a failed fetch and backs the feed off.
+func recordFetchFailure(s *State, feed database.Feed) {
+	failures, err := s.Queries.RecordFeedFetchError(context.Background(), feed.ID)
 	if err != nil {
-		log.Println("Couldn't get next feeds to fetch", err)
-		return
+		feedLog(feed).Error("couldn't record fetch error", "error", err)
+		failures = feed.FetchErrorCount + 1
 	}
-	log.Println("Found a feed to fetch!")
-	scrapeFeed(s.Queries, feed)
+	scheduleFeed(s, feed, int(failures), schedule.Hints{})
 }
 
-func scrapeFeed(db *database.Queries, feed database.Feed) {
-	_, err := db.MarkFeedFetched(context.Background(), feed.ID)
+// scheduleNextFetch stores when feed may next be fetched.
+func scheduleNextFetch(s *State, feed database.Feed, next time.Time) {
+	err := s.Queries.SetFeedNextFetch(context.Background(), database.SetFeedNextFetchParams{
+		ID:          feed.ID,
+		NextFetchAt: sql.NullTime{Time: next.UTC(), Valid: true},
+	})
 	if err != nil {
-		log.Printf("Couldn't mark feed %s fetched: %v", feed.Name, err)
-		return
+		feedLog(feed).Error("couldn't schedule next fetch", "error", err)
 	}
+}
 
-	feedData, err := fetchFeed(context.Background(), feed.Url)
+// feedLog returns a logger carrying the fields that identify feed.
+func feedLog(feed database.Feed) *slog.Logger {
+	return slog.With("feed_id", feed.ID, "feed_url", feed.Url, "feed_name", feed.Name)
+}
+
+// scrapeFeed fetches one feed and saves its posts. It reports false only if
+// the feed couldn't be marked fetched, which leaves it due.
+func scrapeFeed(s *State, feed database.Feed) bool {
+	db := s.Queries
+	start := time.Now()
+	_, err := db.MarkFeedFetched(context.Background(), database.MarkFeedFetchedParams{
+		ID:            feed.ID,
+		LastFetchedAt: sql.NullTime{Time: start.UTC(), Valid: true},
+	})
 	if err != nil {
-		log.Printf("Couldn't collect feed %s: %v", feed.Name, err)
-		return
+		feedLog(feed).Error("couldn't mark feed fetched", "error", err)
+		return false
+	}
+
+	feedData, movedTo, err := s.Fetcher.Fetch(context.Background(), feed.Url)
+	if errors.Is(err, errFeedGone) {
+		feedLog(feed).Warn("feed is gone, deactivating it", "duration", time.Since(start))
+		if err := db.DeactivateFeed(context.Background(), database.DeactivateFeedParams{
+			ID:            feed.ID,
+			DeactivatedAt: sql.NullTime{Time: time.Now().UTC(), Valid: true},
+		}); err != nil {
+			feedLog(feed).Error("couldn't deactivate feed", "error", err)
+		}
+		unsubscribeFeed(s, feed)
+		return true
+	}
+	// Only a response tells us whether the feed is still redirected; a
+	// network error says nothing either way.
+	if movedTo != "" || (err == nil && feed.RedirectUrl.Valid) {
+		if moved, err := trackFeedMove(s, feed, movedTo); err != nil {
+			feedLog(feed).Error("couldn't record redirect", "moved_to", movedTo, "error", err)
+		} else {
+			feed = moved
+		}
 	}
+	var retryAfter *retryAfterError
+	if errors.As(err, &retryAfter) {
+		feedLog(feed).Warn("feed is rate limited", "retry_at", retryAfter.until, "duration", time.Since(start))
+		scheduleNextFetch(s, feed, retryAfter.until)
+		return true
+	}
+	if err != nil {
+		feedLog(feed).Error("couldn't collect feed", "error", err, "duration", time.Since(start))
+		recordFetchFailure(s, feed)
+		return true
+	}
+	if err := db.ResetFeedFetchErrors(context.Background(), feed.ID); err != nil {
+		feedLog(feed).Error("couldn't reset fetch errors", "error", err)
+	}
+
+	savePosts(s, feed, feedData)
+	forgetPrunedPosts(s, feed, feedData)
+	maybeSubscribe(s, feed, feedData)
+	maybeFetchFavicon(s, feed, feedData)
+	feedLog(feed).Info("feed collected", "posts", len(feedData.Channel.Item), "duration", time.Since(start))
+	scheduleFeed(s, feed, 0, feedData.Hints())
+	return true
+}
+
+// savePosts stores the feed's items as posts, skipping ones already saved.
+// Both polling and WebSub pushes go through here.
+func savePosts(s *State, feed database.Feed, feedData *RSSFeed) {
+	var inserted []database.Post
 	for _, item := range feedData.Channel.Item {
 		publishedAt := sql.NullTime{}
 		if t, err := time.Parse(time.RFC1123Z, item.PubDate); err == nil {
@@ -207,7 +605,18 @@ func scrapeFeed(db *database.Queries, feed database.Feed) {
 			}
 		}
 
-		_, err = db.CreatePost(context.Background(), database.CreatePostParams{
+		postURL := itemURL(item)
+		if tooOldToKeep(s, feed, publishedAt) {
+			continue
+		}
+		if pruned, err := s.Queries.PostWasPruned(context.Background(), postURL); err != nil {
+			feedLog(feed).Error("couldn't check for pruned post", "post_url", postURL, "error", err)
+		} else if pruned {
+			s.Metrics.ObservePost(metrics.PostPruned)
+			continue
+		}
+
+		post, err := s.Queries.CreatePost(context.Background(), database.CreatePostParams{
 			ID:        uuid.New(),
 			CreatedAt: time.Now().UTC(),
 			UpdatedAt: time.Now().UTC(),
@@ -217,36 +626,67 @@ func scrapeFeed(db *database.Queries, feed database.Feed) {
 				String: item.Description,
 				Valid:  true,
 			},
-			Url:         item.Link,
+			Url:         postURL,
 			PublishedAt: publishedAt,
+			Author:      strings.TrimSpace(item.AuthorName()),
+			Categories:  itemCategories(item),
 		})
 		if err != nil {
-			if strings.Contains(err.Error(), "duplicate key value violates unique constraint") {
+			// CreatePost skips posts whose URL is already saved.
+			if errors.Is(err, sql.ErrNoRows) {
+				s.Metrics.ObservePost(metrics.PostDuplicate)
 				continue
 			}
-			log.Printf("Couldn't create post: %v", err)
+			s.Metrics.ObservePost(metrics.PostError)
+			feedLog(feed).Error("couldn't create post", "post_url", postURL, "error", err)
 			continue
 		}
+		s.Metrics.ObservePost(metrics.PostInserted)
+		inserted = append(inserted, post)
+	}
+
+	if len(inserted) > 0 {
+		applyRulesToNewPosts(s, feed, inserted)
+		queueNotifications(s, feed, inserted)
 	}
-	log.Printf("Feed %s collected, %v posts found", feed.Name, len(feedData.Channel.Item))
 }
 
-func handlerAddFeed(s *State, user database.User, cmd Command) error {
-	if len(cmd.args) < 2 {
-		fmt.Println("Usage: addfeed <name> <url>")
-		os.Exit(1)
+// itemURL returns the URL a post is saved under for item.
+func itemURL(item RSSItem) string {
+	postURL, err := canonurl.Canonicalize(item.Link)
+	if err != nil {
+		return strings.TrimSpace(item.Link)
 	}
-	feedName := cmd.args[0]
-	feedURL := cmd.args[1]
+	return postURL
+}
 
-	user, err := s.Queries.GetUser(context.Background(), s.Config.CurrentUsername)
+// itemCategories returns the item's non-empty categories. It never returns
+// nil, since posts.categories is NOT NULL.
+func itemCategories(item RSSItem) []string {
+	categories := []string{}
+	for _, c := range item.Categories {
+		if c = strings.TrimSpace(c); c != "" {
+			categories = append(categories, c)
+		}
+	}
+	return categories
+}
+
+func handlerAddFeed(s *State, user database.User, cmd Command) error {
+	feedName := cmd.args[0]
+	feedURL, err := canonurl.Canonicalize(cmd.args[1])
 	if err != nil {
-		fmt.Printf("could not find current username: %s", err)
-		os.Exit(1)
+		return &UsageError{Usage: "addfeed <name> <url>", Reason: "invalid feed url: " + err.Error()}
+	}
+
+	if existing, err := lookupFeed(s, feedURL); err == nil {
+		return &ConflictError{Message: fmt.Sprintf("feed already exists as %s, use follow instead", existing.Url)}
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("could not check for existing feed: %w", err)
 	}
 
 	id := uuid.New()
-	now := time.Now()
+	now := time.Now().UTC()
 	feed, err := s.Queries.CreateFeed(context.Background(), database.CreateFeedParams{
 		ID:        id,
 		CreatedAt: now,
@@ -255,9 +695,11 @@ func handlerAddFeed(s *State, user database.User, cmd Command) error {
 		Url:       feedURL,
 		UserID:    user.ID,
 	})
+	if isUniqueViolation(err) {
+		return &ConflictError{Message: fmt.Sprintf("feed %s already exists", feedURL)}
+	}
 	if err != nil {
-		fmt.Printf("could not create feed: %s", err)
-		os.Exit(1)
+		return fmt.Errorf("could not create feed: %w", err)
 	}
 
 	followID := uuid.New()
@@ -269,8 +711,7 @@ func handlerAddFeed(s *State, user database.User, cmd Command) error {
 		FeedID:    feed.ID,
 	})
 	if err != nil {
-		fmt.Printf("could not create feed follow: %s\n", err)
-		os.Exit(1)
+		return fmt.Errorf("could not create feed follow: %w", err)
 	}
 
 	fmt.Printf("Feed created:\n")
@@ -285,15 +726,57 @@ func handlerAddFeed(s *State, user database.User, cmd Command) error {
 	return nil
 }
 
+// lookupFeed finds a feed by any spelling of its current or a previous URL,
+// trying the canonical form first and then the same URL under the other
+// scheme.
+func lookupFeed(s *State, rawURL string) (database.Feed, error) {
+	candidates, err := canonurl.Variants(rawURL)
+	if err != nil {
+		return database.Feed{}, err
+	}
+	for _, candidate := range candidates {
+		feed, err := s.Queries.GetFeedByURL(context.Background(), candidate)
+		if err == nil {
+			return feed, nil
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return database.Feed{}, err
+		}
+	}
+	// Feeds that have moved are still reachable through their old URLs.
+	for _, candidate := range candidates {
+		feed, err := s.Queries.GetFeedByPreviousURL(context.Background(), candidate)
+		if err == nil {
+			return feed, nil
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return database.Feed{}, err
+		}
+	}
+	return database.Feed{}, sql.ErrNoRows
+}
+
+// findFeed is lookupFeed for command handlers: a feed that doesn't exist is
+// reported as a NotFoundError.
+func findFeed(s *State, rawURL string) (database.Feed, error) {
+	feed, err := lookupFeed(s, rawURL)
+	if errors.Is(err, sql.ErrNoRows) {
+		return database.Feed{}, &NotFoundError{Kind: "feed", Name: rawURL}
+	}
+	if err != nil {
+		return database.Feed{}, fmt.Errorf("could not look up feed %s: %w", rawURL, err)
+	}
+	return feed, nil
+}
+
 func handlerFeeds(s *State, cmd Command) error {
 	feeds, err := s.Queries.GetFeedsWithUser(context.Background())
 	if err != nil {
-		fmt.Printf("failed to fetch feeds: %s\n", err)
-		os.Exit(1)
+		return fmt.Errorf("failed to fetch feeds: %w", err)
 	}
 	if len(feeds) == 0 {
 		fmt.Println("No feeds found")
-		os.Exit(1)
+		return nil
 	}
 	fmt.Println("Feeds:")
 	for _, feed := range feeds {
@@ -303,26 +786,15 @@ func handlerFeeds(s *State, cmd Command) error {
 }
 
 func handlerFollow(s *State, user database.User, cmd Command) error {
-	if len(cmd.args) < 1 {
-		fmt.Println("Usage: follow <feed_url>")
-		os.Exit(1)
-	}
 	feedURL := cmd.args[0]
 
-	user, err := s.Queries.GetUser(context.Background(), s.Config.CurrentUsername)
-	if err != nil {
-		fmt.Printf("could not find current username: %s", err)
-		os.Exit(1)
-	}
-
-	feed, err := s.Queries.GetFeedByURL(context.Background(), feedURL)
+	feed, err := findFeed(s, feedURL)
 	if err != nil {
-		fmt.Printf("could not find feed with url %s: %s\n", feedURL, err)
-		os.Exit(1)
+		return err
 	}
 
 	id := uuid.New()
-	now := time.Now()
+	now := time.Now().UTC()
 	follow, err := s.Queries.CreateFeedFollow(context.Background(), database.CreateFeedFollowParams{
 		ID:        id,
 		CreatedAt: now,
@@ -330,9 +802,11 @@ func handlerFollow(s *State, user database.User, cmd Command) error {
 		UserID:    user.ID,
 		FeedID:    feed.ID,
 	})
+	if isUniqueViolation(err) {
+		return &ConflictError{Message: fmt.Sprintf("already following feed %s", feed.Url)}
+	}
 	if err != nil {
-		fmt.Printf("could not create feed follow: %s\n", err)
-		os.Exit(1)
+		return fmt.Errorf("could not create feed follow: %w", err)
 	}
 
 	fmt.Printf("Now following feed '%s' as user '%s'\n", follow.FeedName, follow.UserName)
@@ -340,47 +814,68 @@ func handlerFollow(s *State, user database.User, cmd Command) error {
 }
 
 func handlerFollowing(s *State, user database.User, cmd Command) error {
-	user, err := s.Queries.GetUser(context.Background(), s.Config.CurrentUsername)
+	folder, tag, err := followFilter(s, user, cmd)
 	if err != nil {
-		fmt.Printf("could not find current user: %s\n", err)
-		os.Exit(1)
+		return err
 	}
-
-	follows, err := s.Queries.GetFeedFollowsForUser(context.Background(), user.ID)
+	follows, err := s.Queries.GetFeedFollowsForUser(context.Background(), database.GetFeedFollowsForUserParams{
+		UserID: user.ID,
+		Folder: folder,
+		Tag:    tag,
+	})
 	if err != nil {
-		fmt.Printf("could not fetch followed feeds: %s\n", err)
-		os.Exit(1)
+		return fmt.Errorf("could not fetch followed feeds: %w", err)
 	}
 
 	if len(follows) == 0 {
 		fmt.Println("You are not following any feeds.")
-		os.Exit(0)
+		return nil
 	}
 
+	// Unfiled feeds sort first, then each folder under a heading.
 	fmt.Println("Feeds you are following:")
+	currentFolder := ""
 	for _, follow := range follows {
-		fmt.Printf("- %s\n", follow.FeedName)
+		indent := ""
+		if follow.FolderName != "" {
+			if follow.FolderName != currentFolder {
+				fmt.Printf("%s/\n", follow.FolderName)
+				currentFolder = follow.FolderName
+			}
+			indent = "  "
+		}
+		line := indent + "- " + follow.FeedName
+		if len(follow.Tags) > 0 {
+			line += " [" + strings.Join(follow.Tags, ", ") + "]"
+		}
+		if follow.Muted {
+			line += " (muted)"
+		}
+		fmt.Println(line)
 	}
 	return nil
 }
 
 func handlerUnfollow(s *State, user database.User, cmd Command) error {
-	if len(cmd.args) < 1 {
-		fmt.Println("Usage: unfollow <feed_url>")
-		os.Exit(1)
-	}
 	feedURL := cmd.args[0]
 
-	err := s.Queries.DelFeedFollow(context.Background(), database.DelFeedFollowParams{
+	feed, err := findFeed(s, feedURL)
+	if err != nil {
+		return err
+	}
+
+	deleted, err := s.Queries.DelFeedFollow(context.Background(), database.DelFeedFollowParams{
 		UserID: user.ID,
-		Url:    feedURL,
+		Url:    feed.Url,
 	})
 	if err != nil {
-		fmt.Printf("could not unfollow feed: %s\n", err)
-		os.Exit(1)
+		return fmt.Errorf("could not unfollow feed: %w", err)
+	}
+	if deleted == 0 {
+		return &NotFoundError{Kind: "followed feed", Name: feed.Url}
 	}
 
-	fmt.Printf("Unfollowed feed with URL: %s\n", feedURL)
+	fmt.Printf("Unfollowed feed with URL: %s\n", feed.Url)
 	return nil
 }
 
@@ -390,26 +885,70 @@ func handlerBrowse(s *State, user database.User, cmd Command) error {
 		if specifiedLimit, err := strconv.Atoi(cmd.args[0]); err == nil {
 			limit = specifiedLimit
 		} else {
-			return fmt.Errorf("invalid limit: %w", err)
+			return &UsageError{Usage: "browse [flags] [limit]", Reason: "invalid limit: " + err.Error()}
 		}
 	}
 
-	posts, err := s.Queries.GetPostsForUser(context.Background(), database.GetPostsForUserParams{
-		UserID: user.ID,
-		Limit:  int32(limit),
-	})
+	folder, tag, err := followFilter(s, user, cmd)
 	if err != nil {
-		return fmt.Errorf("couldn't get posts for user: %w", err)
+		return err
 	}
 
-	fmt.Printf("Found %d posts for user %s:\n", len(posts), user.Name)
-	for _, post := range posts {
+	rules, err := loadRules(s, user)
+	if err != nil {
+		return err
+	}
+
+	// Posts hidden by a rule don't count toward the limit, so keep paging
+	// until it is filled or the posts run out.
+	type shownPost struct {
+		database.GetPostsForUserRow
+		marks []string
+	}
+	var shown []shownPost
+	for skip := 0; len(shown) < limit; skip += limit {
+		posts, err := s.Queries.GetPostsForUser(context.Background(), database.GetPostsForUserParams{
+			UserID:   user.ID,
+			Folder:   folder,
+			Tag:      tag,
+			MaxPosts: int32(limit),
+			Skip:     int32(skip),
+		})
+		if err != nil {
+			return fmt.Errorf("couldn't get posts for user: %w", err)
+		}
+		for _, post := range posts {
+			marks, hidden := ruleMarks(matchingRules(rules, post.FeedID, filter.Post{
+				Title:       post.Title,
+				Description: post.Description.String,
+				Author:      post.Author,
+				Categories:  post.Categories,
+			}))
+			if hidden {
+				continue
+			}
+			shown = append(shown, shownPost{GetPostsForUserRow: post, marks: marks})
+			if len(shown) == limit {
+				break
+			}
+		}
+		if len(posts) < limit {
+			break
+		}
+	}
+
+	fmt.Printf("Found %d posts for user %s:\n", len(shown), user.Name)
+	for _, post := range shown {
 		fmt.Printf("%s from %s\n", post.PublishedAt.Time.Format("Mon Jan 2"), post.FeedName)
-		fmt.Printf("--- %s ---\n", post.Title)
+		if len(post.marks) > 0 {
+			fmt.Printf("--- %s --- [%s]\n", post.Title, strings.Join(post.marks, ", "))
+		} else {
+			fmt.Printf("--- %s ---\n", post.Title)
+		}
 		fmt.Printf("    %v\n", post.Description.String)
 		fmt.Printf("Link: %s\n", post.Url)
 		fmt.Println("=====================================")
 	}
 
 	return nil
-}
\ No newline at end of file
+}
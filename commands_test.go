@@ -0,0 +1,302 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/isaacjstriker/gatorapp/internal/database"
+)
+
+const testFeedURL = "https://example.com/feed.xml"
+
+func TestHandlerExitCodes(t *testing.T) {
+	for _, b := range testBackends(t) {
+		t.Run(b.name, func(t *testing.T) {
+			tests := []struct {
+				name  string
+				setup [][]string
+				args  []string
+				want  int
+			}{
+				{
+					name: "register",
+					args: []string{"register", "alice"},
+					want: exitOK,
+				},
+				{
+					name:  "register taken name",
+					setup: [][]string{{"register", "alice"}},
+					args:  []string{"register", "alice"},
+					want:  exitConflict,
+				},
+				{
+					name: "register without a name",
+					args: []string{"register"},
+					want: exitUsage,
+				},
+				{
+					name: "unknown command",
+					args: []string{"frobnicate"},
+					want: exitUsage,
+				},
+				{
+					name: "unknown subcommand",
+					args: []string{"folder", "frobnicate"},
+					want: exitUsage,
+				},
+				{
+					name:  "login",
+					setup: [][]string{{"register", "alice"}, {"register", "bob"}},
+					args:  []string{"login", "alice"},
+					want:  exitOK,
+				},
+				{
+					name: "login unknown user",
+					args: []string{"login", "alice"},
+					want: exitNotFound,
+				},
+				{
+					name: "login too many arguments",
+					args: []string{"login", "alice", "bob"},
+					want: exitUsage,
+				},
+				{
+					name: "login disabled user",
+					setup: [][]string{
+						{"register", "alice"},
+						{"register", "bob"},
+						{"login", "alice"},
+						{"admin", "disable", "bob"},
+					},
+					args: []string{"login", "bob"},
+					want: exitForbidden,
+				},
+				{
+					name: "following when not logged in",
+					args: []string{"following"},
+					want: exitUnauthenticated,
+				},
+				{
+					name:  "addfeed",
+					setup: [][]string{{"register", "alice"}},
+					args:  []string{"addfeed", "Example", testFeedURL},
+					want:  exitOK,
+				},
+				{
+					name:  "addfeed invalid url",
+					setup: [][]string{{"register", "alice"}},
+					args:  []string{"addfeed", "Example", "mailto:alice@example.com"},
+					want:  exitUsage,
+				},
+				{
+					name:  "addfeed existing feed",
+					setup: [][]string{{"register", "alice"}, {"addfeed", "Example", testFeedURL}},
+					args:  []string{"addfeed", "Again", "http://example.com/feed.xml"},
+					want:  exitConflict,
+				},
+				{
+					name:  "follow unknown feed",
+					setup: [][]string{{"register", "alice"}},
+					args:  []string{"follow", testFeedURL},
+					want:  exitNotFound,
+				},
+				{
+					name:  "follow twice",
+					setup: [][]string{{"register", "alice"}, {"addfeed", "Example", testFeedURL}},
+					args:  []string{"follow", testFeedURL},
+					want:  exitConflict,
+				},
+				{
+					name: "unfollow a feed not followed",
+					setup: [][]string{
+						{"register", "alice"},
+						{"addfeed", "Example", testFeedURL},
+						{"register", "bob"},
+					},
+					args: []string{"unfollow", testFeedURL},
+					want: exitNotFound,
+				},
+				{
+					name: "delete someone else's feed",
+					setup: [][]string{
+						{"register", "alice"},
+						{"addfeed", "Example", testFeedURL},
+						{"register", "bob"},
+					},
+					args: []string{"delfeed", "-yes", testFeedURL},
+					want: exitForbidden,
+				},
+				{
+					name: "delete a feed others follow",
+					setup: [][]string{
+						{"register", "alice"},
+						{"addfeed", "Example", testFeedURL},
+						{"register", "bob"},
+						{"follow", testFeedURL},
+						{"login", "alice"},
+					},
+					args: []string{"delfeed", "-yes", testFeedURL},
+					want: exitConflict,
+				},
+				{
+					name: "delete a user whose feed others follow",
+					setup: [][]string{
+						{"register", "alice"},
+						{"register", "bob"},
+						{"addfeed", "Example", testFeedURL},
+						{"login", "alice"},
+						{"follow", testFeedURL},
+					},
+					args: []string{"deluser", "-yes", "bob"},
+					want: exitConflict,
+				},
+				{
+					name:  "browse invalid limit",
+					setup: [][]string{{"register", "alice"}},
+					args:  []string{"browse", "lots"},
+					want:  exitUsage,
+				},
+				{
+					name:  "admin command as a user",
+					setup: [][]string{{"register", "alice"}, {"register", "bob"}},
+					args:  []string{"users"},
+					want:  exitForbidden,
+				},
+				{
+					name:  "restore as a user",
+					setup: [][]string{{"register", "alice"}, {"register", "bob"}},
+					args:  []string{"restore", "backup.jsonl.gz"},
+					want:  exitForbidden,
+				},
+				{
+					name:  "demote the only admin",
+					setup: [][]string{{"register", "alice"}},
+					args:  []string{"admin", "role", "alice", "user"},
+					want:  exitConflict,
+				},
+			}
+			for _, tt := range tests {
+				t.Run(tt.name, func(t *testing.T) {
+					s := newTestState(t, b)
+					mustRun(t, s, tt.setup...)
+					err := runCommand(s, tt.args...)
+					if got := exitCode(err); got != tt.want {
+						t.Errorf("%v: exit code %d (%v), want %d", tt.args, got, err, tt.want)
+					}
+				})
+			}
+		})
+	}
+}
+
+func TestRegisterLogsIn(t *testing.T) {
+	for _, b := range testBackends(t) {
+		t.Run(b.name, func(t *testing.T) {
+			s := newTestState(t, b)
+			mustRun(t, s, []string{"register", "alice"}, []string{"register", "bob"})
+
+			if s.Config.CurrentUsername != "bob" {
+				t.Errorf("current user = %q, want bob", s.Config.CurrentUsername)
+			}
+			mustRun(t, s, []string{"login", "alice"})
+			if s.Config.CurrentUsername != "alice" {
+				t.Errorf("current user after login = %q, want alice", s.Config.CurrentUsername)
+			}
+		})
+	}
+}
+
+func TestAddFeedFollows(t *testing.T) {
+	for _, b := range testBackends(t) {
+		t.Run(b.name, func(t *testing.T) {
+			s := newTestState(t, b)
+			mustRun(t, s,
+				[]string{"register", "alice"},
+				[]string{"addfeed", "Example", testFeedURL},
+				[]string{"register", "bob"},
+				[]string{"follow", "http://example.com/feed.xml"},
+			)
+
+			ctx := context.Background()
+			feed, err := s.Queries.GetFeedByURL(ctx, testFeedURL)
+			if err != nil {
+				t.Fatalf("GetFeedByURL: %v", err)
+			}
+			followers, err := s.Queries.GetFeedFollowers(ctx, feed.ID)
+			if err != nil {
+				t.Fatalf("GetFeedFollowers: %v", err)
+			}
+			if len(followers) != 2 {
+				t.Errorf("feed has %d followers, want 2", len(followers))
+			}
+
+			mustRun(t, s, []string{"unfollow", testFeedURL})
+			followers, err = s.Queries.GetFeedFollowers(ctx, feed.ID)
+			if err != nil {
+				t.Fatalf("GetFeedFollowers: %v", err)
+			}
+			if len(followers) != 1 || followers[0].Name != "alice" {
+				t.Errorf("followers after unfollow = %v, want only alice", followers)
+			}
+		})
+	}
+}
+
+func TestBrowseOrder(t *testing.T) {
+	for _, b := range testBackends(t) {
+		t.Run(b.name, func(t *testing.T) {
+			const otherFeedURL = "https://example.org/feed.xml"
+			s := newTestState(t, b)
+			mustRun(t, s,
+				[]string{"register", "alice"},
+				[]string{"addfeed", "Low", testFeedURL},
+				[]string{"addfeed", "High", otherFeedURL},
+				[]string{"editfollow", "-priority", "10", otherFeedURL},
+			)
+			ctx := context.Background()
+			low, err := s.Queries.GetFeedByURL(ctx, testFeedURL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			high, err := s.Queries.GetFeedByURL(ctx, otherFeedURL)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			day := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+			item := func(title string, at time.Time) RSSItem {
+				return RSSItem{Title: title, Link: "https://example.net/" + title, PubDate: at.Format(time.RFC1123Z)}
+			}
+			lowData, highData := &RSSFeed{}, &RSSFeed{}
+			lowData.Channel.Item = []RSSItem{
+				item("low-today-late", day.Add(20*time.Hour)),
+				item("low-yesterday", day.Add(-4*time.Hour)),
+			}
+			highData.Channel.Item = []RSSItem{
+				item("high-today-early", day.Add(time.Hour)),
+				item("high-two-days-ago", day.Add(-30*time.Hour)),
+			}
+			savePosts(s, low, lowData)
+			savePosts(s, high, highData)
+
+			user, err := s.Queries.GetUser(ctx, "alice")
+			if err != nil {
+				t.Fatal(err)
+			}
+			posts, err := s.Queries.GetPostsForUser(ctx, database.GetPostsForUserParams{UserID: user.ID, MaxPosts: 10})
+			if err != nil {
+				t.Fatalf("GetPostsForUser: %v", err)
+			}
+			var got []string
+			for _, post := range posts {
+				got = append(got, post.Title)
+			}
+			want := []string{"high-today-early", "low-today-late", "low-yesterday", "high-two-days-ago"}
+			if strings.Join(got, " ") != strings.Join(want, " ") {
+				t.Errorf("browse order = %v, want %v", got, want)
+			}
+		})
+	}
+}
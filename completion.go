@@ -0,0 +1,212 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+var completionShells = []string{"bash", "zsh", "fish"}
+
+// handlerCompletion prints a shell completion script generated from the
+// registered commands and their flags.
+func handlerCompletion(c *Commands) func(*State, Command) error {
+	return func(s *State, cmd Command) error {
+		switch cmd.args[0] {
+		case "bash":
+			c.writeBashCompletion(os.Stdout)
+		case "zsh":
+			c.writeZshCompletion(os.Stdout)
+		case "fish":
+			c.writeFishCompletion(os.Stdout)
+		default:
+			return &UsageError{Usage: "completion <bash|zsh|fish>", Reason: "unsupported shell: " + cmd.args[0]}
+		}
+		return nil
+	}
+}
+
+// completionFlag is a flag as the completion scripts need to see it.
+type completionFlag struct {
+	name  string
+	usage string
+	// takesValue is false for boolean flags, which stand alone.
+	takesValue bool
+}
+
+func completionFlags(fs *flag.FlagSet) []completionFlag {
+	var flags []completionFlag
+	fs.VisitAll(func(f *flag.Flag) {
+		boolFlag, ok := f.Value.(interface{ IsBoolFlag() bool })
+		flags = append(flags, completionFlag{
+			name:       f.Name,
+			usage:      f.Usage,
+			takesValue: !ok || !boolFlag.IsBoolFlag(),
+		})
+	})
+	return flags
+}
+
+func (c *Commands) globalCompletionFlags() []completionFlag {
+	if c.global == nil {
+		return nil
+	}
+	return completionFlags(c.global)
+}
+
+func flagWords(flags []completionFlag) []string {
+	words := make([]string, len(flags))
+	for i, f := range flags {
+		words[i] = "-" + f.name
+	}
+	return words
+}
+
+func (c *Commands) writeBashCompletion(w io.Writer) {
+	global := c.globalCompletionFlags()
+	var valueFlags []string
+	for _, f := range global {
+		if f.takesValue {
+			valueFlags = append(valueFlags, "-"+f.name, "--"+f.name)
+		}
+	}
+
+	fmt.Fprintln(w, "# bash completion for gatorapp")
+	fmt.Fprintln(w, "_gatorapp() {")
+	fmt.Fprintln(w, `    local cur="${COMP_WORDS[COMP_CWORD]}"`)
+	fmt.Fprintln(w, `    local cmd="" i`)
+	fmt.Fprintln(w, "    for ((i = 1; i < COMP_CWORD; i++)); do")
+	fmt.Fprintln(w, `        case "${COMP_WORDS[i]}" in`)
+	if len(valueFlags) > 0 {
+		fmt.Fprintf(w, "            %s) ((i++)) ;;\n", strings.Join(valueFlags, "|"))
+	}
+	fmt.Fprintln(w, "            -*) ;;")
+	fmt.Fprintln(w, `            *) cmd="${COMP_WORDS[i]}"; break ;;`)
+	fmt.Fprintln(w, "        esac")
+	fmt.Fprintln(w, "    done")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, `    if [[ -z "$cmd" ]]; then`)
+	fmt.Fprintf(w, "        COMPREPLY=($(compgen -W %s -- \"$cur\"))\n",
+		shellQuote(strings.Join(append(flagWords(global), c.order...), " ")))
+	fmt.Fprintln(w, "        return")
+	fmt.Fprintln(w, "    fi")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, `    case "$cmd" in`)
+	for _, name := range c.order {
+		spec := c.specs[name]
+		words := append(flagWords(completionFlags(spec.flagSet())), spec.completions()...)
+		if len(words) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "        %s) COMPREPLY=($(compgen -W %s -- \"$cur\")) ;;\n",
+			name, shellQuote(strings.Join(words, " ")))
+	}
+	fmt.Fprintln(w, "    esac")
+	fmt.Fprintln(w, "}")
+	fmt.Fprintln(w, "complete -o default -F _gatorapp gatorapp")
+}
+
+func (c *Commands) writeZshCompletion(w io.Writer) {
+	fmt.Fprintln(w, "#compdef gatorapp")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "_gatorapp() {")
+	fmt.Fprintln(w, "    local -a commands")
+	fmt.Fprintln(w, "    commands=(")
+	for _, name := range c.order {
+		fmt.Fprintf(w, "        %s\n", shellQuote(name+":"+strings.ReplaceAll(c.specs[name].description, ":", `\:`)))
+	}
+	fmt.Fprintln(w, "    )")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "    local curcontext=\"$curcontext\" state line")
+	fmt.Fprint(w, "    _arguments -C")
+	for _, f := range c.globalCompletionFlags() {
+		fmt.Fprintf(w, " \\\n        %s", shellQuote(zshFlagSpec(f)))
+	}
+	fmt.Fprint(w, " \\\n        '1:command:->command' \\\n        '*::argument:->argument'\n")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "    case $state in")
+	fmt.Fprintln(w, "        command)")
+	fmt.Fprintln(w, "            _describe -t commands 'gatorapp command' commands")
+	fmt.Fprintln(w, "            ;;")
+	fmt.Fprintln(w, "        argument)")
+	fmt.Fprintln(w, "            case $words[1] in")
+	for _, name := range c.order {
+		spec := c.specs[name]
+		flags := completionFlags(spec.flagSet())
+		fmt.Fprintf(w, "                %s)\n", name)
+		fmt.Fprint(w, "                    _arguments")
+		for _, f := range flags {
+			fmt.Fprintf(w, " %s", shellQuote(zshFlagSpec(f)))
+		}
+		if words := spec.completions(); len(words) > 0 {
+			fmt.Fprintf(w, " %s", shellQuote("*:argument:("+strings.Join(words, " ")+")"))
+		} else {
+			fmt.Fprint(w, " '*:argument:_files'")
+		}
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "                    ;;")
+	}
+	fmt.Fprintln(w, "            esac")
+	fmt.Fprintln(w, "            ;;")
+	fmt.Fprintln(w, "    esac")
+	fmt.Fprintln(w, "}")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, `if [ "$funcstack[1]" = "_gatorapp" ]; then`)
+	fmt.Fprintln(w, `    _gatorapp "$@"`)
+	fmt.Fprintln(w, "else")
+	fmt.Fprintln(w, "    compdef _gatorapp gatorapp")
+	fmt.Fprintln(w, "fi")
+}
+
+// zshFlagSpec formats a flag for _arguments, e.g. "-limit[max posts]:limit:".
+func zshFlagSpec(f completionFlag) string {
+	desc := strings.NewReplacer("[", `\[`, "]", `\]`).Replace(f.usage)
+	spec := "-" + f.name + "[" + desc + "]"
+	if f.takesValue {
+		spec += ":" + f.name + ":"
+	}
+	return spec
+}
+
+func (c *Commands) writeFishCompletion(w io.Writer) {
+	fmt.Fprintln(w, "# fish completion for gatorapp")
+	fmt.Fprintln(w, "complete -c gatorapp -n __fish_use_subcommand -f")
+	for _, f := range c.globalCompletionFlags() {
+		fmt.Fprintf(w, "complete -c gatorapp -n __fish_use_subcommand%s\n", fishFlag(f))
+	}
+	for _, name := range c.order {
+		fmt.Fprintf(w, "complete -c gatorapp -n __fish_use_subcommand -a %s -d %s\n",
+			name, fishQuote(c.specs[name].description))
+	}
+	for _, name := range c.order {
+		spec := c.specs[name]
+		cond := fishQuote("__fish_seen_subcommand_from " + name)
+		for _, f := range completionFlags(spec.flagSet()) {
+			fmt.Fprintf(w, "complete -c gatorapp -n %s%s\n", cond, fishFlag(f))
+		}
+		if words := spec.completions(); len(words) > 0 {
+			fmt.Fprintf(w, "complete -c gatorapp -n %s -f -a %s\n",
+				cond, fishQuote(strings.Join(words, " ")))
+		}
+	}
+}
+
+func fishFlag(f completionFlag) string {
+	s := " -o " + f.name + " -d " + fishQuote(f.usage)
+	if f.takesValue {
+		s += " -r"
+	}
+	return s
+}
+
+// shellQuote single-quotes s for bash and zsh.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// fishQuote single-quotes s for fish, which escapes inside quotes instead.
+func fishQuote(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, "'", `\'`).Replace(s) + "'"
+}
@@ -0,0 +1,333 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/isaacjstriker/gatorapp/internal/database"
+)
+
+// allowResetEnv must be set to 1 before reset will delete everything.
+const allowResetEnv = "GATOR_ALLOW_RESET"
+
+var errNotConfirmed = errors.New("aborted: not confirmed")
+
+// destructiveFlags defines the flags shared by commands that delete data.
+func destructiveFlags(fs *flag.FlagSet) {
+	fs.Bool("yes", false, "delete without asking for confirmation")
+	fs.Bool("dry-run", false, "report what would be deleted without deleting anything")
+}
+
+// confirmDestructive decides whether a destructive command goes ahead. A dry
+// run reports what would be deleted and stops; otherwise the user is asked
+// to confirm unless -yes was given.
+func confirmDestructive(cmd Command, what string) (bool, error) {
+	if cmd.flagBool("dry-run") {
+		fmt.Printf("Dry run: would delete %s\n", what)
+		return false, nil
+	}
+	if cmd.flagBool("yes") {
+		return true, nil
+	}
+	ok, err := confirm(fmt.Sprintf("Delete %s?", what))
+	if err != nil {
+		return false, err
+	}
+	if !ok {
+		return false, errNotConfirmed
+	}
+	return true, nil
+}
+
+// stdin is shared by the prompts so that input read ahead by one isn't lost
+// to the next.
+var stdin = bufio.NewReader(os.Stdin)
+
+// confirm asks a yes/no question on stdin. Anything but y or yes, including
+// end of input, is a no.
+func confirm(prompt string) (bool, error) {
+	fmt.Printf("%s [y/N] ", prompt)
+	answer, err := stdin.ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return false, fmt.Errorf("could not read answer: %w", err)
+	}
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true, nil
+	}
+	return false, nil
+}
+
+// count formats n with noun, pluralized by a trailing s.
+func count(n int64, noun string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, noun)
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
+
+func deleteUserFlags(fs *flag.FlagSet) {
+	destructiveFlags(fs)
+	fs.Bool("transfer", false, "give the user's feeds that others follow to their longest-standing followers instead of deleting them")
+	fs.Bool("force", false, "delete the user's feeds even if others follow them")
+}
+
+// feedHandover is a feed that others follow, with the follower who takes it
+// over when its owner is deleted.
+type feedHandover struct {
+	feed database.Feed
+	to   database.User
+	// follows is how many users other than the owner follow the feed.
+	follows int
+}
+
+func handlerDeleteUser(s *State, _ database.User, cmd Command) error {
+	ctx := context.Background()
+	name := cmd.args[0]
+
+	user, err := findUser(s, name)
+	if err != nil {
+		return err
+	}
+	if err := checkNotLastAdmin(ctx, s, user); err != nil {
+		return err
+	}
+
+	feeds, err := s.Queries.GetFeedsByOwner(ctx, user.ID)
+	if err != nil {
+		return fmt.Errorf("could not get the user's feeds: %w", err)
+	}
+	var handovers []feedHandover
+	for _, feed := range feeds {
+		others, err := otherFollowers(ctx, s, feed)
+		if err != nil {
+			return err
+		}
+		if len(others) > 0 {
+			handovers = append(handovers, feedHandover{feed: feed, to: others[0], follows: len(others)})
+		}
+	}
+
+	counts, err := s.Queries.CountUserData(ctx, user.ID)
+	if err != nil {
+		return fmt.Errorf("could not count user data: %w", err)
+	}
+	var shared string
+	if len(handovers) > 0 {
+		shared = count(int64(len(handovers)), "feed") + " that others follow"
+		switch {
+		case cmd.flagBool("force"):
+			shared = ", including " + shared
+		case cmd.flagBool("transfer"):
+			// Handed over feeds keep their posts and their other followers.
+			for _, h := range handovers {
+				feedCounts, err := s.Queries.CountFeedData(ctx, h.feed.ID)
+				if err != nil {
+					return fmt.Errorf("could not count feed data: %w", err)
+				}
+				counts.Feeds--
+				counts.Follows -= int64(h.follows)
+				counts.Posts -= feedCounts.Posts
+			}
+			shared = ", and give " + shared + " to their longest-standing followers"
+		default:
+			return &ConflictError{Message: fmt.Sprintf(
+				"user %q added %s; pass -transfer to give them to their longest-standing followers, or -force to delete them for everyone",
+				user.Name, shared)}
+		}
+	}
+	what := fmt.Sprintf("user %q with %s, %s and %s%s", user.Name,
+		count(counts.Feeds, "feed"), count(counts.Follows, "follow"), count(counts.Posts, "post"), shared)
+	if proceed, err := confirmDestructive(cmd, what); err != nil || !proceed {
+		return err
+	}
+
+	err = s.Queries.InTx(ctx, func(qtx database.Querier) error {
+		if !cmd.flagBool("force") {
+			for _, h := range handovers {
+				if err := qtx.SetFeedOwner(ctx, database.SetFeedOwnerParams{ID: h.feed.ID, UserID: h.to.ID, UpdatedAt: time.Now().UTC()}); err != nil {
+					return fmt.Errorf("could not transfer feed: %w", err)
+				}
+			}
+		}
+		deleted, err := qtx.DeleteUser(ctx, user.ID)
+		if err != nil {
+			return fmt.Errorf("could not delete user: %w", err)
+		}
+		if deleted == 0 {
+			return &NotFoundError{Kind: "user", Name: name}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if s.Config.CurrentUsername == user.Name {
+		if err := s.Config.SetUser(""); err != nil {
+			return fmt.Errorf("failed to write config: %w", err)
+		}
+	}
+	fmt.Printf("Deleted %s\n", what)
+	return nil
+}
+
+func deleteFeedFlags(fs *flag.FlagSet) {
+	destructiveFlags(fs)
+	fs.Bool("transfer", false, "if others follow the feed, give it to its longest-standing follower instead of deleting it")
+	fs.Bool("force", false, "delete the feed even if others follow it")
+}
+
+func handlerDeleteFeed(s *State, user database.User, cmd Command) error {
+	ctx := context.Background()
+
+	feed, err := findFeed(s, cmd.args[0])
+	if err != nil {
+		return err
+	}
+	if err := authorizeFeed(s, user, feed); err != nil {
+		return err
+	}
+
+	others, err := otherFollowers(ctx, s, feed)
+	if err != nil {
+		return err
+	}
+
+	if len(others) > 0 && !cmd.flagBool("force") {
+		if !cmd.flagBool("transfer") {
+			return &ConflictError{Message: fmt.Sprintf(
+				"feed %s is followed by %s; pass -transfer to give it to %s, or -force to delete it for everyone",
+				feed.Url, count(int64(len(others)), "other user"), others[0].Name)}
+		}
+		return transferFeed(ctx, s, cmd, feed, others[0])
+	}
+
+	counts, err := s.Queries.CountFeedData(ctx, feed.ID)
+	if err != nil {
+		return fmt.Errorf("could not count feed data: %w", err)
+	}
+	what := fmt.Sprintf("feed %q (%s) with %s and %s", feed.Name, feed.Url,
+		count(counts.Follows, "follow"), count(counts.Posts, "post"))
+	if proceed, err := confirmDestructive(cmd, what); err != nil || !proceed {
+		return err
+	}
+
+	unsubscribeFeed(s, feed)
+	if err := s.Queries.DeleteFeed(ctx, feed.ID); err != nil {
+		return fmt.Errorf("could not delete feed: %w", err)
+	}
+	fmt.Printf("Deleted %s\n", what)
+	return nil
+}
+
+// otherFollowers returns the users other than its owner who follow feed,
+// longest-standing first.
+func otherFollowers(ctx context.Context, s *State, feed database.Feed) ([]database.User, error) {
+	followers, err := s.Queries.GetFeedFollowers(ctx, feed.ID)
+	if err != nil {
+		return nil, fmt.Errorf("could not get feed followers: %w", err)
+	}
+	var others []database.User
+	for _, follower := range followers {
+		if follower.ID != feed.UserID {
+			others = append(others, follower)
+		}
+	}
+	return others, nil
+}
+
+// transferFeed hands feed to newOwner and drops the old owner's follow, so
+// the feed disappears for its owner but not for anyone else.
+func transferFeed(ctx context.Context, s *State, cmd Command, feed database.Feed, newOwner database.User) error {
+	if cmd.flagBool("dry-run") {
+		fmt.Printf("Dry run: would give feed %q (%s) to %s\n", feed.Name, feed.Url, newOwner.Name)
+		return nil
+	}
+
+	err := s.Queries.InTx(ctx, func(qtx database.Querier) error {
+		if err := qtx.SetFeedOwner(ctx, database.SetFeedOwnerParams{ID: feed.ID, UserID: newOwner.ID, UpdatedAt: time.Now().UTC()}); err != nil {
+			return fmt.Errorf("could not transfer feed: %w", err)
+		}
+		if _, err := qtx.DelFeedFollow(ctx, database.DelFeedFollowParams{UserID: feed.UserID, Url: feed.Url}); err != nil {
+			return fmt.Errorf("could not unfollow feed: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Gave feed %q (%s) to %s\n", feed.Name, feed.Url, newOwner.Name)
+	return nil
+}
+
+// parseCutoff accepts a date (2006-01-02, midnight UTC) or an RFC 3339
+// timestamp.
+func parseCutoff(value string) (time.Time, error) {
+	if t, err := time.Parse(time.DateOnly, value); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, errors.New("expected a date like 2024-01-31 or an RFC 3339 timestamp")
+	}
+	return t.UTC(), nil
+}
+
+func handlerPurgePosts(s *State, _ database.User, cmd Command) error {
+	ctx := context.Background()
+
+	before, err := parseCutoff(cmd.args[0])
+	if err != nil {
+		return &UsageError{Usage: "purge [flags] <before>", Reason: "invalid date: " + err.Error()}
+	}
+
+	n, err := s.Queries.CountPostsBefore(ctx, before)
+	if err != nil {
+		return fmt.Errorf("could not count posts: %w", err)
+	}
+	what := fmt.Sprintf("%s published before %s", count(n, "post"), before.Format(time.RFC3339))
+	if proceed, err := confirmDestructive(cmd, what); err != nil || !proceed {
+		return err
+	}
+
+	deleted, err := s.Queries.DeletePostsBefore(ctx, before)
+	if err != nil {
+		return fmt.Errorf("could not delete posts: %w", err)
+	}
+	fmt.Printf("Deleted %s published before %s\n", count(deleted, "post"), before.Format(time.RFC3339))
+	return nil
+}
+
+func handlerReset(s *State, _ database.User, cmd Command) error {
+	ctx := context.Background()
+
+	counts, err := s.Queries.CountAllData(ctx)
+	if err != nil {
+		return fmt.Errorf("could not count data: %w", err)
+	}
+	what := fmt.Sprintf("everything: %s, %s, %s and %s",
+		count(counts.Users, "user"), count(counts.Feeds, "feed"),
+		count(counts.Follows, "follow"), count(counts.Posts, "post"))
+
+	if !cmd.flagBool("dry-run") && os.Getenv(allowResetEnv) != "1" {
+		return fmt.Errorf("refusing to delete %s; set %s=1 to allow a full reset", what, allowResetEnv)
+	}
+	if proceed, err := confirmDestructive(cmd, what); err != nil || !proceed {
+		return err
+	}
+
+	if err := s.Queries.DelUsers(ctx); err != nil {
+		return fmt.Errorf("failed to delete users: %w", err)
+	}
+	fmt.Printf("Deleted %s\n", what)
+	return nil
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+)
+
+// TestDeleteUserSharedFeeds checks that deleting a user keeps the feeds
+// others follow unless forced.
+func TestDeleteUserSharedFeeds(t *testing.T) {
+	for _, b := range testBackends(t) {
+		t.Run(b.name, func(t *testing.T) {
+			tests := []struct {
+				name      string
+				flag      string
+				wantOwner string
+			}{
+				{"transfer", "-transfer", "carol"},
+				{"force", "-force", ""},
+			}
+			for _, tt := range tests {
+				t.Run(tt.name, func(t *testing.T) {
+					s := newTestState(t, b)
+					mustRun(t, s,
+						[]string{"register", "alice"},
+						[]string{"register", "bob"},
+						[]string{"addfeed", "Example", testFeedURL},
+						[]string{"addfeed", "Other", "https://example.org/feed.xml"},
+						[]string{"register", "carol"},
+						[]string{"follow", testFeedURL},
+						[]string{"login", "alice"},
+						[]string{"follow", testFeedURL},
+					)
+					ctx := context.Background()
+					feed, err := s.Queries.GetFeedByURL(ctx, testFeedURL)
+					if err != nil {
+						t.Fatal(err)
+					}
+					savePosts(s, feed, testRSS(1, 2))
+
+					mustRun(t, s, []string{"deluser", "-yes", tt.flag, "bob"})
+
+					if _, err := s.Queries.GetFeedByURL(ctx, "https://example.org/feed.xml"); !errors.Is(err, sql.ErrNoRows) {
+						t.Errorf("feed only bob followed: got error %v, want it deleted", err)
+					}
+					feed, err = s.Queries.GetFeedByURL(ctx, testFeedURL)
+					if tt.wantOwner == "" {
+						if !errors.Is(err, sql.ErrNoRows) {
+							t.Errorf("followed feed: got error %v, want it deleted", err)
+						}
+						return
+					}
+					if err != nil {
+						t.Fatalf("followed feed: %v", err)
+					}
+					owner, err := s.Queries.GetUser(ctx, tt.wantOwner)
+					if err != nil {
+						t.Fatal(err)
+					}
+					if feed.UserID != owner.ID {
+						t.Errorf("feed belongs to %v, want %s, its longest-standing follower", feed.UserID, tt.wantOwner)
+					}
+					if got := countPosts(t, s, feed); got != 2 {
+						t.Errorf("feed has %d posts, want 2", got)
+					}
+					followers, err := s.Queries.GetFeedFollowers(ctx, feed.ID)
+					if err != nil {
+						t.Fatal(err)
+					}
+					if len(followers) != 2 {
+						t.Errorf("feed has %d followers, want carol and alice", len(followers))
+					}
+				})
+			}
+		})
+	}
+}
@@ -0,0 +1,348 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"html/template"
+	"log/slog"
+	"math"
+	netmail "net/mail"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/isaacjstriker/gatorapp/internal/database"
+	"github.com/isaacjstriker/gatorapp/internal/filter"
+	"github.com/isaacjstriker/gatorapp/internal/mail"
+)
+
+// digestTimeout bounds sending one digest.
+const digestTimeout = time.Minute
+
+// digestFeed groups a digest's posts by feed.
+type digestFeed struct {
+	Name  string
+	Posts []database.GetDigestPostsRow
+}
+
+var digestHTML = template.Must(template.New("digest").Parse(`<!DOCTYPE html>
+<html>
+<body style="font-family: sans-serif; max-width: 40em;">
+<h1 style="font-size: 1.3em;">{{.Count}} new posts for {{.User}}</h1>
+{{range .Feeds}}<h2 style="font-size: 1.1em; border-bottom: 1px solid #ccc;">{{.Name}}</h2>
+<ul>
+{{range .Posts}}<li><a href="{{.Url}}">{{.Title}}</a>{{if .Author}} by {{.Author}}{{end}}{{if .PublishedAt.Valid}} <small>({{.PublishedAt.Time.Format "Mon Jan 2"}})</small>{{end}}</li>
+{{end}}</ul>
+{{end}}</body>
+</html>
+`))
+
+// renderDigest returns a digest's subject and its text and HTML bodies.
+func renderDigest(user database.User, posts []database.GetDigestPostsRow) (subject, text, html string, err error) {
+	var feeds []digestFeed
+	for _, post := range posts {
+		if len(feeds) == 0 || feeds[len(feeds)-1].Name != post.FeedName {
+			feeds = append(feeds, digestFeed{Name: post.FeedName})
+		}
+		last := &feeds[len(feeds)-1]
+		last.Posts = append(last.Posts, post)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s for %s\n", count(int64(len(posts)), "new post"), user.Name)
+	for _, feed := range feeds {
+		fmt.Fprintf(&b, "\n%s\n%s\n", feed.Name, strings.Repeat("=", len(feed.Name)))
+		for _, post := range feed.Posts {
+			fmt.Fprintf(&b, "- %s\n  %s\n", post.Title, post.Url)
+		}
+	}
+
+	var h strings.Builder
+	if err := digestHTML.Execute(&h, struct {
+		User  string
+		Count int
+		Feeds []digestFeed
+	}{user.Name, len(posts), feeds}); err != nil {
+		return "", "", "", err
+	}
+
+	subject = fmt.Sprintf("gator digest: %s", count(int64(len(posts)), "new post"))
+	return subject, b.String(), h.String(), nil
+}
+
+// digestPosts returns the posts for the digest covering (since, until],
+// without those the user's filter rules hide, and the end of the period
+// they cover. When there are more than the configured maximum the period
+// ends early, at the last post included, and the next digest starts there.
+func digestPosts(ctx context.Context, s *State, user database.User, settings database.DigestSetting, since, until time.Time) ([]database.GetDigestPostsRow, time.Time, error) {
+	maxPosts := s.Config.Digest.MaxPostsOrDefault()
+	params := database.GetDigestPostsParams{
+		UserID:   user.ID,
+		Since:    since,
+		Until:    until,
+		Folder:   settings.Folder,
+		Tag:      settings.Tag,
+		MaxPosts: int32(maxPosts + 1),
+	}
+	posts, err := s.Queries.GetDigestPosts(ctx, params)
+	if err != nil {
+		return nil, until, fmt.Errorf("could not get digest posts: %w", err)
+	}
+	if len(posts) > maxPosts {
+		// Posts saved at the same moment as the first one left out can't be
+		// split between digests, so they wait for the next one too. If every
+		// post was saved at that moment, they all go in this one.
+		next := posts[maxPosts].CreatedAt
+		posts = posts[:maxPosts]
+		for len(posts) > 0 && posts[len(posts)-1].CreatedAt.Equal(next) {
+			posts = posts[:len(posts)-1]
+		}
+		if len(posts) > 0 {
+			until = posts[len(posts)-1].CreatedAt
+		} else {
+			params.Until, params.MaxPosts = next, math.MaxInt32
+			if posts, err = s.Queries.GetDigestPosts(ctx, params); err != nil {
+				return nil, until, fmt.Errorf("could not get digest posts: %w", err)
+			}
+			until = next
+		}
+	}
+
+	rules, err := loadRules(s, user)
+	if err != nil {
+		return nil, until, err
+	}
+	kept := posts[:0]
+	for _, post := range posts {
+		_, hidden := ruleMarks(matchingRules(rules, post.FeedID, filter.Post{
+			Title:       post.Title,
+			Description: post.Description.String,
+			Author:      post.Author,
+			Categories:  post.Categories,
+		}))
+		if !hidden {
+			kept = append(kept, post)
+		}
+	}
+	// Higher priority feeds first, then by feed, newest posts first.
+	slices.SortStableFunc(kept, func(a, b database.GetDigestPostsRow) int {
+		return cmp.Or(
+			cmp.Compare(b.Priority, a.Priority),
+			cmp.Compare(a.FeedName, b.FeedName),
+			b.PublishedAt.Time.Compare(a.PublishedAt.Time),
+		)
+	})
+	return kept, until, nil
+}
+
+// digestSince is where the next digest starts: the end of the last one, or
+// one interval ago for a user's first digest.
+func digestSince(s *State, settings database.DigestSetting, now time.Time) time.Time {
+	if settings.LastSentAt.Valid {
+		return settings.LastSentAt.Time
+	}
+	return now.Add(-s.Config.Digest.IntervalOrDefault())
+}
+
+// sendDigest emails the user the posts saved since their last digest and
+// records that the period up to now, or up to the last post when there were
+// too many for one digest, has been sent. It reports how many posts
+// were sent; a period without posts is recorded without sending anything.
+func sendDigest(s *State, user database.User, settings database.DigestSetting, now time.Time) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), digestTimeout)
+	defer cancel()
+
+	since := digestSince(s, settings, now)
+	posts, until, err := digestPosts(ctx, s, user, settings, since, now)
+	if err != nil {
+		return 0, err
+	}
+
+	// Claim the period first, so that a digest is never sent twice even if
+	// agg and the digest command run at once.
+	claimed, err := s.Queries.ClaimDigest(ctx, database.ClaimDigestParams{
+		UserID:    user.ID,
+		OldSentAt: settings.LastSentAt,
+		NewSentAt: sql.NullTime{Time: until, Valid: true},
+	})
+	if err != nil {
+		return 0, fmt.Errorf("could not record digest: %w", err)
+	}
+	if claimed == 0 || len(posts) == 0 {
+		return 0, nil
+	}
+
+	subject, text, html, err := renderDigest(user, posts)
+	if err == nil {
+		err = smtpServer(s).Send(ctx, mail.Message{
+			To:      []string{settings.Email},
+			Subject: subject,
+			Text:    text,
+			HTML:    html,
+		})
+	}
+	if err != nil {
+		// Give the period back so the next attempt includes these posts.
+		if _, err := s.Queries.ClaimDigest(context.Background(), database.ClaimDigestParams{
+			UserID:    user.ID,
+			OldSentAt: sql.NullTime{Time: until, Valid: true},
+			NewSentAt: settings.LastSentAt,
+		}); err != nil {
+			slog.Error("couldn't release digest", "user", user.Name, "error", err)
+		}
+		return 0, fmt.Errorf("could not send digest to %s: %w", settings.Email, err)
+	}
+	return len(posts), nil
+}
+
+// sendDueDigests sends a digest to every user whose last one is at least
+// the digest interval old. It does nothing without an SMTP server.
+func sendDueDigests(s *State) {
+	if s.Config.SMTP.Addr == "" {
+		return
+	}
+	now := time.Now().UTC()
+	due, err := s.Queries.GetDueDigests(context.Background(), now.Add(-s.Config.Digest.IntervalOrDefault()))
+	if err != nil {
+		slog.Error("couldn't get due digests", "error", err)
+		return
+	}
+	for _, d := range due {
+		n, err := sendDigest(s, d.User, d.DigestSetting, now)
+		if err != nil {
+			slog.Error("couldn't send digest", "user", d.User.Name, "error", err)
+			continue
+		}
+		if n > 0 {
+			slog.Info("sent digest", "user", d.User.Name, "posts", n)
+		}
+	}
+}
+
+func enableDigestFlags(fs *flag.FlagSet) {
+	fs.String("email", "", "address to send digests to (required)")
+	filterFlags(fs)
+}
+
+func handlerDigestEnable(s *State, user database.User, cmd Command) error {
+	usage := "digest enable -email <address> [-folder name] [-tag tag]"
+	if !cmd.flagGiven("email") {
+		return &UsageError{Usage: usage, Reason: "-email is required"}
+	}
+	addr, err := netmail.ParseAddress(cmd.flagString("email"))
+	if err != nil {
+		return &UsageError{Usage: usage, Reason: "invalid email: " + err.Error()}
+	}
+	folder, tag, err := followFilter(s, user, cmd)
+	if err != nil {
+		return err
+	}
+
+	settings, err := s.Queries.UpsertDigestSettings(context.Background(), database.UpsertDigestSettingsParams{
+		UserID:    user.ID,
+		CreatedAt: time.Now().UTC(),
+		Email:     addr.Address,
+		Folder:    folder,
+		Tag:       tag,
+	})
+	if err != nil {
+		return fmt.Errorf("could not save digest settings: %w", err)
+	}
+	fmt.Printf("Digests will be sent to %s every %s\n", settings.Email, s.Config.Digest.IntervalOrDefault())
+	if s.Config.SMTP.Addr == "" {
+		fmt.Println("Note: no smtp server is configured yet, so none will be sent.")
+	}
+	return nil
+}
+
+func handlerDigestDisable(s *State, user database.User, cmd Command) error {
+	deleted, err := s.Queries.DeleteDigestSettings(context.Background(), user.ID)
+	if err != nil {
+		return fmt.Errorf("could not delete digest settings: %w", err)
+	}
+	if deleted == 0 {
+		fmt.Println("Digests were not enabled.")
+		return nil
+	}
+	fmt.Println("Digests disabled.")
+	return nil
+}
+
+func findDigestSettings(s *State, user database.User) (database.DigestSetting, error) {
+	settings, err := s.Queries.GetDigestSettings(context.Background(), user.ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return settings, &NotFoundError{Kind: "digest settings for user", Name: user.Name}
+	}
+	if err != nil {
+		return settings, fmt.Errorf("could not get digest settings: %w", err)
+	}
+	return settings, nil
+}
+
+func handlerDigestShow(s *State, user database.User, cmd Command) error {
+	settings, err := findDigestSettings(s, user)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Digest settings for %s:\n", user.Name)
+	fmt.Printf("- email: %s\n", settings.Email)
+	fmt.Printf("- every: %s\n", s.Config.Digest.IntervalOrDefault())
+	if settings.Folder.Valid {
+		fmt.Printf("- folder: %s\n", settings.Folder.String)
+	}
+	if settings.Tag.Valid {
+		fmt.Printf("- tag: %s\n", settings.Tag.String)
+	}
+	if settings.LastSentAt.Valid {
+		fmt.Printf("- last sent: %s\n", settings.LastSentAt.Time.Format(time.RFC3339))
+	} else {
+		fmt.Println("- last sent: never")
+	}
+	return nil
+}
+
+func sendDigestFlags(fs *flag.FlagSet) {
+	fs.Bool("dry-run", false, "print the digest instead of sending it, without recording it as sent")
+}
+
+// handlerDigestSend sends the current user's digest now rather than waiting
+// for agg to.
+func handlerDigestSend(s *State, user database.User, cmd Command) error {
+	settings, err := findDigestSettings(s, user)
+	if err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+
+	if cmd.flagBool("dry-run") {
+		posts, _, err := digestPosts(context.Background(), s, user, settings, digestSince(s, settings, now), now)
+		if err != nil {
+			return err
+		}
+		if len(posts) == 0 {
+			fmt.Println("Dry run: no new posts for a digest.")
+			return nil
+		}
+		subject, text, _, err := renderDigest(user, posts)
+		if err != nil {
+			return fmt.Errorf("could not render digest: %w", err)
+		}
+		fmt.Printf("Dry run: would send to %s\nSubject: %s\n\n%s", settings.Email, subject, text)
+		return nil
+	}
+
+	n, err := sendDigest(s, user, settings, now)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		fmt.Println("No new posts since the last digest.")
+		return nil
+	}
+	fmt.Printf("Sent a digest of %s to %s\n", count(int64(n), "post"), settings.Email)
+	return nil
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/isaacjstriker/gatorapp/internal/database"
+)
+
+// TestDigestCap checks that posts beyond the digest cap are left for the
+// next digest rather than skipped.
+func TestDigestCap(t *testing.T) {
+	for _, b := range testBackends(t) {
+		t.Run(b.name, func(t *testing.T) {
+			s := newTestState(t, b)
+			s.Config.Digest.MaxPosts = 2
+			mustRun(t, s,
+				[]string{"register", "alice"},
+				[]string{"addfeed", "Example", testFeedURL},
+				[]string{"digest", "enable", "-email", "alice@example.com"},
+			)
+			ctx := context.Background()
+			feed, err := s.Queries.GetFeedByURL(ctx, testFeedURL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			alice, err := s.Queries.GetUser(ctx, "alice")
+			if err != nil {
+				t.Fatal(err)
+			}
+			settings, err := s.Queries.GetDigestSettings(ctx, alice.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			since := time.Now().UTC().Add(-time.Minute)
+			for n := 1; n <= 5; n++ {
+				savePosts(s, feed, testRSS(n))
+			}
+			now := time.Now().UTC()
+
+			var got []string
+			for round := 0; round < 4; round++ {
+				posts, until, err := digestPosts(ctx, s, alice, settings, since, now)
+				if err != nil {
+					t.Fatalf("digestPosts: %v", err)
+				}
+				if len(posts) > 2 {
+					t.Errorf("digest has %d posts, want at most 2", len(posts))
+				}
+				for _, post := range posts {
+					got = append(got, post.Title)
+				}
+				if until.Equal(now) {
+					break
+				}
+				since = until
+			}
+
+			want := []string{"Post 2", "Post 1", "Post 4", "Post 3", "Post 5"}
+			if len(got) != len(want) {
+				t.Fatalf("digests covered %v, want %v", got, want)
+			}
+			for i := range want {
+				if got[i] != want[i] {
+					t.Errorf("digests covered %v, want %v", got, want)
+					break
+				}
+			}
+		})
+	}
+}
+
+// TestDigestCapSameInstant checks that posts saved at the same moment go
+// in one digest even when there are more of them than the cap.
+func TestDigestCapSameInstant(t *testing.T) {
+	for _, b := range testBackends(t) {
+		t.Run(b.name, func(t *testing.T) {
+			s := newTestState(t, b)
+			s.Config.Digest.MaxPosts = 2
+			mustRun(t, s,
+				[]string{"register", "alice"},
+				[]string{"addfeed", "Example", testFeedURL},
+				[]string{"digest", "enable", "-email", "alice@example.com"},
+			)
+			ctx := context.Background()
+			feed, err := s.Queries.GetFeedByURL(ctx, testFeedURL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			alice, err := s.Queries.GetUser(ctx, "alice")
+			if err != nil {
+				t.Fatal(err)
+			}
+			settings, err := s.Queries.GetDigestSettings(ctx, alice.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			saved := time.Now().UTC().Add(-time.Hour).Truncate(time.Second)
+			for _, item := range testRSS(1, 2, 3).Channel.Item {
+				if _, err := s.Queries.CreatePost(ctx, database.CreatePostParams{
+					ID:        uuid.New(),
+					CreatedAt: saved,
+					UpdatedAt: saved,
+					Title:     item.Title,
+					Url:       item.Link,
+					FeedID:    feed.ID,
+				}); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			posts, until, err := digestPosts(ctx, s, alice, settings, saved.Add(-time.Minute), time.Now().UTC())
+			if err != nil {
+				t.Fatalf("digestPosts: %v", err)
+			}
+			if len(posts) != 3 {
+				t.Errorf("digest has %d posts, want all 3 saved together", len(posts))
+			}
+			if !until.Equal(saved) {
+				t.Errorf("digest covers up to %v, want %v", until, saved)
+			}
+		})
+	}
+}
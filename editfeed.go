@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/isaacjstriker/gatorapp/internal/canonurl"
+	"github.com/isaacjstriker/gatorapp/internal/database"
+)
+
+// authorizeFeed checks that user may change feed: they must own it or be an
+// admin.
+func authorizeFeed(s *State, user database.User, feed database.Feed) error {
+	if feed.UserID == user.ID || isAdmin(user) {
+		return nil
+	}
+	return &ForbiddenError{Reason: fmt.Sprintf("feed %s belongs to another user", feed.Url)}
+}
+
+func editFeedFlags(fs *flag.FlagSet) {
+	fs.String("name", "", "new name for the feed")
+	fs.String("url", "", "new URL for the feed")
+}
+
+// handlerEditFeed renames a feed or corrects its URL.
+func handlerEditFeed(s *State, user database.User, cmd Command) error {
+	ctx := context.Background()
+	usage := "editfeed [flags] <feed_url>"
+
+	if !cmd.flagGiven("name") && !cmd.flagGiven("url") {
+		return &UsageError{Usage: usage, Reason: "nothing to change; pass -name or -url"}
+	}
+
+	feed, err := findFeed(s, cmd.args[0])
+	if err != nil {
+		return err
+	}
+	if err := authorizeFeed(s, user, feed); err != nil {
+		return err
+	}
+
+	name := feed.Name
+	if cmd.flagGiven("name") {
+		name = cmd.flagString("name")
+		if name == "" {
+			return &UsageError{Usage: usage, Reason: "feed name can't be empty"}
+		}
+	}
+
+	newURL := feed.Url
+	if cmd.flagGiven("url") {
+		newURL, err = canonurl.Canonicalize(cmd.flagString("url"))
+		if err != nil {
+			return &UsageError{Usage: usage, Reason: "invalid feed url: " + err.Error()}
+		}
+		if existing, err := lookupFeed(s, newURL); err == nil && existing.ID != feed.ID {
+			return &ConflictError{Message: fmt.Sprintf("feed %s already exists", existing.Url)}
+		} else if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("could not check for existing feed: %w", err)
+		}
+	}
+
+	if newURL != feed.Url {
+		// The hub subscription is for the old URL's topic; the next fetch
+		// subscribes again if the new URL has a hub.
+		unsubscribeFeed(s, feed)
+	}
+
+	now := time.Now().UTC()
+	err = s.Queries.InTx(ctx, func(qtx database.Querier) error {
+		if name != feed.Name {
+			if err := qtx.RenameFeed(ctx, database.RenameFeedParams{ID: feed.ID, Name: name, UpdatedAt: now}); err != nil {
+				return fmt.Errorf("could not rename feed: %w", err)
+			}
+		}
+		if newURL != feed.Url {
+			if err := qtx.AddFeedURLHistory(ctx, database.AddFeedURLHistoryParams{
+				ID:        uuid.New(),
+				CreatedAt: now,
+				Url:       feed.Url,
+				FeedID:    feed.ID,
+			}); err != nil {
+				return fmt.Errorf("could not record old feed url: %w", err)
+			}
+			err := qtx.UpdateFeedURL(ctx, database.UpdateFeedURLParams{ID: feed.ID, Url: newURL, UpdatedAt: now})
+			if isUniqueViolation(err) {
+				return &ConflictError{Message: fmt.Sprintf("feed %s already exists", newURL)}
+			}
+			if err != nil {
+				return fmt.Errorf("could not update feed url: %w", err)
+			}
+			if err := qtx.ResetFeedFetchState(ctx, database.ResetFeedFetchStateParams{ID: feed.ID, UpdatedAt: now}); err != nil {
+				return fmt.Errorf("could not reset feed fetch state: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Updated feed: %s (%s)\n", name, newURL)
+	return nil
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// Process exit codes. Each typed command error maps to its own code so
+// scripts can tell failures apart.
+const (
+	exitOK              = 0
+	exitFailure         = 1
+	exitUsage           = 2
+	exitNotFound        = 3
+	exitConflict        = 4
+	exitUnauthenticated = 5
+	exitForbidden       = 6
+)
+
+// UsageError reports a command invoked with missing or invalid arguments.
+type UsageError struct {
+	Usage  string
+	Reason string
+}
+
+func (e *UsageError) Error() string {
+	if e.Reason == "" {
+		return "usage: " + e.Usage
+	}
+	return e.Reason + "\nusage: " + e.Usage
+}
+
+// NotFoundError reports that a named user, feed or other entity does not
+// exist.
+type NotFoundError struct {
+	Kind string
+	Name string
+}
+
+func (e *NotFoundError) Error() string {
+	return fmt.Sprintf("%s %q not found", e.Kind, e.Name)
+}
+
+// ConflictError reports that an operation clashes with existing state, such
+// as registering a name that is taken.
+type ConflictError struct {
+	Message string
+}
+
+func (e *ConflictError) Error() string {
+	return e.Message
+}
+
+// UnauthenticatedError reports that a command needs a logged in user.
+type UnauthenticatedError struct {
+	Reason string
+}
+
+func (e *UnauthenticatedError) Error() string {
+	return "not logged in: " + e.Reason
+}
+
+// ForbiddenError reports that the current user may not do what they asked,
+// such as editing a feed someone else owns.
+type ForbiddenError struct {
+	Reason string
+}
+
+func (e *ForbiddenError) Error() string {
+	return "permission denied: " + e.Reason
+}
+
+// exitCode maps an error returned by a command to the process exit code.
+func exitCode(err error) int {
+	var (
+		usage    *UsageError
+		notFound *NotFoundError
+		conflict *ConflictError
+		unauth   *UnauthenticatedError
+		denied   *ForbiddenError
+	)
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.As(err, &usage):
+		return exitUsage
+	case errors.As(err, &notFound):
+		return exitNotFound
+	case errors.As(err, &conflict):
+		return exitConflict
+	case errors.As(err, &unauth):
+		return exitUnauthenticated
+	case errors.As(err, &denied):
+		return exitForbidden
+	}
+	return exitFailure
+}
+
+// isUniqueViolation reports whether err is a Postgres or SQLite unique
+// constraint violation.
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pqErr.Code == "23505"
+	}
+	var sqliteErr *sqlite.Error
+	if errors.As(err, &sqliteErr) {
+		code := sqliteErr.Code()
+		return code == sqlite3.SQLITE_CONSTRAINT_UNIQUE || code == sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY
+	}
+	return false
+}
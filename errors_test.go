@@ -0,0 +1,31 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"nil", nil, exitOK},
+		{"plain", errors.New("boom"), exitFailure},
+		{"usage", &UsageError{Usage: "login <name>"}, exitUsage},
+		{"not found", &NotFoundError{Kind: "user", Name: "alice"}, exitNotFound},
+		{"conflict", &ConflictError{Message: "taken"}, exitConflict},
+		{"unauthenticated", &UnauthenticatedError{Reason: "log in"}, exitUnauthenticated},
+		{"forbidden", &ForbiddenError{Reason: "not yours"}, exitForbidden},
+		{"wrapped", fmt.Errorf("couldn't start: %w", &NotFoundError{Kind: "profile", Name: "work"}), exitNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := exitCode(tt.err); got != tt.want {
+				t.Errorf("exitCode(%v) = %d, want %d", tt.err, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/isaacjstriker/gatorapp/internal/database"
+)
+
+// opml is an OPML 2.0 subscription list, the format feed readers use to
+// import and export what they follow.
+type opml struct {
+	XMLName xml.Name      `xml:"opml"`
+	Version string        `xml:"version,attr"`
+	Title   string        `xml:"head>title"`
+	Created string        `xml:"head>dateCreated"`
+	Body    []opmlOutline `xml:"body>outline"`
+}
+
+type opmlOutline struct {
+	Text   string `xml:"text,attr"`
+	Type   string `xml:"type,attr,omitempty"`
+	XMLURL string `xml:"xmlUrl,attr,omitempty"`
+	// Category holds the feed's tags, comma separated.
+	Category string        `xml:"category,attr,omitempty"`
+	Outlines []opmlOutline `xml:"outline"`
+}
+
+// handlerExport writes the current user's followed feeds to stdout as OPML,
+// with folders as nested outlines and tags as categories.
+func handlerExport(s *State, user database.User, cmd Command) error {
+	folder, tag, err := followFilter(s, user, cmd)
+	if err != nil {
+		return err
+	}
+	follows, err := s.Queries.GetFeedFollowsForUser(context.Background(), database.GetFeedFollowsForUserParams{
+		UserID: user.ID,
+		Folder: folder,
+		Tag:    tag,
+	})
+	if err != nil {
+		return fmt.Errorf("could not fetch followed feeds: %w", err)
+	}
+
+	doc := opml{
+		Version: "2.0",
+		Title:   fmt.Sprintf("%s's feeds in gator", user.Name),
+		Created: time.Now().UTC().Format(time.RFC1123Z),
+	}
+	folders := make(map[string]int)
+	for _, follow := range follows {
+		feed := opmlOutline{
+			Text:     follow.FeedName,
+			Type:     "rss",
+			XMLURL:   follow.FeedUrl,
+			Category: strings.Join(follow.Tags, ","),
+		}
+		if follow.FolderName == "" {
+			doc.Body = append(doc.Body, feed)
+			continue
+		}
+		i, ok := folders[follow.FolderName]
+		if !ok {
+			i = len(doc.Body)
+			folders[follow.FolderName] = i
+			doc.Body = append(doc.Body, opmlOutline{Text: follow.FolderName})
+		}
+		doc.Body[i].Outlines = append(doc.Body[i].Outlines, feed)
+	}
+
+	out, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return fmt.Errorf("could not encode opml: %w", err)
+	}
+	os.Stdout.WriteString(xml.Header)
+	os.Stdout.Write(out)
+	fmt.Println()
+	return nil
+}
@@ -0,0 +1,335 @@
+package main
+
+import (
+	"compress/gzip"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/andybalholm/brotli"
+	"github.com/isaacjstriker/gatorapp/internal/config"
+	"github.com/isaacjstriker/gatorapp/internal/hostlimit"
+	"github.com/isaacjstriker/gatorapp/internal/metrics"
+)
+
+// errFeedGone is returned when the server answers 410 Gone, meaning the feed
+// has been removed for good and should no longer be polled.
+var errFeedGone = errors.New("feed is gone")
+
+// errFeedTooLarge is returned when a feed body exceeds the configured limit.
+var errFeedTooLarge = errors.New("feed exceeds maximum size")
+
+const maxRedirects = 10
+
+// maxFaviconBytes caps the size of a site icon; real ones are a few KB.
+const maxFaviconBytes = 256 << 10
+
+// retryAfterError is returned when a host has asked us, through a 429 or
+// 503 response with Retry-After, not to come back before until.
+type retryAfterError struct {
+	host  string
+	until time.Time
+}
+
+func (e *retryAfterError) Error() string {
+	return fmt.Sprintf("%s asked to retry after %s", e.host, e.until.Format(time.RFC3339))
+}
+
+// Fetcher downloads feeds with the limits from config.FetcherConfig applied.
+type Fetcher struct {
+	client       *http.Client
+	limiter      *hostlimit.Limiter
+	metrics      *metrics.Metrics
+	userAgent    string
+	maxBodyBytes int64
+	encodings    []string
+}
+
+func NewFetcher(cfg config.FetcherConfig, m *metrics.Metrics) (*Fetcher, error) {
+	tlsConfig, err := newTLSConfig(cfg.TLS)
+	if err != nil {
+		return nil, err
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	// Decompression is done by Fetch so brotli can be offered alongside gzip
+	// and the size limit applies to the decoded body.
+	transport.DisableCompression = true
+	if cfg.Proxy != "" {
+		proxyURL, err := url.Parse(cfg.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy url: %w", err)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	encodings := cfg.Encodings()
+	for _, encoding := range encodings {
+		if encoding != "gzip" && encoding != "br" {
+			return nil, fmt.Errorf("unsupported content encoding %q", encoding)
+		}
+	}
+
+	return &Fetcher{
+		client: &http.Client{
+			Transport:     transport,
+			Timeout:       cfg.TimeoutOrDefault(),
+			CheckRedirect: checkRedirect,
+		},
+		limiter:      hostlimit.New(cfg.HostIntervalOrDefault(), cfg.HostConcurrencyOrDefault()),
+		metrics:      m,
+		userAgent:    cfg.UserAgentOrDefault(),
+		maxBodyBytes: cfg.MaxBodyBytesOrDefault(),
+		encodings:    encodings,
+	}, nil
+}
+
+func newTLSConfig(cfg config.TLSConfig) (*tls.Config, error) {
+	tlsConfig := &tls.Config{InsecureSkipVerify: cfg.InsecureSkipVerify}
+
+	switch cfg.MinVersion {
+	case "":
+	case "1.0":
+		tlsConfig.MinVersion = tls.VersionTLS10
+	case "1.1":
+		tlsConfig.MinVersion = tls.VersionTLS11
+	case "1.2":
+		tlsConfig.MinVersion = tls.VersionTLS12
+	case "1.3":
+		tlsConfig.MinVersion = tls.VersionTLS13
+	default:
+		return nil, fmt.Errorf("unsupported tls min_version %q", cfg.MinVersion)
+	}
+
+	if cfg.CAFile != "" {
+		pem, err := os.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("could not read tls ca_file: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", cfg.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	return tlsConfig, nil
+}
+
+// redirectTracker remembers where the leading run of permanent redirects
+// for a single request ended up.
+type redirectTracker struct {
+	movedTo string
+	broken  bool
+}
+
+type redirectTrackerKey struct{}
+
+func checkRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= maxRedirects {
+		return fmt.Errorf("stopped after %d redirects", maxRedirects)
+	}
+	t, ok := req.Context().Value(redirectTrackerKey{}).(*redirectTracker)
+	if !ok || t.broken {
+		return nil
+	}
+	switch req.Response.StatusCode {
+	case http.StatusMovedPermanently, http.StatusPermanentRedirect:
+		t.movedTo = req.URL.String()
+	default:
+		t.broken = true
+	}
+	return nil
+}
+
+// Fetch downloads and parses feedURL. When the feed was reached through
+// permanent redirects, movedTo holds the URL they pointed at.
+func (f *Fetcher) Fetch(ctx context.Context, feedURL string) (feed *RSSFeed, movedTo string, err error) {
+	tracker := &redirectTracker{}
+	ctx = context.WithValue(ctx, redirectTrackerKey{}, tracker)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, feedURL, nil)
+	if err != nil {
+		return nil, "", err
+	}
+
+	req.Header.Set("User-Agent", f.userAgent)
+	if len(f.encodings) > 0 {
+		req.Header.Set("Accept-Encoding", strings.Join(f.encodings, ", "))
+	}
+
+	host := strings.ToLower(req.URL.Hostname())
+	if until := f.limiter.BlockedUntil(host); !until.IsZero() {
+		return nil, "", &retryAfterError{host: host, until: until}
+	}
+	release, err := f.limiter.Acquire(ctx, host)
+	if err != nil {
+		return nil, "", err
+	}
+	defer release()
+
+	start := time.Now()
+	status := "error"
+	defer func() {
+		f.metrics.ObserveFetch(status, time.Since(start))
+	}()
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+	status = strconv.Itoa(resp.StatusCode)
+
+	if resp.StatusCode == http.StatusGone {
+		return nil, tracker.movedTo, errFeedGone
+	}
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable {
+		if until, ok := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+			f.limiter.Block(host, until)
+			return nil, tracker.movedTo, &retryAfterError{host: host, until: until}
+		}
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, tracker.movedTo, errors.New("failed to fetch feed: " + resp.Status)
+	}
+	if resp.ContentLength > f.maxBodyBytes {
+		return nil, tracker.movedTo, fmt.Errorf("%w: %d bytes", errFeedTooLarge, resp.ContentLength)
+	}
+
+	body, err := f.readBody(resp)
+	if err != nil {
+		return nil, tracker.movedTo, err
+	}
+
+	feed, err = parseFeed(body)
+	if err != nil {
+		return nil, tracker.movedTo, err
+	}
+	return feed, tracker.movedTo, nil
+}
+
+// readBody decodes the response body and reads at most maxBodyBytes of it.
+func (f *Fetcher) readBody(resp *http.Response) ([]byte, error) {
+	raw := &countingReader{r: resp.Body}
+	defer func() {
+		f.metrics.AddBytes(raw.n)
+	}()
+
+	var r io.Reader = raw
+	switch strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding"))) {
+	case "", "identity":
+	case "gzip", "x-gzip":
+		gz, err := gzip.NewReader(raw)
+		if err != nil {
+			return nil, fmt.Errorf("could not decode gzip body: %w", err)
+		}
+		defer gz.Close()
+		r = gz
+	case "br":
+		r = brotli.NewReader(raw)
+	default:
+		return nil, fmt.Errorf("unsupported content encoding %q", resp.Header.Get("Content-Encoding"))
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r, f.maxBodyBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(body)) > f.maxBodyBytes {
+		return nil, fmt.Errorf("%w of %d bytes", errFeedTooLarge, f.maxBodyBytes)
+	}
+	return body, nil
+}
+
+// FetchFavicon downloads a site icon. A missing icon, or a response that
+// isn't an image, gives no data and no error.
+func (f *Fetcher) FetchFavicon(ctx context.Context, iconURL string) (data []byte, contentType string, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, iconURL, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	req.Header.Set("User-Agent", f.userAgent)
+
+	host := strings.ToLower(req.URL.Hostname())
+	release, err := f.limiter.Acquire(ctx, host)
+	if err != nil {
+		return nil, "", err
+	}
+	defer release()
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone {
+		return nil, "", nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", errors.New("failed to fetch favicon: " + resp.Status)
+	}
+
+	data, err = io.ReadAll(io.LimitReader(resp.Body, maxFaviconBytes+1))
+	if err != nil {
+		return nil, "", err
+	}
+	if len(data) > maxFaviconBytes {
+		return nil, "", fmt.Errorf("favicon exceeds %d bytes", maxFaviconBytes)
+	}
+
+	// Servers often send icons as application/octet-stream, or send an
+	// HTML error page with a 200.
+	contentType, _, _ = strings.Cut(resp.Header.Get("Content-Type"), ";")
+	if !strings.HasPrefix(contentType, "image/") {
+		contentType, _, _ = strings.Cut(http.DetectContentType(data), ";")
+	}
+	if !strings.HasPrefix(contentType, "image/") || len(data) == 0 {
+		return nil, "", nil
+	}
+	return data, contentType, nil
+}
+
+// countingReader counts the bytes read through it.
+type countingReader struct {
+	r io.Reader
+	n int
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += n
+	return n, err
+}
+
+// parseRetryAfter understands both forms of the Retry-After header: a number
+// of seconds or an HTTP date.
+func parseRetryAfter(value string, now time.Time) (time.Time, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return time.Time{}, false
+		}
+		return now.Add(time.Duration(seconds) * time.Second), true
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		return t, true
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,258 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/andybalholm/brotli"
+
+	"github.com/isaacjstriker/gatorapp/internal/config"
+	"github.com/isaacjstriker/gatorapp/internal/metrics"
+)
+
+const testFeedXML = `<?xml version="1.0"?>
+<rss version="2.0"><channel><title>Example</title>
+<item><title>Post 1</title><link>https://example.com/posts/1</link></item>
+</channel></rss>`
+
+// newTestFetcher returns a Fetcher for cfg that doesn't space out requests,
+// since every test server is on the same host.
+func newTestFetcher(t *testing.T, cfg config.FetcherConfig) *Fetcher {
+	t.Helper()
+	cfg.HostInterval = config.Duration{Duration: time.Nanosecond}
+	f, err := NewFetcher(cfg, metrics.New(nil))
+	if err != nil {
+		t.Fatalf("NewFetcher: %v", err)
+	}
+	return f
+}
+
+func gzipped(t *testing.T, data string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write([]byte(data)); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func brotlied(t *testing.T, data string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := brotli.NewWriter(&buf)
+	if _, err := w.Write([]byte(data)); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// serveBody answers every request with body, sent with the given
+// Content-Encoding. A chunked body has no Content-Length.
+func serveBody(t *testing.T, encoding string, body []byte, chunked bool) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if encoding != "" {
+			w.Header().Set("Content-Encoding", encoding)
+		}
+		if chunked {
+			w.Write(body[:1])
+			w.(http.Flusher).Flush()
+			w.Write(body[1:])
+			return
+		}
+		w.Write(body)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestFetchEncodings(t *testing.T) {
+	tests := []struct {
+		name     string
+		encoding string
+		body     []byte
+		wantErr  bool
+	}{
+		{"identity", "", []byte(testFeedXML), false},
+		{"gzip", "gzip", gzipped(t, testFeedXML), false},
+		{"x-gzip", "x-gzip", gzipped(t, testFeedXML), false},
+		{"brotli", "br", brotlied(t, testFeedXML), false},
+		{"corrupt gzip", "gzip", []byte(testFeedXML), true},
+		{"unsupported", "compress", []byte(testFeedXML), true},
+	}
+	f := newTestFetcher(t, config.FetcherConfig{})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := serveBody(t, tt.encoding, tt.body, false)
+			feed, _, err := f.Fetch(context.Background(), srv.URL)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("Fetch succeeded, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Fetch: %v", err)
+			}
+			if feed.Channel.Title != "Example" || len(feed.Channel.Item) != 1 {
+				t.Errorf("Fetch parsed %+v, want the Example feed with one post", feed.Channel)
+			}
+		})
+	}
+}
+
+func TestFetchAcceptEncoding(t *testing.T) {
+	tests := []struct {
+		name      string
+		encodings []string
+		want      string
+	}{
+		{"default", nil, "gzip, br"},
+		{"gzip only", []string{"gzip"}, "gzip"},
+		{"none", []string{}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got atomic.Value
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got.Store(r.Header.Get("Accept-Encoding"))
+				w.Write([]byte(testFeedXML))
+			}))
+			defer srv.Close()
+			f := newTestFetcher(t, config.FetcherConfig{AcceptEncoding: tt.encodings})
+			if _, _, err := f.Fetch(context.Background(), srv.URL); err != nil {
+				t.Fatalf("Fetch: %v", err)
+			}
+			if got.Load() != tt.want {
+				t.Errorf("Accept-Encoding = %q, want %q", got.Load(), tt.want)
+			}
+		})
+	}
+
+	if _, err := NewFetcher(config.FetcherConfig{AcceptEncoding: []string{"deflate"}}, metrics.New(nil)); err == nil {
+		t.Error("NewFetcher accepted an unsupported encoding")
+	}
+}
+
+func TestFetchSizeLimit(t *testing.T) {
+	limit := int64(len(testFeedXML))
+	big := testFeedXML + strings.Repeat(" ", 100)
+	tests := []struct {
+		name     string
+		encoding string
+		body     []byte
+		chunked  bool
+		wantErr  bool
+	}{
+		{"at the limit", "", []byte(testFeedXML), false, false},
+		{"content length over", "", []byte(big), false, true},
+		{"chunked over", "", []byte(big), true, true},
+		{"gzip at the limit", "gzip", gzipped(t, testFeedXML), false, false},
+		{"gzip decoded over", "gzip", gzipped(t, big), false, true},
+		{"brotli decoded over", "br", brotlied(t, big), true, true},
+	}
+	f := newTestFetcher(t, config.FetcherConfig{MaxBodyBytes: limit})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.wantErr && int64(len(tt.body)) > limit && tt.encoding != "" {
+				t.Fatalf("compressed body is %d bytes, want it under the %d byte limit", len(tt.body), limit)
+			}
+			srv := serveBody(t, tt.encoding, tt.body, tt.chunked)
+			_, _, err := f.Fetch(context.Background(), srv.URL)
+			if tt.wantErr && !errors.Is(err, errFeedTooLarge) {
+				t.Errorf("Fetch error = %v, want errFeedTooLarge", err)
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("Fetch: %v", err)
+			}
+		})
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name  string
+		value string
+		want  time.Time
+		ok    bool
+	}{
+		{"empty", "", time.Time{}, false},
+		{"seconds", "120", now.Add(2 * time.Minute), true},
+		{"spaces", " 30 ", now.Add(30 * time.Second), true},
+		{"zero", "0", now, true},
+		{"negative", "-5", time.Time{}, false},
+		{"http date", "Mon, 02 Mar 2026 13:00:00 GMT", now.Add(time.Hour), true},
+		{"garbage", "later", time.Time{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseRetryAfter(tt.value, now)
+			if ok != tt.ok || !got.Equal(tt.want) {
+				t.Errorf("parseRetryAfter(%q) = %v, %v, want %v, %v", tt.value, got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestFetchRetryAfter(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     int
+		retryAfter string
+		blocked    bool
+	}{
+		{"429 with retry-after", http.StatusTooManyRequests, "3600", true},
+		{"503 with retry-after", http.StatusServiceUnavailable, "3600", true},
+		{"429 without retry-after", http.StatusTooManyRequests, "", false},
+		{"500 with retry-after", http.StatusInternalServerError, "3600", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests atomic.Int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests.Add(1)
+				if tt.retryAfter != "" {
+					w.Header().Set("Retry-After", tt.retryAfter)
+				}
+				w.WriteHeader(tt.status)
+			}))
+			defer srv.Close()
+			f := newTestFetcher(t, config.FetcherConfig{})
+
+			for range 2 {
+				_, _, err := f.Fetch(context.Background(), srv.URL)
+				var retryErr *retryAfterError
+				if errors.As(err, &retryErr) != tt.blocked {
+					t.Errorf("Fetch error = %v, want a retry-after error: %v", err, tt.blocked)
+				}
+				if err == nil {
+					t.Error("Fetch succeeded, want an error")
+				}
+			}
+			want := int32(2)
+			if tt.blocked {
+				// The second fetch is refused without asking the server.
+				want = 1
+			}
+			if got := requests.Load(); got != want {
+				t.Errorf("server got %d requests, want %d", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,401 @@
+package main
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base32"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/isaacjstriker/gatorapp/internal/database"
+	"github.com/isaacjstriker/gatorapp/internal/fever"
+)
+
+// faviconMaxAge is how long a feed's icon, or the lack of one, is kept
+// before agg looks again.
+const faviconMaxAge = 30 * 24 * time.Hour
+
+// feverStore backs the Fever API with the posts, feeds and folders of the
+// user whose api_key a client sends.
+type feverStore struct {
+	s *State
+}
+
+func (fs feverStore) Authenticate(ctx context.Context, apiKey string) (string, error) {
+	if apiKey == "" {
+		return "", fever.ErrUnauthorized
+	}
+	user, err := fs.s.Queries.GetUserByFeverAPIKey(ctx, sql.NullString{String: apiKey, Valid: true})
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", fever.ErrUnauthorized
+	}
+	if err != nil {
+		return "", err
+	}
+	if user.DisabledAt.Valid {
+		return "", fever.ErrUnauthorized
+	}
+
+	// Syncing counts as activity, for the users listing.
+	recordActivity(fs.s, user)
+	return user.ID.String(), nil
+}
+
+func (fs feverStore) LastRefreshed(ctx context.Context, user string) (time.Time, error) {
+	userID, err := uuid.Parse(user)
+	if err != nil {
+		return time.Time{}, err
+	}
+	last, err := fs.s.Queries.GetFeverLastRefreshed(ctx, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return time.Time{}, nil
+	}
+	return last.Time, err
+}
+
+func (fs feverStore) Groups(ctx context.Context, user string) ([]fever.Group, error) {
+	userID, err := uuid.Parse(user)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := fs.s.Queries.GetFeverGroups(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	groups := make([]fever.Group, 0, len(rows))
+	for _, row := range rows {
+		groups = append(groups, fever.Group{ID: row.FeverID, Title: row.Name})
+	}
+	return groups, nil
+}
+
+func (fs feverStore) Feeds(ctx context.Context, user string) ([]fever.Feed, error) {
+	userID, err := uuid.Parse(user)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := fs.s.Queries.GetFeverFeeds(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	feeds := make([]fever.Feed, 0, len(rows))
+	for _, row := range rows {
+		feeds = append(feeds, fever.Feed{
+			ID:          row.FeverID,
+			GroupID:     row.GroupID.Int64,
+			Title:       row.Title,
+			URL:         row.Url,
+			SiteURL:     siteRoot(row.Url),
+			IsSpark:     row.Muted,
+			HasFavicon:  row.HasFavicon,
+			LastUpdated: row.LastFetchedAt.Time,
+		})
+	}
+	return feeds, nil
+}
+
+func (fs feverStore) Favicons(ctx context.Context, user string) ([]fever.Favicon, error) {
+	userID, err := uuid.Parse(user)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := fs.s.Queries.GetFeverFavicons(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	favicons := make([]fever.Favicon, 0, len(rows))
+	for _, row := range rows {
+		favicons = append(favicons, fever.Favicon{ID: row.FeverID, ContentType: row.ContentType, Data: row.Data})
+	}
+	return favicons, nil
+}
+
+func (fs feverStore) Items(ctx context.Context, user string, q fever.ItemQuery) ([]fever.Item, error) {
+	userID, err := uuid.Parse(user)
+	if err != nil {
+		return nil, err
+	}
+
+	// The three queries return the same columns.
+	var rows []database.GetFeverItemsAfterRow
+	switch {
+	case len(q.WithIDs) > 0:
+		byID, err := fs.s.Queries.GetFeverItemsByID(ctx, database.GetFeverItemsByIDParams{UserID: userID, Ids: q.WithIDs})
+		if err != nil {
+			return nil, err
+		}
+		for _, row := range byID {
+			rows = append(rows, database.GetFeverItemsAfterRow(row))
+		}
+	case q.MaxID > 0:
+		before, err := fs.s.Queries.GetFeverItemsBefore(ctx, database.GetFeverItemsBeforeParams{
+			UserID:   userID,
+			MaxID:    q.MaxID,
+			MaxItems: fever.MaxItems,
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, row := range before {
+			rows = append(rows, database.GetFeverItemsAfterRow(row))
+		}
+	default:
+		rows, err = fs.s.Queries.GetFeverItemsAfter(ctx, database.GetFeverItemsAfterParams{
+			UserID:   userID,
+			SinceID:  q.SinceID,
+			MaxItems: fever.MaxItems,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	items := make([]fever.Item, 0, len(rows))
+	for _, row := range rows {
+		createdOn := row.CreatedAt
+		if row.PublishedAt.Valid {
+			createdOn = row.PublishedAt.Time
+		}
+		items = append(items, fever.Item{
+			ID:        row.FeverID,
+			FeedID:    row.FeedFeverID,
+			Title:     row.Title,
+			Author:    row.Author,
+			HTML:      row.Description.String,
+			URL:       row.Url,
+			IsSaved:   row.StarredAt.Valid,
+			IsRead:    row.ReadAt.Valid,
+			CreatedOn: createdOn,
+		})
+	}
+	return items, nil
+}
+
+func (fs feverStore) TotalItems(ctx context.Context, user string) (int64, error) {
+	userID, err := uuid.Parse(user)
+	if err != nil {
+		return 0, err
+	}
+	return fs.s.Queries.CountFeverItems(ctx, userID)
+}
+
+func (fs feverStore) UnreadItemIDs(ctx context.Context, user string) ([]int64, error) {
+	userID, err := uuid.Parse(user)
+	if err != nil {
+		return nil, err
+	}
+	return fs.s.Queries.GetFeverUnreadItemIDs(ctx, userID)
+}
+
+func (fs feverStore) SavedItemIDs(ctx context.Context, user string) ([]int64, error) {
+	userID, err := uuid.Parse(user)
+	if err != nil {
+		return nil, err
+	}
+	return fs.s.Queries.GetFeverSavedItemIDs(ctx, userID)
+}
+
+func (fs feverStore) MarkItem(ctx context.Context, user string, id int64, as string) error {
+	userID, err := uuid.Parse(user)
+	if err != nil {
+		return err
+	}
+	postID, err := fs.s.Queries.GetFeverPostID(ctx, database.GetFeverPostIDParams{FeverID: id, UserID: userID})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	now := sql.NullTime{Time: time.Now().UTC(), Valid: true}
+	switch as {
+	case fever.AsRead:
+		return fs.s.Queries.SetPostRead(ctx, database.SetPostReadParams{UserID: userID, PostID: postID, ReadAt: now})
+	case fever.AsUnread:
+		return fs.s.Queries.SetPostRead(ctx, database.SetPostReadParams{UserID: userID, PostID: postID})
+	case fever.AsSaved:
+		return fs.s.Queries.SetPostStarred(ctx, database.SetPostStarredParams{UserID: userID, PostID: postID, StarredAt: now})
+	case fever.AsUnsaved:
+		return fs.s.Queries.SetPostStarred(ctx, database.SetPostStarredParams{UserID: userID, PostID: postID})
+	}
+	return fmt.Errorf("unknown mark %q", as)
+}
+
+func (fs feverStore) MarkFeedRead(ctx context.Context, user string, id int64, before time.Time) error {
+	userID, err := uuid.Parse(user)
+	if err != nil {
+		return err
+	}
+	_, err = fs.s.Queries.MarkFeverFeedRead(ctx, database.MarkFeverFeedReadParams{
+		ReadAt: time.Now().UTC(),
+		UserID: userID,
+		FeedID: id,
+		Before: before.UTC(),
+	})
+	return err
+}
+
+func (fs feverStore) MarkGroupRead(ctx context.Context, user string, id int64, before time.Time) error {
+	userID, err := uuid.Parse(user)
+	if err != nil {
+		return err
+	}
+	// Muted feeds are the sparks.
+	if id == fever.GroupKindling || id == fever.GroupSparks {
+		_, err = fs.s.Queries.MarkFeverSuperGroupRead(ctx, database.MarkFeverSuperGroupReadParams{
+			ReadAt: time.Now().UTC(),
+			UserID: userID,
+			Sparks: id == fever.GroupSparks,
+			Before: before.UTC(),
+		})
+		return err
+	}
+	_, err = fs.s.Queries.MarkFeverGroupRead(ctx, database.MarkFeverGroupReadParams{
+		ReadAt:  time.Now().UTC(),
+		UserID:  userID,
+		GroupID: id,
+		Before:  before.UTC(),
+	})
+	return err
+}
+
+func newFeverHandler(s *State) *fever.Handler {
+	return &fever.Handler{
+		Store:  feverStore{s: s},
+		Logger: slog.Default(),
+	}
+}
+
+// siteRoot returns the root of the site rawURL is on, or "" if it isn't
+// an http or https URL.
+func siteRoot(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return ""
+	}
+	return u.Scheme + "://" + u.Host + "/"
+}
+
+// feverAPIKey is the api_key a Fever client sends for a user name and
+// password: the MD5 of "name:password". Fever asks for an email address;
+// gator users enter their user name there instead.
+func feverAPIKey(name, password string) string {
+	sum := md5.Sum([]byte(name + ":" + password))
+	return hex.EncodeToString(sum[:])
+}
+
+// newFeverPassword returns a random password that is easy to type on a
+// phone.
+func newFeverPassword() (string, error) {
+	b := make([]byte, 10)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return strings.ToLower(base32.StdEncoding.EncodeToString(b)), nil
+}
+
+// handlerFeverEnable gives the user a new Fever password, replacing any
+// they had.
+func handlerFeverEnable(s *State, user database.User, cmd Command) error {
+	password, err := newFeverPassword()
+	if err != nil {
+		return fmt.Errorf("could not generate password: %w", err)
+	}
+	if _, err := s.Queries.SetFeverAPIKey(context.Background(), database.SetFeverAPIKeyParams{
+		ID:          user.ID,
+		FeverApiKey: sql.NullString{String: feverAPIKey(user.Name, password), Valid: true},
+		UpdatedAt:   time.Now().UTC(),
+	}); err != nil {
+		return fmt.Errorf("could not enable Fever: %w", err)
+	}
+
+	if user.FeverApiKey.Valid {
+		fmt.Println("Your old Fever password no longer works.")
+	}
+	fmt.Println("Sign in to your Fever client with:")
+	fmt.Printf("  URL:      %s\n", feverURL(s))
+	fmt.Printf("  Email:    %s\n", user.Name)
+	fmt.Printf("  Password: %s\n", password)
+	fmt.Println("The password is not stored; run fever enable again for a new one.")
+	return nil
+}
+
+func handlerFeverDisable(s *State, user database.User, cmd Command) error {
+	if !user.FeverApiKey.Valid {
+		fmt.Println("Fever is not enabled")
+		return nil
+	}
+	if _, err := s.Queries.SetFeverAPIKey(context.Background(), database.SetFeverAPIKeyParams{ID: user.ID, UpdatedAt: time.Now().UTC()}); err != nil {
+		return fmt.Errorf("could not disable Fever: %w", err)
+	}
+	fmt.Println("Fever disabled; your clients can no longer sign in.")
+	return nil
+}
+
+func handlerFeverShow(s *State, user database.User, cmd Command) error {
+	if !user.FeverApiKey.Valid {
+		fmt.Println("Fever is not enabled. Run fever enable to get a password.")
+		return nil
+	}
+	fmt.Println("Fever is enabled.")
+	fmt.Printf("  URL:   %s\n", feverURL(s))
+	fmt.Printf("  Email: %s\n", user.Name)
+	return nil
+}
+
+// feverURL is where serve answers Fever clients, as far as the config
+// tells us.
+func feverURL(s *State) string {
+	addr := s.Config.Server.ListenAddrOrDefault()
+	if strings.HasPrefix(addr, ":") {
+		addr = "<this host>" + addr
+	}
+	return "http://" + addr + "/fever/"
+}
+
+// maybeFetchFavicon stores the icon of a feed's site, unless it was looked
+// for recently. It is only for Fever clients, so failures are logged and
+// otherwise ignored.
+func maybeFetchFavicon(s *State, feed database.Feed, feedData *RSSFeed) {
+	ctx := context.Background()
+	checkedAt, err := s.Queries.GetFaviconCheckedAt(ctx, feed.ID)
+	if err == nil && time.Since(checkedAt) < faviconMaxAge {
+		return
+	}
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		feedLog(feed).Error("couldn't check favicon", "error", err)
+		return
+	}
+
+	site := siteRoot(feedData.Channel.Link)
+	if site == "" {
+		site = siteRoot(feed.Url)
+	}
+	if site == "" {
+		return
+	}
+	data, contentType, err := s.Fetcher.FetchFavicon(ctx, site+"favicon.ico")
+	if err != nil {
+		// Recorded as missing, so a broken site isn't asked on every fetch.
+		feedLog(feed).Debug("couldn't fetch favicon", "site", site, "error", err)
+	}
+
+	if err := s.Queries.UpsertFavicon(ctx, database.UpsertFaviconParams{
+		FeedID:      feed.ID,
+		CheckedAt:   time.Now().UTC(),
+		ContentType: contentType,
+		Data:        data,
+	}); err != nil {
+		feedLog(feed).Error("couldn't save favicon", "error", err)
+	}
+}
@@ -0,0 +1,351 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/isaacjstriker/gatorapp/internal/database"
+	"github.com/isaacjstriker/gatorapp/internal/fever"
+)
+
+// feverTest is a Fever server on one backend for a user following feeds
+// with numbered posts.
+type feverTest struct {
+	t      *testing.T
+	store  database.Store
+	srv    *httptest.Server
+	user   database.User
+	apiKey string
+}
+
+func newFeverTest(t *testing.T, ts testStore) *feverTest {
+	t.Helper()
+	ctx := context.Background()
+	user := createTestUser(t, ts.store, time.Now().UTC())
+	cleanupUser(t, ts.store, user.ID)
+	apiKey := feverAPIKey(user.Name, "secret")
+	if _, err := ts.store.SetFeverAPIKey(ctx, database.SetFeverAPIKeyParams{
+		ID:          user.ID,
+		FeverApiKey: sql.NullString{String: apiKey, Valid: true},
+	}); err != nil {
+		t.Fatalf("SetFeverAPIKey: %v", err)
+	}
+	srv := httptest.NewServer(newFeverHandler(&State{Queries: ts.store}))
+	t.Cleanup(srv.Close)
+	return &feverTest{t: t, store: ts.store, srv: srv, user: user, apiKey: apiKey}
+}
+
+// addFeed adds a feed the user follows, with posts posts.
+func (ft *feverTest) addFeed(posts int, muted bool) database.Feed {
+	ft.t.Helper()
+	ctx := context.Background()
+	now := time.Now().UTC()
+	feed, err := ft.store.CreateFeed(ctx, database.CreateFeedParams{
+		ID:        uuid.New(),
+		CreatedAt: now,
+		UpdatedAt: now,
+		Name:      "Fever test",
+		Url:       "https://example.com/" + uuid.NewString() + "/feed.xml",
+		UserID:    ft.user.ID,
+	})
+	if err != nil {
+		ft.t.Fatalf("CreateFeed: %v", err)
+	}
+	if _, err := ft.store.CreateFeedFollow(ctx, database.CreateFeedFollowParams{
+		ID:        uuid.New(),
+		CreatedAt: now,
+		UpdatedAt: now,
+		UserID:    ft.user.ID,
+		FeedID:    feed.ID,
+	}); err != nil {
+		ft.t.Fatalf("CreateFeedFollow: %v", err)
+	}
+	if muted {
+		if _, err := ft.store.UpdateFeedFollowSettings(ctx, database.UpdateFeedFollowSettingsParams{
+			UserID: ft.user.ID,
+			FeedID: feed.ID,
+			Muted:  true,
+			Notify: true,
+		}); err != nil {
+			ft.t.Fatalf("UpdateFeedFollowSettings: %v", err)
+		}
+	}
+	for i := range posts {
+		ft.addPost(feed, fmt.Sprintf("%s/posts/%d", feed.Url, i))
+	}
+	return feed
+}
+
+func (ft *feverTest) addPost(feed database.Feed, postURL string) database.Post {
+	ft.t.Helper()
+	now := time.Now().UTC()
+	post, err := ft.store.CreatePost(context.Background(), database.CreatePostParams{
+		ID:        uuid.New(),
+		CreatedAt: now,
+		UpdatedAt: now,
+		Title:     postURL,
+		Url:       postURL,
+		FeedID:    feed.ID,
+	})
+	if err != nil {
+		ft.t.Fatalf("CreatePost: %v", err)
+	}
+	return post
+}
+
+// call makes a Fever request: query is added to "?api", and form is posted
+// along with the user's api_key.
+func (ft *feverTest) call(query string, form url.Values) map[string]any {
+	ft.t.Helper()
+	if form == nil {
+		form = url.Values{}
+	}
+	if !form.Has("api_key") {
+		form.Set("api_key", strings.ToUpper(ft.apiKey))
+	}
+	target := ft.srv.URL + "/?api"
+	if query != "" {
+		target += "&" + query
+	}
+	resp, err := http.PostForm(target, form)
+	if err != nil {
+		ft.t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		ft.t.Fatalf("%s: status %s", query, resp.Status)
+	}
+	var body map[string]any
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		ft.t.Fatalf("%s: %v", query, err)
+	}
+	return body
+}
+
+// itemIDs returns the IDs of the items in a response, in order.
+func itemIDs(body map[string]any) []int64 {
+	items, _ := body["items"].([]any)
+	ids := make([]int64, 0, len(items))
+	for _, item := range items {
+		ids = append(ids, int64(item.(map[string]any)["id"].(float64)))
+	}
+	return ids
+}
+
+// idList parses a comma-separated list of IDs from a response.
+func idList(body map[string]any, key string) []int64 {
+	var ids []int64
+	s, _ := body[key].(string)
+	for field := range strings.SplitSeq(s, ",") {
+		if id, err := strconv.ParseInt(field, 10, 64); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+func TestFeverAuth(t *testing.T) {
+	for _, ts := range testStores(t) {
+		t.Run(ts.name, func(t *testing.T) {
+			ft := newFeverTest(t, ts)
+			ft.addFeed(1, false)
+			tests := []struct {
+				name   string
+				apiKey string
+				auth   float64
+			}{
+				{"valid key", strings.ToUpper(ft.apiKey), 1},
+				{"wrong key", feverAPIKey(ft.user.Name, "guess"), 0},
+				{"no key", "", 0},
+			}
+			for _, tt := range tests {
+				body := ft.call("items", url.Values{"api_key": {tt.apiKey}})
+				if body["auth"] != tt.auth {
+					t.Errorf("%s: auth = %v, want %v", tt.name, body["auth"], tt.auth)
+				}
+				if got := len(itemIDs(body)); tt.auth == 0 && got != 0 {
+					t.Errorf("%s: got %d items without signing in", tt.name, got)
+				}
+			}
+
+			if _, err := ts.store.SetUserDisabled(context.Background(), database.SetUserDisabledParams{
+				ID:         ft.user.ID,
+				DisabledAt: sql.NullTime{Time: time.Now().UTC(), Valid: true},
+			}); err != nil {
+				t.Fatal(err)
+			}
+			if body := ft.call("", nil); body["auth"] != float64(0) {
+				t.Errorf("disabled user: auth = %v, want 0", body["auth"])
+			}
+		})
+	}
+}
+
+func TestFeverItemPaging(t *testing.T) {
+	for _, ts := range testStores(t) {
+		t.Run(ts.name, func(t *testing.T) {
+			ft := newFeverTest(t, ts)
+			ft.addFeed(fever.MaxItems+5, false)
+			ft.addFeed(2, true)
+			const total = fever.MaxItems + 7
+
+			first := ft.call("items", nil)
+			if first["total_items"] != float64(total) {
+				t.Errorf("total_items = %v, want %d", first["total_items"], total)
+			}
+			page := itemIDs(first)
+			if len(page) != fever.MaxItems || !slices.IsSorted(page) {
+				t.Fatalf("first page has %d items, sorted %v; want %d oldest first", len(page), slices.IsSorted(page), fever.MaxItems)
+			}
+			rest := itemIDs(ft.call(fmt.Sprintf("items&since_id=%d", page[len(page)-1]), nil))
+			if len(rest) != total-fever.MaxItems || rest[0] <= page[len(page)-1] {
+				t.Fatalf("since_id page = %v, want the %d items after %d", rest, total-fever.MaxItems, page[len(page)-1])
+			}
+			all := append(page, rest...)
+
+			newest := itemIDs(ft.call("items&max_id=0", nil))
+			want := slices.Clone(all[len(all)-fever.MaxItems:])
+			slices.Reverse(want)
+			if !slices.Equal(newest, want) {
+				t.Errorf("max_id=0 = %v, want the newest %d, newest first", newest, fever.MaxItems)
+			}
+			older := itemIDs(ft.call(fmt.Sprintf("items&max_id=%d", newest[len(newest)-1]), nil))
+			want = slices.Clone(all[:len(all)-fever.MaxItems])
+			slices.Reverse(want)
+			if !slices.Equal(older, want) {
+				t.Errorf("max_id page = %v, want %v", older, want)
+			}
+
+			picked := []int64{all[3], all[0], all[len(all)-1]}
+			withIDs := itemIDs(ft.call(fmt.Sprintf("items&with_ids=%d,%d,%d", picked[0], picked[1], picked[2]), nil))
+			slices.Sort(picked)
+			if !slices.Equal(withIDs, picked) {
+				t.Errorf("with_ids = %v, want %v", withIDs, picked)
+			}
+		})
+	}
+}
+
+func TestFeverMark(t *testing.T) {
+	for _, ts := range testStores(t) {
+		t.Run(ts.name, func(t *testing.T) {
+			ft := newFeverTest(t, ts)
+			kindling := ft.addFeed(3, false)
+			sparks := ft.addFeed(2, true)
+			other := ft.addFeed(1, false)
+			ids := map[uuid.UUID][]int64{}
+			for _, item := range ft.call("items", nil)["items"].([]any) {
+				item := item.(map[string]any)
+				for _, feed := range []database.Feed{kindling, sparks, other} {
+					if int64(item["feed_id"].(float64)) == feed.FeverID {
+						ids[feed.ID] = append(ids[feed.ID], int64(item["id"].(float64)))
+					}
+				}
+			}
+			unread := func(feeds ...database.Feed) []int64 {
+				var want []int64
+				for _, feed := range feeds {
+					want = append(want, ids[feed.ID]...)
+				}
+				slices.Sort(want)
+				return want
+			}
+			before := strconv.FormatInt(time.Now().Add(time.Minute).Unix(), 10)
+			item := ids[kindling.ID][0]
+
+			steps := []struct {
+				name   string
+				form   url.Values
+				key    string
+				want   []int64
+				reason string
+			}{
+				{
+					name: "item saved",
+					form: url.Values{"mark": {"item"}, "as": {fever.AsSaved}, "id": {fmt.Sprint(item)}},
+					key:  "saved_item_ids",
+					want: []int64{item},
+				},
+				{
+					name: "item read",
+					form: url.Values{"mark": {"item"}, "as": {fever.AsRead}, "id": {fmt.Sprint(item)}},
+					key:  "unread_item_ids",
+					want: slices.DeleteFunc(unread(kindling, sparks, other), func(id int64) bool { return id == item }),
+				},
+				{
+					name: "item unread",
+					form: url.Values{"mark": {"item"}, "as": {fever.AsUnread}, "id": {fmt.Sprint(item)}},
+					key:  "unread_item_ids",
+					want: unread(kindling, sparks, other),
+				},
+				{
+					name: "feed read",
+					form: url.Values{"mark": {"feed"}, "as": {fever.AsRead}, "id": {fmt.Sprint(other.FeverID)}, "before": {before}},
+					key:  "unread_item_ids",
+					want: unread(kindling, sparks),
+				},
+				{
+					name: "sparks read",
+					form: url.Values{"mark": {"group"}, "as": {fever.AsRead}, "id": {"-1"}, "before": {before}},
+					key:  "unread_item_ids",
+					want: unread(kindling),
+				},
+				{
+					name: "kindling read",
+					form: url.Values{"mark": {"group"}, "as": {fever.AsRead}, "id": {"0"}, "before": {before}},
+					key:  "unread_item_ids",
+				},
+				{
+					name: "item unsaved",
+					form: url.Values{"mark": {"item"}, "as": {fever.AsUnsaved}, "id": {fmt.Sprint(item)}},
+					key:  "saved_item_ids",
+				},
+			}
+			for _, step := range steps {
+				body := ft.call("", step.form)
+				if got := idList(body, step.key); !slices.Equal(got, step.want) {
+					t.Errorf("%s: %s = %v, want %v", step.name, step.key, got, step.want)
+				}
+			}
+		})
+	}
+}
+
+// TestFeverIDsNotReused checks that the Fever IDs of deleted feeds and
+// posts aren't given out again, since clients remember them.
+func TestFeverIDsNotReused(t *testing.T) {
+	ctx := context.Background()
+	for _, ts := range testStores(t) {
+		t.Run(ts.name, func(t *testing.T) {
+			ft := newFeverTest(t, ts)
+			keep := ft.addFeed(1, false)
+			gone := ft.addFeed(0, false)
+			post := ft.addPost(gone, gone.Url+"/posts/gone")
+			if err := ts.store.DeleteFeed(ctx, gone.ID); err != nil {
+				t.Fatalf("DeleteFeed: %v", err)
+			}
+
+			feed := ft.addFeed(0, false)
+			if feed.FeverID <= gone.FeverID {
+				t.Errorf("new feed got Fever ID %d, want more than the deleted feed's %d", feed.FeverID, gone.FeverID)
+			}
+			next := ft.addPost(keep, keep.Url+"/posts/next")
+			if next.FeverID <= post.FeverID {
+				t.Errorf("new post got Fever ID %d, want more than the deleted post's %d", next.FeverID, post.FeverID)
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+)
+
+// handlerHelp lists the commands, or describes one in detail.
+func handlerHelp(c *Commands) func(*State, Command) error {
+	return func(s *State, cmd Command) error {
+		if len(cmd.args) == 0 {
+			c.printOverview(os.Stdout)
+			return nil
+		}
+		spec, err := c.lookup(cmd.args[0])
+		if err != nil {
+			return err
+		}
+		if len(cmd.args) == 2 {
+			if spec = spec.subcommand(cmd.args[1]); spec == nil {
+				return &UsageError{Usage: "help [command [subcommand]]", Reason: "unknown subcommand: " + cmd.args[1]}
+			}
+		}
+		c.printCommandHelp(os.Stdout, spec)
+		return nil
+	}
+}
+
+// printOverview writes the top-level usage: global flags and a one-line
+// summary of every command.
+func (c *Commands) printOverview(w io.Writer) {
+	fmt.Fprintln(w, "Usage: gatorapp [global flags] <command> [arguments...]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, name := range c.order {
+		fmt.Fprintf(tw, "  %s\t%s\n", name, c.specs[name].description)
+	}
+	tw.Flush()
+
+	if c.global != nil {
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "Global flags:")
+		c.global.SetOutput(w)
+		c.global.PrintDefaults()
+	}
+
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Run 'gatorapp help <command>' for details on a command.")
+}
+
+// printCommandHelp writes a command's usage, description and flags.
+func (c *Commands) printCommandHelp(w io.Writer, spec *commandSpec) {
+	fmt.Fprintf(w, "Usage: gatorapp %s\n", spec.usageLine())
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, spec.description)
+
+	if len(spec.subcommands) > 0 {
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "Subcommands:")
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		for _, sub := range spec.subcommands {
+			fmt.Fprintf(tw, "  %s\t%s\n", spec.subcommand(sub.name).usageLine(), sub.description)
+		}
+		tw.Flush()
+	}
+
+	if spec.flags != nil {
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "Flags:")
+		fs := spec.flagSet()
+		fs.SetOutput(w)
+		fs.PrintDefaults()
+	}
+}
@@ -0,0 +1,112 @@
+package canonurl
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+)
+
+var ErrUnsupportedScheme = errors.New("url must use http or https")
+
+// Query parameters that only exist for click tracking and never change
+// the document a URL points at.
+var trackingParams = map[string]bool{
+	"fbclid":  true,
+	"gclid":   true,
+	"dclid":   true,
+	"msclkid": true,
+	"yclid":   true,
+	"igshid":  true,
+	"mc_cid":  true,
+	"mc_eid":  true,
+	"_ga":     true,
+}
+
+func isTrackingParam(key string) bool {
+	key = strings.ToLower(key)
+	return strings.HasPrefix(key, "utm_") || trackingParams[key]
+}
+
+// Canonicalize returns the normalized form of a feed or post URL: the scheme
+// and host are lowercased, default ports, fragments and tracking parameters
+// are dropped, the remaining query is sorted and trailing slashes are
+// trimmed from non-root paths.
+func Canonicalize(raw string) (string, error) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return "", fmt.Errorf("could not parse url %q: %w", raw, err)
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return "", fmt.Errorf("%w: %q", ErrUnsupportedScheme, raw)
+	}
+	if u.Host == "" {
+		return "", fmt.Errorf("url %q has no host", raw)
+	}
+
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	port := u.Port()
+	if (u.Scheme == "http" && port == "80") || (u.Scheme == "https" && port == "443") {
+		port = ""
+	}
+	if port != "" {
+		u.Host = net.JoinHostPort(host, port)
+	} else if strings.Contains(host, ":") {
+		u.Host = "[" + host + "]"
+	} else {
+		u.Host = host
+	}
+
+	u.Fragment = ""
+	u.RawFragment = ""
+
+	if u.Path == "" {
+		u.Path = "/"
+	} else if len(u.Path) > 1 {
+		u.Path = strings.TrimRight(u.Path, "/")
+		if u.Path == "" {
+			u.Path = "/"
+		}
+	}
+	u.RawPath = ""
+
+	query := u.Query()
+	for key := range query {
+		if isTrackingParam(key) {
+			query.Del(key)
+		}
+	}
+	u.RawQuery = query.Encode()
+	u.ForceQuery = false
+
+	return u.String(), nil
+}
+
+// Key returns the identity used to detect duplicate feeds. It is the
+// canonical URL without its scheme, so the http and https spellings of the
+// same feed collide.
+func Key(raw string) (string, error) {
+	canonical, err := Canonicalize(raw)
+	if err != nil {
+		return "", err
+	}
+	_, rest, _ := strings.Cut(canonical, "://")
+	return rest, nil
+}
+
+// Variants returns the canonical URL followed by the same URL with the other
+// scheme, in the order they should be tried when looking a feed up.
+func Variants(raw string) ([]string, error) {
+	canonical, err := Canonicalize(raw)
+	if err != nil {
+		return nil, err
+	}
+	if rest, ok := strings.CutPrefix(canonical, "https://"); ok {
+		return []string{canonical, "http://" + rest}, nil
+	}
+	rest := strings.TrimPrefix(canonical, "http://")
+	return []string{canonical, "https://" + rest}, nil
+}
@@ -2,64 +2,625 @@ package config
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
+	"path/filepath"
+	"slices"
+	"time"
 )
 
-const configFileName = ".gatorconfig.json"
+const (
+	configFileName       = "config.json"
+	legacyConfigFileName = ".gatorconfig.json"
+)
+
+// Environment variables that override the config file.
+const (
+	// ConfigEnv names the config file to use.
+	ConfigEnv = "GATOR_CONFIG"
+	// DbURLEnv replaces the database URL of whichever profile is in use.
+	DbURLEnv = "GATOR_DB_URL"
+)
+
+// DefaultProfile names the settings at the top level of the config file,
+// used when no other profile is selected.
+const DefaultProfile = "default"
+
+// ErrNoConfig is returned by Read when the config file doesn't exist.
+var ErrNoConfig = errors.New("no config file")
+
+// ErrUnknownProfile is returned for profile names the config file doesn't
+// define.
+var ErrUnknownProfile = errors.New("unknown profile")
+
+// DefaultMovedFeedThreshold is how many fetches in a row must be permanently
+// redirected to the same URL before a feed's URL is updated.
+const DefaultMovedFeedThreshold = 3
+
+// Defaults applied to unset fields of FetcherConfig.
+const (
+	DefaultFetchTimeout    = 30 * time.Second
+	DefaultMaxBodyBytes    = 10 << 20
+	DefaultUserAgent       = "gator"
+	DefaultConcurrency     = 4
+	DefaultHostConcurrency = 1
+	DefaultHostInterval    = 2 * time.Second
+)
 
 type Config struct {
+	DbURL              string          `json:"db_url"`
+	CurrentUsername    string          `json:"current_user_name"`
+	MovedFeedThreshold int             `json:"moved_feed_threshold,omitempty"`
+	Fetcher            FetcherConfig   `json:"fetcher,omitzero"`
+	Schedule           ScheduleConfig  `json:"schedule,omitzero"`
+	Server             ServerConfig    `json:"server,omitzero"`
+	WebSub             WebSubConfig    `json:"websub,omitzero"`
+	Retention          RetentionConfig `json:"retention,omitzero"`
+	// SMTP is the mail server used for email notifications.
+	SMTP          SMTPConfig          `json:"smtp,omitzero"`
+	Notifications NotificationsConfig `json:"notifications,omitzero"`
+	Digest        DigestConfig        `json:"digest,omitzero"`
+	Log           LogConfig           `json:"log,omitzero"`
+
+	// CurrentProfile is the profile used when --profile isn't given. Empty
+	// means DefaultProfile.
+	CurrentProfile string             `json:"current_profile,omitempty"`
+	Profiles       map[string]Profile `json:"profiles,omitempty"`
+
+	// path is the file the config was read from, and file its contents as
+	// read. Changes are made to file and saved from it, so the profile and
+	// environment overrides applied to this Config are never written back.
+	path    string
+	file    *Config
+	profile string
+	// missingProfile is the file's current_profile when no profile has that
+	// name, and the default profile is used instead.
+	missingProfile string
+}
+
+// Profile is a named set of settings that replace the top-level ones, for
+// switching between databases.
+type Profile struct {
 	DbURL           string `json:"db_url"`
-	CurrentUsername string `json:"current_user_name"`
+	CurrentUsername string `json:"current_user_name,omitempty"`
+	// Fetcher, when set, replaces the top-level fetcher settings.
+	Fetcher *FetcherConfig `json:"fetcher,omitempty"`
 }
 
-func getConfigFilePath() (string, error) {
-	home, err := os.UserHomeDir()
+// SMTPConfig is a mail server to send email through.
+type SMTPConfig struct {
+	// Addr is the server's host:port.
+	Addr     string `json:"addr,omitempty"`
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+	From     string `json:"from,omitempty"`
+	// TLS is "starttls" to require STARTTLS, "tls" to connect with TLS from
+	// the start, or "none". By default STARTTLS is used when offered.
+	TLS string `json:"tls,omitempty"`
+}
+
+// Defaults applied to unset fields of DigestConfig.
+const (
+	DefaultDigestInterval = 24 * time.Hour
+	DefaultDigestMaxPosts = 100
+)
+
+// DigestConfig schedules the email digests agg sends to users who enable
+// them. Digests need an SMTP server.
+type DigestConfig struct {
+	// Interval is how often each user gets a digest.
+	Interval Duration `json:"interval,omitzero"`
+	// MaxPosts caps the posts in one digest.
+	MaxPosts int `json:"max_posts,omitempty"`
+}
+
+// IntervalOrDefault returns the configured interval or
+// DefaultDigestInterval.
+func (dc DigestConfig) IntervalOrDefault() time.Duration {
+	if dc.Interval.Duration <= 0 {
+		return DefaultDigestInterval
+	}
+	return dc.Interval.Duration
+}
+
+// MaxPostsOrDefault returns the configured post cap or
+// DefaultDigestMaxPosts.
+func (dc DigestConfig) MaxPostsOrDefault() int {
+	if dc.MaxPosts <= 0 {
+		return DefaultDigestMaxPosts
+	}
+	return dc.MaxPosts
+}
+
+// Defaults applied to unset fields of NotificationsConfig.
+const (
+	DefaultNotifyMaxAttempts  = 5
+	DefaultNotifyRetryBackoff = time.Minute
+	DefaultNotifyTimeout      = 10 * time.Second
+)
+
+// NotificationsConfig defines where notifications about new posts can be
+// sent. Users pick a sink by name with the notify command.
+type NotificationsConfig struct {
+	Sinks map[string]SinkConfig `json:"sinks,omitempty"`
+	// MaxAttempts is how many times a delivery is tried before giving up.
+	MaxAttempts int `json:"max_attempts,omitempty"`
+	// RetryBackoff is the wait after a first failed delivery. It doubles
+	// with each further failure, up to an hour.
+	RetryBackoff Duration `json:"retry_backoff,omitzero"`
+	// Timeout bounds a single delivery.
+	Timeout Duration `json:"timeout,omitzero"`
+}
+
+// SinkConfig is one place notifications can be sent.
+type SinkConfig struct {
+	// Type is "webhook", "slack", "email" or "exec".
+	Type string `json:"type"`
+	// URL is where webhook and slack sinks POST to.
+	URL string `json:"url,omitempty"`
+	// Secret signs webhook bodies with HMAC-SHA256.
+	Secret string `json:"secret,omitempty"`
+	// To lists an email sink's recipients.
+	To []string `json:"to,omitempty"`
+	// Command is an exec sink's program and arguments.
+	Command []string `json:"command,omitempty"`
+}
+
+// MaxAttemptsOrDefault returns the configured attempt limit or
+// DefaultNotifyMaxAttempts.
+func (nc NotificationsConfig) MaxAttemptsOrDefault() int {
+	if nc.MaxAttempts <= 0 {
+		return DefaultNotifyMaxAttempts
+	}
+	return nc.MaxAttempts
+}
+
+// RetryBackoffOrDefault returns the configured backoff or
+// DefaultNotifyRetryBackoff.
+func (nc NotificationsConfig) RetryBackoffOrDefault() time.Duration {
+	if nc.RetryBackoff.Duration <= 0 {
+		return DefaultNotifyRetryBackoff
+	}
+	return nc.RetryBackoff.Duration
+}
+
+// TimeoutOrDefault returns the configured timeout or DefaultNotifyTimeout.
+func (nc NotificationsConfig) TimeoutOrDefault() time.Duration {
+	if nc.Timeout.Duration <= 0 {
+		return DefaultNotifyTimeout
+	}
+	return nc.Timeout.Duration
+}
+
+// LogConfig controls diagnostic logging, which goes to stderr. The
+// --log-level and --log-format flags override it.
+type LogConfig struct {
+	// Level is "debug", "info", "warn" or "error".
+	Level string `json:"level,omitempty"`
+	// Format is "text" or "json".
+	Format string `json:"format,omitempty"`
+}
+
+// Post states a RetentionConfig can exempt from pruning.
+const (
+	ExemptStarred = "starred"
+	ExemptUnread  = "unread"
+)
+
+// RetentionConfig limits how long posts are kept. Feeds can override the
+// limits with the retention command. Zero limits keep posts forever.
+type RetentionConfig struct {
+	// MaxAge prunes posts published longer ago than this.
+	MaxAge Duration `json:"max_age,omitzero"`
+	// MaxPostsPerFeed prunes all but each feed's newest posts.
+	MaxPostsPerFeed int `json:"max_posts_per_feed,omitempty"`
+	// Exempt lists post states that are never pruned, out of "starred" and
+	// "unread" (unread by any follower of the feed). Nil means starred only;
+	// an empty list exempts nothing.
+	Exempt []string `json:"exempt,omitempty"`
+	// PruneInterval is how often agg prunes posts. Zero leaves pruning to the
+	// prune command.
+	PruneInterval Duration `json:"prune_interval,omitzero"`
+}
+
+// Exemptions reports which post states are exempt from pruning.
+func (rc RetentionConfig) Exemptions() (starred, unread bool, err error) {
+	if rc.Exempt == nil {
+		return true, false, nil
+	}
+	for _, e := range rc.Exempt {
+		switch e {
+		case ExemptStarred:
+			starred = true
+		case ExemptUnread:
+			unread = true
+		default:
+			return false, false, fmt.Errorf("unknown retention exemption %q", e)
+		}
+	}
+	return starred, unread, nil
+}
+
+// DefaultListenAddr is where agg's HTTP server listens when enabled.
+const DefaultListenAddr = ":8080"
+
+// ServerConfig configures the HTTP server agg starts for push callbacks.
+type ServerConfig struct {
+	ListenAddr string `json:"listen_addr,omitempty"`
+}
+
+// ListenAddrOrDefault returns the configured address or DefaultListenAddr.
+func (sc ServerConfig) ListenAddrOrDefault() string {
+	if sc.ListenAddr == "" {
+		return DefaultListenAddr
+	}
+	return sc.ListenAddr
+}
+
+// WebSubConfig enables WebSub push subscriptions for feeds that advertise a
+// hub.
+type WebSubConfig struct {
+	// CallbackURL is the public base URL at which hubs can reach agg's HTTP
+	// server, e.g. "https://gator.example.com". WebSub is off when empty.
+	CallbackURL string `json:"callback_url,omitempty"`
+	// Lease is the subscription lease to ask hubs for. Zero lets the hub
+	// choose.
+	Lease Duration `json:"lease,omitzero"`
+}
+
+func (wc WebSubConfig) Enabled() bool {
+	return wc.CallbackURL != ""
+}
+
+// ScheduleConfig bounds how often agg polls a feed. Between the bounds the
+// interval adapts to how often the feed posts. Zero values fall back to the
+// scheduler's defaults.
+type ScheduleConfig struct {
+	MinInterval Duration `json:"min_interval,omitzero"`
+	MaxInterval Duration `json:"max_interval,omitzero"`
+}
+
+// FetcherConfig controls the HTTP client used to download feeds.
+type FetcherConfig struct {
+	// Timeout bounds a whole request, including reading the body.
+	Timeout Duration `json:"timeout,omitzero"`
+	// MaxBodyBytes caps the size of a feed after decompression.
+	MaxBodyBytes int64  `json:"max_body_bytes,omitempty"`
+	UserAgent    string `json:"user_agent,omitempty"`
+	// Proxy is an http, https or socks5 proxy URL. When empty the standard
+	// HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables are used.
+	Proxy string    `json:"proxy,omitempty"`
+	TLS   TLSConfig `json:"tls,omitzero"`
+	// AcceptEncoding lists the content encodings to request, out of "gzip"
+	// and "br". Nil means both; an empty list disables compression.
+	AcceptEncoding []string `json:"accept_encoding,omitempty"`
+	// Concurrency is how many feeds agg fetches at once.
+	Concurrency int `json:"concurrency,omitempty"`
+	// HostConcurrency caps simultaneous requests to a single host.
+	HostConcurrency int `json:"host_concurrency,omitempty"`
+	// HostInterval is the minimum time between requests to a single host.
+	HostInterval Duration `json:"host_interval,omitzero"`
+}
+
+type TLSConfig struct {
+	// CAFile is a PEM bundle trusted in addition to the system roots.
+	CAFile string `json:"ca_file,omitempty"`
+	// MinVersion is "1.0", "1.1", "1.2" or "1.3".
+	MinVersion         string `json:"min_version,omitempty"`
+	InsecureSkipVerify bool   `json:"insecure_skip_verify,omitempty"`
+}
+
+// Duration is a time.Duration written in config files as a string such as
+// "30s" or "1m30s".
+type Duration struct {
+	time.Duration
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("duration must be a string like \"30s\": %w", err)
+	}
+	parsed, err := time.ParseDuration(s)
 	if err != nil {
-		return "", fmt.Errorf("could not get user home directory: %w", err)
+		return err
 	}
-	return home + "/" + configFileName, nil
+	d.Duration = parsed
+	return nil
 }
 
-func Write(cfg Config) error {
-	configPath, err := getConfigFilePath()
+// TimeoutOrDefault returns the configured timeout or DefaultFetchTimeout.
+func (fc FetcherConfig) TimeoutOrDefault() time.Duration {
+	if fc.Timeout.Duration <= 0 {
+		return DefaultFetchTimeout
+	}
+	return fc.Timeout.Duration
+}
+
+// MaxBodyBytesOrDefault returns the configured body limit or
+// DefaultMaxBodyBytes.
+func (fc FetcherConfig) MaxBodyBytesOrDefault() int64 {
+	if fc.MaxBodyBytes <= 0 {
+		return DefaultMaxBodyBytes
+	}
+	return fc.MaxBodyBytes
+}
+
+// UserAgentOrDefault returns the configured User-Agent or DefaultUserAgent.
+func (fc FetcherConfig) UserAgentOrDefault() string {
+	if fc.UserAgent == "" {
+		return DefaultUserAgent
+	}
+	return fc.UserAgent
+}
+
+// ConcurrencyOrDefault returns the configured concurrency or
+// DefaultConcurrency.
+func (fc FetcherConfig) ConcurrencyOrDefault() int {
+	if fc.Concurrency <= 0 {
+		return DefaultConcurrency
+	}
+	return fc.Concurrency
+}
+
+// HostConcurrencyOrDefault returns the configured per-host concurrency or
+// DefaultHostConcurrency.
+func (fc FetcherConfig) HostConcurrencyOrDefault() int {
+	if fc.HostConcurrency <= 0 {
+		return DefaultHostConcurrency
+	}
+	return fc.HostConcurrency
+}
+
+// HostIntervalOrDefault returns the configured per-host interval or
+// DefaultHostInterval.
+func (fc FetcherConfig) HostIntervalOrDefault() time.Duration {
+	if fc.HostInterval.Duration <= 0 {
+		return DefaultHostInterval
+	}
+	return fc.HostInterval.Duration
+}
+
+// Encodings returns the content encodings to request.
+func (fc FetcherConfig) Encodings() []string {
+	if fc.AcceptEncoding == nil {
+		return []string{"gzip", "br"}
+	}
+	return fc.AcceptEncoding
+}
+
+// Path returns the config file to use: $GATOR_CONFIG when set, otherwise
+// gator/config.json under $XDG_CONFIG_HOME (~/.config by default). The
+// older ~/.gatorconfig.json is still used when it exists and the XDG file
+// doesn't.
+func Path() (string, error) {
+	if path := os.Getenv(ConfigEnv); path != "" {
+		return path, nil
+	}
+	home, err := os.UserHomeDir()
 	if err != nil {
-		return fmt.Errorf("could not get config file path: %w", err)
+		return "", fmt.Errorf("could not get user home directory: %w", err)
+	}
+
+	configHome := os.Getenv("XDG_CONFIG_HOME")
+	if configHome == "" {
+		configHome = filepath.Join(home, ".config")
+	}
+	path := filepath.Join(configHome, "gator", configFileName)
+	if _, err := os.Stat(path); err == nil {
+		return path, nil
+	}
+	legacy := filepath.Join(home, legacyConfigFileName)
+	if _, err := os.Stat(legacy); err == nil {
+		return legacy, nil
 	}
+	return path, nil
+}
 
+// write saves cfg to path atomically: it writes a temporary file next to
+// path and renames it over path, so a crash never leaves a partial file.
+// An existing file keeps its permissions; a new one is private to the user.
+func write(path string, cfg Config) error {
 	data, err := json.MarshalIndent(cfg, "", "  ")
 	if err != nil {
 		return fmt.Errorf("could not encode config to json: %w", err)
 	}
+	data = append(data, '\n')
 
-	err = os.WriteFile(configPath, data, 0600)
-	if err != nil {
-		return fmt.Errorf("could not write config file path: %w", err)
+	perm := os.FileMode(0600)
+	if info, err := os.Stat(path); err == nil {
+		perm = info.Mode().Perm()
+	}
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("could not create config directory: %w", err)
 	}
 
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("could not create temporary config file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("could not write config file: %w", err)
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return fmt.Errorf("could not set config file permissions: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("could not write config file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("could not write config file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("could not replace config file: %w", err)
+	}
 	return nil
 }
 
-func Read() (*Config, error) {
-	var config Config
-	configPath, err := getConfigFilePath()
+// Create writes a new config file at path. It fails if the file exists,
+// unless overwrite is set.
+func Create(path string, cfg Config, overwrite bool) error {
+	if _, err := os.Stat(path); err == nil && !overwrite {
+		return fmt.Errorf("%s: %w", path, fs.ErrExist)
+	}
+	if problems := cfg.problems(); len(problems) > 0 {
+		return &ValidationError{Path: path, Problems: problems}
+	}
+	return write(path, cfg)
+}
+
+// Read loads the config file and applies a profile: the named one, or the
+// file's current_profile when name is empty. A current_profile that names
+// no profile falls back to the default one, and MissingProfile reports it,
+// so that it can still be fixed with the profile commands. $GATOR_DB_URL
+// then overrides the database URL.
+func Read(profile string) (*Config, error) {
+	path, err := Path()
 	if err != nil {
-		return nil, fmt.Errorf("could not get user home directory: %w", err)
+		return nil, err
 	}
 
-	data, err := os.ReadFile(configPath)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w at %s", ErrNoConfig, path)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("could not read config file: %w", err)
 	}
-	err = json.Unmarshal(data, &config)
+	var file Config
+	err = json.Unmarshal(data, &file)
 	if err != nil {
-		return nil, fmt.Errorf("could not decode json: %w", err)
+		return nil, fmt.Errorf("could not decode config file %s: %w", path, err)
+	}
+	if err := validate(path, data, &file); err != nil {
+		return nil, err
+	}
+
+	config := file
+	config.path = path
+	config.file = &file
+	if profile == "" {
+		profile = file.CurrentProfile
+		if _, ok := file.Profiles[profile]; !ok && profile != "" && profile != DefaultProfile {
+			config.missingProfile = profile
+			profile = ""
+		}
+	}
+	if profile != "" && profile != DefaultProfile {
+		p, ok := file.Profiles[profile]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownProfile, profile)
+		}
+		config.profile = profile
+		config.DbURL = p.DbURL
+		config.CurrentUsername = p.CurrentUsername
+		if p.Fetcher != nil {
+			config.Fetcher = *p.Fetcher
+		}
+	}
+	if dbURL := os.Getenv(DbURLEnv); dbURL != "" {
+		config.DbURL = dbURL
 	}
 
 	return &config, nil
 }
 
+// MovedThreshold returns the configured moved feed threshold, falling back
+// to DefaultMovedFeedThreshold when unset.
+func (cfg *Config) MovedThreshold() int {
+	if cfg.MovedFeedThreshold <= 0 {
+		return DefaultMovedFeedThreshold
+	}
+	return cfg.MovedFeedThreshold
+}
+
+// File is the path the config was read from.
+func (cfg *Config) File() string {
+	return cfg.path
+}
+
+// Profile is the name of the profile in use, or DefaultProfile.
+func (cfg *Config) Profile() string {
+	if cfg.profile == "" {
+		return DefaultProfile
+	}
+	return cfg.profile
+}
+
+// MissingProfile returns the file's current_profile when no profile has that
+// name, in which case the default profile is in use instead.
+func (cfg *Config) MissingProfile() string {
+	return cfg.missingProfile
+}
+
+// SetUser logs username in under the profile in use and saves the file.
 func (cfg *Config) SetUser(username string) error {
 	cfg.CurrentUsername = username
-	return Write(*cfg)
+	if cfg.profile == "" {
+		cfg.file.CurrentUsername = username
+	} else {
+		p := cfg.file.Profiles[cfg.profile]
+		p.CurrentUsername = username
+		cfg.file.Profiles[cfg.profile] = p
+	}
+	return write(cfg.path, *cfg.file)
+}
+
+// ProfileNames lists DefaultProfile and the file's profiles, sorted.
+func (cfg *Config) ProfileNames() []string {
+	names := []string{DefaultProfile}
+	for name := range cfg.file.Profiles {
+		names = append(names, name)
+	}
+	slices.Sort(names[1:])
+	return names
+}
+
+// LookupProfile returns the settings of the named profile as stored in the
+// file, before environment overrides.
+func (cfg *Config) LookupProfile(name string) (Profile, bool) {
+	if name == DefaultProfile {
+		return Profile{
+			DbURL:           cfg.file.DbURL,
+			CurrentUsername: cfg.file.CurrentUsername,
+		}, true
+	}
+	p, ok := cfg.file.Profiles[name]
+	return p, ok
+}
+
+// AddProfile adds a profile to the file and saves it.
+func (cfg *Config) AddProfile(name string, p Profile) error {
+	if _, ok := cfg.LookupProfile(name); ok {
+		return fmt.Errorf("profile %s already exists", name)
+	}
+	if cfg.file.Profiles == nil {
+		cfg.file.Profiles = make(map[string]Profile)
+	}
+	cfg.file.Profiles[name] = p
+	return write(cfg.path, *cfg.file)
+}
+
+// UseProfile makes the named profile the file's current one and saves it.
+// It takes effect from the next command.
+func (cfg *Config) UseProfile(name string) error {
+	if _, ok := cfg.LookupProfile(name); !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownProfile, name)
+	}
+	cfg.file.CurrentProfile = name
+	if name == DefaultProfile {
+		cfg.file.CurrentProfile = ""
+	}
+	return write(cfg.path, *cfg.file)
 }
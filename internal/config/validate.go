@@ -0,0 +1,242 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net/url"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// Values accepted by string settings with a fixed set of choices.
+var (
+	logLevels       = []string{"debug", "info", "warn", "error"}
+	logFormats      = []string{"text", "json"}
+	tlsVersions     = []string{"1.0", "1.1", "1.2", "1.3"}
+	smtpTLSModes    = []string{"starttls", "tls", "none"}
+	sinkTypes       = []string{"webhook", "slack", "email", "exec"}
+	acceptEncodings = []string{"gzip", "br"}
+)
+
+// ValidationError lists everything wrong with a config file.
+type ValidationError struct {
+	Path     string
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("config file %s is invalid:\n  - %s", e.Path, strings.Join(e.Problems, "\n  - "))
+}
+
+// validate checks the raw file for keys Config doesn't have and the decoded
+// config for values it can't use.
+func validate(path string, data []byte, cfg *Config) error {
+	var raw any
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	var problems []string
+	problems = append(problems, unknownKeys(raw, reflect.TypeFor[Config](), "")...)
+	problems = append(problems, cfg.problems()...)
+	if len(problems) > 0 {
+		return &ValidationError{Path: path, Problems: problems}
+	}
+	return nil
+}
+
+// unknownKeys walks raw alongside the Go type it decodes into and reports
+// the keys that type has no field for.
+func unknownKeys(raw any, t reflect.Type, prefix string) []string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	obj, ok := raw.(map[string]any)
+	if !ok {
+		return nil
+	}
+
+	var problems []string
+	keys := slices.Sorted(maps.Keys(obj))
+
+	switch t.Kind() {
+	case reflect.Map:
+		for _, key := range keys {
+			problems = append(problems, unknownKeys(obj[key], t.Elem(), prefix+key+".")...)
+		}
+	case reflect.Struct:
+		if t == reflect.TypeFor[Duration]() {
+			return nil
+		}
+		fields := jsonFields(t)
+		for _, key := range keys {
+			ft, ok := fields[key]
+			if !ok {
+				problems = append(problems, unknownKeyProblem(prefix+key, key, fields))
+				continue
+			}
+			problems = append(problems, unknownKeys(obj[key], ft, prefix+key+".")...)
+		}
+	}
+	return problems
+}
+
+// jsonFields maps the JSON names of a struct's exported fields to their
+// types.
+func jsonFields(t reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type)
+	for i := range t.NumField() {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields[name] = f.Type
+	}
+	return fields
+}
+
+func unknownKeyProblem(path, key string, fields map[string]reflect.Type) string {
+	best, bestDist := "", 3
+	for name := range fields {
+		if d := editDistance(strings.ToLower(key), name); d < bestDist || (d == bestDist && name < best) {
+			best, bestDist = name, d
+		}
+	}
+	if best != "" {
+		return fmt.Sprintf("%s: unknown key (did you mean %q?)", path, best)
+	}
+	return fmt.Sprintf("%s: unknown key", path)
+}
+
+// editDistance is the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+// problems reports values that are the right type but can't be used.
+func (cfg *Config) problems() []string {
+	var problems []string
+	oneOf := func(key, value string, allowed []string) {
+		if value != "" && !slices.Contains(allowed, value) {
+			problems = append(problems, fmt.Sprintf("%s: %q is not one of %s", key, value, strings.Join(allowed, ", ")))
+		}
+	}
+	nonNegative := func(key string, d Duration) {
+		if d.Duration < 0 {
+			problems = append(problems, fmt.Sprintf("%s: must not be negative", key))
+		}
+	}
+
+	oneOf("log.level", strings.ToLower(cfg.Log.Level), logLevels)
+	oneOf("log.format", strings.ToLower(cfg.Log.Format), logFormats)
+
+	problems = append(problems, cfg.Fetcher.problems("fetcher")...)
+	nonNegative("schedule.min_interval", cfg.Schedule.MinInterval)
+	nonNegative("schedule.max_interval", cfg.Schedule.MaxInterval)
+	nonNegative("websub.lease", cfg.WebSub.Lease)
+	if cb := cfg.WebSub.CallbackURL; cb != "" {
+		if u, err := url.Parse(cb); err != nil || u.Scheme == "" || u.Host == "" {
+			problems = append(problems, fmt.Sprintf("websub.callback_url: %q is not an absolute URL", cb))
+		}
+	}
+
+	nonNegative("retention.max_age", cfg.Retention.MaxAge)
+	nonNegative("retention.prune_interval", cfg.Retention.PruneInterval)
+	if _, _, err := cfg.Retention.Exemptions(); err != nil {
+		problems = append(problems, "retention.exempt: "+err.Error())
+	}
+
+	oneOf("smtp.tls", cfg.SMTP.TLS, smtpTLSModes)
+	nonNegative("notifications.retry_backoff", cfg.Notifications.RetryBackoff)
+	nonNegative("notifications.timeout", cfg.Notifications.Timeout)
+	for _, name := range slices.Sorted(maps.Keys(cfg.Notifications.Sinks)) {
+		sink := cfg.Notifications.Sinks[name]
+		key := "notifications.sinks." + name
+		switch sink.Type {
+		case "webhook", "slack":
+			if sink.URL == "" {
+				problems = append(problems, key+".url: required for "+sink.Type+" sinks")
+			}
+		case "email":
+			if len(sink.To) == 0 {
+				problems = append(problems, key+".to: required for email sinks")
+			}
+			if cfg.SMTP.Addr == "" {
+				problems = append(problems, key+": email sinks need smtp.addr")
+			}
+		case "exec":
+			if len(sink.Command) == 0 {
+				problems = append(problems, key+".command: required for exec sinks")
+			}
+		case "":
+			problems = append(problems, key+".type: required")
+		default:
+			oneOf(key+".type", sink.Type, sinkTypes)
+		}
+	}
+	nonNegative("digest.interval", cfg.Digest.Interval)
+
+	for _, name := range slices.Sorted(maps.Keys(cfg.Profiles)) {
+		p := cfg.Profiles[name]
+		key := "profiles." + name
+		if name == DefaultProfile {
+			problems = append(problems, key+": "+DefaultProfile+" is the top-level settings and can't be redefined")
+		}
+		if p.DbURL == "" {
+			problems = append(problems, key+".db_url: required")
+		}
+		if p.Fetcher != nil {
+			problems = append(problems, p.Fetcher.problems(key+".fetcher")...)
+		}
+	}
+	return problems
+}
+
+func (fc FetcherConfig) problems(prefix string) []string {
+	var problems []string
+	if fc.Timeout.Duration < 0 {
+		problems = append(problems, prefix+".timeout: must not be negative")
+	}
+	if fc.HostInterval.Duration < 0 {
+		problems = append(problems, prefix+".host_interval: must not be negative")
+	}
+	if fc.Proxy != "" {
+		if _, err := url.Parse(fc.Proxy); err != nil {
+			problems = append(problems, fmt.Sprintf("%s.proxy: %v", prefix, errors.Unwrap(err)))
+		}
+	}
+	if v := fc.TLS.MinVersion; v != "" && !slices.Contains(tlsVersions, v) {
+		problems = append(problems, fmt.Sprintf("%s.tls.min_version: %q is not one of %s", prefix, v, strings.Join(tlsVersions, ", ")))
+	}
+	for _, enc := range fc.AcceptEncoding {
+		if !slices.Contains(acceptEncodings, enc) {
+			problems = append(problems, fmt.Sprintf("%s.accept_encoding: %q is not one of %s", prefix, enc, strings.Join(acceptEncodings, ", ")))
+		}
+	}
+	return problems
+}
@@ -0,0 +1,157 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: backup.sql
+
+package database
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const getAllPostStates = `-- name: GetAllPostStates :many
+SELECT user_id, post_id, read_at, starred_at, hidden_at FROM post_states
+WHERE read_at IS NOT NULL OR starred_at IS NOT NULL OR hidden_at IS NOT NULL
+ORDER BY user_id, post_id
+`
+
+// Every user's read, starred and hidden marks, leaving out rows with none.
+func (q *Queries) GetAllPostStates(ctx context.Context) ([]PostState, error) {
+	rows, err := q.db.QueryContext(ctx, getAllPostStates)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []PostState
+	for rows.Next() {
+		var i PostState
+		if err := rows.Scan(
+			&i.UserID,
+			&i.PostID,
+			&i.ReadAt,
+			&i.StarredAt,
+			&i.HiddenAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getAllPostTags = `-- name: GetAllPostTags :many
+SELECT user_id, post_id, tag FROM post_tags
+ORDER BY user_id, post_id, tag
+`
+
+func (q *Queries) GetAllPostTags(ctx context.Context) ([]PostTag, error) {
+	rows, err := q.db.QueryContext(ctx, getAllPostTags)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []PostTag
+	for rows.Next() {
+		var i PostTag
+		if err := rows.Scan(&i.UserID, &i.PostID, &i.Tag); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPostsAfter = `-- name: GetPostsAfter :many
+SELECT id, created_at, updated_at, title, url, description, published_at, feed_id, author, categories, fever_id FROM posts
+WHERE id > $1
+ORDER BY id
+LIMIT $2
+`
+
+type GetPostsAfterParams struct {
+	After    uuid.UUID
+	MaxPosts int32
+}
+
+// A page of every post in id order, for backups. Pass the nil UUID for the
+// first page and the last id of each page for the next.
+func (q *Queries) GetPostsAfter(ctx context.Context, arg GetPostsAfterParams) ([]Post, error) {
+	rows, err := q.db.QueryContext(ctx, getPostsAfter, arg.After, arg.MaxPosts)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Post
+	for rows.Next() {
+		var i Post
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Title,
+			&i.Url,
+			&i.Description,
+			&i.PublishedAt,
+			&i.FeedID,
+			&i.Author,
+			pq.Array(&i.Categories),
+			&i.FeverID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const restorePostState = `-- name: RestorePostState :execrows
+INSERT INTO post_states (user_id, post_id, read_at, starred_at, hidden_at)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (user_id, post_id) DO NOTHING
+`
+
+type RestorePostStateParams struct {
+	UserID    uuid.UUID
+	PostID    uuid.UUID
+	ReadAt    sql.NullTime
+	StarredAt sql.NullTime
+	HiddenAt  sql.NullTime
+}
+
+// Adds a user's marks on a post from a backup, leaving any the user already
+// has alone.
+func (q *Queries) RestorePostState(ctx context.Context, arg RestorePostStateParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, restorePostState,
+		arg.UserID,
+		arg.PostID,
+		arg.ReadAt,
+		arg.StarredAt,
+		arg.HiddenAt,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
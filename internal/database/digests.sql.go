@@ -0,0 +1,265 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: digests.sql
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const claimDigest = `-- name: ClaimDigest :execrows
+UPDATE digest_settings
+SET last_sent_at = $1::timestamp
+WHERE user_id = $2
+    AND last_sent_at IS NOT DISTINCT FROM $3::timestamp
+`
+
+type ClaimDigestParams struct {
+	NewSentAt sql.NullTime
+	UserID    uuid.UUID
+	OldSentAt sql.NullTime
+}
+
+// Moves last_sent_at from one value to another, unless someone else already
+// moved it. Claiming a period before sending its digest keeps two senders
+// from sending it twice.
+func (q *Queries) ClaimDigest(ctx context.Context, arg ClaimDigestParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, claimDigest, arg.NewSentAt, arg.UserID, arg.OldSentAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteDigestSettings = `-- name: DeleteDigestSettings :execrows
+DELETE FROM digest_settings
+WHERE user_id = $1
+`
+
+func (q *Queries) DeleteDigestSettings(ctx context.Context, userID uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteDigestSettings, userID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getDigestPosts = `-- name: GetDigestPosts :many
+SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.author, posts.categories, posts.fever_id, COALESCE(feed_follows.title, feeds.name)::text AS feed_name,
+    feed_follows.priority
+FROM posts
+JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+JOIN feeds ON posts.feed_id = feeds.id
+LEFT JOIN folders ON feed_follows.folder_id = folders.id
+LEFT JOIN post_states ON post_states.post_id = posts.id
+    AND post_states.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = $1
+    AND NOT feed_follows.muted
+    AND posts.created_at > $2::timestamp
+    AND posts.created_at <= $3::timestamp
+    AND post_states.read_at IS NULL
+    AND post_states.hidden_at IS NULL
+    AND ($4::text IS NULL OR folders.name = $4::text)
+    AND ($5::text IS NULL OR EXISTS (
+        SELECT 1 FROM feed_follow_tags
+        WHERE feed_follow_tags.feed_follow_id = feed_follows.id
+            AND feed_follow_tags.tag = $5::text
+    ))
+ORDER BY posts.created_at, posts.id
+LIMIT $6
+`
+
+type GetDigestPostsParams struct {
+	UserID   uuid.UUID
+	Since    time.Time
+	Until    time.Time
+	Folder   sql.NullString
+	Tag      sql.NullString
+	MaxPosts int32
+}
+
+type GetDigestPostsRow struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Title       string
+	Url         string
+	Description sql.NullString
+	PublishedAt sql.NullTime
+	FeedID      uuid.UUID
+	Author      string
+	Categories  []string
+	FeverID     int64
+	FeedName    string
+	Priority    int32
+}
+
+// Unread posts saved in (since, until] for the user's followed feeds, leaving
+// out muted feeds and hidden posts. The oldest come first, so a digest cut
+// off at max_posts covers everything saved up to its last post.
+func (q *Queries) GetDigestPosts(ctx context.Context, arg GetDigestPostsParams) ([]GetDigestPostsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getDigestPosts,
+		arg.UserID,
+		arg.Since,
+		arg.Until,
+		arg.Folder,
+		arg.Tag,
+		arg.MaxPosts,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetDigestPostsRow
+	for rows.Next() {
+		var i GetDigestPostsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Title,
+			&i.Url,
+			&i.Description,
+			&i.PublishedAt,
+			&i.FeedID,
+			&i.Author,
+			pq.Array(&i.Categories),
+			&i.FeverID,
+			&i.FeedName,
+			&i.Priority,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getDigestSettings = `-- name: GetDigestSettings :one
+SELECT user_id, created_at, updated_at, email, folder, tag, last_sent_at FROM digest_settings
+WHERE user_id = $1
+`
+
+func (q *Queries) GetDigestSettings(ctx context.Context, userID uuid.UUID) (DigestSetting, error) {
+	row := q.db.QueryRowContext(ctx, getDigestSettings, userID)
+	var i DigestSetting
+	err := row.Scan(
+		&i.UserID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.Folder,
+		&i.Tag,
+		&i.LastSentAt,
+	)
+	return i, err
+}
+
+const getDueDigests = `-- name: GetDueDigests :many
+SELECT digest_settings.user_id, digest_settings.created_at, digest_settings.updated_at, digest_settings.email, digest_settings.folder, digest_settings.tag, digest_settings.last_sent_at, users.id, users.created_at, users.updated_at, users.name, users.role, users.disabled_at, users.last_active_at, users.fever_api_key
+FROM digest_settings
+JOIN users ON digest_settings.user_id = users.id
+WHERE users.disabled_at IS NULL
+    AND (digest_settings.last_sent_at IS NULL
+        OR digest_settings.last_sent_at <= $1::timestamp)
+ORDER BY digest_settings.last_sent_at NULLS FIRST
+`
+
+type GetDueDigestsRow struct {
+	DigestSetting DigestSetting
+	User          User
+}
+
+// Users whose last digest covered up to before the cutoff, or who have never
+// had one. Disabled users get none.
+func (q *Queries) GetDueDigests(ctx context.Context, cutoff time.Time) ([]GetDueDigestsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getDueDigests, cutoff)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetDueDigestsRow
+	for rows.Next() {
+		var i GetDueDigestsRow
+		if err := rows.Scan(
+			&i.DigestSetting.UserID,
+			&i.DigestSetting.CreatedAt,
+			&i.DigestSetting.UpdatedAt,
+			&i.DigestSetting.Email,
+			&i.DigestSetting.Folder,
+			&i.DigestSetting.Tag,
+			&i.DigestSetting.LastSentAt,
+			&i.User.ID,
+			&i.User.CreatedAt,
+			&i.User.UpdatedAt,
+			&i.User.Name,
+			&i.User.Role,
+			&i.User.DisabledAt,
+			&i.User.LastActiveAt,
+			&i.User.FeverApiKey,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const upsertDigestSettings = `-- name: UpsertDigestSettings :one
+INSERT INTO digest_settings (user_id, created_at, updated_at, email, folder, tag)
+VALUES ($1, $2, $2, $3, $4, $5)
+ON CONFLICT (user_id) DO UPDATE
+SET updated_at = EXCLUDED.updated_at,
+    email = EXCLUDED.email,
+    folder = EXCLUDED.folder,
+    tag = EXCLUDED.tag
+RETURNING user_id, created_at, updated_at, email, folder, tag, last_sent_at
+`
+
+type UpsertDigestSettingsParams struct {
+	UserID    uuid.UUID
+	CreatedAt time.Time
+	Email     string
+	Folder    sql.NullString
+	Tag       sql.NullString
+}
+
+func (q *Queries) UpsertDigestSettings(ctx context.Context, arg UpsertDigestSettingsParams) (DigestSetting, error) {
+	row := q.db.QueryRowContext(ctx, upsertDigestSettings,
+		arg.UserID,
+		arg.CreatedAt,
+		arg.Email,
+		arg.Folder,
+		arg.Tag,
+	)
+	var i DigestSetting
+	err := row.Scan(
+		&i.UserID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.Folder,
+		&i.Tag,
+		&i.LastSentAt,
+	)
+	return i, err
+}
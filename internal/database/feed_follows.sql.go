@@ -7,19 +7,21 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 const createFeedFollow = `-- name: CreateFeedFollow :one
 WITH inserted_feed_follow AS (
     INSERT INTO feed_follows (id, created_at, updated_at, user_id, feed_id)
     VALUES ($1, $2, $3, $4, $5)
-    RETURNING id, created_at, updated_at, user_id, feed_id
+    RETURNING id, created_at, updated_at, user_id, feed_id, folder_id, title, muted, priority, notify
 )
 SELECT
-    f.id, f.created_at, f.updated_at, f.user_id, f.feed_id,
+    f.id, f.created_at, f.updated_at, f.user_id, f.feed_id, f.folder_id, f.title, f.muted, f.priority, f.notify,
     u.name AS user_name,
     fe.name AS feed_name
 FROM inserted_feed_follow f
@@ -41,6 +43,11 @@ type CreateFeedFollowRow struct {
 	UpdatedAt time.Time
 	UserID    uuid.UUID
 	FeedID    uuid.UUID
+	FolderID  uuid.NullUUID
+	Title     sql.NullString
+	Muted     bool
+	Priority  int32
+	Notify    bool
 	UserName  string
 	FeedName  string
 }
@@ -60,13 +67,18 @@ func (q *Queries) CreateFeedFollow(ctx context.Context, arg CreateFeedFollowPara
 		&i.UpdatedAt,
 		&i.UserID,
 		&i.FeedID,
+		&i.FolderID,
+		&i.Title,
+		&i.Muted,
+		&i.Priority,
+		&i.Notify,
 		&i.UserName,
 		&i.FeedName,
 	)
 	return i, err
 }
 
-const delFeedFollow = `-- name: DelFeedFollow :exec
+const delFeedFollow = `-- name: DelFeedFollow :execrows
 DELETE FROM feed_follows
 USING feeds
 WHERE feed_follows.user_id = $1
@@ -79,34 +91,133 @@ type DelFeedFollowParams struct {
 	Url    string
 }
 
-func (q *Queries) DelFeedFollow(ctx context.Context, arg DelFeedFollowParams) error {
-	_, err := q.db.ExecContext(ctx, delFeedFollow, arg.UserID, arg.Url)
-	return err
+func (q *Queries) DelFeedFollow(ctx context.Context, arg DelFeedFollowParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, delFeedFollow, arg.UserID, arg.Url)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getFeedFollow = `-- name: GetFeedFollow :one
+SELECT id, created_at, updated_at, user_id, feed_id, folder_id, title, muted, priority, notify FROM feed_follows
+WHERE user_id = $1 AND feed_id = $2
+`
+
+type GetFeedFollowParams struct {
+	UserID uuid.UUID
+	FeedID uuid.UUID
+}
+
+func (q *Queries) GetFeedFollow(ctx context.Context, arg GetFeedFollowParams) (FeedFollow, error) {
+	row := q.db.QueryRowContext(ctx, getFeedFollow, arg.UserID, arg.FeedID)
+	var i FeedFollow
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.FeedID,
+		&i.FolderID,
+		&i.Title,
+		&i.Muted,
+		&i.Priority,
+		&i.Notify,
+	)
+	return i, err
+}
+
+const getFeedFollowers = `-- name: GetFeedFollowers :many
+SELECT users.id, users.created_at, users.updated_at, users.name, users.role, users.disabled_at, users.last_active_at, users.fever_api_key FROM users
+JOIN feed_follows ON feed_follows.user_id = users.id
+WHERE feed_follows.feed_id = $1
+ORDER BY feed_follows.created_at ASC
+`
+
+func (q *Queries) GetFeedFollowers(ctx context.Context, feedID uuid.UUID) ([]User, error) {
+	rows, err := q.db.QueryContext(ctx, getFeedFollowers, feedID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []User
+	for rows.Next() {
+		var i User
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Name,
+			&i.Role,
+			&i.DisabledAt,
+			&i.LastActiveAt,
+			&i.FeverApiKey,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
 }
 
 const getFeedFollowsForUser = `-- name: GetFeedFollowsForUser :many
 SELECT
-    ff.id, ff.created_at, ff.updated_at, ff.user_id, ff.feed_id,
+    ff.id, ff.created_at, ff.updated_at, ff.user_id, ff.feed_id, ff.folder_id, ff.title, ff.muted, ff.priority, ff.notify,
     u.name AS user_name,
-    f.name AS feed_name
+    COALESCE(ff.title, f.name)::text AS feed_name,
+    f.url AS feed_url,
+    COALESCE(folders.name, '')::text AS folder_name,
+    COALESCE(
+        (SELECT array_agg(t.tag ORDER BY t.tag) FROM feed_follow_tags t WHERE t.feed_follow_id = ff.id),
+        '{}'
+    )::text[] AS tags
 FROM feed_follows ff 
 INNER JOIN users u ON ff.user_id = u.id 
 INNER JOIN feeds f ON ff.feed_id = f.id 
+LEFT JOIN folders ON ff.folder_id = folders.id
 WHERE ff.user_id = $1
+    AND ($2::text IS NULL OR folders.name = $2::text)
+    AND ($3::text IS NULL OR EXISTS (
+        SELECT 1 FROM feed_follow_tags t
+        WHERE t.feed_follow_id = ff.id AND t.tag = $3::text
+    ))
+ORDER BY folder_name, feed_name
 `
 
+type GetFeedFollowsForUserParams struct {
+	UserID uuid.UUID
+	Folder sql.NullString
+	Tag    sql.NullString
+}
+
 type GetFeedFollowsForUserRow struct {
-	ID        uuid.UUID
-	CreatedAt time.Time
-	UpdatedAt time.Time
-	UserID    uuid.UUID
-	FeedID    uuid.UUID
-	UserName  string
-	FeedName  string
+	ID         uuid.UUID
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	UserID     uuid.UUID
+	FeedID     uuid.UUID
+	FolderID   uuid.NullUUID
+	Title      sql.NullString
+	Muted      bool
+	Priority   int32
+	Notify     bool
+	UserName   string
+	FeedName   string
+	FeedUrl    string
+	FolderName string
+	Tags       []string
 }
 
-func (q *Queries) GetFeedFollowsForUser(ctx context.Context, userID uuid.UUID) ([]GetFeedFollowsForUserRow, error) {
-	rows, err := q.db.QueryContext(ctx, getFeedFollowsForUser, userID)
+// Optionally narrowed to one folder and/or tag. feed_name is the user's own
+// title for the feed when they have set one.
+func (q *Queries) GetFeedFollowsForUser(ctx context.Context, arg GetFeedFollowsForUserParams) ([]GetFeedFollowsForUserRow, error) {
+	rows, err := q.db.QueryContext(ctx, getFeedFollowsForUser, arg.UserID, arg.Folder, arg.Tag)
 	if err != nil {
 		return nil, err
 	}
@@ -120,8 +231,16 @@ func (q *Queries) GetFeedFollowsForUser(ctx context.Context, userID uuid.UUID) (
 			&i.UpdatedAt,
 			&i.UserID,
 			&i.FeedID,
+			&i.FolderID,
+			&i.Title,
+			&i.Muted,
+			&i.Priority,
+			&i.Notify,
 			&i.UserName,
 			&i.FeedName,
+			&i.FeedUrl,
+			&i.FolderName,
+			pq.Array(&i.Tags),
 		); err != nil {
 			return nil, err
 		}
@@ -135,3 +254,89 @@ func (q *Queries) GetFeedFollowsForUser(ctx context.Context, userID uuid.UUID) (
 	}
 	return items, nil
 }
+
+const mergeFeedFollowSettings = `-- name: MergeFeedFollowSettings :exec
+UPDATE feed_follows
+SET title = COALESCE(feed_follows.title, merged.title),
+    folder_id = COALESCE(feed_follows.folder_id, merged.folder_id),
+    priority = CASE WHEN feed_follows.priority <> 0 THEN feed_follows.priority ELSE merged.priority END,
+    muted = feed_follows.muted OR merged.muted,
+    notify = feed_follows.notify AND merged.notify,
+    updated_at = $1
+FROM feed_follows AS merged
+WHERE feed_follows.feed_id = $2
+    AND merged.feed_id = $3
+    AND merged.user_id = feed_follows.user_id
+`
+
+type MergeFeedFollowSettingsParams struct {
+	UpdatedAt  time.Time
+	ToFeedID   uuid.UUID
+	FromFeedID uuid.UUID
+}
+
+// For users who follow both feeds, fills in their follow of to_feed_id from
+// their follow of from_feed_id: a title or folder it lacks, a priority when
+// it has none, and muting or turning notifications off.
+func (q *Queries) MergeFeedFollowSettings(ctx context.Context, arg MergeFeedFollowSettingsParams) error {
+	_, err := q.db.ExecContext(ctx, mergeFeedFollowSettings, arg.UpdatedAt, arg.ToFeedID, arg.FromFeedID)
+	return err
+}
+
+const moveFeedFollows = `-- name: MoveFeedFollows :exec
+UPDATE feed_follows
+SET feed_id = $1,
+    updated_at = $2
+WHERE feed_follows.feed_id = $3
+    AND feed_follows.user_id NOT IN (
+        SELECT existing.user_id FROM feed_follows existing
+        WHERE existing.feed_id = $1
+    )
+`
+
+type MoveFeedFollowsParams struct {
+	ToFeedID   uuid.UUID
+	UpdatedAt  time.Time
+	FromFeedID uuid.UUID
+}
+
+func (q *Queries) MoveFeedFollows(ctx context.Context, arg MoveFeedFollowsParams) error {
+	_, err := q.db.ExecContext(ctx, moveFeedFollows, arg.ToFeedID, arg.UpdatedAt, arg.FromFeedID)
+	return err
+}
+
+const updateFeedFollowSettings = `-- name: UpdateFeedFollowSettings :execrows
+UPDATE feed_follows
+SET title = $3,
+    muted = $4,
+    priority = $5,
+    notify = $6,
+    updated_at = $7
+WHERE user_id = $1 AND feed_id = $2
+`
+
+type UpdateFeedFollowSettingsParams struct {
+	UserID    uuid.UUID
+	FeedID    uuid.UUID
+	Title     sql.NullString
+	Muted     bool
+	Priority  int32
+	Notify    bool
+	UpdatedAt time.Time
+}
+
+func (q *Queries) UpdateFeedFollowSettings(ctx context.Context, arg UpdateFeedFollowSettingsParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, updateFeedFollowSettings,
+		arg.UserID,
+		arg.FeedID,
+		arg.Title,
+		arg.Muted,
+		arg.Priority,
+		arg.Notify,
+		arg.UpdatedAt,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,674 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: fever.sql
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const countFeverItems = `-- name: CountFeverItems :one
+SELECT COUNT(*) FROM posts
+JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+LEFT JOIN post_states ON post_states.post_id = posts.id AND post_states.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = $1
+    AND post_states.hidden_at IS NULL
+`
+
+func (q *Queries) CountFeverItems(ctx context.Context, userID uuid.UUID) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countFeverItems, userID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const getFaviconCheckedAt = `-- name: GetFaviconCheckedAt :one
+SELECT checked_at FROM favicons
+WHERE feed_id = $1
+`
+
+func (q *Queries) GetFaviconCheckedAt(ctx context.Context, feedID uuid.UUID) (time.Time, error) {
+	row := q.db.QueryRowContext(ctx, getFaviconCheckedAt, feedID)
+	var checked_at time.Time
+	err := row.Scan(&checked_at)
+	return checked_at, err
+}
+
+const getFeverFavicons = `-- name: GetFeverFavicons :many
+SELECT feeds.fever_id, favicons.content_type, favicons.data FROM favicons
+JOIN feeds ON favicons.feed_id = feeds.id
+JOIN feed_follows ON feed_follows.feed_id = feeds.id
+WHERE feed_follows.user_id = $1 AND favicons.data IS NOT NULL
+ORDER BY feeds.fever_id
+`
+
+type GetFeverFaviconsRow struct {
+	FeverID     int64
+	ContentType string
+	Data        []byte
+}
+
+func (q *Queries) GetFeverFavicons(ctx context.Context, userID uuid.UUID) ([]GetFeverFaviconsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getFeverFavicons, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetFeverFaviconsRow
+	for rows.Next() {
+		var i GetFeverFaviconsRow
+		if err := rows.Scan(&i.FeverID, &i.ContentType, &i.Data); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getFeverFeeds = `-- name: GetFeverFeeds :many
+SELECT
+    feeds.fever_id,
+    COALESCE(feed_follows.title, feeds.name)::text AS title,
+    feeds.url,
+    feeds.last_fetched_at,
+    feed_follows.muted,
+    folders.fever_id AS group_id,
+    (favicons.data IS NOT NULL)::boolean AS has_favicon
+FROM feed_follows
+JOIN feeds ON feed_follows.feed_id = feeds.id
+LEFT JOIN folders ON feed_follows.folder_id = folders.id
+LEFT JOIN favicons ON favicons.feed_id = feeds.id
+WHERE feed_follows.user_id = $1
+ORDER BY feeds.fever_id
+`
+
+type GetFeverFeedsRow struct {
+	FeverID       int64
+	Title         string
+	Url           string
+	LastFetchedAt sql.NullTime
+	Muted         bool
+	GroupID       sql.NullInt64
+	HasFavicon    bool
+}
+
+// The feeds the user follows, with the folder each is in, if any, and
+// whether we have its icon.
+func (q *Queries) GetFeverFeeds(ctx context.Context, userID uuid.UUID) ([]GetFeverFeedsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getFeverFeeds, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetFeverFeedsRow
+	for rows.Next() {
+		var i GetFeverFeedsRow
+		if err := rows.Scan(
+			&i.FeverID,
+			&i.Title,
+			&i.Url,
+			&i.LastFetchedAt,
+			&i.Muted,
+			&i.GroupID,
+			&i.HasFavicon,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getFeverGroups = `-- name: GetFeverGroups :many
+SELECT fever_id, name FROM folders
+WHERE user_id = $1
+ORDER BY name
+`
+
+type GetFeverGroupsRow struct {
+	FeverID int64
+	Name    string
+}
+
+func (q *Queries) GetFeverGroups(ctx context.Context, userID uuid.UUID) ([]GetFeverGroupsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getFeverGroups, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetFeverGroupsRow
+	for rows.Next() {
+		var i GetFeverGroupsRow
+		if err := rows.Scan(&i.FeverID, &i.Name); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getFeverItemsAfter = `-- name: GetFeverItemsAfter :many
+SELECT
+    posts.fever_id,
+    feeds.fever_id AS feed_fever_id,
+    posts.title,
+    posts.author,
+    posts.description,
+    posts.url,
+    posts.published_at,
+    posts.created_at,
+    post_states.read_at,
+    post_states.starred_at
+FROM posts
+JOIN feeds ON posts.feed_id = feeds.id
+JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+LEFT JOIN post_states ON post_states.post_id = posts.id AND post_states.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = $1
+    AND post_states.hidden_at IS NULL
+    AND posts.fever_id > $2
+ORDER BY posts.fever_id
+LIMIT $3
+`
+
+type GetFeverItemsAfterParams struct {
+	UserID   uuid.UUID
+	SinceID  int64
+	MaxItems int32
+}
+
+type GetFeverItemsAfterRow struct {
+	FeverID     int64
+	FeedFeverID int64
+	Title       string
+	Author      string
+	Description sql.NullString
+	Url         string
+	PublishedAt sql.NullTime
+	CreatedAt   time.Time
+	ReadAt      sql.NullTime
+	StarredAt   sql.NullTime
+}
+
+// The oldest posts in the user's feeds after since_id. Hidden posts are
+// left out here and in the other item queries.
+func (q *Queries) GetFeverItemsAfter(ctx context.Context, arg GetFeverItemsAfterParams) ([]GetFeverItemsAfterRow, error) {
+	rows, err := q.db.QueryContext(ctx, getFeverItemsAfter, arg.UserID, arg.SinceID, arg.MaxItems)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetFeverItemsAfterRow
+	for rows.Next() {
+		var i GetFeverItemsAfterRow
+		if err := rows.Scan(
+			&i.FeverID,
+			&i.FeedFeverID,
+			&i.Title,
+			&i.Author,
+			&i.Description,
+			&i.Url,
+			&i.PublishedAt,
+			&i.CreatedAt,
+			&i.ReadAt,
+			&i.StarredAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getFeverItemsBefore = `-- name: GetFeverItemsBefore :many
+SELECT
+    posts.fever_id,
+    feeds.fever_id AS feed_fever_id,
+    posts.title,
+    posts.author,
+    posts.description,
+    posts.url,
+    posts.published_at,
+    posts.created_at,
+    post_states.read_at,
+    post_states.starred_at
+FROM posts
+JOIN feeds ON posts.feed_id = feeds.id
+JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+LEFT JOIN post_states ON post_states.post_id = posts.id AND post_states.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = $1
+    AND post_states.hidden_at IS NULL
+    AND posts.fever_id < $2
+ORDER BY posts.fever_id DESC
+LIMIT $3
+`
+
+type GetFeverItemsBeforeParams struct {
+	UserID   uuid.UUID
+	MaxID    int64
+	MaxItems int32
+}
+
+type GetFeverItemsBeforeRow struct {
+	FeverID     int64
+	FeedFeverID int64
+	Title       string
+	Author      string
+	Description sql.NullString
+	Url         string
+	PublishedAt sql.NullTime
+	CreatedAt   time.Time
+	ReadAt      sql.NullTime
+	StarredAt   sql.NullTime
+}
+
+// The newest posts in the user's feeds before max_id.
+func (q *Queries) GetFeverItemsBefore(ctx context.Context, arg GetFeverItemsBeforeParams) ([]GetFeverItemsBeforeRow, error) {
+	rows, err := q.db.QueryContext(ctx, getFeverItemsBefore, arg.UserID, arg.MaxID, arg.MaxItems)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetFeverItemsBeforeRow
+	for rows.Next() {
+		var i GetFeverItemsBeforeRow
+		if err := rows.Scan(
+			&i.FeverID,
+			&i.FeedFeverID,
+			&i.Title,
+			&i.Author,
+			&i.Description,
+			&i.Url,
+			&i.PublishedAt,
+			&i.CreatedAt,
+			&i.ReadAt,
+			&i.StarredAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getFeverItemsByID = `-- name: GetFeverItemsByID :many
+SELECT
+    posts.fever_id,
+    feeds.fever_id AS feed_fever_id,
+    posts.title,
+    posts.author,
+    posts.description,
+    posts.url,
+    posts.published_at,
+    posts.created_at,
+    post_states.read_at,
+    post_states.starred_at
+FROM posts
+JOIN feeds ON posts.feed_id = feeds.id
+JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+LEFT JOIN post_states ON post_states.post_id = posts.id AND post_states.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = $1
+    AND post_states.hidden_at IS NULL
+    AND posts.fever_id = ANY($2::bigint[])
+ORDER BY posts.fever_id
+`
+
+type GetFeverItemsByIDParams struct {
+	UserID uuid.UUID
+	Ids    []int64
+}
+
+type GetFeverItemsByIDRow struct {
+	FeverID     int64
+	FeedFeverID int64
+	Title       string
+	Author      string
+	Description sql.NullString
+	Url         string
+	PublishedAt sql.NullTime
+	CreatedAt   time.Time
+	ReadAt      sql.NullTime
+	StarredAt   sql.NullTime
+}
+
+func (q *Queries) GetFeverItemsByID(ctx context.Context, arg GetFeverItemsByIDParams) ([]GetFeverItemsByIDRow, error) {
+	rows, err := q.db.QueryContext(ctx, getFeverItemsByID, arg.UserID, pq.Array(arg.Ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetFeverItemsByIDRow
+	for rows.Next() {
+		var i GetFeverItemsByIDRow
+		if err := rows.Scan(
+			&i.FeverID,
+			&i.FeedFeverID,
+			&i.Title,
+			&i.Author,
+			&i.Description,
+			&i.Url,
+			&i.PublishedAt,
+			&i.CreatedAt,
+			&i.ReadAt,
+			&i.StarredAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getFeverLastRefreshed = `-- name: GetFeverLastRefreshed :one
+SELECT feeds.last_fetched_at FROM feeds
+JOIN feed_follows ON feed_follows.feed_id = feeds.id
+WHERE feed_follows.user_id = $1 AND feeds.last_fetched_at IS NOT NULL
+ORDER BY feeds.last_fetched_at DESC
+LIMIT 1
+`
+
+// When the most recently fetched of the user's feeds was fetched.
+func (q *Queries) GetFeverLastRefreshed(ctx context.Context, userID uuid.UUID) (sql.NullTime, error) {
+	row := q.db.QueryRowContext(ctx, getFeverLastRefreshed, userID)
+	var last_fetched_at sql.NullTime
+	err := row.Scan(&last_fetched_at)
+	return last_fetched_at, err
+}
+
+const getFeverPostID = `-- name: GetFeverPostID :one
+SELECT posts.id FROM posts
+JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+WHERE posts.fever_id = $1 AND feed_follows.user_id = $2
+`
+
+type GetFeverPostIDParams struct {
+	FeverID int64
+	UserID  uuid.UUID
+}
+
+// The post with this Fever ID, if it is in one of the user's feeds.
+func (q *Queries) GetFeverPostID(ctx context.Context, arg GetFeverPostIDParams) (uuid.UUID, error) {
+	row := q.db.QueryRowContext(ctx, getFeverPostID, arg.FeverID, arg.UserID)
+	var id uuid.UUID
+	err := row.Scan(&id)
+	return id, err
+}
+
+const getFeverSavedItemIDs = `-- name: GetFeverSavedItemIDs :many
+SELECT posts.fever_id FROM posts
+JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+JOIN post_states ON post_states.post_id = posts.id AND post_states.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = $1
+    AND post_states.starred_at IS NOT NULL
+    AND post_states.hidden_at IS NULL
+ORDER BY posts.fever_id
+`
+
+func (q *Queries) GetFeverSavedItemIDs(ctx context.Context, userID uuid.UUID) ([]int64, error) {
+	rows, err := q.db.QueryContext(ctx, getFeverSavedItemIDs, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []int64
+	for rows.Next() {
+		var fever_id int64
+		if err := rows.Scan(&fever_id); err != nil {
+			return nil, err
+		}
+		items = append(items, fever_id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getFeverUnreadItemIDs = `-- name: GetFeverUnreadItemIDs :many
+SELECT posts.fever_id FROM posts
+JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+LEFT JOIN post_states ON post_states.post_id = posts.id AND post_states.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = $1
+    AND post_states.read_at IS NULL
+    AND post_states.hidden_at IS NULL
+ORDER BY posts.fever_id
+`
+
+func (q *Queries) GetFeverUnreadItemIDs(ctx context.Context, userID uuid.UUID) ([]int64, error) {
+	rows, err := q.db.QueryContext(ctx, getFeverUnreadItemIDs, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []int64
+	for rows.Next() {
+		var fever_id int64
+		if err := rows.Scan(&fever_id); err != nil {
+			return nil, err
+		}
+		items = append(items, fever_id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getUserByFeverAPIKey = `-- name: GetUserByFeverAPIKey :one
+SELECT id, created_at, updated_at, name, role, disabled_at, last_active_at, fever_api_key FROM users
+WHERE fever_api_key = $1
+`
+
+func (q *Queries) GetUserByFeverAPIKey(ctx context.Context, feverApiKey sql.NullString) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUserByFeverAPIKey, feverApiKey)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Role,
+		&i.DisabledAt,
+		&i.LastActiveAt,
+		&i.FeverApiKey,
+	)
+	return i, err
+}
+
+const markFeverFeedRead = `-- name: MarkFeverFeedRead :execrows
+INSERT INTO post_states (user_id, post_id, read_at)
+SELECT feed_follows.user_id, posts.id, $1::timestamp
+FROM posts
+JOIN feeds ON posts.feed_id = feeds.id
+JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+WHERE feed_follows.user_id = $2
+    AND feeds.fever_id = $3
+    AND posts.created_at < $4
+ON CONFLICT (user_id, post_id) DO UPDATE SET read_at = COALESCE(post_states.read_at, EXCLUDED.read_at)
+`
+
+type MarkFeverFeedReadParams struct {
+	ReadAt time.Time
+	UserID uuid.UUID
+	FeedID int64
+	Before time.Time
+}
+
+// Marks the feed's posts saved before before as read, keeping the read time
+// of ones already read.
+func (q *Queries) MarkFeverFeedRead(ctx context.Context, arg MarkFeverFeedReadParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, markFeverFeedRead,
+		arg.ReadAt,
+		arg.UserID,
+		arg.FeedID,
+		arg.Before,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const markFeverGroupRead = `-- name: MarkFeverGroupRead :execrows
+INSERT INTO post_states (user_id, post_id, read_at)
+SELECT feed_follows.user_id, posts.id, $1::timestamp
+FROM posts
+JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+JOIN folders ON feed_follows.folder_id = folders.id
+WHERE feed_follows.user_id = $2
+    AND folders.fever_id = $3
+    AND posts.created_at < $4
+ON CONFLICT (user_id, post_id) DO UPDATE SET read_at = COALESCE(post_states.read_at, EXCLUDED.read_at)
+`
+
+type MarkFeverGroupReadParams struct {
+	ReadAt  time.Time
+	UserID  uuid.UUID
+	GroupID int64
+	Before  time.Time
+}
+
+// Like MarkFeverFeedRead, for every feed in the folder.
+func (q *Queries) MarkFeverGroupRead(ctx context.Context, arg MarkFeverGroupReadParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, markFeverGroupRead,
+		arg.ReadAt,
+		arg.UserID,
+		arg.GroupID,
+		arg.Before,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const markFeverSuperGroupRead = `-- name: MarkFeverSuperGroupRead :execrows
+INSERT INTO post_states (user_id, post_id, read_at)
+SELECT feed_follows.user_id, posts.id, $1::timestamp
+FROM posts
+JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+WHERE feed_follows.user_id = $2
+    AND feed_follows.muted = $3
+    AND posts.created_at < $4
+ON CONFLICT (user_id, post_id) DO UPDATE SET read_at = COALESCE(post_states.read_at, EXCLUDED.read_at)
+`
+
+type MarkFeverSuperGroupReadParams struct {
+	ReadAt time.Time
+	UserID uuid.UUID
+	Sparks bool
+	Before time.Time
+}
+
+// Like MarkFeverFeedRead, for every feed the user follows that is muted when
+// sparks is set, which Fever calls Sparks, or isn't muted otherwise, which it
+// calls Kindling.
+func (q *Queries) MarkFeverSuperGroupRead(ctx context.Context, arg MarkFeverSuperGroupReadParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, markFeverSuperGroupRead,
+		arg.ReadAt,
+		arg.UserID,
+		arg.Sparks,
+		arg.Before,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const setFeverAPIKey = `-- name: SetFeverAPIKey :execrows
+UPDATE users
+SET fever_api_key = $1,
+updated_at = $2
+WHERE id = $3
+`
+
+type SetFeverAPIKeyParams struct {
+	FeverApiKey sql.NullString
+	UpdatedAt   time.Time
+	ID          uuid.UUID
+}
+
+// Pass a NULL fever_api_key to turn Fever off for the user.
+func (q *Queries) SetFeverAPIKey(ctx context.Context, arg SetFeverAPIKeyParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, setFeverAPIKey, arg.FeverApiKey, arg.UpdatedAt, arg.ID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const upsertFavicon = `-- name: UpsertFavicon :exec
+INSERT INTO favicons (feed_id, checked_at, content_type, data)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (feed_id) DO UPDATE SET
+    checked_at = EXCLUDED.checked_at,
+    content_type = EXCLUDED.content_type,
+    data = EXCLUDED.data
+`
+
+type UpsertFaviconParams struct {
+	FeedID      uuid.UUID
+	CheckedAt   time.Time
+	ContentType string
+	Data        []byte
+}
+
+// data is NULL when the site has no icon.
+func (q *Queries) UpsertFavicon(ctx context.Context, arg UpsertFaviconParams) error {
+	_, err := q.db.ExecContext(ctx, upsertFavicon,
+		arg.FeedID,
+		arg.CheckedAt,
+		arg.ContentType,
+		arg.Data,
+	)
+	return err
+}
@@ -0,0 +1,270 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: folders.sql
+
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const addFeedFollowTag = `-- name: AddFeedFollowTag :execrows
+INSERT INTO feed_follow_tags (feed_follow_id, tag)
+SELECT feed_follows.id, $1 FROM feed_follows
+WHERE feed_follows.user_id = $2 AND feed_follows.feed_id = $3
+ON CONFLICT DO NOTHING
+`
+
+type AddFeedFollowTagParams struct {
+	Tag    string
+	UserID uuid.UUID
+	FeedID uuid.UUID
+}
+
+func (q *Queries) AddFeedFollowTag(ctx context.Context, arg AddFeedFollowTagParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, addFeedFollowTag, arg.Tag, arg.UserID, arg.FeedID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const createFolder = `-- name: CreateFolder :one
+INSERT INTO folders (id, created_at, updated_at, user_id, name)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING id, created_at, updated_at, user_id, name, fever_id
+`
+
+type CreateFolderParams struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	UserID    uuid.UUID
+	Name      string
+}
+
+func (q *Queries) CreateFolder(ctx context.Context, arg CreateFolderParams) (Folder, error) {
+	row := q.db.QueryRowContext(ctx, createFolder,
+		arg.ID,
+		arg.CreatedAt,
+		arg.UpdatedAt,
+		arg.UserID,
+		arg.Name,
+	)
+	var i Folder
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.Name,
+		&i.FeverID,
+	)
+	return i, err
+}
+
+const deleteFolder = `-- name: DeleteFolder :execrows
+DELETE FROM folders
+WHERE user_id = $1 AND name = $2
+`
+
+type DeleteFolderParams struct {
+	UserID uuid.UUID
+	Name   string
+}
+
+func (q *Queries) DeleteFolder(ctx context.Context, arg DeleteFolderParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteFolder, arg.UserID, arg.Name)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getFolderByName = `-- name: GetFolderByName :one
+SELECT id, created_at, updated_at, user_id, name, fever_id FROM folders
+WHERE user_id = $1 AND name = $2
+`
+
+type GetFolderByNameParams struct {
+	UserID uuid.UUID
+	Name   string
+}
+
+func (q *Queries) GetFolderByName(ctx context.Context, arg GetFolderByNameParams) (Folder, error) {
+	row := q.db.QueryRowContext(ctx, getFolderByName, arg.UserID, arg.Name)
+	var i Folder
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.Name,
+		&i.FeverID,
+	)
+	return i, err
+}
+
+const getFoldersForUser = `-- name: GetFoldersForUser :many
+SELECT folders.id, folders.created_at, folders.updated_at, folders.user_id, folders.name, folders.fever_id, COUNT(feed_follows.id) AS feed_count
+FROM folders
+LEFT JOIN feed_follows ON feed_follows.folder_id = folders.id
+WHERE folders.user_id = $1
+GROUP BY folders.id
+ORDER BY folders.name
+`
+
+type GetFoldersForUserRow struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	UserID    uuid.UUID
+	Name      string
+	FeverID   int64
+	FeedCount int64
+}
+
+func (q *Queries) GetFoldersForUser(ctx context.Context, userID uuid.UUID) ([]GetFoldersForUserRow, error) {
+	rows, err := q.db.QueryContext(ctx, getFoldersForUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetFoldersForUserRow
+	for rows.Next() {
+		var i GetFoldersForUserRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.UserID,
+			&i.Name,
+			&i.FeverID,
+			&i.FeedCount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getTagsForUser = `-- name: GetTagsForUser :many
+SELECT feed_follow_tags.tag, COUNT(*) AS feed_count
+FROM feed_follow_tags
+JOIN feed_follows ON feed_follow_tags.feed_follow_id = feed_follows.id
+WHERE feed_follows.user_id = $1
+GROUP BY feed_follow_tags.tag
+ORDER BY feed_follow_tags.tag
+`
+
+type GetTagsForUserRow struct {
+	Tag       string
+	FeedCount int64
+}
+
+func (q *Queries) GetTagsForUser(ctx context.Context, userID uuid.UUID) ([]GetTagsForUserRow, error) {
+	rows, err := q.db.QueryContext(ctx, getTagsForUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetTagsForUserRow
+	for rows.Next() {
+		var i GetTagsForUserRow
+		if err := rows.Scan(&i.Tag, &i.FeedCount); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const mergeFeedFollowTags = `-- name: MergeFeedFollowTags :exec
+INSERT INTO feed_follow_tags (feed_follow_id, tag)
+SELECT kept.id, feed_follow_tags.tag
+FROM feed_follow_tags
+JOIN feed_follows AS merged ON merged.id = feed_follow_tags.feed_follow_id
+JOIN feed_follows AS kept ON kept.user_id = merged.user_id
+WHERE merged.feed_id = $1
+    AND kept.feed_id = $2
+ON CONFLICT DO NOTHING
+`
+
+type MergeFeedFollowTagsParams struct {
+	FromFeedID uuid.UUID
+	ToFeedID   uuid.UUID
+}
+
+// For users who follow both feeds, adds the tags of their follow of
+// from_feed_id to their follow of to_feed_id.
+func (q *Queries) MergeFeedFollowTags(ctx context.Context, arg MergeFeedFollowTagsParams) error {
+	_, err := q.db.ExecContext(ctx, mergeFeedFollowTags, arg.FromFeedID, arg.ToFeedID)
+	return err
+}
+
+const removeFeedFollowTag = `-- name: RemoveFeedFollowTag :execrows
+DELETE FROM feed_follow_tags
+USING feed_follows
+WHERE feed_follow_tags.feed_follow_id = feed_follows.id
+    AND feed_follows.user_id = $1
+    AND feed_follows.feed_id = $2
+    AND feed_follow_tags.tag = $3
+`
+
+type RemoveFeedFollowTagParams struct {
+	UserID uuid.UUID
+	FeedID uuid.UUID
+	Tag    string
+}
+
+func (q *Queries) RemoveFeedFollowTag(ctx context.Context, arg RemoveFeedFollowTagParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, removeFeedFollowTag, arg.UserID, arg.FeedID, arg.Tag)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const setFeedFollowFolder = `-- name: SetFeedFollowFolder :execrows
+UPDATE feed_follows
+SET folder_id = $3,
+    updated_at = $4
+WHERE user_id = $1 AND feed_id = $2
+`
+
+type SetFeedFollowFolderParams struct {
+	UserID    uuid.UUID
+	FeedID    uuid.UUID
+	FolderID  uuid.NullUUID
+	UpdatedAt time.Time
+}
+
+func (q *Queries) SetFeedFollowFolder(ctx context.Context, arg SetFeedFollowFolderParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, setFeedFollowFolder,
+		arg.UserID,
+		arg.FeedID,
+		arg.FolderID,
+		arg.UpdatedAt,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
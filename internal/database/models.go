@@ -11,14 +11,39 @@ import (
 	"github.com/google/uuid"
 )
 
+type DigestSetting struct {
+	UserID     uuid.UUID
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	Email      string
+	Folder     sql.NullString
+	Tag        sql.NullString
+	LastSentAt sql.NullTime
+}
+
+type Favicon struct {
+	FeedID      uuid.UUID
+	CheckedAt   time.Time
+	ContentType string
+	Data        []byte
+}
+
 type Feed struct {
-	ID            uuid.UUID
-	CreatedAt     time.Time
-	UpdatedAt     time.Time
-	Name          string
-	Url           string
-	UserID        uuid.UUID
-	LastFetchedAt sql.NullTime
+	ID                     uuid.UUID
+	CreatedAt              time.Time
+	UpdatedAt              time.Time
+	Name                   string
+	Url                    string
+	UserID                 uuid.UUID
+	LastFetchedAt          sql.NullTime
+	RedirectUrl            sql.NullString
+	RedirectCount          int32
+	DeactivatedAt          sql.NullTime
+	NextFetchAt            sql.NullTime
+	FetchErrorCount        int32
+	RetentionMaxAgeSeconds sql.NullInt64
+	RetentionMaxPosts      sql.NullInt32
+	FeverID                int64
 }
 
 type FeedFollow struct {
@@ -27,6 +52,68 @@ type FeedFollow struct {
 	UpdatedAt time.Time
 	UserID    uuid.UUID
 	FeedID    uuid.UUID
+	FolderID  uuid.NullUUID
+	Title     sql.NullString
+	Muted     bool
+	Priority  int32
+	Notify    bool
+}
+
+type FeedFollowTag struct {
+	FeedFollowID uuid.UUID
+	Tag          string
+}
+
+type FeedUrlHistory struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	Url       string
+	FeedID    uuid.UUID
+}
+
+type FilterRule struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UserID    uuid.UUID
+	FeedID    uuid.NullUUID
+	Field     string
+	MatchType string
+	Pattern   string
+	Action    string
+	Tag       string
+}
+
+type Folder struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	UserID    uuid.UUID
+	Name      string
+	FeverID   int64
+}
+
+type NotificationDelivery struct {
+	ID            uuid.UUID
+	CreatedAt     time.Time
+	UserID        uuid.UUID
+	PostID        uuid.NullUUID
+	PostUrl       string
+	Sink          string
+	Attempts      int32
+	NextAttemptAt time.Time
+	DeliveredAt   sql.NullTime
+	LastError     string
+}
+
+type NotificationRule struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UserID    uuid.UUID
+	FeedID    uuid.NullUUID
+	Field     string
+	MatchType string
+	Pattern   string
+	Sink      string
 }
 
 type Post struct {
@@ -38,11 +125,52 @@ type Post struct {
 	Description sql.NullString
 	PublishedAt sql.NullTime
 	FeedID      uuid.UUID
+	Author      string
+	Categories  []string
+	FeverID     int64
+}
+
+type PostState struct {
+	UserID    uuid.UUID
+	PostID    uuid.UUID
+	ReadAt    sql.NullTime
+	StarredAt sql.NullTime
+	HiddenAt  sql.NullTime
+}
+
+type PostTag struct {
+	UserID uuid.UUID
+	PostID uuid.UUID
+	Tag    string
+}
+
+type PrunedPost struct {
+	Url      string
+	FeedID   uuid.UUID
+	PrunedAt time.Time
 }
 
 type User struct {
-	ID        uuid.UUID
-	CreatedAt time.Time
-	UpdatedAt time.Time
-	Name      string
+	ID           uuid.UUID
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	Name         string
+	Role         string
+	DisabledAt   sql.NullTime
+	LastActiveAt sql.NullTime
+	FeverApiKey  sql.NullString
+}
+
+type WebsubSubscription struct {
+	ID             uuid.UUID
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	FeedID         uuid.UUID
+	HubUrl         string
+	TopicUrl       string
+	Secret         string
+	PendingSecret  sql.NullString
+	State          string
+	LeaseExpiresAt sql.NullTime
+	RenewAt        sql.NullTime
 }
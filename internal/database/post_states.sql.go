@@ -0,0 +1,84 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: post_states.sql
+
+package database
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+)
+
+const addPostTag = `-- name: AddPostTag :execrows
+INSERT INTO post_tags (user_id, post_id, tag)
+VALUES ($1, $2, $3)
+ON CONFLICT DO NOTHING
+`
+
+type AddPostTagParams struct {
+	UserID uuid.UUID
+	PostID uuid.UUID
+	Tag    string
+}
+
+func (q *Queries) AddPostTag(ctx context.Context, arg AddPostTagParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, addPostTag, arg.UserID, arg.PostID, arg.Tag)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const setPostHidden = `-- name: SetPostHidden :exec
+INSERT INTO post_states (user_id, post_id, hidden_at)
+VALUES ($1, $2, $3)
+ON CONFLICT (user_id, post_id) DO UPDATE SET hidden_at = EXCLUDED.hidden_at
+`
+
+type SetPostHiddenParams struct {
+	UserID   uuid.UUID
+	PostID   uuid.UUID
+	HiddenAt sql.NullTime
+}
+
+func (q *Queries) SetPostHidden(ctx context.Context, arg SetPostHiddenParams) error {
+	_, err := q.db.ExecContext(ctx, setPostHidden, arg.UserID, arg.PostID, arg.HiddenAt)
+	return err
+}
+
+const setPostRead = `-- name: SetPostRead :exec
+INSERT INTO post_states (user_id, post_id, read_at)
+VALUES ($1, $2, $3)
+ON CONFLICT (user_id, post_id) DO UPDATE SET read_at = EXCLUDED.read_at
+`
+
+type SetPostReadParams struct {
+	UserID uuid.UUID
+	PostID uuid.UUID
+	ReadAt sql.NullTime
+}
+
+func (q *Queries) SetPostRead(ctx context.Context, arg SetPostReadParams) error {
+	_, err := q.db.ExecContext(ctx, setPostRead, arg.UserID, arg.PostID, arg.ReadAt)
+	return err
+}
+
+const setPostStarred = `-- name: SetPostStarred :exec
+INSERT INTO post_states (user_id, post_id, starred_at)
+VALUES ($1, $2, $3)
+ON CONFLICT (user_id, post_id) DO UPDATE SET starred_at = EXCLUDED.starred_at
+`
+
+type SetPostStarredParams struct {
+	UserID    uuid.UUID
+	PostID    uuid.UUID
+	StarredAt sql.NullTime
+}
+
+func (q *Queries) SetPostStarred(ctx context.Context, arg SetPostStarredParams) error {
+	_, err := q.db.ExecContext(ctx, setPostStarred, arg.UserID, arg.PostID, arg.StarredAt)
+	return err
+}
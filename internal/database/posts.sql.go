@@ -11,12 +11,26 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
+const countPostsBefore = `-- name: CountPostsBefore :one
+SELECT COUNT(*) FROM posts
+WHERE COALESCE(published_at, created_at) < $1::timestamp
+`
+
+func (q *Queries) CountPostsBefore(ctx context.Context, before time.Time) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countPostsBefore, before)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createPost = `-- name: CreatePost :one
-INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id)
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
-RETURNING id, created_at, updated_at, title, url, description, published_at, feed_id
+INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id, author, categories)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+ON CONFLICT (url) DO NOTHING
+RETURNING id, created_at, updated_at, title, url, description, published_at, feed_id, author, categories, fever_id
 `
 
 type CreatePostParams struct {
@@ -28,6 +42,8 @@ type CreatePostParams struct {
 	Description sql.NullString
 	PublishedAt sql.NullTime
 	FeedID      uuid.UUID
+	Author      string
+	Categories  []string
 }
 
 func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, error) {
@@ -40,7 +56,129 @@ func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, e
 		arg.Description,
 		arg.PublishedAt,
 		arg.FeedID,
+		arg.Author,
+		pq.Array(arg.Categories),
+	)
+	var i Post
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Title,
+		&i.Url,
+		&i.Description,
+		&i.PublishedAt,
+		&i.FeedID,
+		&i.Author,
+		pq.Array(&i.Categories),
+		&i.FeverID,
 	)
+	return i, err
+}
+
+const deletePost = `-- name: DeletePost :exec
+DELETE FROM posts WHERE id = $1
+`
+
+func (q *Queries) DeletePost(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deletePost, id)
+	return err
+}
+
+const deletePostsBefore = `-- name: DeletePostsBefore :execrows
+DELETE FROM posts
+WHERE COALESCE(published_at, created_at) < $1::timestamp
+`
+
+func (q *Queries) DeletePostsBefore(ctx context.Context, before time.Time) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deletePostsBefore, before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deletePostsByID = `-- name: DeletePostsByID :execrows
+DELETE FROM posts WHERE id = ANY($1::uuid[])
+`
+
+func (q *Queries) DeletePostsByID(ctx context.Context, ids []uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deletePostsByID, pq.Array(ids))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const forgetPrunedPosts = `-- name: ForgetPrunedPosts :execrows
+DELETE FROM pruned_posts
+WHERE feed_id = $1 AND url <> ALL($2::text[])
+`
+
+type ForgetPrunedPostsParams struct {
+	FeedID uuid.UUID
+	Listed []string
+}
+
+// Forgets the feed's pruned posts it no longer lists, which can't come back.
+func (q *Queries) ForgetPrunedPosts(ctx context.Context, arg ForgetPrunedPostsParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, forgetPrunedPosts, arg.FeedID, pq.Array(arg.Listed))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getAllPostsForUser = `-- name: GetAllPostsForUser :many
+SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.author, posts.categories, posts.fever_id FROM posts
+JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+WHERE feed_follows.user_id = $1
+ORDER BY posts.created_at
+`
+
+// Every post of every feed the user follows, muted or not, for applying
+// filter rules.
+func (q *Queries) GetAllPostsForUser(ctx context.Context, userID uuid.UUID) ([]Post, error) {
+	rows, err := q.db.QueryContext(ctx, getAllPostsForUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Post
+	for rows.Next() {
+		var i Post
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Title,
+			&i.Url,
+			&i.Description,
+			&i.PublishedAt,
+			&i.FeedID,
+			&i.Author,
+			pq.Array(&i.Categories),
+			&i.FeverID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPostByURL = `-- name: GetPostByURL :one
+SELECT id, created_at, updated_at, title, url, description, published_at, feed_id, author, categories, fever_id FROM posts WHERE url = $1
+`
+
+func (q *Queries) GetPostByURL(ctx context.Context, url string) (Post, error) {
+	row := q.db.QueryRowContext(ctx, getPostByURL, url)
 	var i Post
 	err := row.Scan(
 		&i.ID,
@@ -51,23 +189,78 @@ func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, e
 		&i.Description,
 		&i.PublishedAt,
 		&i.FeedID,
+		&i.Author,
+		pq.Array(&i.Categories),
+		&i.FeverID,
 	)
 	return i, err
 }
 
+const getPostURLs = `-- name: GetPostURLs :many
+SELECT id, url FROM posts
+ORDER BY created_at ASC
+`
+
+type GetPostURLsRow struct {
+	ID  uuid.UUID
+	Url string
+}
+
+func (q *Queries) GetPostURLs(ctx context.Context) ([]GetPostURLsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getPostURLs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetPostURLsRow
+	for rows.Next() {
+		var i GetPostURLsRow
+		if err := rows.Scan(&i.ID, &i.Url); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getPostsForUser = `-- name: GetPostsForUser :many
 
-SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, feeds.name AS feed_name FROM posts
+SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.author, posts.categories, posts.fever_id, COALESCE(feed_follows.title, feeds.name)::text AS feed_name FROM posts
 JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
 JOIN feeds ON posts.feed_id = feeds.id
+LEFT JOIN folders ON feed_follows.folder_id = folders.id
 WHERE feed_follows.user_id = $1
-ORDER BY posts.published_at DESC
-LIMIT $2
+    AND NOT feed_follows.muted
+    AND NOT EXISTS (
+        SELECT 1 FROM post_states
+        WHERE post_states.user_id = $1
+            AND post_states.post_id = posts.id
+            AND post_states.hidden_at IS NOT NULL
+    )
+    AND ($2::text IS NULL OR folders.name = $2::text)
+    AND ($3::text IS NULL OR EXISTS (
+        SELECT 1 FROM feed_follow_tags
+        WHERE feed_follow_tags.feed_follow_id = feed_follows.id
+            AND feed_follow_tags.tag = $3::text
+    ))
+ORDER BY date_trunc('day', COALESCE(posts.published_at, posts.created_at)) DESC,
+    feed_follows.priority DESC,
+    COALESCE(posts.published_at, posts.created_at) DESC
+LIMIT $5 OFFSET $4
 `
 
 type GetPostsForUserParams struct {
-	UserID uuid.UUID
-	Limit  int32
+	UserID   uuid.UUID
+	Folder   sql.NullString
+	Tag      sql.NullString
+	Skip     int32
+	MaxPosts int32
 }
 
 type GetPostsForUserRow struct {
@@ -79,11 +272,23 @@ type GetPostsForUserRow struct {
 	Description sql.NullString
 	PublishedAt sql.NullTime
 	FeedID      uuid.UUID
+	Author      string
+	Categories  []string
+	FeverID     int64
 	FeedName    string
 }
 
+// Optionally narrowed to the feeds in one folder and/or with one tag. Muted
+// feeds and hidden posts are left out. Newest days come first; within a day,
+// posts from higher priority feeds come first.
 func (q *Queries) GetPostsForUser(ctx context.Context, arg GetPostsForUserParams) ([]GetPostsForUserRow, error) {
-	rows, err := q.db.QueryContext(ctx, getPostsForUser, arg.UserID, arg.Limit)
+	rows, err := q.db.QueryContext(ctx, getPostsForUser,
+		arg.UserID,
+		arg.Folder,
+		arg.Tag,
+		arg.Skip,
+		arg.MaxPosts,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -100,6 +305,9 @@ func (q *Queries) GetPostsForUser(ctx context.Context, arg GetPostsForUserParams
 			&i.Description,
 			&i.PublishedAt,
 			&i.FeedID,
+			&i.Author,
+			pq.Array(&i.Categories),
+			&i.FeverID,
 			&i.FeedName,
 		); err != nil {
 			return nil, err
@@ -114,3 +322,171 @@ func (q *Queries) GetPostsForUser(ctx context.Context, arg GetPostsForUserParams
 	}
 	return items, nil
 }
+
+const getPrunablePosts = `-- name: GetPrunablePosts :many
+SELECT ranked.id FROM (
+    SELECT posts.id,
+        COALESCE(posts.published_at, posts.created_at) AS posted_at,
+        ROW_NUMBER() OVER (ORDER BY COALESCE(posts.published_at, posts.created_at) DESC) AS rank
+    FROM posts
+    WHERE posts.feed_id = $1
+) ranked
+WHERE (
+        ranked.posted_at < $2::timestamp
+        OR ($3::int > 0 AND ranked.rank > $3::int)
+    )
+    AND NOT ($4::bool AND EXISTS (
+        SELECT 1 FROM post_states
+        WHERE post_states.post_id = ranked.id AND post_states.starred_at IS NOT NULL
+    ))
+    AND NOT ($5::bool AND EXISTS (
+        SELECT 1 FROM feed_follows
+        WHERE feed_follows.feed_id = $1
+            AND NOT EXISTS (
+                SELECT 1 FROM post_states
+                WHERE post_states.post_id = ranked.id
+                    AND post_states.user_id = feed_follows.user_id
+                    AND post_states.read_at IS NOT NULL
+            )
+    ))
+`
+
+type GetPrunablePostsParams struct {
+	FeedID      uuid.UUID
+	Cutoff      sql.NullTime
+	MaxPosts    int32
+	KeepStarred bool
+	KeepUnread  bool
+}
+
+// Posts of a feed that are older than the cutoff or beyond the newest
+// max_posts, less those kept for being starred or unread by a follower.
+func (q *Queries) GetPrunablePosts(ctx context.Context, arg GetPrunablePostsParams) ([]uuid.UUID, error) {
+	rows, err := q.db.QueryContext(ctx, getPrunablePosts,
+		arg.FeedID,
+		arg.Cutoff,
+		arg.MaxPosts,
+		arg.KeepStarred,
+		arg.KeepUnread,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getRecentPostTimes = `-- name: GetRecentPostTimes :many
+SELECT COALESCE(published_at, created_at)::timestamp AS posted_at
+FROM posts
+WHERE feed_id = $1
+ORDER BY posted_at DESC
+LIMIT $2
+`
+
+type GetRecentPostTimesParams struct {
+	FeedID uuid.UUID
+	Limit  int32
+}
+
+func (q *Queries) GetRecentPostTimes(ctx context.Context, arg GetRecentPostTimesParams) ([]time.Time, error) {
+	rows, err := q.db.QueryContext(ctx, getRecentPostTimes, arg.FeedID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []time.Time
+	for rows.Next() {
+		var posted_at time.Time
+		if err := rows.Scan(&posted_at); err != nil {
+			return nil, err
+		}
+		items = append(items, posted_at)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const movePosts = `-- name: MovePosts :exec
+
+UPDATE posts
+SET feed_id = $1,
+    updated_at = $2
+WHERE feed_id = $3
+`
+
+type MovePostsParams struct {
+	ToFeedID   uuid.UUID
+	UpdatedAt  time.Time
+	FromFeedID uuid.UUID
+}
+
+func (q *Queries) MovePosts(ctx context.Context, arg MovePostsParams) error {
+	_, err := q.db.ExecContext(ctx, movePosts, arg.ToFeedID, arg.UpdatedAt, arg.FromFeedID)
+	return err
+}
+
+const postWasPruned = `-- name: PostWasPruned :one
+SELECT EXISTS (SELECT 1 FROM pruned_posts WHERE url = $1)
+`
+
+func (q *Queries) PostWasPruned(ctx context.Context, url string) (bool, error) {
+	row := q.db.QueryRowContext(ctx, postWasPruned, url)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
+const recordPrunedPosts = `-- name: RecordPrunedPosts :exec
+INSERT INTO pruned_posts (url, feed_id, pruned_at)
+SELECT url, feed_id, $1::timestamp FROM posts
+WHERE id = ANY($2::uuid[])
+ON CONFLICT (url) DO NOTHING
+`
+
+type RecordPrunedPostsParams struct {
+	PrunedAt time.Time
+	Ids      []uuid.UUID
+}
+
+func (q *Queries) RecordPrunedPosts(ctx context.Context, arg RecordPrunedPostsParams) error {
+	_, err := q.db.ExecContext(ctx, recordPrunedPosts, arg.PrunedAt, pq.Array(arg.Ids))
+	return err
+}
+
+const updatePostURL = `-- name: UpdatePostURL :exec
+UPDATE posts
+SET url = $2,
+updated_at = $3
+WHERE id = $1
+`
+
+type UpdatePostURLParams struct {
+	ID        uuid.UUID
+	Url       string
+	UpdatedAt time.Time
+}
+
+func (q *Queries) UpdatePostURL(ctx context.Context, arg UpdatePostURLParams) error {
+	_, err := q.db.ExecContext(ctx, updatePostURL, arg.ID, arg.Url, arg.UpdatedAt)
+	return err
+}
@@ -0,0 +1,214 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Querier interface {
+	ActivateWebSubSubscription(ctx context.Context, arg ActivateWebSubSubscriptionParams) error
+	AddFeedFollowTag(ctx context.Context, arg AddFeedFollowTagParams) (int64, error)
+	AddFeedURLHistory(ctx context.Context, arg AddFeedURLHistoryParams) error
+	AddPostTag(ctx context.Context, arg AddPostTagParams) (int64, error)
+	// Moves last_sent_at from one value to another, unless someone else already
+	// moved it. Claiming a period before sending its digest keeps two senders
+	// from sending it twice.
+	ClaimDigest(ctx context.Context, arg ClaimDigestParams) (int64, error)
+	ClearFeedRedirect(ctx context.Context, arg ClearFeedRedirectParams) error
+	CountActiveAdmins(ctx context.Context) (int64, error)
+	CountAllData(ctx context.Context) (CountAllDataRow, error)
+	CountFeedData(ctx context.Context, feedID uuid.UUID) (CountFeedDataRow, error)
+	CountFeverItems(ctx context.Context, userID uuid.UUID) (int64, error)
+	CountOverdueFeeds(ctx context.Context, now time.Time) (int64, error)
+	CountPostsBefore(ctx context.Context, before time.Time) (int64, error)
+	// Counts what deleting the user cascades to: the feeds they added, those
+	// feeds' posts, and follows by or of them.
+	CountUserData(ctx context.Context, userID uuid.UUID) (CountUserDataRow, error)
+	CreateFeed(ctx context.Context, arg CreateFeedParams) (Feed, error)
+	CreateFeedFollow(ctx context.Context, arg CreateFeedFollowParams) (CreateFeedFollowRow, error)
+	CreateFilterRule(ctx context.Context, arg CreateFilterRuleParams) (FilterRule, error)
+	CreateFolder(ctx context.Context, arg CreateFolderParams) (Folder, error)
+	CreateNotificationRule(ctx context.Context, arg CreateNotificationRuleParams) (NotificationRule, error)
+	CreatePost(ctx context.Context, arg CreatePostParams) (Post, error)
+	CreateUser(ctx context.Context, arg CreateUserParams) (User, error)
+	DeactivateFeed(ctx context.Context, arg DeactivateFeedParams) error
+	DelFeedFollow(ctx context.Context, arg DelFeedFollowParams) (int64, error)
+	DelUsers(ctx context.Context) error
+	DeleteDigestSettings(ctx context.Context, userID uuid.UUID) (int64, error)
+	DeleteFeed(ctx context.Context, id uuid.UUID) error
+	DeleteFilterRule(ctx context.Context, arg DeleteFilterRuleParams) (int64, error)
+	DeleteFolder(ctx context.Context, arg DeleteFolderParams) (int64, error)
+	DeleteNotificationRule(ctx context.Context, arg DeleteNotificationRuleParams) (int64, error)
+	DeletePost(ctx context.Context, id uuid.UUID) error
+	DeletePostsBefore(ctx context.Context, before time.Time) (int64, error)
+	DeletePostsByID(ctx context.Context, ids []uuid.UUID) (int64, error)
+	DeleteUser(ctx context.Context, id uuid.UUID) (int64, error)
+	// Gives up on pending deliveries whose post was deleted before they were
+	// sent, since there is nothing left to send.
+	FailOrphanedNotifications(ctx context.Context, arg FailOrphanedNotificationsParams) (int64, error)
+	// Forgets the feed's pruned posts it no longer lists, which can't come back.
+	ForgetPrunedPosts(ctx context.Context, arg ForgetPrunedPostsParams) (int64, error)
+	// Every user's read, starred and hidden marks, leaving out rows with none.
+	GetAllPostStates(ctx context.Context) ([]PostState, error)
+	GetAllPostTags(ctx context.Context) ([]PostTag, error)
+	// Every post of every feed the user follows, muted or not, for applying
+	// filter rules.
+	GetAllPostsForUser(ctx context.Context, userID uuid.UUID) ([]Post, error)
+	// Unread posts saved in (since, until] for the user's followed feeds, leaving
+	// out muted feeds and hidden posts. The oldest come first, so a digest cut
+	// off at max_posts covers everything saved up to its last post.
+	GetDigestPosts(ctx context.Context, arg GetDigestPostsParams) ([]GetDigestPostsRow, error)
+	GetDigestSettings(ctx context.Context, userID uuid.UUID) (DigestSetting, error)
+	// Users whose last digest covered up to before the cutoff, or who have never
+	// had one. Disabled users get none.
+	GetDueDigests(ctx context.Context, cutoff time.Time) ([]GetDueDigestsRow, error)
+	GetDueNotifications(ctx context.Context, arg GetDueNotificationsParams) ([]GetDueNotificationsRow, error)
+	GetEarliestNextFetch(ctx context.Context) (sql.NullTime, error)
+	// Deliveries that have used up their attempts.
+	GetFailedNotificationsForUser(ctx context.Context, arg GetFailedNotificationsForUserParams) ([]NotificationDelivery, error)
+	GetFaviconCheckedAt(ctx context.Context, feedID uuid.UUID) (time.Time, error)
+	GetFeedByID(ctx context.Context, id uuid.UUID) (Feed, error)
+	GetFeedByPreviousURL(ctx context.Context, url string) (Feed, error)
+	GetFeedByURL(ctx context.Context, url string) (Feed, error)
+	GetFeedFollow(ctx context.Context, arg GetFeedFollowParams) (FeedFollow, error)
+	GetFeedFollowers(ctx context.Context, feedID uuid.UUID) ([]User, error)
+	// Optionally narrowed to one folder and/or tag. feed_name is the user's own
+	// title for the feed when they have set one.
+	GetFeedFollowsForUser(ctx context.Context, arg GetFeedFollowsForUserParams) ([]GetFeedFollowsForUserRow, error)
+	GetFeedURLHistory(ctx context.Context, feedID uuid.UUID) ([]FeedUrlHistory, error)
+	GetFeeds(ctx context.Context) ([]Feed, error)
+	GetFeedsByOwner(ctx context.Context, userID uuid.UUID) ([]Feed, error)
+	GetFeedsWithUser(ctx context.Context) ([]GetFeedsWithUserRow, error)
+	GetFeverFavicons(ctx context.Context, userID uuid.UUID) ([]GetFeverFaviconsRow, error)
+	// The feeds the user follows, with the folder each is in, if any, and
+	// whether we have its icon.
+	GetFeverFeeds(ctx context.Context, userID uuid.UUID) ([]GetFeverFeedsRow, error)
+	GetFeverGroups(ctx context.Context, userID uuid.UUID) ([]GetFeverGroupsRow, error)
+	// The oldest posts in the user's feeds after since_id. Hidden posts are
+	// left out here and in the other item queries.
+	GetFeverItemsAfter(ctx context.Context, arg GetFeverItemsAfterParams) ([]GetFeverItemsAfterRow, error)
+	// The newest posts in the user's feeds before max_id.
+	GetFeverItemsBefore(ctx context.Context, arg GetFeverItemsBeforeParams) ([]GetFeverItemsBeforeRow, error)
+	GetFeverItemsByID(ctx context.Context, arg GetFeverItemsByIDParams) ([]GetFeverItemsByIDRow, error)
+	// When the most recently fetched of the user's feeds was fetched.
+	GetFeverLastRefreshed(ctx context.Context, userID uuid.UUID) (sql.NullTime, error)
+	// The post with this Fever ID, if it is in one of the user's feeds.
+	GetFeverPostID(ctx context.Context, arg GetFeverPostIDParams) (uuid.UUID, error)
+	GetFeverSavedItemIDs(ctx context.Context, userID uuid.UUID) ([]int64, error)
+	GetFeverUnreadItemIDs(ctx context.Context, userID uuid.UUID) ([]int64, error)
+	// The rules of the feed's followers that cover its posts: each follower's
+	// rules for every feed and their rules for this one.
+	GetFilterRulesForFeed(ctx context.Context, feedID uuid.UUID) ([]FilterRule, error)
+	GetFilterRulesForUser(ctx context.Context, userID uuid.UUID) ([]GetFilterRulesForUserRow, error)
+	GetFolderByName(ctx context.Context, arg GetFolderByNameParams) (Folder, error)
+	GetFoldersForUser(ctx context.Context, userID uuid.UUID) ([]GetFoldersForUserRow, error)
+	GetNextFeedsToFetch(ctx context.Context, arg GetNextFeedsToFetchParams) ([]Feed, error)
+	// The rules that watch a feed, for followers who haven't muted it or turned
+	// its notifications off, and aren't disabled.
+	GetNotificationRulesForFeed(ctx context.Context, feedID uuid.UUID) ([]NotificationRule, error)
+	GetNotificationRulesForUser(ctx context.Context, userID uuid.UUID) ([]GetNotificationRulesForUserRow, error)
+	GetPostByURL(ctx context.Context, url string) (Post, error)
+	GetPostURLs(ctx context.Context) ([]GetPostURLsRow, error)
+	// A page of every post in id order, for backups. Pass the nil UUID for the
+	// first page and the last id of each page for the next.
+	GetPostsAfter(ctx context.Context, arg GetPostsAfterParams) ([]Post, error)
+	//
+	// Optionally narrowed to the feeds in one folder and/or with one tag. Muted
+	// feeds and hidden posts are left out. Newest days come first; within a day,
+	// posts from higher priority feeds come first.
+	GetPostsForUser(ctx context.Context, arg GetPostsForUserParams) ([]GetPostsForUserRow, error)
+	// Posts of a feed that are older than the cutoff or beyond the newest
+	// max_posts, less those kept for being starred or unread by a follower.
+	GetPrunablePosts(ctx context.Context, arg GetPrunablePostsParams) ([]uuid.UUID, error)
+	GetRecentPostTimes(ctx context.Context, arg GetRecentPostTimesParams) ([]time.Time, error)
+	GetTagsForUser(ctx context.Context, userID uuid.UUID) ([]GetTagsForUserRow, error)
+	GetUser(ctx context.Context, name string) (User, error)
+	GetUserByFeverAPIKey(ctx context.Context, feverApiKey sql.NullString) (User, error)
+	// Every user with how many feeds they follow and have added, and how many
+	// posts in the feeds they haven't muted they have neither read nor hidden.
+	GetUserStats(ctx context.Context) ([]GetUserStatsRow, error)
+	GetUsers(ctx context.Context) ([]User, error)
+	GetWebSubSubscription(ctx context.Context, id uuid.UUID) (WebsubSubscription, error)
+	GetWebSubSubscriptionForFeed(ctx context.Context, feedID uuid.UUID) (WebsubSubscription, error)
+	GetWebSubSubscriptionsToRenew(ctx context.Context, before time.Time) ([]WebsubSubscription, error)
+	// Keeps other transactions from adding users until this one ends, so what
+	// it finds in users still holds when it commits.
+	LockUsers(ctx context.Context) error
+	MarkFeedFetched(ctx context.Context, arg MarkFeedFetchedParams) (Feed, error)
+	// Marks the feed's posts saved before before as read, keeping the read time
+	// of ones already read.
+	MarkFeverFeedRead(ctx context.Context, arg MarkFeverFeedReadParams) (int64, error)
+	// Like MarkFeverFeedRead, for every feed in the folder.
+	MarkFeverGroupRead(ctx context.Context, arg MarkFeverGroupReadParams) (int64, error)
+	// Like MarkFeverFeedRead, for every feed the user follows that is muted when
+	// sparks is set, which Fever calls Sparks, or isn't muted otherwise, which it
+	// calls Kindling.
+	MarkFeverSuperGroupRead(ctx context.Context, arg MarkFeverSuperGroupReadParams) (int64, error)
+	MarkNotificationDelivered(ctx context.Context, arg MarkNotificationDeliveredParams) error
+	MarkNotificationFailed(ctx context.Context, arg MarkNotificationFailedParams) error
+	// For users who follow both feeds, fills in their follow of to_feed_id from
+	// their follow of from_feed_id: a title or folder it lacks, a priority when
+	// it has none, and muting or turning notifications off.
+	MergeFeedFollowSettings(ctx context.Context, arg MergeFeedFollowSettingsParams) error
+	// For users who follow both feeds, adds the tags of their follow of
+	// from_feed_id to their follow of to_feed_id.
+	MergeFeedFollowTags(ctx context.Context, arg MergeFeedFollowTagsParams) error
+	// Gives to_feed_id the retention limits of from_feed_id that it hasn't set
+	// itself.
+	MergeFeedRetention(ctx context.Context, arg MergeFeedRetentionParams) error
+	MoveFeedFollows(ctx context.Context, arg MoveFeedFollowsParams) error
+	MoveFeedURLHistory(ctx context.Context, arg MoveFeedURLHistoryParams) error
+	MoveFilterRules(ctx context.Context, arg MoveFilterRulesParams) error
+	MoveNotificationRules(ctx context.Context, arg MoveNotificationRulesParams) error
+	//
+	MovePosts(ctx context.Context, arg MovePostsParams) error
+	PostWasPruned(ctx context.Context, url string) (bool, error)
+	// A post is queued once per user and sink by its URL, so saving it again
+	// after it was pruned doesn't send it again.
+	QueueNotification(ctx context.Context, arg QueueNotificationParams) error
+	RecordFeedFetchError(ctx context.Context, id uuid.UUID) (int32, error)
+	RecordFeedRedirect(ctx context.Context, arg RecordFeedRedirectParams) (Feed, error)
+	RecordPrunedPosts(ctx context.Context, arg RecordPrunedPostsParams) error
+	RecordUserActivity(ctx context.Context, arg RecordUserActivityParams) error
+	// Adds a user, as an admin if they are the first one.
+	RegisterUser(ctx context.Context, arg RegisterUserParams) (User, error)
+	RemoveFeedFollowTag(ctx context.Context, arg RemoveFeedFollowTagParams) (int64, error)
+	RenameFeed(ctx context.Context, arg RenameFeedParams) error
+	RenameUser(ctx context.Context, arg RenameUserParams) (int64, error)
+	ResetFeedFetchErrors(ctx context.Context, id uuid.UUID) error
+	// Forgets what fetching the feed's previous URL taught us, so a corrected
+	// URL is fetched straight away.
+	ResetFeedFetchState(ctx context.Context, arg ResetFeedFetchStateParams) error
+	// Adds a user's marks on a post from a backup, leaving any the user already
+	// has alone.
+	RestorePostState(ctx context.Context, arg RestorePostStateParams) (int64, error)
+	SetFeedFollowFolder(ctx context.Context, arg SetFeedFollowFolderParams) (int64, error)
+	SetFeedNextFetch(ctx context.Context, arg SetFeedNextFetchParams) error
+	SetFeedOwner(ctx context.Context, arg SetFeedOwnerParams) error
+	SetFeedRetention(ctx context.Context, arg SetFeedRetentionParams) error
+	// Pass a NULL fever_api_key to turn Fever off for the user.
+	SetFeverAPIKey(ctx context.Context, arg SetFeverAPIKeyParams) (int64, error)
+	SetPostHidden(ctx context.Context, arg SetPostHiddenParams) error
+	SetPostRead(ctx context.Context, arg SetPostReadParams) error
+	SetPostStarred(ctx context.Context, arg SetPostStarredParams) error
+	// Pass a NULL disabled_at to enable the user again.
+	SetUserDisabled(ctx context.Context, arg SetUserDisabledParams) (int64, error)
+	SetUserRole(ctx context.Context, arg SetUserRoleParams) (int64, error)
+	SetWebSubSubscriptionState(ctx context.Context, arg SetWebSubSubscriptionStateParams) error
+	UpdateFeedFollowSettings(ctx context.Context, arg UpdateFeedFollowSettingsParams) (int64, error)
+	UpdateFeedURL(ctx context.Context, arg UpdateFeedURLParams) error
+	UpdatePostURL(ctx context.Context, arg UpdatePostURLParams) error
+	UpsertDigestSettings(ctx context.Context, arg UpsertDigestSettingsParams) (DigestSetting, error)
+	// data is NULL when the site has no icon.
+	UpsertFavicon(ctx context.Context, arg UpsertFaviconParams) error
+	UpsertWebSubSubscription(ctx context.Context, arg UpsertWebSubSubscriptionParams) (WebsubSubscription, error)
+}
+
+var _ Querier = (*Queries)(nil)
@@ -0,0 +1,47 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// Store is everything gatorapp needs from its database: the queries in
+// Querier, plus transactions. PostgresStore implements it here, and package
+// sqlitedb implements it for SQLite.
+type Store interface {
+	Querier
+	// InTx calls fn with a Querier whose queries all run in one
+	// transaction, which is committed if fn returns nil and rolled back
+	// otherwise.
+	InTx(ctx context.Context, fn func(Querier) error) error
+}
+
+var _ Store = (*PostgresStore)(nil)
+
+// PostgresStore is a Store backed by Postgres.
+type PostgresStore struct {
+	*Queries
+	db *sql.DB
+}
+
+// NewPostgresStore returns a Store that runs queries on dbtx, which is db or
+// a wrapper around it, and transactions on db.
+func NewPostgresStore(db *sql.DB, dbtx DBTX) *PostgresStore {
+	return &PostgresStore{Queries: New(dbtx), db: db}
+}
+
+func (s *PostgresStore) InTx(ctx context.Context, fn func(Querier) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("could not start transaction: %w", err)
+	}
+	defer tx.Rollback()
+	if err := fn(s.Queries.WithTx(tx)); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("could not commit transaction: %w", err)
+	}
+	return nil
+}
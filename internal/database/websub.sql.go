@@ -0,0 +1,198 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: websub.sql
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const activateWebSubSubscription = `-- name: ActivateWebSubSubscription :exec
+UPDATE websub_subscriptions
+SET state = 'active',
+    secret = COALESCE(pending_secret, secret),
+    pending_secret = NULL,
+    lease_expires_at = $2,
+    renew_at = $3,
+    updated_at = $4
+WHERE id = $1
+`
+
+type ActivateWebSubSubscriptionParams struct {
+	ID             uuid.UUID
+	LeaseExpiresAt sql.NullTime
+	RenewAt        sql.NullTime
+	UpdatedAt      time.Time
+}
+
+func (q *Queries) ActivateWebSubSubscription(ctx context.Context, arg ActivateWebSubSubscriptionParams) error {
+	_, err := q.db.ExecContext(ctx, activateWebSubSubscription,
+		arg.ID,
+		arg.LeaseExpiresAt,
+		arg.RenewAt,
+		arg.UpdatedAt,
+	)
+	return err
+}
+
+const getWebSubSubscription = `-- name: GetWebSubSubscription :one
+SELECT id, created_at, updated_at, feed_id, hub_url, topic_url, secret, pending_secret, state, lease_expires_at, renew_at FROM websub_subscriptions WHERE id = $1
+`
+
+func (q *Queries) GetWebSubSubscription(ctx context.Context, id uuid.UUID) (WebsubSubscription, error) {
+	row := q.db.QueryRowContext(ctx, getWebSubSubscription, id)
+	var i WebsubSubscription
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.FeedID,
+		&i.HubUrl,
+		&i.TopicUrl,
+		&i.Secret,
+		&i.PendingSecret,
+		&i.State,
+		&i.LeaseExpiresAt,
+		&i.RenewAt,
+	)
+	return i, err
+}
+
+const getWebSubSubscriptionForFeed = `-- name: GetWebSubSubscriptionForFeed :one
+SELECT id, created_at, updated_at, feed_id, hub_url, topic_url, secret, pending_secret, state, lease_expires_at, renew_at FROM websub_subscriptions WHERE feed_id = $1
+`
+
+func (q *Queries) GetWebSubSubscriptionForFeed(ctx context.Context, feedID uuid.UUID) (WebsubSubscription, error) {
+	row := q.db.QueryRowContext(ctx, getWebSubSubscriptionForFeed, feedID)
+	var i WebsubSubscription
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.FeedID,
+		&i.HubUrl,
+		&i.TopicUrl,
+		&i.Secret,
+		&i.PendingSecret,
+		&i.State,
+		&i.LeaseExpiresAt,
+		&i.RenewAt,
+	)
+	return i, err
+}
+
+const getWebSubSubscriptionsToRenew = `-- name: GetWebSubSubscriptionsToRenew :many
+SELECT id, created_at, updated_at, feed_id, hub_url, topic_url, secret, pending_secret, state, lease_expires_at, renew_at FROM websub_subscriptions
+WHERE state = 'active' AND renew_at <= $1::timestamp
+ORDER BY renew_at ASC
+`
+
+func (q *Queries) GetWebSubSubscriptionsToRenew(ctx context.Context, before time.Time) ([]WebsubSubscription, error) {
+	rows, err := q.db.QueryContext(ctx, getWebSubSubscriptionsToRenew, before)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []WebsubSubscription
+	for rows.Next() {
+		var i WebsubSubscription
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.FeedID,
+			&i.HubUrl,
+			&i.TopicUrl,
+			&i.Secret,
+			&i.PendingSecret,
+			&i.State,
+			&i.LeaseExpiresAt,
+			&i.RenewAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const setWebSubSubscriptionState = `-- name: SetWebSubSubscriptionState :exec
+UPDATE websub_subscriptions
+SET state = $2,
+    updated_at = $3
+WHERE id = $1
+`
+
+type SetWebSubSubscriptionStateParams struct {
+	ID        uuid.UUID
+	State     string
+	UpdatedAt time.Time
+}
+
+func (q *Queries) SetWebSubSubscriptionState(ctx context.Context, arg SetWebSubSubscriptionStateParams) error {
+	_, err := q.db.ExecContext(ctx, setWebSubSubscriptionState, arg.ID, arg.State, arg.UpdatedAt)
+	return err
+}
+
+const upsertWebSubSubscription = `-- name: UpsertWebSubSubscription :one
+INSERT INTO websub_subscriptions (id, created_at, updated_at, feed_id, hub_url, topic_url, secret, pending_secret, state)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, 'pending')
+ON CONFLICT (feed_id) DO UPDATE
+SET updated_at = EXCLUDED.updated_at,
+    hub_url = EXCLUDED.hub_url,
+    topic_url = EXCLUDED.topic_url,
+    pending_secret = EXCLUDED.pending_secret,
+    state = 'pending'
+RETURNING id, created_at, updated_at, feed_id, hub_url, topic_url, secret, pending_secret, state, lease_expires_at, renew_at
+`
+
+type UpsertWebSubSubscriptionParams struct {
+	ID            uuid.UUID
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	FeedID        uuid.UUID
+	HubUrl        string
+	TopicUrl      string
+	Secret        string
+	PendingSecret sql.NullString
+}
+
+func (q *Queries) UpsertWebSubSubscription(ctx context.Context, arg UpsertWebSubSubscriptionParams) (WebsubSubscription, error) {
+	row := q.db.QueryRowContext(ctx, upsertWebSubSubscription,
+		arg.ID,
+		arg.CreatedAt,
+		arg.UpdatedAt,
+		arg.FeedID,
+		arg.HubUrl,
+		arg.TopicUrl,
+		arg.Secret,
+		arg.PendingSecret,
+	)
+	var i WebsubSubscription
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.FeedID,
+		&i.HubUrl,
+		&i.TopicUrl,
+		&i.Secret,
+		&i.PendingSecret,
+		&i.State,
+		&i.LeaseExpiresAt,
+		&i.RenewAt,
+	)
+	return i, err
+}
@@ -0,0 +1,468 @@
+// Package fever serves the Fever API (https://feedafever.com/api), which
+// many mobile and desktop feed readers use to sync with a self-hosted
+// aggregator. Only the JSON form of the API is supported.
+package fever
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// APIVersion is the version of the Fever API this package speaks.
+const APIVersion = 3
+
+// MaxItems is how many items one request returns, as in Fever itself.
+const MaxItems = 50
+
+// ErrUnauthorized is returned by a Store for an api_key that doesn't belong
+// to an active user.
+var ErrUnauthorized = errors.New("unknown api_key")
+
+// The super groups Fever clients mark as read with group IDs of their own.
+const (
+	// GroupKindling is every feed that isn't a spark.
+	GroupKindling = 0
+	// GroupSparks is every spark.
+	GroupSparks = -1
+)
+
+// What an item can be marked as.
+const (
+	AsRead    = "read"
+	AsUnread  = "unread"
+	AsSaved   = "saved"
+	AsUnsaved = "unsaved"
+)
+
+// Group is a folder of feeds.
+type Group struct {
+	ID    int64
+	Title string
+}
+
+type Feed struct {
+	ID int64
+	// GroupID is the feed's group, or 0 if it isn't in one.
+	GroupID int64
+	Title   string
+	URL     string
+	SiteURL string
+	// IsSpark marks feeds the user reads less often; Fever clients keep
+	// their items out of the main list.
+	IsSpark     bool
+	HasFavicon  bool
+	LastUpdated time.Time
+}
+
+// Favicon is a feed's icon. Its ID is the ID of the feed.
+type Favicon struct {
+	ID          int64
+	ContentType string
+	Data        []byte
+}
+
+type Item struct {
+	ID        int64
+	FeedID    int64
+	Title     string
+	Author    string
+	HTML      string
+	URL       string
+	IsSaved   bool
+	IsRead    bool
+	CreatedOn time.Time
+}
+
+// ItemQuery picks the items to return, at most MaxItems of them. If WithIDs
+// is set, those items are returned. Otherwise, if MaxID is set, the newest
+// items before it are, newest first; if not, the oldest items after SinceID
+// are, oldest first.
+type ItemQuery struct {
+	SinceID int64
+	MaxID   int64
+	WithIDs []int64
+}
+
+// Store is the aggregator behind the API. user is whatever Authenticate
+// returns for the api_key the request carried.
+type Store interface {
+	Authenticate(ctx context.Context, apiKey string) (user string, err error)
+	// LastRefreshed is when the user's feeds were last fetched, or zero if
+	// they never have been.
+	LastRefreshed(ctx context.Context, user string) (time.Time, error)
+	Groups(ctx context.Context, user string) ([]Group, error)
+	Feeds(ctx context.Context, user string) ([]Feed, error)
+	Favicons(ctx context.Context, user string) ([]Favicon, error)
+	Items(ctx context.Context, user string, q ItemQuery) ([]Item, error)
+	TotalItems(ctx context.Context, user string) (int64, error)
+	UnreadItemIDs(ctx context.Context, user string) ([]int64, error)
+	SavedItemIDs(ctx context.Context, user string) ([]int64, error)
+	// MarkItem marks an item as read, unread, saved or unsaved. Unknown
+	// items are ignored.
+	MarkItem(ctx context.Context, user string, id int64, as string) error
+	// MarkFeedRead marks the feed's items added before before as read.
+	MarkFeedRead(ctx context.Context, user string, id int64, before time.Time) error
+	// MarkGroupRead marks the items added before before in the group's feeds
+	// as read. id may also be GroupKindling or GroupSparks.
+	MarkGroupRead(ctx context.Context, user string, id int64, before time.Time) error
+}
+
+// Handler serves the API. Clients are given its URL and add "?api" and the
+// names of what they want to it.
+type Handler struct {
+	Store Store
+	// Logger reports errors the client only sees as a 500. Optional.
+	Logger *slog.Logger
+}
+
+// badRequestError is a malformed parameter.
+type badRequestError struct {
+	param string
+}
+
+func (e *badRequestError) Error() string {
+	return "invalid " + e.param
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !r.URL.Query().Has("api") {
+		http.Error(w, "not a Fever API request", http.StatusBadRequest)
+		return
+	}
+	if api := r.URL.Query().Get("api"); api != "" && api != "json" {
+		http.Error(w, "only the JSON API is supported", http.StatusBadRequest)
+		return
+	}
+
+	resp, err := h.respond(r)
+	var badRequest *badRequestError
+	if errors.As(err, &badRequest) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		if h.Logger != nil {
+			h.Logger.Error("fever: couldn't answer request", "query", r.URL.RawQuery, "error", err)
+		}
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil && h.Logger != nil {
+		// The status is already sent, so all we can do is note it.
+		h.Logger.Error("fever: couldn't write response", "query", r.URL.RawQuery, "error", err)
+	}
+}
+
+// respond builds the response to a request: authentication first, then
+// any change the client asked for, then everything it asked to read.
+func (h *Handler) respond(r *http.Request) (map[string]any, error) {
+	ctx := r.Context()
+	resp := map[string]any{"api_version": APIVersion, "auth": 0}
+
+	user, err := h.Store.Authenticate(ctx, strings.ToLower(r.FormValue("api_key")))
+	if errors.Is(err, ErrUnauthorized) {
+		return resp, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("authenticate: %w", err)
+	}
+	resp["auth"] = 1
+
+	lastRefreshed, err := h.Store.LastRefreshed(ctx, user)
+	if err != nil {
+		return nil, fmt.Errorf("last refreshed: %w", err)
+	}
+	resp["last_refreshed_on_time"] = unixTime(lastRefreshed)
+
+	q := r.URL.Query()
+	wantUnread := q.Has("unread_item_ids")
+	wantSaved := q.Has("saved_item_ids")
+
+	if mark := r.FormValue("mark"); mark != "" {
+		changed, err := h.mark(r, user, mark)
+		if err != nil {
+			return nil, err
+		}
+		// Clients expect the list the change affected back.
+		if changed == AsSaved {
+			wantSaved = true
+		} else {
+			wantUnread = true
+		}
+	}
+
+	if q.Has("groups") || q.Has("feeds") {
+		feeds, err := h.Store.Feeds(ctx, user)
+		if err != nil {
+			return nil, fmt.Errorf("feeds: %w", err)
+		}
+		if q.Has("groups") {
+			groups, err := h.Store.Groups(ctx, user)
+			if err != nil {
+				return nil, fmt.Errorf("groups: %w", err)
+			}
+			resp["groups"] = groupsJSON(groups)
+		}
+		if q.Has("feeds") {
+			resp["feeds"] = feedsJSON(feeds)
+		}
+		resp["feeds_groups"] = feedsGroupsJSON(feeds)
+	}
+
+	if q.Has("favicons") {
+		favicons, err := h.Store.Favicons(ctx, user)
+		if err != nil {
+			return nil, fmt.Errorf("favicons: %w", err)
+		}
+		resp["favicons"] = faviconsJSON(favicons)
+	}
+
+	if q.Has("items") {
+		itemQuery, err := parseItemQuery(q)
+		if err != nil {
+			return nil, err
+		}
+		items, err := h.Store.Items(ctx, user, itemQuery)
+		if err != nil {
+			return nil, fmt.Errorf("items: %w", err)
+		}
+		total, err := h.Store.TotalItems(ctx, user)
+		if err != nil {
+			return nil, fmt.Errorf("total items: %w", err)
+		}
+		resp["items"] = itemsJSON(items)
+		resp["total_items"] = total
+	}
+
+	// Hot links are a Fever feature we have nothing for.
+	if q.Has("links") {
+		resp["links"] = []any{}
+	}
+
+	if wantUnread {
+		ids, err := h.Store.UnreadItemIDs(ctx, user)
+		if err != nil {
+			return nil, fmt.Errorf("unread item ids: %w", err)
+		}
+		resp["unread_item_ids"] = joinIDs(ids)
+	}
+	if wantSaved {
+		ids, err := h.Store.SavedItemIDs(ctx, user)
+		if err != nil {
+			return nil, fmt.Errorf("saved item ids: %w", err)
+		}
+		resp["saved_item_ids"] = joinIDs(ids)
+	}
+	return resp, nil
+}
+
+// mark applies a mark request, returning AsSaved if it changed what is
+// saved and AsRead if it changed what is read.
+func (h *Handler) mark(r *http.Request, user, mark string) (string, error) {
+	ctx := r.Context()
+	id, err := parseID(r.FormValue("id"), "id")
+	if err != nil {
+		return "", err
+	}
+	as := r.FormValue("as")
+
+	switch mark {
+	case "item":
+		switch as {
+		case AsRead, AsUnread, AsSaved, AsUnsaved:
+		default:
+			return "", &badRequestError{param: "as"}
+		}
+		if err := h.Store.MarkItem(ctx, user, id, as); err != nil {
+			return "", fmt.Errorf("mark item: %w", err)
+		}
+		if as == AsSaved || as == AsUnsaved {
+			return AsSaved, nil
+		}
+		return AsRead, nil
+	case "feed", "group":
+		if as != AsRead {
+			return "", &badRequestError{param: "as"}
+		}
+		before, err := parseID(r.FormValue("before"), "before")
+		if err != nil {
+			return "", err
+		}
+		beforeTime := time.Unix(before, 0)
+		if mark == "feed" {
+			err = h.Store.MarkFeedRead(ctx, user, id, beforeTime)
+		} else {
+			err = h.Store.MarkGroupRead(ctx, user, id, beforeTime)
+		}
+		if err != nil {
+			return "", fmt.Errorf("mark %s: %w", mark, err)
+		}
+		return AsRead, nil
+	}
+	return "", &badRequestError{param: "mark"}
+}
+
+func parseItemQuery(q url.Values) (ItemQuery, error) {
+	var itemQuery ItemQuery
+	if withIDs := q.Get("with_ids"); withIDs != "" {
+		for _, field := range strings.Split(withIDs, ",") {
+			id, err := parseID(strings.TrimSpace(field), "with_ids")
+			if err != nil {
+				return itemQuery, err
+			}
+			itemQuery.WithIDs = append(itemQuery.WithIDs, id)
+			if len(itemQuery.WithIDs) == MaxItems {
+				break
+			}
+		}
+		return itemQuery, nil
+	}
+	if q.Has("max_id") {
+		maxID, err := parseID(q.Get("max_id"), "max_id")
+		if err != nil {
+			return itemQuery, err
+		}
+		// max_id=0 asks for the newest items.
+		if maxID <= 0 {
+			maxID = math.MaxInt64
+		}
+		itemQuery.MaxID = maxID
+		return itemQuery, nil
+	}
+	sinceID, err := parseID(q.Get("since_id"), "since_id")
+	if err != nil {
+		return itemQuery, err
+	}
+	itemQuery.SinceID = sinceID
+	return itemQuery, nil
+}
+
+// parseID parses a numeric parameter; a missing one is 0.
+func parseID(value, param string) (int64, error) {
+	if value == "" {
+		return 0, nil
+	}
+	id, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, &badRequestError{param: param}
+	}
+	return id, nil
+}
+
+func unixTime(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.Unix()
+}
+
+func boolInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// joinIDs formats IDs as Fever does, as one comma-separated string.
+func joinIDs(ids []int64) string {
+	fields := make([]string, len(ids))
+	for i, id := range ids {
+		fields[i] = strconv.FormatInt(id, 10)
+	}
+	return strings.Join(fields, ",")
+}
+
+func groupsJSON(groups []Group) []map[string]any {
+	out := make([]map[string]any, 0, len(groups))
+	for _, g := range groups {
+		out = append(out, map[string]any{"id": g.ID, "title": g.Title})
+	}
+	return out
+}
+
+func feedsJSON(feeds []Feed) []map[string]any {
+	out := make([]map[string]any, 0, len(feeds))
+	for _, f := range feeds {
+		var faviconID int64
+		if f.HasFavicon {
+			faviconID = f.ID
+		}
+		out = append(out, map[string]any{
+			"id":                   f.ID,
+			"favicon_id":           faviconID,
+			"title":                f.Title,
+			"url":                  f.URL,
+			"site_url":             f.SiteURL,
+			"is_spark":             boolInt(f.IsSpark),
+			"last_updated_on_time": unixTime(f.LastUpdated),
+		})
+	}
+	return out
+}
+
+// feedsGroupsJSON lists the feeds in each group.
+func feedsGroupsJSON(feeds []Feed) []map[string]any {
+	var order []int64
+	members := map[int64][]int64{}
+	for _, f := range feeds {
+		if f.GroupID == 0 {
+			continue
+		}
+		if _, ok := members[f.GroupID]; !ok {
+			order = append(order, f.GroupID)
+		}
+		members[f.GroupID] = append(members[f.GroupID], f.ID)
+	}
+
+	out := make([]map[string]any, 0, len(order))
+	for _, groupID := range order {
+		out = append(out, map[string]any{"group_id": groupID, "feed_ids": joinIDs(members[groupID])})
+	}
+	return out
+}
+
+func faviconsJSON(favicons []Favicon) []map[string]any {
+	out := make([]map[string]any, 0, len(favicons))
+	for _, f := range favicons {
+		out = append(out, map[string]any{
+			"id":   f.ID,
+			"data": f.ContentType + ";base64," + base64.StdEncoding.EncodeToString(f.Data),
+		})
+	}
+	return out
+}
+
+func itemsJSON(items []Item) []map[string]any {
+	out := make([]map[string]any, 0, len(items))
+	for _, item := range items {
+		out = append(out, map[string]any{
+			"id":              item.ID,
+			"feed_id":         item.FeedID,
+			"title":           item.Title,
+			"author":          item.Author,
+			"html":            item.HTML,
+			"url":             item.URL,
+			"is_saved":        boolInt(item.IsSaved),
+			"is_read":         boolInt(item.IsRead),
+			"created_on_time": unixTime(item.CreatedOn),
+		})
+	}
+	return out
+}
@@ -0,0 +1,97 @@
+// Package filter matches posts against user-defined filter rules.
+package filter
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Post fields a rule can match on.
+const (
+	FieldTitle       = "title"
+	FieldDescription = "description"
+	FieldAuthor      = "author"
+	FieldCategory    = "category"
+)
+
+// Ways a rule's pattern can match.
+const (
+	// MatchKeyword matches when the field contains the pattern, ignoring
+	// case.
+	MatchKeyword = "keyword"
+	// MatchRegex matches when the field matches the pattern as a Go regular
+	// expression.
+	MatchRegex = "regex"
+)
+
+var (
+	Fields  = []string{FieldTitle, FieldDescription, FieldAuthor, FieldCategory}
+	Matches = []string{MatchKeyword, MatchRegex}
+)
+
+// Post is what rules see of a post.
+type Post struct {
+	Title       string
+	Description string
+	Author      string
+	Categories  []string
+}
+
+// Rule is a compiled match condition.
+type Rule struct {
+	field   string
+	keyword string
+	re      *regexp.Regexp
+}
+
+// Compile checks a rule's field, match type and pattern and prepares it for
+// matching.
+func Compile(field, match, pattern string) (*Rule, error) {
+	if !slices.Contains(Fields, field) {
+		return nil, fmt.Errorf("unknown field %q, want one of %s", field, strings.Join(Fields, ", "))
+	}
+	if pattern == "" {
+		return nil, errors.New("pattern can't be empty")
+	}
+
+	r := &Rule{field: field}
+	switch match {
+	case MatchKeyword:
+		r.keyword = strings.ToLower(pattern)
+	case MatchRegex:
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regex: %w", err)
+		}
+		r.re = re
+	default:
+		return nil, fmt.Errorf("unknown match type %q, want one of %s", match, strings.Join(Matches, ", "))
+	}
+	return r, nil
+}
+
+// Matches reports whether the rule matches p. A category rule matches if any
+// of the post's categories does.
+func (r *Rule) Matches(p Post) bool {
+	switch r.field {
+	case FieldTitle:
+		return r.matchString(p.Title)
+	case FieldDescription:
+		return r.matchString(p.Description)
+	case FieldAuthor:
+		return r.matchString(p.Author)
+	case FieldCategory:
+		return slices.ContainsFunc(p.Categories, r.matchString)
+	}
+	return false
+}
+
+func (r *Rule) matchString(s string) bool {
+	if r.re != nil {
+		return r.re.MatchString(s)
+	}
+	return strings.Contains(strings.ToLower(s), r.keyword)
+}
@@ -0,0 +1,108 @@
+package hostlimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Limiter spaces out and caps concurrent requests per host, so feeds that
+// share a host are fetched politely even when the aggregator runs several
+// fetches at once.
+type Limiter struct {
+	interval    time.Duration
+	concurrency int
+
+	mu    sync.Mutex
+	hosts map[string]*host
+}
+
+type host struct {
+	slots        chan struct{}
+	next         time.Time
+	blockedUntil time.Time
+}
+
+// New returns a Limiter that allows at most concurrency simultaneous
+// requests per host and starts them at least interval apart.
+func New(interval time.Duration, concurrency int) *Limiter {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	return &Limiter{
+		interval:    interval,
+		concurrency: concurrency,
+		hosts:       make(map[string]*host),
+	}
+}
+
+func (l *Limiter) host(name string) *host {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	h, ok := l.hosts[name]
+	if !ok {
+		h = &host{slots: make(chan struct{}, l.concurrency)}
+		l.hosts[name] = h
+	}
+	return h
+}
+
+// Acquire blocks until a request to the host may start. The returned
+// function must be called once the request has finished.
+func (l *Limiter) Acquire(ctx context.Context, name string) (release func(), err error) {
+	h := l.host(name)
+
+	select {
+	case h.slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	release = func() { <-h.slots }
+
+	for {
+		l.mu.Lock()
+		now := time.Now()
+		start := h.next
+		if h.blockedUntil.After(start) {
+			start = h.blockedUntil
+		}
+		if !start.After(now) {
+			h.next = now.Add(l.interval)
+			l.mu.Unlock()
+			return release, nil
+		}
+		l.mu.Unlock()
+
+		timer := time.NewTimer(start.Sub(now))
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			release()
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// Block stops new requests to the host from starting before until, as asked
+// by a Retry-After header.
+func (l *Limiter) Block(name string, until time.Time) {
+	h := l.host(name)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if until.After(h.blockedUntil) {
+		h.blockedUntil = until
+	}
+}
+
+// BlockedUntil reports when the host becomes available again, or the zero
+// time if it is not blocked.
+func (l *Limiter) BlockedUntil(name string) time.Time {
+	h := l.host(name)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if h.blockedUntil.After(time.Now()) {
+		return h.blockedUntil
+	}
+	return time.Time{}
+}
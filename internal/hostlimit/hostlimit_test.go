@@ -0,0 +1,137 @@
+package hostlimit
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestAcquireInterval(t *testing.T) {
+	const interval = 50 * time.Millisecond
+	l := New(interval, 2)
+	ctx := context.Background()
+
+	start := time.Now()
+	for range 3 {
+		release, err := l.Acquire(ctx, "example.com")
+		if err != nil {
+			t.Fatalf("Acquire: %v", err)
+		}
+		release()
+	}
+	if elapsed := time.Since(start); elapsed < 2*interval {
+		t.Errorf("3 requests to one host started within %v, want at least %v", elapsed, 2*interval)
+	}
+
+	// Other hosts aren't held up.
+	start = time.Now()
+	release, err := l.Acquire(ctx, "example.org")
+	if err != nil {
+		t.Fatalf("Acquire: %v", err)
+	}
+	release()
+	if elapsed := time.Since(start); elapsed >= interval {
+		t.Errorf("request to another host waited %v", elapsed)
+	}
+}
+
+func TestAcquireConcurrency(t *testing.T) {
+	tests := []struct {
+		name        string
+		concurrency int
+		want        int32
+	}{
+		{"one", 1, 1},
+		{"three", 3, 3},
+		{"unset", 0, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := New(0, tt.concurrency)
+			var running, most atomic.Int32
+			var wg sync.WaitGroup
+			for range 8 {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					release, err := l.Acquire(context.Background(), "example.com")
+					if err != nil {
+						t.Errorf("Acquire: %v", err)
+						return
+					}
+					defer release()
+					n := running.Add(1)
+					for {
+						m := most.Load()
+						if n <= m || most.CompareAndSwap(m, n) {
+							break
+						}
+					}
+					time.Sleep(10 * time.Millisecond)
+					running.Add(-1)
+				}()
+			}
+			wg.Wait()
+			if got := most.Load(); got != tt.want {
+				t.Errorf("at most %d requests ran at once, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAcquireCanceled(t *testing.T) {
+	l := New(time.Hour, 1)
+	release, err := l.Acquire(context.Background(), "example.com")
+	if err != nil {
+		t.Fatalf("Acquire: %v", err)
+	}
+	release()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := l.Acquire(ctx, "example.com"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Acquire within the interval = %v, want context.DeadlineExceeded", err)
+	}
+}
+
+func TestBlock(t *testing.T) {
+	l := New(0, 1)
+	until := time.Now().Add(time.Hour)
+	tests := []struct {
+		name  string
+		until time.Time
+		want  time.Time
+	}{
+		{"block", until, until},
+		{"shorter block is ignored", until.Add(-time.Minute), until},
+		{"longer block extends", until.Add(time.Minute), until.Add(time.Minute)},
+	}
+	for _, tt := range tests {
+		l.Block("example.com", tt.until)
+		if got := l.BlockedUntil("example.com"); !got.Equal(tt.want) {
+			t.Errorf("%s: BlockedUntil = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+	if got := l.BlockedUntil("example.org"); !got.IsZero() {
+		t.Errorf("another host is blocked until %v", got)
+	}
+
+	l.Block("example.net", time.Now().Add(-time.Minute))
+	if got := l.BlockedUntil("example.net"); !got.IsZero() {
+		t.Errorf("a past block reports %v, want the zero time", got)
+	}
+
+	l.Block("example.net", time.Now().Add(30*time.Millisecond))
+	start := time.Now()
+	release, err := l.Acquire(context.Background(), "example.net")
+	if err != nil {
+		t.Fatalf("Acquire: %v", err)
+	}
+	release()
+	if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+		t.Errorf("Acquire on a blocked host returned after %v", elapsed)
+	}
+}
@@ -0,0 +1,216 @@
+package mail
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	netmail "net/mail"
+	"net/textproto"
+	"strings"
+	"testing"
+	"time"
+)
+
+// envelope is what the stand-in SMTP server received.
+type envelope struct {
+	auth string
+	from string
+	to   []string
+	data string
+}
+
+// smtpServer is a stand-in SMTP server that accepts one session. It never
+// offers STARTTLS, advertises PLAIN auth, and refuses the rejected
+// recipient.
+type smtpServer struct {
+	ln       net.Listener
+	rejected string
+	done     chan envelope
+}
+
+func newSMTPServer(t *testing.T, rejected string) *smtpServer {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	srv := &smtpServer{ln: ln, rejected: rejected, done: make(chan envelope, 1)}
+	go srv.serve()
+	return srv
+}
+
+func (srv *smtpServer) serve() {
+	conn, err := srv.ln.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	tp := textproto.NewConn(conn)
+	var env envelope
+	defer func() { srv.done <- env }()
+
+	tp.PrintfLine("220 localhost stand-in")
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "EHLO":
+			tp.PrintfLine("250-localhost")
+			tp.PrintfLine("250 AUTH PLAIN")
+		case "AUTH":
+			creds, _ := base64.StdEncoding.DecodeString(strings.TrimPrefix(arg, "PLAIN "))
+			env.auth = string(creds)
+			tp.PrintfLine("235 ok")
+		case "MAIL":
+			env.from = strings.Trim(strings.TrimPrefix(arg, "FROM:"), "<>")
+			tp.PrintfLine("250 ok")
+		case "RCPT":
+			to := strings.Trim(strings.TrimPrefix(arg, "TO:"), "<>")
+			if to == srv.rejected {
+				tp.PrintfLine("550 no such user")
+				continue
+			}
+			env.to = append(env.to, to)
+			tp.PrintfLine("250 ok")
+		case "DATA":
+			tp.PrintfLine("354 go ahead")
+			data, err := io.ReadAll(tp.DotReader())
+			if err != nil {
+				return
+			}
+			env.data = string(data)
+			tp.PrintfLine("250 queued")
+		case "QUIT":
+			tp.PrintfLine("221 bye")
+			return
+		default:
+			tp.PrintfLine("502 not implemented")
+		}
+	}
+}
+
+func (srv *smtpServer) received(t *testing.T) envelope {
+	t.Helper()
+	select {
+	case env := <-srv.done:
+		return env
+	case <-time.After(5 * time.Second):
+		t.Fatal("smtp session didn't end")
+		return envelope{}
+	}
+}
+
+func TestSend(t *testing.T) {
+	srv := newSMTPServer(t, "")
+	server := Server{
+		Addr:     srv.ln.Addr().String(),
+		Username: "gator",
+		Password: "hunter2",
+		From:     "gator@example.com",
+	}
+	m := Message{
+		To:      []string{"alice@example.com", "bob@example.com"},
+		Subject: "3 new posts",
+		Text:    "Hello\nthere",
+		HTML:    "<p>Hello there</p>",
+	}
+	if err := server.Send(context.Background(), m); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	env := srv.received(t)
+	if env.auth != "\x00gator\x00hunter2" {
+		t.Errorf("auth = %q, want PLAIN as gator", env.auth)
+	}
+	if env.from != server.From {
+		t.Errorf("MAIL FROM = %q, want %q", env.from, server.From)
+	}
+	if strings.Join(env.to, ",") != strings.Join(m.To, ",") {
+		t.Errorf("RCPT TO = %v, want %v", env.to, m.To)
+	}
+
+	msg, err := netmail.ReadMessage(strings.NewReader(env.data))
+	if err != nil {
+		t.Fatalf("couldn't parse message: %v", err)
+	}
+	if got := msg.Header.Get("Subject"); got != m.Subject {
+		t.Errorf("Subject = %q, want %q", got, m.Subject)
+	}
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("Content-Type = %q, want multipart/alternative", msg.Header.Get("Content-Type"))
+	}
+	parts := multipart.NewReader(msg.Body, params["boundary"])
+	for _, want := range []struct{ contentType, body string }{
+		{"text/plain; charset=utf-8", "Hello\nthere"},
+		{"text/html; charset=utf-8", "<p>Hello there</p>"},
+	} {
+		part, err := parts.NextPart()
+		if err != nil {
+			t.Fatalf("reading %s part: %v", want.contentType, err)
+		}
+		body, _ := io.ReadAll(part)
+		if got := part.Header.Get("Content-Type"); got != want.contentType {
+			t.Errorf("part Content-Type = %q, want %q", got, want.contentType)
+		}
+		if string(body) != want.body {
+			t.Errorf("%s part = %q, want %q", want.contentType, body, want.body)
+		}
+	}
+}
+
+func TestSendErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		tls      string
+		to       []string
+		rejected string
+		want     string
+	}{
+		{"starttls required", TLSStartTLS, []string{"alice@example.com"}, "", "STARTTLS"},
+		{"recipient refused", TLSNone, []string{"alice@example.com", "nobody@example.com"}, "nobody@example.com", "recipient nobody@example.com"},
+		{"unknown tls mode", "ssl", []string{"alice@example.com"}, "", "unknown smtp tls mode"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newSMTPServer(t, tt.rejected)
+			server := Server{Addr: srv.ln.Addr().String(), From: "gator@example.com", TLS: tt.tls}
+			err := server.Send(context.Background(), Message{To: tt.to, Subject: "hi", Text: "hi"})
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Send error = %v, want one mentioning %q", err, tt.want)
+			}
+			srv.received(t)
+		})
+	}
+}
+
+func TestBytesPlain(t *testing.T) {
+	m := Message{To: []string{"alice@example.com"}, Subject: "Grüße", Text: "line one\nline two"}
+	b, err := m.Bytes("gator@example.com", time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg, err := netmail.ReadMessage(bufio.NewReader(strings.NewReader(string(b))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil || subject != m.Subject {
+		t.Errorf("Subject = %q (%v), want %q", subject, err, m.Subject)
+	}
+	if got := msg.Header.Get("Date"); got != "Mon, 02 Mar 2026 09:00:00 +0000" {
+		t.Errorf("Date = %q", got)
+	}
+	body, _ := io.ReadAll(msg.Body)
+	if string(body) != "line one\r\nline two" {
+		t.Errorf("body = %q, want CRLF line endings", body)
+	}
+}
@@ -0,0 +1,178 @@
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/isaacjstriker/gatorapp/internal/database"
+)
+
+// Outcomes recorded for each post the aggregator tries to save.
+const (
+	PostInserted  = "inserted"
+	PostDuplicate = "duplicate"
+	PostPruned    = "pruned"
+	PostError     = "error"
+)
+
+// Outcomes recorded for each notification delivery attempt.
+const (
+	NotificationDelivered = "delivered"
+	NotificationFailed    = "failed"
+)
+
+// Metrics holds the aggregator's Prometheus collectors.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	fetches       *prometheus.CounterVec
+	fetchDuration prometheus.Histogram
+	bytes         prometheus.Counter
+	posts         *prometheus.CounterVec
+	notifications *prometheus.CounterVec
+	dbErrors      *prometheus.CounterVec
+}
+
+// New registers the aggregator's metrics. overdue is called on every scrape
+// to report how many feeds are past their next fetch time.
+func New(overdue func() (int64, error)) *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		fetches: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "gator_feed_fetches_total",
+			Help: "Feed fetches by HTTP status, or \"error\" when no response was received.",
+		}, []string{"status"}),
+		fetchDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "gator_feed_fetch_duration_seconds",
+			Help:    "Time taken to fetch and read a feed.",
+			Buckets: []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60},
+		}),
+		bytes: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "gator_feed_bytes_downloaded_total",
+			Help: "Bytes of feed bodies received over the wire, before decompression.",
+		}),
+		posts: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "gator_posts_total",
+			Help: "Posts seen by the aggregator, by whether they were inserted, already known, pruned before, or failed.",
+		}, []string{"result"}),
+		notifications: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "gator_notifications_total",
+			Help: "Notification delivery attempts by sink type and whether they were delivered or failed.",
+		}, []string{"type", "result"}),
+		dbErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "gator_db_errors_total",
+			Help: "Failed database queries by query name.",
+		}, []string{"query"}),
+	}
+
+	m.registry.MustRegister(
+		m.fetches,
+		m.fetchDuration,
+		m.bytes,
+		m.posts,
+		m.notifications,
+		m.dbErrors,
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "gator_feeds_overdue",
+			Help: "Active feeds whose next fetch time has passed.",
+		}, func() float64 {
+			n, err := overdue()
+			if err != nil {
+				m.dbErrors.WithLabelValues("CountOverdueFeeds").Inc()
+				return 0
+			}
+			return float64(n)
+		}),
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	return m
+}
+
+// Handler serves the metrics in the Prometheus text exposition format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// ObserveFetch records one fetch attempt.
+func (m *Metrics) ObserveFetch(status string, duration time.Duration) {
+	m.fetches.WithLabelValues(status).Inc()
+	m.fetchDuration.Observe(duration.Seconds())
+}
+
+// AddBytes records bytes received from a feed server.
+func (m *Metrics) AddBytes(n int) {
+	m.bytes.Add(float64(n))
+}
+
+// ObservePost records the outcome of saving one post.
+func (m *Metrics) ObservePost(result string) {
+	m.posts.WithLabelValues(result).Inc()
+}
+
+// ObserveNotification records the outcome of one delivery attempt to a sink
+// of the given type.
+func (m *Metrics) ObserveNotification(sinkType, result string) {
+	m.notifications.WithLabelValues(sinkType, result).Inc()
+}
+
+// WrapDB returns a database.DBTX that counts failed queries, labelled with
+// the sqlc query name.
+func (m *Metrics) WrapDB(db database.DBTX) database.DBTX {
+	return &instrumentedDB{db: db, m: m}
+}
+
+type instrumentedDB struct {
+	db database.DBTX
+	m  *Metrics
+}
+
+// queryName extracts the name from the "-- name: Foo :one" header sqlc puts
+// at the top of every query.
+func queryName(query string) string {
+	rest, ok := strings.CutPrefix(query, "-- name: ")
+	if !ok {
+		return "unknown"
+	}
+	name, _, _ := strings.Cut(rest, " ")
+	return name
+}
+
+func (d *instrumentedDB) observe(query string, err error) {
+	if err != nil && err != sql.ErrNoRows {
+		d.m.dbErrors.WithLabelValues(queryName(query)).Inc()
+	}
+}
+
+func (d *instrumentedDB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	res, err := d.db.ExecContext(ctx, query, args...)
+	d.observe(query, err)
+	return res, err
+}
+
+func (d *instrumentedDB) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	stmt, err := d.db.PrepareContext(ctx, query)
+	d.observe(query, err)
+	return stmt, err
+}
+
+func (d *instrumentedDB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	rows, err := d.db.QueryContext(ctx, query, args...)
+	d.observe(query, err)
+	return rows, err
+}
+
+func (d *instrumentedDB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	row := d.db.QueryRowContext(ctx, query, args...)
+	// Err reports failures of the query itself; sql.ErrNoRows only shows up
+	// later, at Scan, and isn't a database error anyway.
+	d.observe(query, row.Err())
+	return row
+}
@@ -0,0 +1,198 @@
+// Package notify delivers notifications about new posts to webhooks, Slack,
+// email and local commands.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/isaacjstriker/gatorapp/internal/mail"
+)
+
+// Sink types a notification can be sent to.
+const (
+	TypeWebhook = "webhook"
+	TypeSlack   = "slack"
+	TypeEmail   = "email"
+	TypeExec    = "exec"
+)
+
+var Types = []string{TypeWebhook, TypeSlack, TypeEmail, TypeExec}
+
+// SignatureHeader carries the HMAC-SHA256 of a webhook body, as
+// "sha256=<hex>", when the webhook has a secret.
+const SignatureHeader = "X-Gator-Signature"
+
+// Message describes a new post for one user.
+type Message struct {
+	User        string    `json:"user"`
+	Feed        string    `json:"feed"`
+	FeedURL     string    `json:"feed_url"`
+	Title       string    `json:"title"`
+	URL         string    `json:"url"`
+	Description string    `json:"description,omitempty"`
+	Author      string    `json:"author,omitempty"`
+	PublishedAt time.Time `json:"published_at,omitzero"`
+}
+
+// Summary is a one-line description of the post.
+func (m Message) Summary() string {
+	return fmt.Sprintf("New post in %s: %s", m.Feed, m.Title)
+}
+
+// Sink sends messages somewhere.
+type Sink interface {
+	Send(ctx context.Context, m Message) error
+}
+
+// Webhook POSTs the message as JSON.
+type Webhook struct {
+	URL string
+	// Secret, when set, signs the body in SignatureHeader.
+	Secret string
+	Client *http.Client
+}
+
+func (w *Webhook) Send(ctx context.Context, m Message) error {
+	body, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	header := http.Header{}
+	if w.Secret != "" {
+		header.Set(SignatureHeader, Sign(w.Secret, body))
+	}
+	return postJSON(ctx, w.Client, w.URL, body, header)
+}
+
+// Sign returns the SignatureHeader value for body.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Slack POSTs the message to a Slack-compatible incoming webhook.
+type Slack struct {
+	URL    string
+	Client *http.Client
+}
+
+func (s *Slack) Send(ctx context.Context, m Message) error {
+	body, err := json.Marshal(map[string]string{
+		"text": fmt.Sprintf("New post in %s: <%s|%s>", slackEscape(m.Feed), m.URL, slackEscape(m.Title)),
+	})
+	if err != nil {
+		return err
+	}
+	return postJSON(ctx, s.Client, s.URL, body, nil)
+}
+
+// slackEscape escapes the characters Slack's message formatting treats
+// specially.
+func slackEscape(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(s)
+}
+
+func postJSON(ctx context.Context, client *http.Client, url string, body []byte, header http.Header) error {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		text, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s refused notification: %s: %s", url, resp.Status, strings.TrimSpace(string(text)))
+	}
+	return nil
+}
+
+// Email mails the message.
+type Email struct {
+	Server mail.Server
+	To     []string
+}
+
+func (e *Email) Send(ctx context.Context, m Message) error {
+	var body strings.Builder
+	fmt.Fprintf(&body, "%s\n\n", m.Title)
+	if m.Author != "" {
+		fmt.Fprintf(&body, "By %s\n", m.Author)
+	}
+	fmt.Fprintf(&body, "From %s (%s)\n", m.Feed, m.FeedURL)
+	fmt.Fprintf(&body, "%s\n", m.URL)
+	if m.Description != "" {
+		fmt.Fprintf(&body, "\n%s\n", m.Description)
+	}
+	return e.Server.Send(ctx, mail.Message{
+		To:      e.To,
+		Subject: m.Summary(),
+		Text:    body.String(),
+	})
+}
+
+// Exec runs a local command for each message. The command gets the message
+// as JSON on stdin and its main fields in GATOR_* environment variables.
+type Exec struct {
+	Command []string
+}
+
+func (e *Exec) Send(ctx context.Context, m Message) error {
+	if len(e.Command) == 0 {
+		return errors.New("no command configured")
+	}
+	body, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.CommandContext(ctx, e.Command[0], e.Command[1:]...)
+	cmd.Stdin = bytes.NewReader(body)
+	cmd.Env = append(os.Environ(),
+		"GATOR_USER="+m.User,
+		"GATOR_FEED="+m.Feed,
+		"GATOR_FEED_URL="+m.FeedURL,
+		"GATOR_TITLE="+m.Title,
+		"GATOR_URL="+m.URL,
+	)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%s: %w: %s", e.Command[0], err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// Backoff returns how long to wait before retrying a delivery that has
+// failed attempts times: base, doubling with each further failure, capped at
+// limit.
+func Backoff(attempts int, base, limit time.Duration) time.Duration {
+	wait := base
+	for i := 1; i < attempts && wait < limit; i++ {
+		wait *= 2
+	}
+	return min(wait, limit)
+}
@@ -0,0 +1,128 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testMessage = Message{
+	User:        "alice",
+	Feed:        "Example <News>",
+	FeedURL:     "https://example.com/feed.xml",
+	Title:       "Hello & welcome",
+	URL:         "https://example.com/hello",
+	PublishedAt: time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC),
+}
+
+type request struct {
+	contentType string
+	signature   string
+	body        []byte
+}
+
+// newEndpoint starts a stand-in webhook that records each request and
+// answers with status.
+func newEndpoint(t *testing.T, status int) (*httptest.Server, *[]request) {
+	t.Helper()
+	var got []request
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		got = append(got, request{r.Header.Get("Content-Type"), r.Header.Get(SignatureHeader), body})
+		w.WriteHeader(status)
+		io.WriteString(w, http.StatusText(status))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &got
+}
+
+func TestWebhook(t *testing.T) {
+	tests := []struct {
+		name   string
+		secret string
+	}{
+		{"signed", "s3cret"},
+		{"unsigned", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, got := newEndpoint(t, http.StatusNoContent)
+			w := &Webhook{URL: srv.URL, Secret: tt.secret, Client: srv.Client()}
+			if err := w.Send(context.Background(), testMessage); err != nil {
+				t.Fatalf("Send: %v", err)
+			}
+			if len(*got) != 1 {
+				t.Fatalf("webhook got %d requests, want 1", len(*got))
+			}
+			req := (*got)[0]
+			if req.contentType != "application/json" {
+				t.Errorf("Content-Type = %q, want application/json", req.contentType)
+			}
+			var m Message
+			if err := json.Unmarshal(req.body, &m); err != nil {
+				t.Fatalf("body isn't a message: %v", err)
+			}
+			if m != testMessage {
+				t.Errorf("message = %+v, want %+v", m, testMessage)
+			}
+
+			want := ""
+			if tt.secret != "" {
+				want = Sign(tt.secret, req.body)
+			}
+			if req.signature != want {
+				t.Errorf("%s = %q, want %q", SignatureHeader, req.signature, want)
+			}
+		})
+	}
+}
+
+func TestWebhookRefused(t *testing.T) {
+	srv, _ := newEndpoint(t, http.StatusGone)
+	w := &Webhook{URL: srv.URL, Client: srv.Client()}
+	err := w.Send(context.Background(), testMessage)
+	if err == nil || !strings.Contains(err.Error(), "410") {
+		t.Errorf("Send error = %v, want the webhook's 410", err)
+	}
+}
+
+func TestSlack(t *testing.T) {
+	srv, got := newEndpoint(t, http.StatusOK)
+	s := &Slack{URL: srv.URL, Client: srv.Client()}
+	if err := s.Send(context.Background(), testMessage); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if len(*got) != 1 {
+		t.Fatalf("Slack got %d requests, want 1", len(*got))
+	}
+	var body map[string]string
+	if err := json.Unmarshal((*got)[0].body, &body); err != nil {
+		t.Fatal(err)
+	}
+	want := "New post in Example &lt;News&gt;: <https://example.com/hello|Hello &amp; welcome>"
+	if body["text"] != want {
+		t.Errorf("text = %q, want %q", body["text"], want)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, time.Minute},
+		{2, 2 * time.Minute},
+		{4, 8 * time.Minute},
+		{10, time.Hour},
+	}
+	for _, tt := range tests {
+		if got := Backoff(tt.attempts, time.Minute, time.Hour); got != tt.want {
+			t.Errorf("Backoff(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
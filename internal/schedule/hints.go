@@ -0,0 +1,111 @@
+package schedule
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Hints are the polling hints a feed publishes about itself: RSS <ttl>,
+// <skipHours> and <skipDays>, and the syndication module's updatePeriod
+// and updateFrequency.
+type Hints struct {
+	// TTL is how long the feed may be cached before fetching it again.
+	TTL time.Duration
+	// UpdateInterval is the period implied by sy:updatePeriod divided by
+	// sy:updateFrequency.
+	UpdateInterval time.Duration
+	// SkipHours are hours of the day, in GMT, during which the feed should
+	// not be fetched.
+	SkipHours map[int]bool
+	// SkipDays are days of the week, in GMT, during which the feed should
+	// not be fetched.
+	SkipDays map[time.Weekday]bool
+}
+
+var updatePeriods = map[string]time.Duration{
+	"hourly":  time.Hour,
+	"daily":   24 * time.Hour,
+	"weekly":  7 * 24 * time.Hour,
+	"monthly": 30 * 24 * time.Hour,
+	"yearly":  365 * 24 * time.Hour,
+}
+
+var weekdays = map[string]time.Weekday{
+	"sunday":    time.Sunday,
+	"monday":    time.Monday,
+	"tuesday":   time.Tuesday,
+	"wednesday": time.Wednesday,
+	"thursday":  time.Thursday,
+	"friday":    time.Friday,
+	"saturday":  time.Saturday,
+}
+
+// ParseHints builds Hints from the raw element values of a feed. Values that
+// are missing or malformed are ignored.
+func ParseHints(ttl, updatePeriod, updateFrequency string, skipHours, skipDays []string) Hints {
+	var h Hints
+
+	if minutes, err := strconv.Atoi(strings.TrimSpace(ttl)); err == nil && minutes > 0 {
+		h.TTL = time.Duration(minutes) * time.Minute
+	}
+
+	if period, ok := updatePeriods[strings.ToLower(strings.TrimSpace(updatePeriod))]; ok {
+		frequency := 1
+		if f, err := strconv.Atoi(strings.TrimSpace(updateFrequency)); err == nil && f > 0 {
+			frequency = f
+		}
+		h.UpdateInterval = period / time.Duration(frequency)
+	}
+
+	for _, raw := range skipHours {
+		hour, err := strconv.Atoi(strings.TrimSpace(raw))
+		if err != nil || hour < 0 || hour > 24 {
+			continue
+		}
+		if h.SkipHours == nil {
+			h.SkipHours = make(map[int]bool)
+		}
+		// Some feeds number hours 1-24 rather than 0-23.
+		h.SkipHours[hour%24] = true
+	}
+
+	for _, raw := range skipDays {
+		day, ok := weekdays[strings.ToLower(strings.TrimSpace(raw))]
+		if !ok {
+			continue
+		}
+		if h.SkipDays == nil {
+			h.SkipDays = make(map[time.Weekday]bool)
+		}
+		h.SkipDays[day] = true
+	}
+
+	return h
+}
+
+// MinInterval is the shortest interval between fetches the feed asks for.
+func (h Hints) MinInterval() time.Duration {
+	return max(h.TTL, h.UpdateInterval)
+}
+
+// Allowed moves t forward to the first moment outside the feed's skip
+// hours and skip days.
+func (h Hints) Allowed(t time.Time) time.Time {
+	if len(h.SkipHours) >= 24 || len(h.SkipDays) >= 7 {
+		// A feed that skips everything is nonsensical; ignore the hint.
+		return t
+	}
+	utc := t.UTC()
+	for range 24 * 8 {
+		switch {
+		case h.SkipDays[utc.Weekday()]:
+			utc = utc.Truncate(time.Hour).Add(time.Duration(24-utc.Hour()) * time.Hour)
+		case h.SkipHours[utc.Hour()]:
+			utc = utc.Truncate(time.Hour).Add(time.Hour)
+		default:
+			return utc.In(t.Location())
+		}
+	}
+	return t
+}
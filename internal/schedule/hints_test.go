@@ -0,0 +1,95 @@
+package schedule
+
+import (
+	"maps"
+	"testing"
+	"time"
+)
+
+func TestParseHints(t *testing.T) {
+	tests := []struct {
+		name                         string
+		ttl, updatePeriod, frequency string
+		skipHours, skipDays          []string
+		want                         Hints
+	}{
+		{name: "none"},
+		{name: "ttl", ttl: " 60 ", want: Hints{TTL: time.Hour}},
+		{name: "bad ttl", ttl: "soon"},
+		{name: "negative ttl", ttl: "-5"},
+		{name: "update period", updatePeriod: "Daily", want: Hints{UpdateInterval: 24 * time.Hour}},
+		{name: "update frequency", updatePeriod: "hourly", frequency: "4", want: Hints{UpdateInterval: 15 * time.Minute}},
+		{name: "bad update frequency", updatePeriod: "weekly", frequency: "0", want: Hints{UpdateInterval: 7 * 24 * time.Hour}},
+		{name: "unknown update period", updatePeriod: "fortnightly", frequency: "2"},
+		{
+			name:      "skip hours",
+			skipHours: []string{"0", " 5", "24", "25", "x"},
+			want:      Hints{SkipHours: map[int]bool{0: true, 5: true}},
+		},
+		{
+			name:     "skip days",
+			skipDays: []string{"Saturday", "sunday ", "someday"},
+			want:     Hints{SkipDays: map[time.Weekday]bool{time.Saturday: true, time.Sunday: true}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ParseHints(tt.ttl, tt.updatePeriod, tt.frequency, tt.skipHours, tt.skipDays)
+			if got.TTL != tt.want.TTL || got.UpdateInterval != tt.want.UpdateInterval ||
+				!maps.Equal(got.SkipHours, tt.want.SkipHours) || !maps.Equal(got.SkipDays, tt.want.SkipDays) {
+				t.Errorf("ParseHints() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMinInterval(t *testing.T) {
+	h := Hints{TTL: time.Hour, UpdateInterval: 2 * time.Hour}
+	if got := h.MinInterval(); got != 2*time.Hour {
+		t.Errorf("MinInterval() = %v, want 2h", got)
+	}
+}
+
+func TestAllowed(t *testing.T) {
+	// A Monday.
+	at := time.Date(2026, 3, 2, 22, 30, 0, 0, time.UTC)
+	everyHour := make(map[int]bool)
+	for h := range 24 {
+		everyHour[h] = true
+	}
+	tests := []struct {
+		name  string
+		hints Hints
+		t     time.Time
+		want  time.Time
+	}{
+		{"no hints", Hints{}, at, at},
+		{"allowed hour", Hints{SkipHours: map[int]bool{3: true}}, at, at},
+		{"skipped hours", Hints{SkipHours: map[int]bool{22: true, 23: true}}, at, time.Date(2026, 3, 3, 0, 0, 0, 0, time.UTC)},
+		{"skipped day", Hints{SkipDays: map[time.Weekday]bool{time.Monday: true}}, at, time.Date(2026, 3, 3, 0, 0, 0, 0, time.UTC)},
+		{
+			"skipped day and hour",
+			Hints{SkipHours: map[int]bool{0: true}, SkipDays: map[time.Weekday]bool{time.Monday: true}},
+			at,
+			time.Date(2026, 3, 3, 1, 0, 0, 0, time.UTC),
+		},
+		{"every hour skipped", Hints{SkipHours: everyHour}, at, at},
+		{
+			"other zone",
+			Hints{SkipHours: map[int]bool{22: true}},
+			at.In(time.FixedZone("UTC+2", 2*60*60)),
+			time.Date(2026, 3, 2, 23, 0, 0, 0, time.UTC),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.hints.Allowed(tt.t)
+			if !got.Equal(tt.want) {
+				t.Errorf("Allowed(%v) = %v, want %v", tt.t, got, tt.want)
+			}
+			if got.Location() != tt.t.Location() {
+				t.Errorf("Allowed(%v) is in %v, want %v", tt.t, got.Location(), tt.t.Location())
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package schedule
+
+import (
+	"slices"
+	"time"
+)
+
+// Defaults used when a Policy leaves its bounds unset.
+const (
+	DefaultMinInterval = 10 * time.Minute
+	DefaultMaxInterval = 24 * time.Hour
+)
+
+// Policy decides when a feed should next be fetched. Feeds are polled about
+// twice per typical gap between their posts, backed off exponentially while
+// fetches fail, and always kept within [MinInterval, MaxInterval].
+type Policy struct {
+	MinInterval time.Duration
+	MaxInterval time.Duration
+}
+
+// Bounds returns the policy's interval bounds with defaults filled in.
+func (p Policy) Bounds() (lo, hi time.Duration) {
+	lo, hi = p.MinInterval, p.MaxInterval
+	if lo <= 0 {
+		lo = DefaultMinInterval
+	}
+	if hi <= 0 {
+		hi = DefaultMaxInterval
+	}
+	if hi < lo {
+		hi = lo
+	}
+	return lo, hi
+}
+
+// Interval returns how long to wait after a fetch at now. postTimes are the
+// feed's most recent post timestamps in any order, and failures is the
+// number of fetches in a row that have failed.
+func (p Policy) Interval(now time.Time, postTimes []time.Time, failures int) time.Duration {
+	lo, hi := p.Bounds()
+
+	interval := lo
+	if gap, ok := typicalGap(postTimes); ok {
+		interval = gap / 2
+		// A feed that has gone quiet for longer than it usually does is
+		// probably posting less often than its history suggests.
+		if latest := slices.MaxFunc(postTimes, time.Time.Compare); now.Sub(latest) > gap {
+			interval = max(interval, now.Sub(latest)/2)
+		}
+	}
+
+	for range failures {
+		if interval >= hi {
+			break
+		}
+		interval *= 2
+	}
+
+	return min(max(interval, lo), hi)
+}
+
+// Next returns when a feed fetched at now should next be fetched, honoring
+// the feed's own hints on top of the policy. Hints can lengthen the wait but
+// never past MaxInterval, so a feed asking to be left alone for a year is
+// still fetched.
+func (p Policy) Next(now time.Time, postTimes []time.Time, failures int, hints Hints) time.Time {
+	_, hi := p.Bounds()
+	interval := min(max(p.Interval(now, postTimes, failures), hints.MinInterval()), hi)
+	next := hints.Allowed(now.Add(interval))
+	if latest := now.Add(hi); next.After(latest) {
+		return latest
+	}
+	return next
+}
+
+// typicalGap is the median gap between consecutive posts. It needs at least
+// two distinct timestamps.
+func typicalGap(postTimes []time.Time) (time.Duration, bool) {
+	if len(postTimes) < 2 {
+		return 0, false
+	}
+	sorted := slices.Clone(postTimes)
+	slices.SortFunc(sorted, time.Time.Compare)
+
+	gaps := make([]time.Duration, 0, len(sorted)-1)
+	for i := 1; i < len(sorted); i++ {
+		if gap := sorted[i].Sub(sorted[i-1]); gap > 0 {
+			gaps = append(gaps, gap)
+		}
+	}
+	if len(gaps) == 0 {
+		return 0, false
+	}
+	slices.Sort(gaps)
+	return gaps[len(gaps)/2], true
+}
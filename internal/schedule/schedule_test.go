@@ -0,0 +1,97 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+var now = time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC)
+
+// hoursAgo returns post times the given numbers of hours before now.
+func hoursAgo(hours ...int) []time.Time {
+	times := make([]time.Time, len(hours))
+	for i, h := range hours {
+		times[i] = now.Add(-time.Duration(h) * time.Hour)
+	}
+	return times
+}
+
+func TestBounds(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy Policy
+		lo, hi time.Duration
+	}{
+		{"defaults", Policy{}, DefaultMinInterval, DefaultMaxInterval},
+		{"set", Policy{MinInterval: time.Minute, MaxInterval: time.Hour}, time.Minute, time.Hour},
+		{"max below min", Policy{MinInterval: time.Hour, MaxInterval: time.Minute}, time.Hour, time.Hour},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lo, hi := tt.policy.Bounds()
+			if lo != tt.lo || hi != tt.hi {
+				t.Errorf("Bounds() = %v, %v, want %v, %v", lo, hi, tt.lo, tt.hi)
+			}
+		})
+	}
+}
+
+func TestInterval(t *testing.T) {
+	policy := Policy{MinInterval: 10 * time.Minute, MaxInterval: 24 * time.Hour}
+	tests := []struct {
+		name      string
+		postTimes []time.Time
+		failures  int
+		want      time.Duration
+	}{
+		{"no posts", nil, 0, 10 * time.Minute},
+		{"one post", hoursAgo(1), 0, 10 * time.Minute},
+		{"same instant", hoursAgo(1, 1, 1), 0, 10 * time.Minute},
+		{"hourly", hoursAgo(0, 1, 2, 3), 0, 30 * time.Minute},
+		{"median gap", hoursAgo(0, 2, 4, 5, 30), 0, time.Hour},
+		{"gone quiet", hoursAgo(10, 11, 12), 0, 5 * time.Hour},
+		{"below min", []time.Time{now, now.Add(-time.Minute)}, 0, 10 * time.Minute},
+		{"above max", hoursAgo(0, 100, 200), 0, 24 * time.Hour},
+		{"one failure", hoursAgo(0, 1, 2), 1, time.Hour},
+		{"three failures", hoursAgo(0, 1, 2), 3, 4 * time.Hour},
+		{"failures capped", hoursAgo(0, 1, 2), 50, 24 * time.Hour},
+		{"failures without posts", nil, 2, 40 * time.Minute},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := policy.Interval(now, tt.postTimes, tt.failures); got != tt.want {
+				t.Errorf("Interval() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNext(t *testing.T) {
+	policy := Policy{MinInterval: 10 * time.Minute, MaxInterval: 24 * time.Hour}
+	hourly := hoursAgo(0, 1, 2, 3)
+	tests := []struct {
+		name  string
+		hints Hints
+		want  time.Time
+	}{
+		{"no hints", Hints{}, now.Add(30 * time.Minute)},
+		{"shorter ttl", Hints{TTL: 5 * time.Minute}, now.Add(30 * time.Minute)},
+		{"longer ttl", Hints{TTL: 2 * time.Hour}, now.Add(2 * time.Hour)},
+		{"update period", Hints{UpdateInterval: 6 * time.Hour}, now.Add(6 * time.Hour)},
+		{"yearly update period", Hints{UpdateInterval: 365 * 24 * time.Hour}, now.Add(24 * time.Hour)},
+		{"skip hours", Hints{SkipHours: map[int]bool{12: true, 13: true}}, time.Date(2026, 3, 2, 14, 0, 0, 0, time.UTC)},
+		{"skip days", Hints{SkipDays: map[time.Weekday]bool{time.Monday: true}}, time.Date(2026, 3, 3, 0, 0, 0, 0, time.UTC)},
+		{
+			"skip days past max",
+			Hints{SkipDays: map[time.Weekday]bool{time.Monday: true, time.Tuesday: true, time.Wednesday: true}},
+			now.Add(24 * time.Hour),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := policy.Next(now, hourly, 0, tt.hints); !got.Equal(tt.want) {
+				t.Errorf("Next() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,366 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: feed_follows.sql
+
+package sqlitedb
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const createFeedFollow = `-- name: CreateFeedFollow :exec
+INSERT INTO feed_follows (id, created_at, updated_at, user_id, feed_id)
+VALUES (?, ?, ?, ?, ?)
+`
+
+type CreateFeedFollowParams struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	UserID    uuid.UUID
+	FeedID    uuid.UUID
+}
+
+// SQLite can't insert in a WITH clause, so unlike the Postgres query this
+// only inserts; GetFeedFollowWithNames reads the follow back.
+func (q *Queries) CreateFeedFollow(ctx context.Context, arg CreateFeedFollowParams) error {
+	_, err := q.db.ExecContext(ctx, createFeedFollow,
+		arg.ID,
+		arg.CreatedAt,
+		arg.UpdatedAt,
+		arg.UserID,
+		arg.FeedID,
+	)
+	return err
+}
+
+const delFeedFollow = `-- name: DelFeedFollow :execrows
+DELETE FROM feed_follows
+WHERE feed_follows.user_id = ?1
+    AND feed_follows.feed_id IN (SELECT feeds.id FROM feeds WHERE feeds.url = ?2)
+`
+
+type DelFeedFollowParams struct {
+	UserID uuid.UUID
+	Url    string
+}
+
+func (q *Queries) DelFeedFollow(ctx context.Context, arg DelFeedFollowParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, delFeedFollow, arg.UserID, arg.Url)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getFeedFollow = `-- name: GetFeedFollow :one
+SELECT id, created_at, updated_at, user_id, feed_id, folder_id, title, muted, priority, notify FROM feed_follows
+WHERE user_id = ? AND feed_id = ?
+`
+
+type GetFeedFollowParams struct {
+	UserID uuid.UUID
+	FeedID uuid.UUID
+}
+
+func (q *Queries) GetFeedFollow(ctx context.Context, arg GetFeedFollowParams) (FeedFollow, error) {
+	row := q.db.QueryRowContext(ctx, getFeedFollow, arg.UserID, arg.FeedID)
+	var i FeedFollow
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.FeedID,
+		&i.FolderID,
+		&i.Title,
+		&i.Muted,
+		&i.Priority,
+		&i.Notify,
+	)
+	return i, err
+}
+
+const getFeedFollowWithNames = `-- name: GetFeedFollowWithNames :one
+SELECT
+    f.id, f.created_at, f.updated_at, f.user_id, f.feed_id, f.folder_id, f.title, f.muted, f.priority, f.notify,
+    u.name AS user_name,
+    fe.name AS feed_name
+FROM feed_follows f
+INNER JOIN users u ON f.user_id = u.id
+INNER JOIN feeds fe ON f.feed_id = fe.id
+WHERE f.id = ?
+`
+
+type GetFeedFollowWithNamesRow struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	UserID    uuid.UUID
+	FeedID    uuid.UUID
+	FolderID  uuid.NullUUID
+	Title     sql.NullString
+	Muted     bool
+	Priority  int32
+	Notify    bool
+	UserName  string
+	FeedName  string
+}
+
+func (q *Queries) GetFeedFollowWithNames(ctx context.Context, id uuid.UUID) (GetFeedFollowWithNamesRow, error) {
+	row := q.db.QueryRowContext(ctx, getFeedFollowWithNames, id)
+	var i GetFeedFollowWithNamesRow
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.FeedID,
+		&i.FolderID,
+		&i.Title,
+		&i.Muted,
+		&i.Priority,
+		&i.Notify,
+		&i.UserName,
+		&i.FeedName,
+	)
+	return i, err
+}
+
+const getFeedFollowers = `-- name: GetFeedFollowers :many
+SELECT users.id, users.created_at, users.updated_at, users.name, users.role, users.disabled_at, users.last_active_at, users.fever_api_key FROM users
+JOIN feed_follows ON feed_follows.user_id = users.id
+WHERE feed_follows.feed_id = ?
+ORDER BY feed_follows.created_at ASC
+`
+
+func (q *Queries) GetFeedFollowers(ctx context.Context, feedID uuid.UUID) ([]User, error) {
+	rows, err := q.db.QueryContext(ctx, getFeedFollowers, feedID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []User
+	for rows.Next() {
+		var i User
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Name,
+			&i.Role,
+			&i.DisabledAt,
+			&i.LastActiveAt,
+			&i.FeverApiKey,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getFeedFollowsForUser = `-- name: GetFeedFollowsForUser :many
+SELECT
+    ff.id, ff.created_at, ff.updated_at, ff.user_id, ff.feed_id, ff.folder_id, ff.title, ff.muted, ff.priority, ff.notify,
+    u.name AS user_name,
+    CAST(COALESCE(ff.title, f.name) AS TEXT) AS feed_name,
+    f.url AS feed_url,
+    CAST(COALESCE(folders.name, '') AS TEXT) AS folder_name,
+    CAST((
+        SELECT json_group_array(t.tag) FROM (
+            SELECT feed_follow_tags.tag FROM feed_follow_tags
+            WHERE feed_follow_tags.feed_follow_id = ff.id
+            ORDER BY feed_follow_tags.tag
+        ) t
+    ) AS TEXT) AS tags
+FROM feed_follows ff
+INNER JOIN users u ON ff.user_id = u.id
+INNER JOIN feeds f ON ff.feed_id = f.id
+LEFT JOIN folders ON ff.folder_id = folders.id
+WHERE ff.user_id = ?1
+    AND (CAST(?2 AS TEXT) IS NULL OR folders.name = CAST(?2 AS TEXT))
+    AND (CAST(?3 AS TEXT) IS NULL OR EXISTS (
+        SELECT 1 FROM feed_follow_tags t
+        WHERE t.feed_follow_id = ff.id AND t.tag = CAST(?3 AS TEXT)
+    ))
+ORDER BY folder_name, feed_name
+`
+
+type GetFeedFollowsForUserParams struct {
+	UserID uuid.UUID
+	Folder sql.NullString
+	Tag    sql.NullString
+}
+
+type GetFeedFollowsForUserRow struct {
+	ID         uuid.UUID
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	UserID     uuid.UUID
+	FeedID     uuid.UUID
+	FolderID   uuid.NullUUID
+	Title      sql.NullString
+	Muted      bool
+	Priority   int32
+	Notify     bool
+	UserName   string
+	FeedName   string
+	FeedUrl    string
+	FolderName string
+	Tags       string
+}
+
+// Optionally narrowed to one folder and/or tag. feed_name is the user's own
+// title for the feed when they have set one, and tags is a JSON array.
+func (q *Queries) GetFeedFollowsForUser(ctx context.Context, arg GetFeedFollowsForUserParams) ([]GetFeedFollowsForUserRow, error) {
+	rows, err := q.db.QueryContext(ctx, getFeedFollowsForUser, arg.UserID, arg.Folder, arg.Tag)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetFeedFollowsForUserRow
+	for rows.Next() {
+		var i GetFeedFollowsForUserRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.UserID,
+			&i.FeedID,
+			&i.FolderID,
+			&i.Title,
+			&i.Muted,
+			&i.Priority,
+			&i.Notify,
+			&i.UserName,
+			&i.FeedName,
+			&i.FeedUrl,
+			&i.FolderName,
+			&i.Tags,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const mergeFeedFollowSettings = `-- name: MergeFeedFollowSettings :exec
+UPDATE feed_follows
+SET title = COALESCE(title, (
+        SELECT merged.title FROM feed_follows AS merged
+        WHERE merged.feed_id = ?1 AND merged.user_id = feed_follows.user_id
+    )),
+    folder_id = COALESCE(folder_id, (
+        SELECT merged.folder_id FROM feed_follows AS merged
+        WHERE merged.feed_id = ?1 AND merged.user_id = feed_follows.user_id
+    )),
+    priority = CASE WHEN priority <> 0 THEN priority ELSE (
+        SELECT merged.priority FROM feed_follows AS merged
+        WHERE merged.feed_id = ?1 AND merged.user_id = feed_follows.user_id
+    ) END,
+    muted = muted OR (
+        SELECT merged.muted FROM feed_follows AS merged
+        WHERE merged.feed_id = ?1 AND merged.user_id = feed_follows.user_id
+    ),
+    notify = notify AND (
+        SELECT merged.notify FROM feed_follows AS merged
+        WHERE merged.feed_id = ?1 AND merged.user_id = feed_follows.user_id
+    ),
+    updated_at = ?2
+WHERE feed_follows.feed_id = ?3
+    AND feed_follows.user_id IN (
+        SELECT merged.user_id FROM feed_follows AS merged
+        WHERE merged.feed_id = ?1
+    )
+`
+
+type MergeFeedFollowSettingsParams struct {
+	FromFeedID uuid.UUID
+	UpdatedAt  time.Time
+	ToFeedID   uuid.UUID
+}
+
+// For users who follow both feeds, fills in their follow of to_feed_id from
+// their follow of from_feed_id: a title or folder it lacks, a priority when
+// it has none, and muting or turning notifications off.
+func (q *Queries) MergeFeedFollowSettings(ctx context.Context, arg MergeFeedFollowSettingsParams) error {
+	_, err := q.db.ExecContext(ctx, mergeFeedFollowSettings, arg.FromFeedID, arg.UpdatedAt, arg.ToFeedID)
+	return err
+}
+
+const moveFeedFollows = `-- name: MoveFeedFollows :exec
+UPDATE feed_follows
+SET feed_id = ?1,
+    updated_at = ?2
+WHERE feed_follows.feed_id = ?3
+    AND feed_follows.user_id NOT IN (
+        SELECT existing.user_id FROM feed_follows existing
+        WHERE existing.feed_id = ?1
+    )
+`
+
+type MoveFeedFollowsParams struct {
+	ToFeedID   uuid.UUID
+	UpdatedAt  time.Time
+	FromFeedID uuid.UUID
+}
+
+func (q *Queries) MoveFeedFollows(ctx context.Context, arg MoveFeedFollowsParams) error {
+	_, err := q.db.ExecContext(ctx, moveFeedFollows, arg.ToFeedID, arg.UpdatedAt, arg.FromFeedID)
+	return err
+}
+
+const updateFeedFollowSettings = `-- name: UpdateFeedFollowSettings :execrows
+UPDATE feed_follows
+SET title = ?1,
+    muted = ?2,
+    priority = ?3,
+    notify = ?4,
+    updated_at = ?5
+WHERE user_id = ?6 AND feed_id = ?7
+`
+
+type UpdateFeedFollowSettingsParams struct {
+	Title     sql.NullString
+	Muted     bool
+	Priority  int32
+	Notify    bool
+	UpdatedAt time.Time
+	UserID    uuid.UUID
+	FeedID    uuid.UUID
+}
+
+func (q *Queries) UpdateFeedFollowSettings(ctx context.Context, arg UpdateFeedFollowSettingsParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, updateFeedFollowSettings,
+		arg.Title,
+		arg.Muted,
+		arg.Priority,
+		arg.Notify,
+		arg.UpdatedAt,
+		arg.UserID,
+		arg.FeedID,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
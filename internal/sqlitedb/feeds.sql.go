@@ -0,0 +1,724 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: feeds.sql
+
+package sqlitedb
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const addFeedURLHistory = `-- name: AddFeedURLHistory :exec
+INSERT INTO feed_url_history (id, created_at, url, feed_id)
+VALUES (?, ?, ?, ?)
+ON CONFLICT (url) DO UPDATE SET feed_id = excluded.feed_id
+`
+
+type AddFeedURLHistoryParams struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	Url       string
+	FeedID    uuid.UUID
+}
+
+func (q *Queries) AddFeedURLHistory(ctx context.Context, arg AddFeedURLHistoryParams) error {
+	_, err := q.db.ExecContext(ctx, addFeedURLHistory,
+		arg.ID,
+		arg.CreatedAt,
+		arg.Url,
+		arg.FeedID,
+	)
+	return err
+}
+
+const clearFeedRedirect = `-- name: ClearFeedRedirect :exec
+UPDATE feeds
+SET redirect_url = NULL,
+redirect_count = 0,
+updated_at = ?1
+WHERE id = ?2 AND redirect_url IS NOT NULL
+`
+
+type ClearFeedRedirectParams struct {
+	UpdatedAt time.Time
+	ID        uuid.UUID
+}
+
+func (q *Queries) ClearFeedRedirect(ctx context.Context, arg ClearFeedRedirectParams) error {
+	_, err := q.db.ExecContext(ctx, clearFeedRedirect, arg.UpdatedAt, arg.ID)
+	return err
+}
+
+const countFeedData = `-- name: CountFeedData :one
+SELECT
+    (SELECT COUNT(*) FROM feed_follows WHERE feed_follows.feed_id = ?1) AS follows,
+    (SELECT COUNT(*) FROM posts WHERE posts.feed_id = ?1) AS posts
+`
+
+type CountFeedDataRow struct {
+	Follows int64
+	Posts   int64
+}
+
+func (q *Queries) CountFeedData(ctx context.Context, feedID uuid.UUID) (CountFeedDataRow, error) {
+	row := q.db.QueryRowContext(ctx, countFeedData, feedID)
+	var i CountFeedDataRow
+	err := row.Scan(&i.Follows, &i.Posts)
+	return i, err
+}
+
+const countOverdueFeeds = `-- name: CountOverdueFeeds :one
+SELECT COUNT(*) FROM feeds
+WHERE deactivated_at IS NULL
+    AND (next_fetch_at IS NULL OR next_fetch_at <= ?1)
+`
+
+func (q *Queries) CountOverdueFeeds(ctx context.Context, now sql.NullTime) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countOverdueFeeds, now)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const createFeed = `-- name: CreateFeed :one
+INSERT INTO feeds (id, created_at, updated_at, name, url, user_id, fever_id)
+VALUES (?, ?, ?, ?, ?, ?, (SELECT last_id + 1 FROM fever_sequences WHERE name = 'feeds'))
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, redirect_url, redirect_count, deactivated_at, next_fetch_at, fetch_error_count, retention_max_age_seconds, retention_max_posts, fever_id
+`
+
+type CreateFeedParams struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	Name      string
+	Url       string
+	UserID    uuid.UUID
+}
+
+func (q *Queries) CreateFeed(ctx context.Context, arg CreateFeedParams) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, createFeed,
+		arg.ID,
+		arg.CreatedAt,
+		arg.UpdatedAt,
+		arg.Name,
+		arg.Url,
+		arg.UserID,
+	)
+	var i Feed
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Url,
+		&i.UserID,
+		&i.LastFetchedAt,
+		&i.RedirectUrl,
+		&i.RedirectCount,
+		&i.DeactivatedAt,
+		&i.NextFetchAt,
+		&i.FetchErrorCount,
+		&i.RetentionMaxAgeSeconds,
+		&i.RetentionMaxPosts,
+		&i.FeverID,
+	)
+	return i, err
+}
+
+const deactivateFeed = `-- name: DeactivateFeed :exec
+UPDATE feeds
+SET deactivated_at = ?1,
+updated_at = ?1
+WHERE id = ?2
+`
+
+type DeactivateFeedParams struct {
+	DeactivatedAt sql.NullTime
+	ID            uuid.UUID
+}
+
+func (q *Queries) DeactivateFeed(ctx context.Context, arg DeactivateFeedParams) error {
+	_, err := q.db.ExecContext(ctx, deactivateFeed, arg.DeactivatedAt, arg.ID)
+	return err
+}
+
+const deleteFeed = `-- name: DeleteFeed :exec
+DELETE FROM feeds WHERE id = ?
+`
+
+func (q *Queries) DeleteFeed(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deleteFeed, id)
+	return err
+}
+
+const getEarliestNextFetch = `-- name: GetEarliestNextFetch :one
+SELECT next_fetch_at FROM feeds
+WHERE deactivated_at IS NULL
+ORDER BY next_fetch_at ASC NULLS FIRST
+LIMIT 1
+`
+
+func (q *Queries) GetEarliestNextFetch(ctx context.Context) (sql.NullTime, error) {
+	row := q.db.QueryRowContext(ctx, getEarliestNextFetch)
+	var next_fetch_at sql.NullTime
+	err := row.Scan(&next_fetch_at)
+	return next_fetch_at, err
+}
+
+const getFeedByID = `-- name: GetFeedByID :one
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, redirect_url, redirect_count, deactivated_at, next_fetch_at, fetch_error_count, retention_max_age_seconds, retention_max_posts, fever_id FROM feeds WHERE id = ?
+`
+
+func (q *Queries) GetFeedByID(ctx context.Context, id uuid.UUID) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, getFeedByID, id)
+	var i Feed
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Url,
+		&i.UserID,
+		&i.LastFetchedAt,
+		&i.RedirectUrl,
+		&i.RedirectCount,
+		&i.DeactivatedAt,
+		&i.NextFetchAt,
+		&i.FetchErrorCount,
+		&i.RetentionMaxAgeSeconds,
+		&i.RetentionMaxPosts,
+		&i.FeverID,
+	)
+	return i, err
+}
+
+const getFeedByPreviousURL = `-- name: GetFeedByPreviousURL :one
+SELECT feeds.id, feeds.created_at, feeds.updated_at, feeds.name, feeds.url, feeds.user_id, feeds.last_fetched_at, feeds.redirect_url, feeds.redirect_count, feeds.deactivated_at, feeds.next_fetch_at, feeds.fetch_error_count, feeds.retention_max_age_seconds, feeds.retention_max_posts, feeds.fever_id FROM feeds
+JOIN feed_url_history ON feed_url_history.feed_id = feeds.id
+WHERE feed_url_history.url = ?
+`
+
+func (q *Queries) GetFeedByPreviousURL(ctx context.Context, url string) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, getFeedByPreviousURL, url)
+	var i Feed
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Url,
+		&i.UserID,
+		&i.LastFetchedAt,
+		&i.RedirectUrl,
+		&i.RedirectCount,
+		&i.DeactivatedAt,
+		&i.NextFetchAt,
+		&i.FetchErrorCount,
+		&i.RetentionMaxAgeSeconds,
+		&i.RetentionMaxPosts,
+		&i.FeverID,
+	)
+	return i, err
+}
+
+const getFeedByURL = `-- name: GetFeedByURL :one
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, redirect_url, redirect_count, deactivated_at, next_fetch_at, fetch_error_count, retention_max_age_seconds, retention_max_posts, fever_id FROM feeds WHERE url = ?
+`
+
+func (q *Queries) GetFeedByURL(ctx context.Context, url string) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, getFeedByURL, url)
+	var i Feed
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Url,
+		&i.UserID,
+		&i.LastFetchedAt,
+		&i.RedirectUrl,
+		&i.RedirectCount,
+		&i.DeactivatedAt,
+		&i.NextFetchAt,
+		&i.FetchErrorCount,
+		&i.RetentionMaxAgeSeconds,
+		&i.RetentionMaxPosts,
+		&i.FeverID,
+	)
+	return i, err
+}
+
+const getFeedURLHistory = `-- name: GetFeedURLHistory :many
+SELECT id, created_at, url, feed_id FROM feed_url_history
+WHERE feed_id = ?
+ORDER BY created_at
+`
+
+func (q *Queries) GetFeedURLHistory(ctx context.Context, feedID uuid.UUID) ([]FeedUrlHistory, error) {
+	rows, err := q.db.QueryContext(ctx, getFeedURLHistory, feedID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []FeedUrlHistory
+	for rows.Next() {
+		var i FeedUrlHistory
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.Url,
+			&i.FeedID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getFeeds = `-- name: GetFeeds :many
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, redirect_url, redirect_count, deactivated_at, next_fetch_at, fetch_error_count, retention_max_age_seconds, retention_max_posts, fever_id FROM feeds
+ORDER BY created_at ASC
+`
+
+func (q *Queries) GetFeeds(ctx context.Context) ([]Feed, error) {
+	rows, err := q.db.QueryContext(ctx, getFeeds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Feed
+	for rows.Next() {
+		var i Feed
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Name,
+			&i.Url,
+			&i.UserID,
+			&i.LastFetchedAt,
+			&i.RedirectUrl,
+			&i.RedirectCount,
+			&i.DeactivatedAt,
+			&i.NextFetchAt,
+			&i.FetchErrorCount,
+			&i.RetentionMaxAgeSeconds,
+			&i.RetentionMaxPosts,
+			&i.FeverID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getFeedsByOwner = `-- name: GetFeedsByOwner :many
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, redirect_url, redirect_count, deactivated_at, next_fetch_at, fetch_error_count, retention_max_age_seconds, retention_max_posts, fever_id FROM feeds
+WHERE user_id = ?
+ORDER BY created_at ASC
+`
+
+func (q *Queries) GetFeedsByOwner(ctx context.Context, userID uuid.UUID) ([]Feed, error) {
+	rows, err := q.db.QueryContext(ctx, getFeedsByOwner, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Feed
+	for rows.Next() {
+		var i Feed
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Name,
+			&i.Url,
+			&i.UserID,
+			&i.LastFetchedAt,
+			&i.RedirectUrl,
+			&i.RedirectCount,
+			&i.DeactivatedAt,
+			&i.NextFetchAt,
+			&i.FetchErrorCount,
+			&i.RetentionMaxAgeSeconds,
+			&i.RetentionMaxPosts,
+			&i.FeverID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getFeedsWithUser = `-- name: GetFeedsWithUser :many
+SELECT feeds.name, feeds.url, users.name AS user_name
+FROM feeds
+JOIN users ON feeds.user_id = users.id
+`
+
+type GetFeedsWithUserRow struct {
+	Name     string
+	Url      string
+	UserName string
+}
+
+func (q *Queries) GetFeedsWithUser(ctx context.Context) ([]GetFeedsWithUserRow, error) {
+	rows, err := q.db.QueryContext(ctx, getFeedsWithUser)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetFeedsWithUserRow
+	for rows.Next() {
+		var i GetFeedsWithUserRow
+		if err := rows.Scan(&i.Name, &i.Url, &i.UserName); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getNextFeedsToFetch = `-- name: GetNextFeedsToFetch :many
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, redirect_url, redirect_count, deactivated_at, next_fetch_at, fetch_error_count, retention_max_age_seconds, retention_max_posts, fever_id FROM feeds
+WHERE deactivated_at IS NULL
+    AND (next_fetch_at IS NULL OR next_fetch_at <= ?1)
+ORDER BY next_fetch_at ASC NULLS FIRST, last_fetched_at ASC NULLS FIRST
+LIMIT ?2
+`
+
+type GetNextFeedsToFetchParams struct {
+	Now      sql.NullTime
+	MaxFeeds int64
+}
+
+func (q *Queries) GetNextFeedsToFetch(ctx context.Context, arg GetNextFeedsToFetchParams) ([]Feed, error) {
+	rows, err := q.db.QueryContext(ctx, getNextFeedsToFetch, arg.Now, arg.MaxFeeds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Feed
+	for rows.Next() {
+		var i Feed
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Name,
+			&i.Url,
+			&i.UserID,
+			&i.LastFetchedAt,
+			&i.RedirectUrl,
+			&i.RedirectCount,
+			&i.DeactivatedAt,
+			&i.NextFetchAt,
+			&i.FetchErrorCount,
+			&i.RetentionMaxAgeSeconds,
+			&i.RetentionMaxPosts,
+			&i.FeverID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const markFeedFetched = `-- name: MarkFeedFetched :one
+UPDATE feeds
+SET last_fetched_at = ?1,
+updated_at = ?1
+WHERE id = ?2
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, redirect_url, redirect_count, deactivated_at, next_fetch_at, fetch_error_count, retention_max_age_seconds, retention_max_posts, fever_id
+`
+
+type MarkFeedFetchedParams struct {
+	LastFetchedAt sql.NullTime
+	ID            uuid.UUID
+}
+
+func (q *Queries) MarkFeedFetched(ctx context.Context, arg MarkFeedFetchedParams) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, markFeedFetched, arg.LastFetchedAt, arg.ID)
+	var i Feed
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Url,
+		&i.UserID,
+		&i.LastFetchedAt,
+		&i.RedirectUrl,
+		&i.RedirectCount,
+		&i.DeactivatedAt,
+		&i.NextFetchAt,
+		&i.FetchErrorCount,
+		&i.RetentionMaxAgeSeconds,
+		&i.RetentionMaxPosts,
+		&i.FeverID,
+	)
+	return i, err
+}
+
+const mergeFeedRetention = `-- name: MergeFeedRetention :exec
+UPDATE feeds
+SET retention_max_age_seconds = COALESCE(retention_max_age_seconds, (
+        SELECT merged.retention_max_age_seconds FROM feeds AS merged
+        WHERE merged.id = ?1
+    )),
+    retention_max_posts = COALESCE(retention_max_posts, (
+        SELECT merged.retention_max_posts FROM feeds AS merged
+        WHERE merged.id = ?1
+    )),
+    updated_at = ?2
+WHERE feeds.id = ?3
+`
+
+type MergeFeedRetentionParams struct {
+	FromFeedID uuid.UUID
+	UpdatedAt  time.Time
+	ToFeedID   uuid.UUID
+}
+
+// Gives to_feed_id the retention limits of from_feed_id that it hasn't set
+// itself.
+func (q *Queries) MergeFeedRetention(ctx context.Context, arg MergeFeedRetentionParams) error {
+	_, err := q.db.ExecContext(ctx, mergeFeedRetention, arg.FromFeedID, arg.UpdatedAt, arg.ToFeedID)
+	return err
+}
+
+const moveFeedURLHistory = `-- name: MoveFeedURLHistory :exec
+UPDATE feed_url_history
+SET feed_id = ?1
+WHERE feed_id = ?2
+`
+
+type MoveFeedURLHistoryParams struct {
+	ToFeedID   uuid.UUID
+	FromFeedID uuid.UUID
+}
+
+func (q *Queries) MoveFeedURLHistory(ctx context.Context, arg MoveFeedURLHistoryParams) error {
+	_, err := q.db.ExecContext(ctx, moveFeedURLHistory, arg.ToFeedID, arg.FromFeedID)
+	return err
+}
+
+const recordFeedFetchError = `-- name: RecordFeedFetchError :one
+UPDATE feeds
+SET fetch_error_count = fetch_error_count + 1
+WHERE id = ?
+RETURNING fetch_error_count
+`
+
+func (q *Queries) RecordFeedFetchError(ctx context.Context, id uuid.UUID) (int32, error) {
+	row := q.db.QueryRowContext(ctx, recordFeedFetchError, id)
+	var fetch_error_count int32
+	err := row.Scan(&fetch_error_count)
+	return fetch_error_count, err
+}
+
+const recordFeedRedirect = `-- name: RecordFeedRedirect :one
+UPDATE feeds
+SET redirect_count = CASE WHEN redirect_url = ?1 THEN redirect_count + 1 ELSE 1 END,
+redirect_url = ?1,
+updated_at = ?2
+WHERE id = ?3
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, redirect_url, redirect_count, deactivated_at, next_fetch_at, fetch_error_count, retention_max_age_seconds, retention_max_posts, fever_id
+`
+
+type RecordFeedRedirectParams struct {
+	RedirectUrl sql.NullString
+	UpdatedAt   time.Time
+	ID          uuid.UUID
+}
+
+func (q *Queries) RecordFeedRedirect(ctx context.Context, arg RecordFeedRedirectParams) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, recordFeedRedirect, arg.RedirectUrl, arg.UpdatedAt, arg.ID)
+	var i Feed
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Url,
+		&i.UserID,
+		&i.LastFetchedAt,
+		&i.RedirectUrl,
+		&i.RedirectCount,
+		&i.DeactivatedAt,
+		&i.NextFetchAt,
+		&i.FetchErrorCount,
+		&i.RetentionMaxAgeSeconds,
+		&i.RetentionMaxPosts,
+		&i.FeverID,
+	)
+	return i, err
+}
+
+const renameFeed = `-- name: RenameFeed :exec
+UPDATE feeds
+SET name = ?1,
+updated_at = ?2
+WHERE id = ?3
+`
+
+type RenameFeedParams struct {
+	Name      string
+	UpdatedAt time.Time
+	ID        uuid.UUID
+}
+
+func (q *Queries) RenameFeed(ctx context.Context, arg RenameFeedParams) error {
+	_, err := q.db.ExecContext(ctx, renameFeed, arg.Name, arg.UpdatedAt, arg.ID)
+	return err
+}
+
+const resetFeedFetchErrors = `-- name: ResetFeedFetchErrors :exec
+UPDATE feeds
+SET fetch_error_count = 0
+WHERE id = ? AND fetch_error_count <> 0
+`
+
+func (q *Queries) ResetFeedFetchErrors(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, resetFeedFetchErrors, id)
+	return err
+}
+
+const resetFeedFetchState = `-- name: ResetFeedFetchState :exec
+UPDATE feeds
+SET redirect_url = NULL,
+redirect_count = 0,
+deactivated_at = NULL,
+fetch_error_count = 0,
+next_fetch_at = NULL,
+updated_at = ?1
+WHERE id = ?2
+`
+
+type ResetFeedFetchStateParams struct {
+	UpdatedAt time.Time
+	ID        uuid.UUID
+}
+
+// Forgets what fetching the feed's previous URL taught us, so a corrected
+// URL is fetched straight away.
+func (q *Queries) ResetFeedFetchState(ctx context.Context, arg ResetFeedFetchStateParams) error {
+	_, err := q.db.ExecContext(ctx, resetFeedFetchState, arg.UpdatedAt, arg.ID)
+	return err
+}
+
+const setFeedNextFetch = `-- name: SetFeedNextFetch :exec
+UPDATE feeds
+SET next_fetch_at = ?1
+WHERE id = ?2
+`
+
+type SetFeedNextFetchParams struct {
+	NextFetchAt sql.NullTime
+	ID          uuid.UUID
+}
+
+func (q *Queries) SetFeedNextFetch(ctx context.Context, arg SetFeedNextFetchParams) error {
+	_, err := q.db.ExecContext(ctx, setFeedNextFetch, arg.NextFetchAt, arg.ID)
+	return err
+}
+
+const setFeedOwner = `-- name: SetFeedOwner :exec
+UPDATE feeds
+SET user_id = ?1,
+updated_at = ?2
+WHERE id = ?3
+`
+
+type SetFeedOwnerParams struct {
+	UserID    uuid.UUID
+	UpdatedAt time.Time
+	ID        uuid.UUID
+}
+
+func (q *Queries) SetFeedOwner(ctx context.Context, arg SetFeedOwnerParams) error {
+	_, err := q.db.ExecContext(ctx, setFeedOwner, arg.UserID, arg.UpdatedAt, arg.ID)
+	return err
+}
+
+const setFeedRetention = `-- name: SetFeedRetention :exec
+UPDATE feeds
+SET retention_max_age_seconds = ?1,
+retention_max_posts = ?2,
+updated_at = ?3
+WHERE id = ?4
+`
+
+type SetFeedRetentionParams struct {
+	RetentionMaxAgeSeconds sql.NullInt64
+	RetentionMaxPosts      sql.NullInt32
+	UpdatedAt              time.Time
+	ID                     uuid.UUID
+}
+
+func (q *Queries) SetFeedRetention(ctx context.Context, arg SetFeedRetentionParams) error {
+	_, err := q.db.ExecContext(ctx, setFeedRetention,
+		arg.RetentionMaxAgeSeconds,
+		arg.RetentionMaxPosts,
+		arg.UpdatedAt,
+		arg.ID,
+	)
+	return err
+}
+
+const updateFeedURL = `-- name: UpdateFeedURL :exec
+UPDATE feeds
+SET url = ?1,
+updated_at = ?2
+WHERE id = ?3
+`
+
+type UpdateFeedURLParams struct {
+	Url       string
+	UpdatedAt time.Time
+	ID        uuid.UUID
+}
+
+func (q *Queries) UpdateFeedURL(ctx context.Context, arg UpdateFeedURLParams) error {
+	_, err := q.db.ExecContext(ctx, updateFeedURL, arg.Url, arg.UpdatedAt, arg.ID)
+	return err
+}
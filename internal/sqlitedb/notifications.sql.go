@@ -0,0 +1,420 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: notifications.sql
+
+package sqlitedb
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const createNotificationRule = `-- name: CreateNotificationRule :one
+INSERT INTO notification_rules (id, created_at, user_id, feed_id, field, match_type, pattern, sink)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+RETURNING id, created_at, user_id, feed_id, field, match_type, pattern, sink
+`
+
+type CreateNotificationRuleParams struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UserID    uuid.UUID
+	FeedID    uuid.NullUUID
+	Field     string
+	MatchType string
+	Pattern   string
+	Sink      string
+}
+
+func (q *Queries) CreateNotificationRule(ctx context.Context, arg CreateNotificationRuleParams) (NotificationRule, error) {
+	row := q.db.QueryRowContext(ctx, createNotificationRule,
+		arg.ID,
+		arg.CreatedAt,
+		arg.UserID,
+		arg.FeedID,
+		arg.Field,
+		arg.MatchType,
+		arg.Pattern,
+		arg.Sink,
+	)
+	var i NotificationRule
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UserID,
+		&i.FeedID,
+		&i.Field,
+		&i.MatchType,
+		&i.Pattern,
+		&i.Sink,
+	)
+	return i, err
+}
+
+const deleteNotificationRule = `-- name: DeleteNotificationRule :execrows
+DELETE FROM notification_rules
+WHERE user_id = ? AND id = ?
+`
+
+type DeleteNotificationRuleParams struct {
+	UserID uuid.UUID
+	ID     uuid.UUID
+}
+
+func (q *Queries) DeleteNotificationRule(ctx context.Context, arg DeleteNotificationRuleParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteNotificationRule, arg.UserID, arg.ID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const failOrphanedNotifications = `-- name: FailOrphanedNotifications :execrows
+UPDATE notification_deliveries
+SET attempts = CAST(?1 AS INTEGER),
+    last_error = ?2
+WHERE post_id IS NULL
+    AND delivered_at IS NULL
+    AND attempts < CAST(?1 AS INTEGER)
+`
+
+type FailOrphanedNotificationsParams struct {
+	MaxAttempts int64
+	LastError   string
+}
+
+// Gives up on pending deliveries whose post was deleted before they were
+// sent, since there is nothing left to send.
+func (q *Queries) FailOrphanedNotifications(ctx context.Context, arg FailOrphanedNotificationsParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, failOrphanedNotifications, arg.MaxAttempts, arg.LastError)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getDueNotifications = `-- name: GetDueNotifications :many
+SELECT
+    notification_deliveries.id, notification_deliveries.created_at, notification_deliveries.user_id, notification_deliveries.post_id, notification_deliveries.post_url, notification_deliveries.sink, notification_deliveries.attempts, notification_deliveries.next_attempt_at, notification_deliveries.delivered_at, notification_deliveries.last_error,
+    users.name AS user_name,
+    CAST(COALESCE(feed_follows.title, feeds.name) AS TEXT) AS feed_name,
+    feeds.url AS feed_url,
+    posts.title,
+    posts.url,
+    posts.description,
+    posts.author,
+    posts.published_at
+FROM notification_deliveries
+JOIN users ON notification_deliveries.user_id = users.id
+JOIN posts ON notification_deliveries.post_id = posts.id
+JOIN feeds ON posts.feed_id = feeds.id
+LEFT JOIN feed_follows ON feed_follows.feed_id = feeds.id AND feed_follows.user_id = users.id
+WHERE notification_deliveries.delivered_at IS NULL
+    AND notification_deliveries.attempts < CAST(?1 AS INTEGER)
+    AND notification_deliveries.next_attempt_at <= ?2
+ORDER BY notification_deliveries.next_attempt_at
+LIMIT ?3
+`
+
+type GetDueNotificationsParams struct {
+	MaxAttempts   int64
+	Now           time.Time
+	MaxDeliveries int64
+}
+
+type GetDueNotificationsRow struct {
+	ID            uuid.UUID
+	CreatedAt     time.Time
+	UserID        uuid.UUID
+	PostID        uuid.NullUUID
+	PostUrl       string
+	Sink          string
+	Attempts      int32
+	NextAttemptAt time.Time
+	DeliveredAt   sql.NullTime
+	LastError     string
+	UserName      string
+	FeedName      string
+	FeedUrl       string
+	Title         string
+	Url           string
+	Description   sql.NullString
+	Author        string
+	PublishedAt   sql.NullTime
+}
+
+func (q *Queries) GetDueNotifications(ctx context.Context, arg GetDueNotificationsParams) ([]GetDueNotificationsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getDueNotifications, arg.MaxAttempts, arg.Now, arg.MaxDeliveries)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetDueNotificationsRow
+	for rows.Next() {
+		var i GetDueNotificationsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UserID,
+			&i.PostID,
+			&i.PostUrl,
+			&i.Sink,
+			&i.Attempts,
+			&i.NextAttemptAt,
+			&i.DeliveredAt,
+			&i.LastError,
+			&i.UserName,
+			&i.FeedName,
+			&i.FeedUrl,
+			&i.Title,
+			&i.Url,
+			&i.Description,
+			&i.Author,
+			&i.PublishedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getFailedNotificationsForUser = `-- name: GetFailedNotificationsForUser :many
+SELECT id, created_at, user_id, post_id, post_url, sink, attempts, next_attempt_at, delivered_at, last_error FROM notification_deliveries
+WHERE notification_deliveries.user_id = ?1
+    AND notification_deliveries.delivered_at IS NULL
+    AND notification_deliveries.attempts >= CAST(?2 AS INTEGER)
+ORDER BY notification_deliveries.created_at DESC
+LIMIT 20
+`
+
+type GetFailedNotificationsForUserParams struct {
+	UserID      uuid.UUID
+	MaxAttempts int64
+}
+
+// Deliveries that have used up their attempts.
+func (q *Queries) GetFailedNotificationsForUser(ctx context.Context, arg GetFailedNotificationsForUserParams) ([]NotificationDelivery, error) {
+	rows, err := q.db.QueryContext(ctx, getFailedNotificationsForUser, arg.UserID, arg.MaxAttempts)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []NotificationDelivery
+	for rows.Next() {
+		var i NotificationDelivery
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UserID,
+			&i.PostID,
+			&i.PostUrl,
+			&i.Sink,
+			&i.Attempts,
+			&i.NextAttemptAt,
+			&i.DeliveredAt,
+			&i.LastError,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getNotificationRulesForFeed = `-- name: GetNotificationRulesForFeed :many
+SELECT notification_rules.id, notification_rules.created_at, notification_rules.user_id, notification_rules.feed_id, notification_rules.field, notification_rules.match_type, notification_rules.pattern, notification_rules.sink FROM notification_rules
+JOIN feed_follows ON feed_follows.user_id = notification_rules.user_id
+JOIN users ON users.id = notification_rules.user_id
+WHERE feed_follows.feed_id = ?1
+    AND users.disabled_at IS NULL
+    AND feed_follows.notify
+    AND NOT feed_follows.muted
+    AND (notification_rules.feed_id IS NULL OR notification_rules.feed_id = ?1)
+ORDER BY notification_rules.created_at
+`
+
+// The rules that watch a feed, for followers who haven't muted it or turned
+// its notifications off, and aren't disabled.
+func (q *Queries) GetNotificationRulesForFeed(ctx context.Context, feedID uuid.UUID) ([]NotificationRule, error) {
+	rows, err := q.db.QueryContext(ctx, getNotificationRulesForFeed, feedID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []NotificationRule
+	for rows.Next() {
+		var i NotificationRule
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UserID,
+			&i.FeedID,
+			&i.Field,
+			&i.MatchType,
+			&i.Pattern,
+			&i.Sink,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getNotificationRulesForUser = `-- name: GetNotificationRulesForUser :many
+SELECT notification_rules.id, notification_rules.created_at, notification_rules.user_id, notification_rules.feed_id, notification_rules.field, notification_rules.match_type, notification_rules.pattern, notification_rules.sink, CAST(COALESCE(feeds.url, '') AS TEXT) AS feed_url
+FROM notification_rules
+LEFT JOIN feeds ON notification_rules.feed_id = feeds.id
+WHERE notification_rules.user_id = ?
+ORDER BY notification_rules.created_at
+`
+
+type GetNotificationRulesForUserRow struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UserID    uuid.UUID
+	FeedID    uuid.NullUUID
+	Field     string
+	MatchType string
+	Pattern   string
+	Sink      string
+	FeedUrl   string
+}
+
+func (q *Queries) GetNotificationRulesForUser(ctx context.Context, userID uuid.UUID) ([]GetNotificationRulesForUserRow, error) {
+	rows, err := q.db.QueryContext(ctx, getNotificationRulesForUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetNotificationRulesForUserRow
+	for rows.Next() {
+		var i GetNotificationRulesForUserRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UserID,
+			&i.FeedID,
+			&i.Field,
+			&i.MatchType,
+			&i.Pattern,
+			&i.Sink,
+			&i.FeedUrl,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const markNotificationDelivered = `-- name: MarkNotificationDelivered :exec
+UPDATE notification_deliveries
+SET delivered_at = ?1,
+    attempts = attempts + 1,
+    last_error = ''
+WHERE id = ?2
+`
+
+type MarkNotificationDeliveredParams struct {
+	DeliveredAt sql.NullTime
+	ID          uuid.UUID
+}
+
+func (q *Queries) MarkNotificationDelivered(ctx context.Context, arg MarkNotificationDeliveredParams) error {
+	_, err := q.db.ExecContext(ctx, markNotificationDelivered, arg.DeliveredAt, arg.ID)
+	return err
+}
+
+const markNotificationFailed = `-- name: MarkNotificationFailed :exec
+UPDATE notification_deliveries
+SET attempts = attempts + 1,
+    next_attempt_at = ?1,
+    last_error = ?2
+WHERE id = ?3
+`
+
+type MarkNotificationFailedParams struct {
+	NextAttemptAt time.Time
+	LastError     string
+	ID            uuid.UUID
+}
+
+func (q *Queries) MarkNotificationFailed(ctx context.Context, arg MarkNotificationFailedParams) error {
+	_, err := q.db.ExecContext(ctx, markNotificationFailed, arg.NextAttemptAt, arg.LastError, arg.ID)
+	return err
+}
+
+const moveNotificationRules = `-- name: MoveNotificationRules :exec
+UPDATE notification_rules
+SET feed_id = ?1
+WHERE feed_id = ?2
+`
+
+type MoveNotificationRulesParams struct {
+	ToFeedID   uuid.NullUUID
+	FromFeedID uuid.NullUUID
+}
+
+func (q *Queries) MoveNotificationRules(ctx context.Context, arg MoveNotificationRulesParams) error {
+	_, err := q.db.ExecContext(ctx, moveNotificationRules, arg.ToFeedID, arg.FromFeedID)
+	return err
+}
+
+const queueNotification = `-- name: QueueNotification :exec
+INSERT INTO notification_deliveries (id, created_at, user_id, post_id, post_url, sink, next_attempt_at)
+VALUES (?1, ?2, ?3, ?4, ?5, ?6, ?2)
+ON CONFLICT (user_id, post_url, sink) DO NOTHING
+`
+
+type QueueNotificationParams struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UserID    uuid.UUID
+	PostID    uuid.NullUUID
+	PostUrl   string
+	Sink      string
+}
+
+// A post is queued once per user and sink by its URL, so saving it again
+// after it was pruned doesn't send it again.
+func (q *Queries) QueueNotification(ctx context.Context, arg QueueNotificationParams) error {
+	_, err := q.db.ExecContext(ctx, queueNotification,
+		arg.ID,
+		arg.CreatedAt,
+		arg.UserID,
+		arg.PostID,
+		arg.PostUrl,
+		arg.Sink,
+	)
+	return err
+}
@@ -0,0 +1,90 @@
+package sqlitedb
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"strconv"
+	"strings"
+
+	_ "modernc.org/sqlite"
+)
+
+// Open opens the SQLite database file at path, creating it if it doesn't
+// exist. Foreign keys are enforced, as they are in Postgres, and other
+// processes writing to the file are waited for rather than failed.
+func Open(path string) (*sql.DB, error) {
+	params := url.Values{}
+	params.Add("_pragma", "foreign_keys(1)")
+	params.Add("_pragma", "busy_timeout(5000)")
+	params.Add("_pragma", "journal_mode(WAL)")
+	// Store times as text that sorts in time order, so queries can compare
+	// them.
+	params.Set("_time_format", "sqlite")
+	db, err := sql.Open("sqlite", "file:"+path+"?"+params.Encode())
+	if err != nil {
+		return nil, err
+	}
+	// SQLite allows one writer at a time. A single connection queues this
+	// process's writes instead of failing them as busy.
+	db.SetMaxOpenConns(1)
+	return db, nil
+}
+
+// Migrate applies the goose migrations in migrations that db doesn't have
+// yet, recording the latest one applied in its user_version. Only the Up
+// sections are run.
+func Migrate(ctx context.Context, db *sql.DB, migrations fs.FS) error {
+	var current int
+	if err := db.QueryRowContext(ctx, "PRAGMA user_version").Scan(&current); err != nil {
+		return fmt.Errorf("could not read schema version: %w", err)
+	}
+
+	names, err := fs.Glob(migrations, "*.sql")
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		prefix, _, _ := strings.Cut(name, "_")
+		version, err := strconv.Atoi(prefix)
+		if err != nil {
+			return fmt.Errorf("migration %s: name doesn't start with a version number", name)
+		}
+		if version <= current {
+			continue
+		}
+		data, err := fs.ReadFile(migrations, name)
+		if err != nil {
+			return err
+		}
+		if err := migrate(ctx, db, version, upSection(string(data))); err != nil {
+			return fmt.Errorf("migration %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+func migrate(ctx context.Context, db *sql.DB, version int, up string) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx, up); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf("PRAGMA user_version = %d", version)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// upSection returns the part of a goose migration between its Up and Down
+// annotations.
+func upSection(migration string) string {
+	_, up, _ := strings.Cut(migration, "-- +goose Up")
+	up, _, _ = strings.Cut(up, "-- +goose Down")
+	return up
+}
@@ -0,0 +1,77 @@
+package sqlitedb
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func userVersion(t *testing.T, db *sql.DB) int {
+	t.Helper()
+	var version int
+	if err := db.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		t.Fatal(err)
+	}
+	return version
+}
+
+func hasTable(t *testing.T, db *sql.DB, name string) bool {
+	t.Helper()
+	var n int
+	if err := db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?", name).Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	return n == 1
+}
+
+func TestMigrate(t *testing.T) {
+	ctx := context.Background()
+	db, err := Open(filepath.Join(t.TempDir(), "gator.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	migrations := fstest.MapFS{
+		"001_users.sql": {Data: []byte("-- +goose Up\nCREATE TABLE users (id TEXT PRIMARY KEY);\n\n-- +goose Down\nDROP TABLE users;\n")},
+		"002_feeds.sql": {Data: []byte("-- +goose Up\nCREATE TABLE feeds (id TEXT PRIMARY KEY);\nCREATE TABLE folders (id TEXT PRIMARY KEY);\n\n-- +goose Down\nDROP TABLE feeds;\n")},
+	}
+	if err := Migrate(ctx, db, migrations); err != nil {
+		t.Fatalf("Migrate: %v", err)
+	}
+	if v := userVersion(t, db); v != 2 {
+		t.Errorf("user_version = %d, want 2", v)
+	}
+	for _, table := range []string{"users", "feeds", "folders"} {
+		if !hasTable(t, db, table) {
+			t.Errorf("table %s is missing", table)
+		}
+	}
+
+	// Applied migrations aren't run again; this one would fail if it were.
+	if err := Migrate(ctx, db, migrations); err != nil {
+		t.Fatalf("Migrate again: %v", err)
+	}
+
+	// A failing migration is rolled back whole and not recorded.
+	migrations["003_posts.sql"] = &fstest.MapFile{Data: []byte("-- +goose Up\nCREATE TABLE posts (id TEXT PRIMARY KEY);\nCREATE TABLE users (id TEXT PRIMARY KEY);\n")}
+	err = Migrate(ctx, db, migrations)
+	if err == nil || !strings.Contains(err.Error(), "003_posts.sql") {
+		t.Errorf("Migrate error = %v, want one naming 003_posts.sql", err)
+	}
+	if v := userVersion(t, db); v != 2 {
+		t.Errorf("user_version after a failed migration = %d, want 2", v)
+	}
+	if hasTable(t, db, "posts") {
+		t.Error("failed migration left table posts behind")
+	}
+
+	delete(migrations, "003_posts.sql")
+	migrations["bad.sql"] = &fstest.MapFile{}
+	if err := Migrate(ctx, db, migrations); err == nil {
+		t.Error("Migrate accepted a migration without a version number")
+	}
+}
@@ -0,0 +1,531 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: posts.sql
+
+package sqlitedb
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const countPostsBefore = `-- name: CountPostsBefore :one
+SELECT COUNT(*) FROM posts
+WHERE COALESCE(published_at, created_at) < ?1
+`
+
+func (q *Queries) CountPostsBefore(ctx context.Context, before sql.NullTime) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countPostsBefore, before)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const createPost = `-- name: CreatePost :one
+INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id, author, categories, fever_id)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, (SELECT last_id + 1 FROM fever_sequences WHERE name = 'posts'))
+ON CONFLICT (url) DO NOTHING
+RETURNING id, created_at, updated_at, title, url, description, published_at, feed_id, author, categories, fever_id
+`
+
+type CreatePostParams struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Title       string
+	Url         string
+	Description sql.NullString
+	PublishedAt sql.NullTime
+	FeedID      uuid.UUID
+	Author      string
+	Categories  string
+}
+
+func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, error) {
+	row := q.db.QueryRowContext(ctx, createPost,
+		arg.ID,
+		arg.CreatedAt,
+		arg.UpdatedAt,
+		arg.Title,
+		arg.Url,
+		arg.Description,
+		arg.PublishedAt,
+		arg.FeedID,
+		arg.Author,
+		arg.Categories,
+	)
+	var i Post
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Title,
+		&i.Url,
+		&i.Description,
+		&i.PublishedAt,
+		&i.FeedID,
+		&i.Author,
+		&i.Categories,
+		&i.FeverID,
+	)
+	return i, err
+}
+
+const deletePost = `-- name: DeletePost :exec
+DELETE FROM posts WHERE id = ?
+`
+
+func (q *Queries) DeletePost(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deletePost, id)
+	return err
+}
+
+const deletePostsBefore = `-- name: DeletePostsBefore :execrows
+DELETE FROM posts
+WHERE COALESCE(published_at, created_at) < ?1
+`
+
+func (q *Queries) DeletePostsBefore(ctx context.Context, before sql.NullTime) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deletePostsBefore, before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deletePostsByID = `-- name: DeletePostsByID :execrows
+DELETE FROM posts WHERE id IN (/*SLICE:ids*/?)
+`
+
+func (q *Queries) DeletePostsByID(ctx context.Context, ids []uuid.UUID) (int64, error) {
+	query := deletePostsByID
+	var queryParams []interface{}
+	if len(ids) > 0 {
+		for _, v := range ids {
+			queryParams = append(queryParams, v)
+		}
+		query = strings.Replace(query, "/*SLICE:ids*/?", strings.Repeat(",?", len(ids))[1:], 1)
+	} else {
+		query = strings.Replace(query, "/*SLICE:ids*/?", "NULL", 1)
+	}
+	result, err := q.db.ExecContext(ctx, query, queryParams...)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const forgetPrunedPosts = `-- name: ForgetPrunedPosts :execrows
+DELETE FROM pruned_posts
+WHERE feed_id = ?1 AND url NOT IN (/*SLICE:listed*/?)
+`
+
+type ForgetPrunedPostsParams struct {
+	FeedID uuid.UUID
+	Listed []string
+}
+
+// Forgets the feed's pruned posts it no longer lists, which can't come back.
+func (q *Queries) ForgetPrunedPosts(ctx context.Context, arg ForgetPrunedPostsParams) (int64, error) {
+	query := forgetPrunedPosts
+	var queryParams []interface{}
+	queryParams = append(queryParams, arg.FeedID)
+	if len(arg.Listed) > 0 {
+		for _, v := range arg.Listed {
+			queryParams = append(queryParams, v)
+		}
+		query = strings.Replace(query, "/*SLICE:listed*/?", strings.Repeat(",?", len(arg.Listed))[1:], 1)
+	} else {
+		query = strings.Replace(query, "/*SLICE:listed*/?", "NULL", 1)
+	}
+	result, err := q.db.ExecContext(ctx, query, queryParams...)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getAllPostsForUser = `-- name: GetAllPostsForUser :many
+SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.author, posts.categories, posts.fever_id FROM posts
+JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+WHERE feed_follows.user_id = ?
+ORDER BY posts.created_at
+`
+
+// Every post of every feed the user follows, muted or not, for applying
+// filter rules.
+func (q *Queries) GetAllPostsForUser(ctx context.Context, userID uuid.UUID) ([]Post, error) {
+	rows, err := q.db.QueryContext(ctx, getAllPostsForUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Post
+	for rows.Next() {
+		var i Post
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Title,
+			&i.Url,
+			&i.Description,
+			&i.PublishedAt,
+			&i.FeedID,
+			&i.Author,
+			&i.Categories,
+			&i.FeverID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPostByURL = `-- name: GetPostByURL :one
+SELECT id, created_at, updated_at, title, url, description, published_at, feed_id, author, categories, fever_id FROM posts WHERE url = ?
+`
+
+func (q *Queries) GetPostByURL(ctx context.Context, url string) (Post, error) {
+	row := q.db.QueryRowContext(ctx, getPostByURL, url)
+	var i Post
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Title,
+		&i.Url,
+		&i.Description,
+		&i.PublishedAt,
+		&i.FeedID,
+		&i.Author,
+		&i.Categories,
+		&i.FeverID,
+	)
+	return i, err
+}
+
+const getPostURLs = `-- name: GetPostURLs :many
+SELECT id, url FROM posts
+ORDER BY created_at ASC
+`
+
+type GetPostURLsRow struct {
+	ID  uuid.UUID
+	Url string
+}
+
+func (q *Queries) GetPostURLs(ctx context.Context) ([]GetPostURLsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getPostURLs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetPostURLsRow
+	for rows.Next() {
+		var i GetPostURLsRow
+		if err := rows.Scan(&i.ID, &i.Url); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPostsForUser = `-- name: GetPostsForUser :many
+SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.author, posts.categories, posts.fever_id, CAST(COALESCE(feed_follows.title, feeds.name) AS TEXT) AS feed_name FROM posts
+JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+JOIN feeds ON posts.feed_id = feeds.id
+LEFT JOIN folders ON feed_follows.folder_id = folders.id
+WHERE feed_follows.user_id = ?1
+    AND NOT feed_follows.muted
+    AND NOT EXISTS (
+        SELECT 1 FROM post_states
+        WHERE post_states.user_id = feed_follows.user_id
+            AND post_states.post_id = posts.id
+            AND post_states.hidden_at IS NOT NULL
+    )
+    AND (CAST(?2 AS TEXT) IS NULL OR folders.name = CAST(?2 AS TEXT))
+    AND (CAST(?3 AS TEXT) IS NULL OR EXISTS (
+        SELECT 1 FROM feed_follow_tags
+        WHERE feed_follow_tags.feed_follow_id = feed_follows.id
+            AND feed_follow_tags.tag = CAST(?3 AS TEXT)
+    ))
+ORDER BY date(COALESCE(posts.published_at, posts.created_at)) DESC,
+    feed_follows.priority DESC,
+    COALESCE(posts.published_at, posts.created_at) DESC
+LIMIT ?5 OFFSET ?4
+`
+
+type GetPostsForUserParams struct {
+	UserID   uuid.UUID
+	Folder   sql.NullString
+	Tag      sql.NullString
+	Skip     int64
+	MaxPosts int64
+}
+
+type GetPostsForUserRow struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Title       string
+	Url         string
+	Description sql.NullString
+	PublishedAt sql.NullTime
+	FeedID      uuid.UUID
+	Author      string
+	Categories  string
+	FeverID     int64
+	FeedName    string
+}
+
+// Optionally narrowed to the feeds in one folder and/or with one tag. Muted
+// feeds and hidden posts are left out. Newest days come first; within a day,
+// posts from higher priority feeds come first.
+func (q *Queries) GetPostsForUser(ctx context.Context, arg GetPostsForUserParams) ([]GetPostsForUserRow, error) {
+	rows, err := q.db.QueryContext(ctx, getPostsForUser,
+		arg.UserID,
+		arg.Folder,
+		arg.Tag,
+		arg.Skip,
+		arg.MaxPosts,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetPostsForUserRow
+	for rows.Next() {
+		var i GetPostsForUserRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Title,
+			&i.Url,
+			&i.Description,
+			&i.PublishedAt,
+			&i.FeedID,
+			&i.Author,
+			&i.Categories,
+			&i.FeverID,
+			&i.FeedName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPrunablePosts = `-- name: GetPrunablePosts :many
+SELECT ranked.id FROM (
+    SELECT posts.id,
+        posts.feed_id,
+        COALESCE(posts.published_at, posts.created_at) < ?1 AS too_old,
+        ROW_NUMBER() OVER (ORDER BY COALESCE(posts.published_at, posts.created_at) DESC) AS post_rank
+    FROM posts
+    WHERE posts.feed_id = ?2
+) ranked
+WHERE (
+        ranked.too_old
+        OR (CAST(?3 AS INTEGER) > 0 AND ranked.post_rank > CAST(?3 AS INTEGER))
+    )
+    AND (CAST(?4 AS BOOLEAN) = FALSE OR NOT EXISTS (
+        SELECT 1 FROM post_states
+        WHERE post_states.post_id = ranked.id AND post_states.starred_at IS NOT NULL
+    ))
+    AND (CAST(?5 AS BOOLEAN) = FALSE OR NOT EXISTS (
+        SELECT 1 FROM feed_follows
+        WHERE feed_follows.feed_id = ranked.feed_id
+            AND NOT EXISTS (
+                SELECT 1 FROM post_states
+                WHERE post_states.post_id = ranked.id
+                    AND post_states.user_id = feed_follows.user_id
+                    AND post_states.read_at IS NOT NULL
+            )
+    ))
+`
+
+type GetPrunablePostsParams struct {
+	Cutoff      sql.NullTime
+	FeedID      uuid.UUID
+	MaxPosts    int64
+	KeepStarred bool
+	KeepUnread  bool
+}
+
+// Posts of a feed that are older than the cutoff or beyond the newest
+// max_posts, less those kept for being starred or unread by a follower.
+func (q *Queries) GetPrunablePosts(ctx context.Context, arg GetPrunablePostsParams) ([]uuid.UUID, error) {
+	rows, err := q.db.QueryContext(ctx, getPrunablePosts,
+		arg.Cutoff,
+		arg.FeedID,
+		arg.MaxPosts,
+		arg.KeepStarred,
+		arg.KeepUnread,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getRecentPostTimes = `-- name: GetRecentPostTimes :many
+SELECT published_at, created_at
+FROM posts
+WHERE feed_id = ?
+ORDER BY COALESCE(published_at, created_at) DESC
+LIMIT ?
+`
+
+type GetRecentPostTimesParams struct {
+	FeedID uuid.UUID
+	Limit  int64
+}
+
+type GetRecentPostTimesRow struct {
+	PublishedAt sql.NullTime
+	CreatedAt   time.Time
+}
+
+// SQLite only reads a value back as a time when it comes straight from a
+// TIMESTAMP column, so unlike the Postgres query this returns both columns
+// for the caller to coalesce.
+func (q *Queries) GetRecentPostTimes(ctx context.Context, arg GetRecentPostTimesParams) ([]GetRecentPostTimesRow, error) {
+	rows, err := q.db.QueryContext(ctx, getRecentPostTimes, arg.FeedID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetRecentPostTimesRow
+	for rows.Next() {
+		var i GetRecentPostTimesRow
+		if err := rows.Scan(&i.PublishedAt, &i.CreatedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const movePosts = `-- name: MovePosts :exec
+UPDATE posts
+SET feed_id = ?1,
+    updated_at = ?2
+WHERE feed_id = ?3
+`
+
+type MovePostsParams struct {
+	ToFeedID   uuid.UUID
+	UpdatedAt  time.Time
+	FromFeedID uuid.UUID
+}
+
+func (q *Queries) MovePosts(ctx context.Context, arg MovePostsParams) error {
+	_, err := q.db.ExecContext(ctx, movePosts, arg.ToFeedID, arg.UpdatedAt, arg.FromFeedID)
+	return err
+}
+
+const postWasPruned = `-- name: PostWasPruned :one
+SELECT CAST(EXISTS (SELECT 1 FROM pruned_posts WHERE url = ?) AS BOOLEAN)
+`
+
+func (q *Queries) PostWasPruned(ctx context.Context, url string) (bool, error) {
+	row := q.db.QueryRowContext(ctx, postWasPruned, url)
+	var column_1 bool
+	err := row.Scan(&column_1)
+	return column_1, err
+}
+
+const recordPrunedPosts = `-- name: RecordPrunedPosts :exec
+INSERT INTO pruned_posts (url, feed_id, pruned_at)
+SELECT url, feed_id, ?1 FROM posts
+WHERE id IN (/*SLICE:ids*/?)
+ON CONFLICT (url) DO NOTHING
+`
+
+type RecordPrunedPostsParams struct {
+	PrunedAt time.Time
+	Ids      []uuid.UUID
+}
+
+func (q *Queries) RecordPrunedPosts(ctx context.Context, arg RecordPrunedPostsParams) error {
+	query := recordPrunedPosts
+	var queryParams []interface{}
+	queryParams = append(queryParams, arg.PrunedAt)
+	if len(arg.Ids) > 0 {
+		for _, v := range arg.Ids {
+			queryParams = append(queryParams, v)
+		}
+		query = strings.Replace(query, "/*SLICE:ids*/?", strings.Repeat(",?", len(arg.Ids))[1:], 1)
+	} else {
+		query = strings.Replace(query, "/*SLICE:ids*/?", "NULL", 1)
+	}
+	_, err := q.db.ExecContext(ctx, query, queryParams...)
+	return err
+}
+
+const updatePostURL = `-- name: UpdatePostURL :exec
+UPDATE posts
+SET url = ?1,
+updated_at = ?2
+WHERE id = ?3
+`
+
+type UpdatePostURLParams struct {
+	Url       string
+	UpdatedAt time.Time
+	ID        uuid.UUID
+}
+
+func (q *Queries) UpdatePostURL(ctx context.Context, arg UpdatePostURLParams) error {
+	_, err := q.db.ExecContext(ctx, updatePostURL, arg.Url, arg.UpdatedAt, arg.ID)
+	return err
+}
@@ -0,0 +1,939 @@
+package sqlitedb
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/isaacjstriker/gatorapp/internal/database"
+)
+
+// Store is a database.Store backed by SQLite. It runs the queries in this
+// package, which mirror the Postgres ones, and converts between their types
+// and package database's.
+type Store struct {
+	q  *Queries
+	db *sql.DB
+}
+
+var _ database.Store = (*Store)(nil)
+
+// NewStore returns a Store that runs queries on dbtx, which is db or a
+// wrapper around it, and transactions on db.
+func NewStore(db *sql.DB, dbtx DBTX) *Store {
+	return &Store{q: New(utcDB{dbtx}), db: db}
+}
+
+func (s *Store) InTx(ctx context.Context, fn func(database.Querier) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("could not start transaction: %w", err)
+	}
+	defer tx.Rollback()
+	if err := fn(&Store{q: New(utcDB{tx}), db: s.db}); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("could not commit transaction: %w", err)
+	}
+	return nil
+}
+
+// utcDB passes times on to SQLite in UTC. Times are stored as text, which
+// only sorts in time order when every time has the same offset.
+type utcDB struct {
+	DBTX
+}
+
+func (d utcDB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return d.DBTX.ExecContext(ctx, query, utcArgs(args)...)
+}
+
+func (d utcDB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return d.DBTX.QueryContext(ctx, query, utcArgs(args)...)
+}
+
+func (d utcDB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return d.DBTX.QueryRowContext(ctx, query, utcArgs(args)...)
+}
+
+func utcArgs(args []interface{}) []interface{} {
+	for i, arg := range args {
+		switch v := arg.(type) {
+		case time.Time:
+			args[i] = v.UTC()
+		case sql.NullTime:
+			v.Time = v.Time.UTC()
+			args[i] = v
+		}
+	}
+	return args
+}
+
+// encodeList stores a list of strings as a JSON array.
+func encodeList(list []string) string {
+	if len(list) == 0 {
+		return "[]"
+	}
+	b, _ := json.Marshal(list)
+	return string(b)
+}
+
+// decodeList reads a list of strings stored by encodeList. Anything else,
+// which gatorapp never writes, reads as an empty list.
+func decodeList(s string) []string {
+	var list []string
+	if err := json.Unmarshal([]byte(s), &list); err != nil || list == nil {
+		return []string{}
+	}
+	return list
+}
+
+// validTime is t as a non-null sql.NullTime, for comparing with nullable
+// columns.
+func validTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: true}
+}
+
+func convertAll[T, U any](items []T, convert func(T) U) []U {
+	converted := make([]U, len(items))
+	for i, item := range items {
+		converted[i] = convert(item)
+	}
+	return converted
+}
+
+func toPost(p Post) database.Post {
+	return database.Post{
+		ID:          p.ID,
+		CreatedAt:   p.CreatedAt,
+		UpdatedAt:   p.UpdatedAt,
+		Title:       p.Title,
+		Url:         p.Url,
+		Description: p.Description,
+		PublishedAt: p.PublishedAt,
+		FeedID:      p.FeedID,
+		Author:      p.Author,
+		Categories:  decodeList(p.Categories),
+		FeverID:     p.FeverID,
+	}
+}
+
+func toGetPostsForUserRow(r GetPostsForUserRow) database.GetPostsForUserRow {
+	return database.GetPostsForUserRow{
+		ID:          r.ID,
+		CreatedAt:   r.CreatedAt,
+		UpdatedAt:   r.UpdatedAt,
+		Title:       r.Title,
+		Url:         r.Url,
+		Description: r.Description,
+		PublishedAt: r.PublishedAt,
+		FeedID:      r.FeedID,
+		Author:      r.Author,
+		Categories:  decodeList(r.Categories),
+		FeverID:     r.FeverID,
+		FeedName:    r.FeedName,
+	}
+}
+
+func toGetDigestPostsRow(r GetDigestPostsRow) database.GetDigestPostsRow {
+	return database.GetDigestPostsRow{
+		ID:          r.ID,
+		CreatedAt:   r.CreatedAt,
+		UpdatedAt:   r.UpdatedAt,
+		Title:       r.Title,
+		Url:         r.Url,
+		Description: r.Description,
+		PublishedAt: r.PublishedAt,
+		FeedID:      r.FeedID,
+		Author:      r.Author,
+		Categories:  decodeList(r.Categories),
+		FeverID:     r.FeverID,
+		FeedName:    r.FeedName,
+		Priority:    r.Priority,
+	}
+}
+
+func toGetFeedFollowsForUserRow(r GetFeedFollowsForUserRow) database.GetFeedFollowsForUserRow {
+	return database.GetFeedFollowsForUserRow{
+		ID:         r.ID,
+		CreatedAt:  r.CreatedAt,
+		UpdatedAt:  r.UpdatedAt,
+		UserID:     r.UserID,
+		FeedID:     r.FeedID,
+		FolderID:   r.FolderID,
+		Title:      r.Title,
+		Muted:      r.Muted,
+		Priority:   r.Priority,
+		Notify:     r.Notify,
+		UserName:   r.UserName,
+		FeedName:   r.FeedName,
+		FeedUrl:    r.FeedUrl,
+		FolderName: r.FolderName,
+		Tags:       decodeList(r.Tags),
+	}
+}
+
+func toGetDueDigestsRow(r GetDueDigestsRow) database.GetDueDigestsRow {
+	return database.GetDueDigestsRow{
+		DigestSetting: database.DigestSetting(r.DigestSetting),
+		User:          database.User(r.User),
+	}
+}
+
+func toGetUserStatsRow(r GetUserStatsRow) database.GetUserStatsRow {
+	return database.GetUserStatsRow{
+		User:         database.User(r.User),
+		Follows:      r.Follows,
+		FeedsCreated: r.FeedsCreated,
+		Unread:       r.Unread,
+	}
+}
+
+func (s *Store) ActivateWebSubSubscription(ctx context.Context, arg database.ActivateWebSubSubscriptionParams) error {
+	return s.q.ActivateWebSubSubscription(ctx, ActivateWebSubSubscriptionParams{
+		ID:             arg.ID,
+		LeaseExpiresAt: arg.LeaseExpiresAt,
+		RenewAt:        arg.RenewAt,
+		UpdatedAt:      arg.UpdatedAt,
+	})
+}
+
+func (s *Store) AddFeedFollowTag(ctx context.Context, arg database.AddFeedFollowTagParams) (int64, error) {
+	return s.q.AddFeedFollowTag(ctx, AddFeedFollowTagParams(arg))
+}
+
+func (s *Store) AddFeedURLHistory(ctx context.Context, arg database.AddFeedURLHistoryParams) error {
+	return s.q.AddFeedURLHistory(ctx, AddFeedURLHistoryParams(arg))
+}
+
+func (s *Store) AddPostTag(ctx context.Context, arg database.AddPostTagParams) (int64, error) {
+	return s.q.AddPostTag(ctx, AddPostTagParams(arg))
+}
+
+func (s *Store) ClaimDigest(ctx context.Context, arg database.ClaimDigestParams) (int64, error) {
+	return s.q.ClaimDigest(ctx, ClaimDigestParams(arg))
+}
+
+func (s *Store) ClearFeedRedirect(ctx context.Context, arg database.ClearFeedRedirectParams) error {
+	return s.q.ClearFeedRedirect(ctx, ClearFeedRedirectParams{
+		ID:        arg.ID,
+		UpdatedAt: arg.UpdatedAt,
+	})
+}
+
+func (s *Store) CountActiveAdmins(ctx context.Context) (int64, error) {
+	return s.q.CountActiveAdmins(ctx)
+}
+
+func (s *Store) CountAllData(ctx context.Context) (database.CountAllDataRow, error) {
+	row, err := s.q.CountAllData(ctx)
+	return database.CountAllDataRow(row), err
+}
+
+func (s *Store) CountFeedData(ctx context.Context, feedID uuid.UUID) (database.CountFeedDataRow, error) {
+	row, err := s.q.CountFeedData(ctx, feedID)
+	return database.CountFeedDataRow(row), err
+}
+
+func (s *Store) CountFeverItems(ctx context.Context, userID uuid.UUID) (int64, error) {
+	return s.q.CountFeverItems(ctx, userID)
+}
+
+func (s *Store) CountOverdueFeeds(ctx context.Context, now time.Time) (int64, error) {
+	return s.q.CountOverdueFeeds(ctx, validTime(now))
+}
+
+func (s *Store) CountPostsBefore(ctx context.Context, before time.Time) (int64, error) {
+	return s.q.CountPostsBefore(ctx, validTime(before))
+}
+
+func (s *Store) CountUserData(ctx context.Context, userID uuid.UUID) (database.CountUserDataRow, error) {
+	row, err := s.q.CountUserData(ctx, userID)
+	return database.CountUserDataRow(row), err
+}
+
+func (s *Store) CreateFeed(ctx context.Context, arg database.CreateFeedParams) (database.Feed, error) {
+	row, err := s.q.CreateFeed(ctx, CreateFeedParams(arg))
+	return database.Feed(row), err
+}
+
+// CreateFeedFollow inserts the follow and reads it back with the user and
+// feed names, which the Postgres query does in one statement.
+func (s *Store) CreateFeedFollow(ctx context.Context, arg database.CreateFeedFollowParams) (database.CreateFeedFollowRow, error) {
+	if err := s.q.CreateFeedFollow(ctx, CreateFeedFollowParams(arg)); err != nil {
+		return database.CreateFeedFollowRow{}, err
+	}
+	row, err := s.q.GetFeedFollowWithNames(ctx, arg.ID)
+	return database.CreateFeedFollowRow(row), err
+}
+
+func (s *Store) CreateFilterRule(ctx context.Context, arg database.CreateFilterRuleParams) (database.FilterRule, error) {
+	row, err := s.q.CreateFilterRule(ctx, CreateFilterRuleParams(arg))
+	return database.FilterRule(row), err
+}
+
+func (s *Store) CreateFolder(ctx context.Context, arg database.CreateFolderParams) (database.Folder, error) {
+	row, err := s.q.CreateFolder(ctx, CreateFolderParams(arg))
+	return database.Folder(row), err
+}
+
+func (s *Store) CreateNotificationRule(ctx context.Context, arg database.CreateNotificationRuleParams) (database.NotificationRule, error) {
+	row, err := s.q.CreateNotificationRule(ctx, CreateNotificationRuleParams(arg))
+	return database.NotificationRule(row), err
+}
+
+func (s *Store) CreatePost(ctx context.Context, arg database.CreatePostParams) (database.Post, error) {
+	row, err := s.q.CreatePost(ctx, CreatePostParams{
+		ID:          arg.ID,
+		CreatedAt:   arg.CreatedAt,
+		UpdatedAt:   arg.UpdatedAt,
+		Title:       arg.Title,
+		Url:         arg.Url,
+		Description: arg.Description,
+		PublishedAt: arg.PublishedAt,
+		FeedID:      arg.FeedID,
+		Author:      arg.Author,
+		Categories:  encodeList(arg.Categories),
+	})
+	return toPost(row), err
+}
+
+func (s *Store) CreateUser(ctx context.Context, arg database.CreateUserParams) (database.User, error) {
+	row, err := s.q.CreateUser(ctx, CreateUserParams(arg))
+	return database.User(row), err
+}
+
+func (s *Store) DeactivateFeed(ctx context.Context, arg database.DeactivateFeedParams) error {
+	return s.q.DeactivateFeed(ctx, DeactivateFeedParams{
+		ID:            arg.ID,
+		DeactivatedAt: arg.DeactivatedAt,
+	})
+}
+
+func (s *Store) DelFeedFollow(ctx context.Context, arg database.DelFeedFollowParams) (int64, error) {
+	return s.q.DelFeedFollow(ctx, DelFeedFollowParams(arg))
+}
+
+func (s *Store) DelUsers(ctx context.Context) error {
+	return s.q.DelUsers(ctx)
+}
+
+func (s *Store) DeleteDigestSettings(ctx context.Context, userID uuid.UUID) (int64, error) {
+	return s.q.DeleteDigestSettings(ctx, userID)
+}
+
+func (s *Store) DeleteFeed(ctx context.Context, id uuid.UUID) error {
+	return s.q.DeleteFeed(ctx, id)
+}
+
+func (s *Store) DeleteFilterRule(ctx context.Context, arg database.DeleteFilterRuleParams) (int64, error) {
+	return s.q.DeleteFilterRule(ctx, DeleteFilterRuleParams(arg))
+}
+
+func (s *Store) DeleteFolder(ctx context.Context, arg database.DeleteFolderParams) (int64, error) {
+	return s.q.DeleteFolder(ctx, DeleteFolderParams(arg))
+}
+
+func (s *Store) DeleteNotificationRule(ctx context.Context, arg database.DeleteNotificationRuleParams) (int64, error) {
+	return s.q.DeleteNotificationRule(ctx, DeleteNotificationRuleParams(arg))
+}
+
+func (s *Store) DeletePost(ctx context.Context, id uuid.UUID) error {
+	return s.q.DeletePost(ctx, id)
+}
+
+func (s *Store) DeletePostsBefore(ctx context.Context, before time.Time) (int64, error) {
+	return s.q.DeletePostsBefore(ctx, validTime(before))
+}
+
+func (s *Store) DeletePostsByID(ctx context.Context, ids []uuid.UUID) (int64, error) {
+	return s.q.DeletePostsByID(ctx, ids)
+}
+
+func (s *Store) DeleteUser(ctx context.Context, id uuid.UUID) (int64, error) {
+	return s.q.DeleteUser(ctx, id)
+}
+
+func (s *Store) FailOrphanedNotifications(ctx context.Context, arg database.FailOrphanedNotificationsParams) (int64, error) {
+	return s.q.FailOrphanedNotifications(ctx, FailOrphanedNotificationsParams{
+		MaxAttempts: int64(arg.MaxAttempts),
+		LastError:   arg.LastError,
+	})
+}
+
+func (s *Store) ForgetPrunedPosts(ctx context.Context, arg database.ForgetPrunedPostsParams) (int64, error) {
+	return s.q.ForgetPrunedPosts(ctx, ForgetPrunedPostsParams(arg))
+}
+
+func (s *Store) GetAllPostStates(ctx context.Context) ([]database.PostState, error) {
+	rows, err := s.q.GetAllPostStates(ctx)
+	return convertAll(rows, func(r PostState) database.PostState { return database.PostState(r) }), err
+}
+
+func (s *Store) GetAllPostTags(ctx context.Context) ([]database.PostTag, error) {
+	rows, err := s.q.GetAllPostTags(ctx)
+	return convertAll(rows, func(r PostTag) database.PostTag { return database.PostTag(r) }), err
+}
+
+func (s *Store) GetAllPostsForUser(ctx context.Context, userID uuid.UUID) ([]database.Post, error) {
+	rows, err := s.q.GetAllPostsForUser(ctx, userID)
+	return convertAll(rows, toPost), err
+}
+
+func (s *Store) GetDigestPosts(ctx context.Context, arg database.GetDigestPostsParams) ([]database.GetDigestPostsRow, error) {
+	rows, err := s.q.GetDigestPosts(ctx, GetDigestPostsParams{
+		UserID:   arg.UserID,
+		Since:    arg.Since,
+		Until:    arg.Until,
+		Folder:   arg.Folder,
+		Tag:      arg.Tag,
+		MaxPosts: int64(arg.MaxPosts),
+	})
+	return convertAll(rows, toGetDigestPostsRow), err
+}
+
+func (s *Store) GetDigestSettings(ctx context.Context, userID uuid.UUID) (database.DigestSetting, error) {
+	row, err := s.q.GetDigestSettings(ctx, userID)
+	return database.DigestSetting(row), err
+}
+
+func (s *Store) GetDueDigests(ctx context.Context, cutoff time.Time) ([]database.GetDueDigestsRow, error) {
+	rows, err := s.q.GetDueDigests(ctx, validTime(cutoff))
+	return convertAll(rows, toGetDueDigestsRow), err
+}
+
+func (s *Store) GetDueNotifications(ctx context.Context, arg database.GetDueNotificationsParams) ([]database.GetDueNotificationsRow, error) {
+	rows, err := s.q.GetDueNotifications(ctx, GetDueNotificationsParams{
+		MaxAttempts:   int64(arg.MaxAttempts),
+		Now:           arg.Now,
+		MaxDeliveries: int64(arg.MaxDeliveries),
+	})
+	return convertAll(rows, func(r GetDueNotificationsRow) database.GetDueNotificationsRow {
+		return database.GetDueNotificationsRow(r)
+	}), err
+}
+
+func (s *Store) GetEarliestNextFetch(ctx context.Context) (sql.NullTime, error) {
+	return s.q.GetEarliestNextFetch(ctx)
+}
+
+func (s *Store) GetFailedNotificationsForUser(ctx context.Context, arg database.GetFailedNotificationsForUserParams) ([]database.NotificationDelivery, error) {
+	rows, err := s.q.GetFailedNotificationsForUser(ctx, GetFailedNotificationsForUserParams{
+		UserID:      arg.UserID,
+		MaxAttempts: int64(arg.MaxAttempts),
+	})
+	return convertAll(rows, func(r NotificationDelivery) database.NotificationDelivery {
+		return database.NotificationDelivery(r)
+	}), err
+}
+
+func (s *Store) GetFaviconCheckedAt(ctx context.Context, feedID uuid.UUID) (time.Time, error) {
+	return s.q.GetFaviconCheckedAt(ctx, feedID)
+}
+
+func (s *Store) GetFeedByID(ctx context.Context, id uuid.UUID) (database.Feed, error) {
+	row, err := s.q.GetFeedByID(ctx, id)
+	return database.Feed(row), err
+}
+
+func (s *Store) GetFeedByPreviousURL(ctx context.Context, url string) (database.Feed, error) {
+	row, err := s.q.GetFeedByPreviousURL(ctx, url)
+	return database.Feed(row), err
+}
+
+func (s *Store) GetFeedByURL(ctx context.Context, url string) (database.Feed, error) {
+	row, err := s.q.GetFeedByURL(ctx, url)
+	return database.Feed(row), err
+}
+
+func (s *Store) GetFeedFollow(ctx context.Context, arg database.GetFeedFollowParams) (database.FeedFollow, error) {
+	row, err := s.q.GetFeedFollow(ctx, GetFeedFollowParams(arg))
+	return database.FeedFollow(row), err
+}
+
+func (s *Store) GetFeedFollowers(ctx context.Context, feedID uuid.UUID) ([]database.User, error) {
+	rows, err := s.q.GetFeedFollowers(ctx, feedID)
+	return convertAll(rows, func(r User) database.User { return database.User(r) }), err
+}
+
+func (s *Store) GetFeedFollowsForUser(ctx context.Context, arg database.GetFeedFollowsForUserParams) ([]database.GetFeedFollowsForUserRow, error) {
+	rows, err := s.q.GetFeedFollowsForUser(ctx, GetFeedFollowsForUserParams(arg))
+	return convertAll(rows, toGetFeedFollowsForUserRow), err
+}
+
+func (s *Store) GetFeedURLHistory(ctx context.Context, feedID uuid.UUID) ([]database.FeedUrlHistory, error) {
+	rows, err := s.q.GetFeedURLHistory(ctx, feedID)
+	return convertAll(rows, func(r FeedUrlHistory) database.FeedUrlHistory { return database.FeedUrlHistory(r) }), err
+}
+
+func (s *Store) GetFeeds(ctx context.Context) ([]database.Feed, error) {
+	rows, err := s.q.GetFeeds(ctx)
+	return convertAll(rows, func(r Feed) database.Feed { return database.Feed(r) }), err
+}
+
+func (s *Store) GetFeedsByOwner(ctx context.Context, userID uuid.UUID) ([]database.Feed, error) {
+	rows, err := s.q.GetFeedsByOwner(ctx, userID)
+	return convertAll(rows, func(r Feed) database.Feed { return database.Feed(r) }), err
+}
+
+func (s *Store) GetFeedsWithUser(ctx context.Context) ([]database.GetFeedsWithUserRow, error) {
+	rows, err := s.q.GetFeedsWithUser(ctx)
+	return convertAll(rows, func(r GetFeedsWithUserRow) database.GetFeedsWithUserRow { return database.GetFeedsWithUserRow(r) }), err
+}
+
+func (s *Store) GetFeverFavicons(ctx context.Context, userID uuid.UUID) ([]database.GetFeverFaviconsRow, error) {
+	rows, err := s.q.GetFeverFavicons(ctx, userID)
+	return convertAll(rows, func(r GetFeverFaviconsRow) database.GetFeverFaviconsRow { return database.GetFeverFaviconsRow(r) }), err
+}
+
+func (s *Store) GetFeverFeeds(ctx context.Context, userID uuid.UUID) ([]database.GetFeverFeedsRow, error) {
+	rows, err := s.q.GetFeverFeeds(ctx, userID)
+	return convertAll(rows, func(r GetFeverFeedsRow) database.GetFeverFeedsRow { return database.GetFeverFeedsRow(r) }), err
+}
+
+func (s *Store) GetFeverGroups(ctx context.Context, userID uuid.UUID) ([]database.GetFeverGroupsRow, error) {
+	rows, err := s.q.GetFeverGroups(ctx, userID)
+	return convertAll(rows, func(r GetFeverGroupsRow) database.GetFeverGroupsRow { return database.GetFeverGroupsRow(r) }), err
+}
+
+func (s *Store) GetFeverItemsAfter(ctx context.Context, arg database.GetFeverItemsAfterParams) ([]database.GetFeverItemsAfterRow, error) {
+	rows, err := s.q.GetFeverItemsAfter(ctx, GetFeverItemsAfterParams{
+		UserID:   arg.UserID,
+		SinceID:  arg.SinceID,
+		MaxItems: int64(arg.MaxItems),
+	})
+	return convertAll(rows, func(r GetFeverItemsAfterRow) database.GetFeverItemsAfterRow { return database.GetFeverItemsAfterRow(r) }), err
+}
+
+func (s *Store) GetFeverItemsBefore(ctx context.Context, arg database.GetFeverItemsBeforeParams) ([]database.GetFeverItemsBeforeRow, error) {
+	rows, err := s.q.GetFeverItemsBefore(ctx, GetFeverItemsBeforeParams{
+		UserID:   arg.UserID,
+		MaxID:    arg.MaxID,
+		MaxItems: int64(arg.MaxItems),
+	})
+	return convertAll(rows, func(r GetFeverItemsBeforeRow) database.GetFeverItemsBeforeRow {
+		return database.GetFeverItemsBeforeRow(r)
+	}), err
+}
+
+func (s *Store) GetFeverItemsByID(ctx context.Context, arg database.GetFeverItemsByIDParams) ([]database.GetFeverItemsByIDRow, error) {
+	rows, err := s.q.GetFeverItemsByID(ctx, GetFeverItemsByIDParams(arg))
+	return convertAll(rows, func(r GetFeverItemsByIDRow) database.GetFeverItemsByIDRow { return database.GetFeverItemsByIDRow(r) }), err
+}
+
+func (s *Store) GetFeverLastRefreshed(ctx context.Context, userID uuid.UUID) (sql.NullTime, error) {
+	return s.q.GetFeverLastRefreshed(ctx, userID)
+}
+
+func (s *Store) GetFeverPostID(ctx context.Context, arg database.GetFeverPostIDParams) (uuid.UUID, error) {
+	return s.q.GetFeverPostID(ctx, GetFeverPostIDParams(arg))
+}
+
+func (s *Store) GetFeverSavedItemIDs(ctx context.Context, userID uuid.UUID) ([]int64, error) {
+	return s.q.GetFeverSavedItemIDs(ctx, userID)
+}
+
+func (s *Store) GetFeverUnreadItemIDs(ctx context.Context, userID uuid.UUID) ([]int64, error) {
+	return s.q.GetFeverUnreadItemIDs(ctx, userID)
+}
+
+func (s *Store) GetFilterRulesForFeed(ctx context.Context, feedID uuid.UUID) ([]database.FilterRule, error) {
+	rows, err := s.q.GetFilterRulesForFeed(ctx, feedID)
+	return convertAll(rows, func(r FilterRule) database.FilterRule { return database.FilterRule(r) }), err
+}
+
+func (s *Store) GetFilterRulesForUser(ctx context.Context, userID uuid.UUID) ([]database.GetFilterRulesForUserRow, error) {
+	rows, err := s.q.GetFilterRulesForUser(ctx, userID)
+	return convertAll(rows, func(r GetFilterRulesForUserRow) database.GetFilterRulesForUserRow {
+		return database.GetFilterRulesForUserRow(r)
+	}), err
+}
+
+func (s *Store) GetFolderByName(ctx context.Context, arg database.GetFolderByNameParams) (database.Folder, error) {
+	row, err := s.q.GetFolderByName(ctx, GetFolderByNameParams(arg))
+	return database.Folder(row), err
+}
+
+func (s *Store) GetFoldersForUser(ctx context.Context, userID uuid.UUID) ([]database.GetFoldersForUserRow, error) {
+	rows, err := s.q.GetFoldersForUser(ctx, userID)
+	return convertAll(rows, func(r GetFoldersForUserRow) database.GetFoldersForUserRow { return database.GetFoldersForUserRow(r) }), err
+}
+
+func (s *Store) GetNextFeedsToFetch(ctx context.Context, arg database.GetNextFeedsToFetchParams) ([]database.Feed, error) {
+	rows, err := s.q.GetNextFeedsToFetch(ctx, GetNextFeedsToFetchParams{
+		Now:      validTime(arg.Now),
+		MaxFeeds: int64(arg.MaxFeeds),
+	})
+	return convertAll(rows, func(r Feed) database.Feed { return database.Feed(r) }), err
+}
+
+func (s *Store) GetNotificationRulesForFeed(ctx context.Context, feedID uuid.UUID) ([]database.NotificationRule, error) {
+	rows, err := s.q.GetNotificationRulesForFeed(ctx, feedID)
+	return convertAll(rows, func(r NotificationRule) database.NotificationRule { return database.NotificationRule(r) }), err
+}
+
+func (s *Store) GetNotificationRulesForUser(ctx context.Context, userID uuid.UUID) ([]database.GetNotificationRulesForUserRow, error) {
+	rows, err := s.q.GetNotificationRulesForUser(ctx, userID)
+	return convertAll(rows, func(r GetNotificationRulesForUserRow) database.GetNotificationRulesForUserRow {
+		return database.GetNotificationRulesForUserRow(r)
+	}), err
+}
+
+func (s *Store) GetPostByURL(ctx context.Context, url string) (database.Post, error) {
+	row, err := s.q.GetPostByURL(ctx, url)
+	return toPost(row), err
+}
+
+func (s *Store) GetPostURLs(ctx context.Context) ([]database.GetPostURLsRow, error) {
+	rows, err := s.q.GetPostURLs(ctx)
+	return convertAll(rows, func(r GetPostURLsRow) database.GetPostURLsRow { return database.GetPostURLsRow(r) }), err
+}
+
+func (s *Store) GetPostsAfter(ctx context.Context, arg database.GetPostsAfterParams) ([]database.Post, error) {
+	rows, err := s.q.GetPostsAfter(ctx, GetPostsAfterParams{
+		After:    arg.After,
+		MaxPosts: int64(arg.MaxPosts),
+	})
+	return convertAll(rows, toPost), err
+}
+
+func (s *Store) GetPostsForUser(ctx context.Context, arg database.GetPostsForUserParams) ([]database.GetPostsForUserRow, error) {
+	rows, err := s.q.GetPostsForUser(ctx, GetPostsForUserParams{
+		UserID:   arg.UserID,
+		Folder:   arg.Folder,
+		Tag:      arg.Tag,
+		Skip:     int64(arg.Skip),
+		MaxPosts: int64(arg.MaxPosts),
+	})
+	return convertAll(rows, toGetPostsForUserRow), err
+}
+
+func (s *Store) GetPrunablePosts(ctx context.Context, arg database.GetPrunablePostsParams) ([]uuid.UUID, error) {
+	return s.q.GetPrunablePosts(ctx, GetPrunablePostsParams{
+		FeedID:      arg.FeedID,
+		Cutoff:      arg.Cutoff,
+		MaxPosts:    int64(arg.MaxPosts),
+		KeepStarred: arg.KeepStarred,
+		KeepUnread:  arg.KeepUnread,
+	})
+}
+
+func (s *Store) GetRecentPostTimes(ctx context.Context, arg database.GetRecentPostTimesParams) ([]time.Time, error) {
+	rows, err := s.q.GetRecentPostTimes(ctx, GetRecentPostTimesParams{
+		FeedID: arg.FeedID,
+		Limit:  int64(arg.Limit),
+	})
+	return convertAll(rows, func(r GetRecentPostTimesRow) time.Time {
+		if r.PublishedAt.Valid {
+			return r.PublishedAt.Time
+		}
+		return r.CreatedAt
+	}), err
+}
+
+func (s *Store) GetTagsForUser(ctx context.Context, userID uuid.UUID) ([]database.GetTagsForUserRow, error) {
+	rows, err := s.q.GetTagsForUser(ctx, userID)
+	return convertAll(rows, func(r GetTagsForUserRow) database.GetTagsForUserRow { return database.GetTagsForUserRow(r) }), err
+}
+
+func (s *Store) GetUser(ctx context.Context, name string) (database.User, error) {
+	row, err := s.q.GetUser(ctx, name)
+	return database.User(row), err
+}
+
+func (s *Store) GetUserByFeverAPIKey(ctx context.Context, feverApiKey sql.NullString) (database.User, error) {
+	row, err := s.q.GetUserByFeverAPIKey(ctx, feverApiKey)
+	return database.User(row), err
+}
+
+func (s *Store) GetUserStats(ctx context.Context) ([]database.GetUserStatsRow, error) {
+	rows, err := s.q.GetUserStats(ctx)
+	return convertAll(rows, toGetUserStatsRow), err
+}
+
+func (s *Store) GetUsers(ctx context.Context) ([]database.User, error) {
+	rows, err := s.q.GetUsers(ctx)
+	return convertAll(rows, func(r User) database.User { return database.User(r) }), err
+}
+
+func (s *Store) GetWebSubSubscription(ctx context.Context, id uuid.UUID) (database.WebsubSubscription, error) {
+	row, err := s.q.GetWebSubSubscription(ctx, id)
+	return database.WebsubSubscription(row), err
+}
+
+func (s *Store) GetWebSubSubscriptionForFeed(ctx context.Context, feedID uuid.UUID) (database.WebsubSubscription, error) {
+	row, err := s.q.GetWebSubSubscriptionForFeed(ctx, feedID)
+	return database.WebsubSubscription(row), err
+}
+
+func (s *Store) GetWebSubSubscriptionsToRenew(ctx context.Context, before time.Time) ([]database.WebsubSubscription, error) {
+	rows, err := s.q.GetWebSubSubscriptionsToRenew(ctx, validTime(before))
+	return convertAll(rows, func(r WebsubSubscription) database.WebsubSubscription { return database.WebsubSubscription(r) }), err
+}
+
+func (s *Store) LockUsers(ctx context.Context) error {
+	return s.q.LockUsers(ctx)
+}
+
+func (s *Store) MarkFeedFetched(ctx context.Context, arg database.MarkFeedFetchedParams) (database.Feed, error) {
+	row, err := s.q.MarkFeedFetched(ctx, MarkFeedFetchedParams{
+		ID:            arg.ID,
+		LastFetchedAt: arg.LastFetchedAt,
+	})
+	return database.Feed(row), err
+}
+
+func (s *Store) MarkFeverFeedRead(ctx context.Context, arg database.MarkFeverFeedReadParams) (int64, error) {
+	return s.q.MarkFeverFeedRead(ctx, MarkFeverFeedReadParams{
+		ReadAt: validTime(arg.ReadAt),
+		UserID: arg.UserID,
+		FeedID: arg.FeedID,
+		Before: arg.Before,
+	})
+}
+
+func (s *Store) MarkFeverGroupRead(ctx context.Context, arg database.MarkFeverGroupReadParams) (int64, error) {
+	return s.q.MarkFeverGroupRead(ctx, MarkFeverGroupReadParams{
+		ReadAt:  validTime(arg.ReadAt),
+		UserID:  arg.UserID,
+		GroupID: arg.GroupID,
+		Before:  arg.Before,
+	})
+}
+
+func (s *Store) MarkFeverSuperGroupRead(ctx context.Context, arg database.MarkFeverSuperGroupReadParams) (int64, error) {
+	return s.q.MarkFeverSuperGroupRead(ctx, MarkFeverSuperGroupReadParams{
+		ReadAt: validTime(arg.ReadAt),
+		UserID: arg.UserID,
+		Sparks: arg.Sparks,
+		Before: arg.Before,
+	})
+}
+
+func (s *Store) MarkNotificationDelivered(ctx context.Context, arg database.MarkNotificationDeliveredParams) error {
+	return s.q.MarkNotificationDelivered(ctx, MarkNotificationDeliveredParams{
+		ID:          arg.ID,
+		DeliveredAt: arg.DeliveredAt,
+	})
+}
+
+func (s *Store) MarkNotificationFailed(ctx context.Context, arg database.MarkNotificationFailedParams) error {
+	return s.q.MarkNotificationFailed(ctx, MarkNotificationFailedParams{
+		ID:            arg.ID,
+		NextAttemptAt: arg.NextAttemptAt,
+		LastError:     arg.LastError,
+	})
+}
+
+func (s *Store) MergeFeedFollowSettings(ctx context.Context, arg database.MergeFeedFollowSettingsParams) error {
+	return s.q.MergeFeedFollowSettings(ctx, MergeFeedFollowSettingsParams{
+		FromFeedID: arg.FromFeedID,
+		UpdatedAt:  arg.UpdatedAt,
+		ToFeedID:   arg.ToFeedID,
+	})
+}
+
+func (s *Store) MergeFeedFollowTags(ctx context.Context, arg database.MergeFeedFollowTagsParams) error {
+	return s.q.MergeFeedFollowTags(ctx, MergeFeedFollowTagsParams(arg))
+}
+
+func (s *Store) MergeFeedRetention(ctx context.Context, arg database.MergeFeedRetentionParams) error {
+	return s.q.MergeFeedRetention(ctx, MergeFeedRetentionParams{
+		FromFeedID: arg.FromFeedID,
+		UpdatedAt:  arg.UpdatedAt,
+		ToFeedID:   arg.ToFeedID,
+	})
+}
+
+func (s *Store) MoveFeedFollows(ctx context.Context, arg database.MoveFeedFollowsParams) error {
+	return s.q.MoveFeedFollows(ctx, MoveFeedFollowsParams(arg))
+}
+
+func (s *Store) MoveFeedURLHistory(ctx context.Context, arg database.MoveFeedURLHistoryParams) error {
+	return s.q.MoveFeedURLHistory(ctx, MoveFeedURLHistoryParams(arg))
+}
+
+func (s *Store) MoveFilterRules(ctx context.Context, arg database.MoveFilterRulesParams) error {
+	return s.q.MoveFilterRules(ctx, MoveFilterRulesParams(arg))
+}
+
+func (s *Store) MoveNotificationRules(ctx context.Context, arg database.MoveNotificationRulesParams) error {
+	return s.q.MoveNotificationRules(ctx, MoveNotificationRulesParams(arg))
+}
+
+func (s *Store) MovePosts(ctx context.Context, arg database.MovePostsParams) error {
+	return s.q.MovePosts(ctx, MovePostsParams(arg))
+}
+
+func (s *Store) PostWasPruned(ctx context.Context, url string) (bool, error) {
+	return s.q.PostWasPruned(ctx, url)
+}
+
+func (s *Store) QueueNotification(ctx context.Context, arg database.QueueNotificationParams) error {
+	return s.q.QueueNotification(ctx, QueueNotificationParams(arg))
+}
+
+func (s *Store) RecordFeedFetchError(ctx context.Context, id uuid.UUID) (int32, error) {
+	return s.q.RecordFeedFetchError(ctx, id)
+}
+
+func (s *Store) RecordFeedRedirect(ctx context.Context, arg database.RecordFeedRedirectParams) (database.Feed, error) {
+	row, err := s.q.RecordFeedRedirect(ctx, RecordFeedRedirectParams(arg))
+	return database.Feed(row), err
+}
+
+func (s *Store) RecordPrunedPosts(ctx context.Context, arg database.RecordPrunedPostsParams) error {
+	return s.q.RecordPrunedPosts(ctx, RecordPrunedPostsParams(arg))
+}
+
+func (s *Store) RecordUserActivity(ctx context.Context, arg database.RecordUserActivityParams) error {
+	return s.q.RecordUserActivity(ctx, RecordUserActivityParams(arg))
+}
+
+func (s *Store) RegisterUser(ctx context.Context, arg database.RegisterUserParams) (database.User, error) {
+	row, err := s.q.RegisterUser(ctx, RegisterUserParams(arg))
+	return database.User(row), err
+}
+
+func (s *Store) RemoveFeedFollowTag(ctx context.Context, arg database.RemoveFeedFollowTagParams) (int64, error) {
+	return s.q.RemoveFeedFollowTag(ctx, RemoveFeedFollowTagParams(arg))
+}
+
+func (s *Store) RenameFeed(ctx context.Context, arg database.RenameFeedParams) error {
+	return s.q.RenameFeed(ctx, RenameFeedParams{
+		ID:        arg.ID,
+		Name:      arg.Name,
+		UpdatedAt: arg.UpdatedAt,
+	})
+}
+
+func (s *Store) RenameUser(ctx context.Context, arg database.RenameUserParams) (int64, error) {
+	return s.q.RenameUser(ctx, RenameUserParams(arg))
+}
+
+func (s *Store) ResetFeedFetchErrors(ctx context.Context, id uuid.UUID) error {
+	return s.q.ResetFeedFetchErrors(ctx, id)
+}
+
+func (s *Store) ResetFeedFetchState(ctx context.Context, arg database.ResetFeedFetchStateParams) error {
+	return s.q.ResetFeedFetchState(ctx, ResetFeedFetchStateParams{
+		ID:        arg.ID,
+		UpdatedAt: arg.UpdatedAt,
+	})
+}
+
+func (s *Store) RestorePostState(ctx context.Context, arg database.RestorePostStateParams) (int64, error) {
+	return s.q.RestorePostState(ctx, RestorePostStateParams(arg))
+}
+
+func (s *Store) SetFeedFollowFolder(ctx context.Context, arg database.SetFeedFollowFolderParams) (int64, error) {
+	return s.q.SetFeedFollowFolder(ctx, SetFeedFollowFolderParams{
+		UserID:    arg.UserID,
+		FeedID:    arg.FeedID,
+		FolderID:  arg.FolderID,
+		UpdatedAt: arg.UpdatedAt,
+	})
+}
+
+func (s *Store) SetFeedNextFetch(ctx context.Context, arg database.SetFeedNextFetchParams) error {
+	return s.q.SetFeedNextFetch(ctx, SetFeedNextFetchParams{
+		ID:          arg.ID,
+		NextFetchAt: arg.NextFetchAt,
+	})
+}
+
+func (s *Store) SetFeedOwner(ctx context.Context, arg database.SetFeedOwnerParams) error {
+	return s.q.SetFeedOwner(ctx, SetFeedOwnerParams{
+		ID:        arg.ID,
+		UserID:    arg.UserID,
+		UpdatedAt: arg.UpdatedAt,
+	})
+}
+
+func (s *Store) SetFeedRetention(ctx context.Context, arg database.SetFeedRetentionParams) error {
+	return s.q.SetFeedRetention(ctx, SetFeedRetentionParams{
+		ID:                     arg.ID,
+		RetentionMaxAgeSeconds: arg.RetentionMaxAgeSeconds,
+		RetentionMaxPosts:      arg.RetentionMaxPosts,
+		UpdatedAt:              arg.UpdatedAt,
+	})
+}
+
+func (s *Store) SetFeverAPIKey(ctx context.Context, arg database.SetFeverAPIKeyParams) (int64, error) {
+	return s.q.SetFeverAPIKey(ctx, SetFeverAPIKeyParams(arg))
+}
+
+func (s *Store) SetPostHidden(ctx context.Context, arg database.SetPostHiddenParams) error {
+	return s.q.SetPostHidden(ctx, SetPostHiddenParams(arg))
+}
+
+func (s *Store) SetPostRead(ctx context.Context, arg database.SetPostReadParams) error {
+	return s.q.SetPostRead(ctx, SetPostReadParams(arg))
+}
+
+func (s *Store) SetPostStarred(ctx context.Context, arg database.SetPostStarredParams) error {
+	return s.q.SetPostStarred(ctx, SetPostStarredParams(arg))
+}
+
+func (s *Store) SetUserDisabled(ctx context.Context, arg database.SetUserDisabledParams) (int64, error) {
+	return s.q.SetUserDisabled(ctx, SetUserDisabledParams(arg))
+}
+
+func (s *Store) SetUserRole(ctx context.Context, arg database.SetUserRoleParams) (int64, error) {
+	return s.q.SetUserRole(ctx, SetUserRoleParams(arg))
+}
+
+func (s *Store) SetWebSubSubscriptionState(ctx context.Context, arg database.SetWebSubSubscriptionStateParams) error {
+	return s.q.SetWebSubSubscriptionState(ctx, SetWebSubSubscriptionStateParams{
+		ID:        arg.ID,
+		State:     arg.State,
+		UpdatedAt: arg.UpdatedAt,
+	})
+}
+
+func (s *Store) UpdateFeedFollowSettings(ctx context.Context, arg database.UpdateFeedFollowSettingsParams) (int64, error) {
+	return s.q.UpdateFeedFollowSettings(ctx, UpdateFeedFollowSettingsParams{
+		UserID:    arg.UserID,
+		FeedID:    arg.FeedID,
+		Title:     arg.Title,
+		Muted:     arg.Muted,
+		Priority:  arg.Priority,
+		Notify:    arg.Notify,
+		UpdatedAt: arg.UpdatedAt,
+	})
+}
+
+func (s *Store) UpdateFeedURL(ctx context.Context, arg database.UpdateFeedURLParams) error {
+	return s.q.UpdateFeedURL(ctx, UpdateFeedURLParams{
+		ID:        arg.ID,
+		Url:       arg.Url,
+		UpdatedAt: arg.UpdatedAt,
+	})
+}
+
+func (s *Store) UpdatePostURL(ctx context.Context, arg database.UpdatePostURLParams) error {
+	return s.q.UpdatePostURL(ctx, UpdatePostURLParams{
+		ID:        arg.ID,
+		Url:       arg.Url,
+		UpdatedAt: arg.UpdatedAt,
+	})
+}
+
+func (s *Store) UpsertDigestSettings(ctx context.Context, arg database.UpsertDigestSettingsParams) (database.DigestSetting, error) {
+	row, err := s.q.UpsertDigestSettings(ctx, UpsertDigestSettingsParams(arg))
+	return database.DigestSetting(row), err
+}
+
+func (s *Store) UpsertFavicon(ctx context.Context, arg database.UpsertFaviconParams) error {
+	return s.q.UpsertFavicon(ctx, UpsertFaviconParams(arg))
+}
+
+func (s *Store) UpsertWebSubSubscription(ctx context.Context, arg database.UpsertWebSubSubscriptionParams) (database.WebsubSubscription, error) {
+	row, err := s.q.UpsertWebSubSubscription(ctx, UpsertWebSubSubscriptionParams(arg))
+	return database.WebsubSubscription(row), err
+}
@@ -0,0 +1,385 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: users.sql
+
+package sqlitedb
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const countActiveAdmins = `-- name: CountActiveAdmins :one
+SELECT COUNT(*) FROM users
+WHERE role = 'admin' AND disabled_at IS NULL
+`
+
+func (q *Queries) CountActiveAdmins(ctx context.Context) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countActiveAdmins)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countAllData = `-- name: CountAllData :one
+SELECT
+    (SELECT COUNT(*) FROM users) AS users,
+    (SELECT COUNT(*) FROM feeds) AS feeds,
+    (SELECT COUNT(*) FROM feed_follows) AS follows,
+    (SELECT COUNT(*) FROM posts) AS posts
+`
+
+type CountAllDataRow struct {
+	Users   int64
+	Feeds   int64
+	Follows int64
+	Posts   int64
+}
+
+func (q *Queries) CountAllData(ctx context.Context) (CountAllDataRow, error) {
+	row := q.db.QueryRowContext(ctx, countAllData)
+	var i CountAllDataRow
+	err := row.Scan(
+		&i.Users,
+		&i.Feeds,
+		&i.Follows,
+		&i.Posts,
+	)
+	return i, err
+}
+
+const countUserData = `-- name: CountUserData :one
+SELECT
+    (SELECT COUNT(*) FROM feeds WHERE feeds.user_id = ?1) AS feeds,
+    (SELECT COUNT(*) FROM feed_follows
+        WHERE feed_follows.user_id = ?1
+            OR feed_follows.feed_id IN (SELECT id FROM feeds WHERE feeds.user_id = ?1)) AS follows,
+    (SELECT COUNT(*) FROM posts
+        JOIN feeds ON posts.feed_id = feeds.id
+        WHERE feeds.user_id = ?1) AS posts
+`
+
+type CountUserDataRow struct {
+	Feeds   int64
+	Follows int64
+	Posts   int64
+}
+
+// Counts what deleting the user cascades to: the feeds they added, those
+// feeds' posts, and follows by or of them.
+func (q *Queries) CountUserData(ctx context.Context, userID uuid.UUID) (CountUserDataRow, error) {
+	row := q.db.QueryRowContext(ctx, countUserData, userID)
+	var i CountUserDataRow
+	err := row.Scan(&i.Feeds, &i.Follows, &i.Posts)
+	return i, err
+}
+
+const createUser = `-- name: CreateUser :one
+INSERT INTO users (id, created_at, updated_at, name, role)
+VALUES (?, ?, ?, ?, ?)
+RETURNING id, created_at, updated_at, name, role, disabled_at, last_active_at, fever_api_key
+`
+
+type CreateUserParams struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	Name      string
+	Role      string
+}
+
+func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, createUser,
+		arg.ID,
+		arg.CreatedAt,
+		arg.UpdatedAt,
+		arg.Name,
+		arg.Role,
+	)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Role,
+		&i.DisabledAt,
+		&i.LastActiveAt,
+		&i.FeverApiKey,
+	)
+	return i, err
+}
+
+const delUsers = `-- name: DelUsers :exec
+DELETE FROM users
+`
+
+func (q *Queries) DelUsers(ctx context.Context) error {
+	_, err := q.db.ExecContext(ctx, delUsers)
+	return err
+}
+
+const deleteUser = `-- name: DeleteUser :execrows
+DELETE FROM users WHERE id = ?
+`
+
+func (q *Queries) DeleteUser(ctx context.Context, id uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteUser, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getUser = `-- name: GetUser :one
+SELECT id, created_at, updated_at, name, role, disabled_at, last_active_at, fever_api_key FROM users
+WHERE name = ?
+`
+
+func (q *Queries) GetUser(ctx context.Context, name string) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUser, name)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Role,
+		&i.DisabledAt,
+		&i.LastActiveAt,
+		&i.FeverApiKey,
+	)
+	return i, err
+}
+
+const getUserStats = `-- name: GetUserStats :many
+SELECT
+    users.id, users.created_at, users.updated_at, users.name, users.role, users.disabled_at, users.last_active_at, users.fever_api_key,
+    (SELECT COUNT(*) FROM feed_follows WHERE feed_follows.user_id = users.id) AS follows,
+    (SELECT COUNT(*) FROM feeds WHERE feeds.user_id = users.id) AS feeds_created,
+    (SELECT COUNT(*) FROM posts
+        JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+        LEFT JOIN post_states ON post_states.post_id = posts.id
+            AND post_states.user_id = feed_follows.user_id
+        WHERE feed_follows.user_id = users.id
+            AND NOT feed_follows.muted
+            AND post_states.read_at IS NULL
+            AND post_states.hidden_at IS NULL) AS unread
+FROM users
+ORDER BY users.name
+`
+
+type GetUserStatsRow struct {
+	User         User
+	Follows      int64
+	FeedsCreated int64
+	Unread       int64
+}
+
+// Every user with how many feeds they follow and have added, and how many
+// posts in the feeds they haven't muted they have neither read nor hidden.
+func (q *Queries) GetUserStats(ctx context.Context) ([]GetUserStatsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getUserStats)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetUserStatsRow
+	for rows.Next() {
+		var i GetUserStatsRow
+		if err := rows.Scan(
+			&i.User.ID,
+			&i.User.CreatedAt,
+			&i.User.UpdatedAt,
+			&i.User.Name,
+			&i.User.Role,
+			&i.User.DisabledAt,
+			&i.User.LastActiveAt,
+			&i.User.FeverApiKey,
+			&i.Follows,
+			&i.FeedsCreated,
+			&i.Unread,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getUsers = `-- name: GetUsers :many
+SELECT id, created_at, updated_at, name, role, disabled_at, last_active_at, fever_api_key FROM users
+`
+
+func (q *Queries) GetUsers(ctx context.Context) ([]User, error) {
+	rows, err := q.db.QueryContext(ctx, getUsers)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []User
+	for rows.Next() {
+		var i User
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Name,
+			&i.Role,
+			&i.DisabledAt,
+			&i.LastActiveAt,
+			&i.FeverApiKey,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const lockUsers = `-- name: LockUsers :exec
+SELECT 1
+`
+
+// SQLite runs one write at a time and RegisterUser checks and inserts in a
+// single statement, so there is nothing to lock.
+func (q *Queries) LockUsers(ctx context.Context) error {
+	_, err := q.db.ExecContext(ctx, lockUsers)
+	return err
+}
+
+const recordUserActivity = `-- name: RecordUserActivity :exec
+UPDATE users
+SET last_active_at = ?1
+WHERE id = ?2 AND disabled_at IS NULL
+`
+
+type RecordUserActivityParams struct {
+	LastActiveAt sql.NullTime
+	ID           uuid.UUID
+}
+
+func (q *Queries) RecordUserActivity(ctx context.Context, arg RecordUserActivityParams) error {
+	_, err := q.db.ExecContext(ctx, recordUserActivity, arg.LastActiveAt, arg.ID)
+	return err
+}
+
+const registerUser = `-- name: RegisterUser :one
+INSERT INTO users (id, created_at, updated_at, name, role)
+SELECT
+    ?1,
+    ?2,
+    ?3,
+    ?4,
+    CASE WHEN EXISTS (SELECT 1 FROM users) THEN 'user' ELSE 'admin' END
+RETURNING id, created_at, updated_at, name, role, disabled_at, last_active_at, fever_api_key
+`
+
+type RegisterUserParams struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	Name      string
+}
+
+// Adds a user, as an admin if they are the first one.
+func (q *Queries) RegisterUser(ctx context.Context, arg RegisterUserParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, registerUser,
+		arg.ID,
+		arg.CreatedAt,
+		arg.UpdatedAt,
+		arg.Name,
+	)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Role,
+		&i.DisabledAt,
+		&i.LastActiveAt,
+		&i.FeverApiKey,
+	)
+	return i, err
+}
+
+const renameUser = `-- name: RenameUser :execrows
+UPDATE users
+SET name = ?1,
+updated_at = ?2
+WHERE id = ?3
+`
+
+type RenameUserParams struct {
+	Name      string
+	UpdatedAt time.Time
+	ID        uuid.UUID
+}
+
+func (q *Queries) RenameUser(ctx context.Context, arg RenameUserParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, renameUser, arg.Name, arg.UpdatedAt, arg.ID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const setUserDisabled = `-- name: SetUserDisabled :execrows
+UPDATE users
+SET disabled_at = ?1,
+updated_at = ?2
+WHERE id = ?3
+`
+
+type SetUserDisabledParams struct {
+	DisabledAt sql.NullTime
+	UpdatedAt  time.Time
+	ID         uuid.UUID
+}
+
+// Pass a NULL disabled_at to enable the user again.
+func (q *Queries) SetUserDisabled(ctx context.Context, arg SetUserDisabledParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, setUserDisabled, arg.DisabledAt, arg.UpdatedAt, arg.ID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const setUserRole = `-- name: SetUserRole :execrows
+UPDATE users
+SET role = ?1,
+updated_at = ?2
+WHERE id = ?3
+`
+
+type SetUserRoleParams struct {
+	Role      string
+	UpdatedAt time.Time
+	ID        uuid.UUID
+}
+
+func (q *Queries) SetUserRole(ctx context.Context, arg SetUserRoleParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, setUserRole, arg.Role, arg.UpdatedAt, arg.ID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
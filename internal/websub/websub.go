@@ -0,0 +1,292 @@
+// Package websub implements the subscriber side of WebSub (formerly
+// PubSubHubbub): asking a hub to push a topic to us, answering the hub's
+// verification of intent, and authenticating the content it distributes.
+package websub
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	ModeSubscribe   = "subscribe"
+	ModeUnsubscribe = "unsubscribe"
+	ModeDenied      = "denied"
+)
+
+// MaxContentBytes caps the size of a distributed content body.
+const MaxContentBytes = 10 << 20
+
+// ErrUnknownSubscription is returned by a Store for callbacks that don't
+// match any subscription.
+var ErrUnknownSubscription = errors.New("unknown subscription")
+
+// Request is a subscription request sent to a hub.
+type Request struct {
+	Hub      string
+	Topic    string
+	Callback string
+	Secret   string
+	// Lease is the requested lease; zero lets the hub decide.
+	Lease time.Duration
+}
+
+// Subscribe asks the hub to start pushing the topic to the callback. The
+// hub confirms asynchronously by calling the callback.
+func Subscribe(ctx context.Context, client *http.Client, r Request) error {
+	return send(ctx, client, ModeSubscribe, r)
+}
+
+// Unsubscribe asks the hub to stop pushing the topic to the callback.
+func Unsubscribe(ctx context.Context, client *http.Client, r Request) error {
+	return send(ctx, client, ModeUnsubscribe, r)
+}
+
+func send(ctx context.Context, client *http.Client, mode string, r Request) error {
+	form := url.Values{
+		"hub.mode":     {mode},
+		"hub.topic":    {r.Topic},
+		"hub.callback": {r.Callback},
+	}
+	if r.Secret != "" && mode == ModeSubscribe {
+		form.Set("hub.secret", r.Secret)
+	}
+	if r.Lease > 0 {
+		form.Set("hub.lease_seconds", strconv.Itoa(int(r.Lease.Seconds())))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.Hub, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusAccepted && resp.StatusCode != http.StatusNoContent {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("hub refused %s: %s: %s", mode, resp.Status, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+// NewSecret returns a random secret for signing distributed content.
+func NewSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// Subscription is what the callback handler needs to know about one of our
+// subscriptions.
+type Subscription struct {
+	ID     string
+	Topic  string
+	Secret string
+	// PendingSecret is the secret of a subscribe request the hub hasn't
+	// verified yet. Content signed with it is accepted too, since a hub may
+	// switch to it as soon as it verifies.
+	PendingSecret string
+	// Pending is the mode we are waiting for the hub to verify: subscribe
+	// while subscribing or renewing, unsubscribe while unsubscribing.
+	Pending string
+}
+
+// Store looks subscriptions up by callback ID and records the outcome of
+// verifications.
+type Store interface {
+	Lookup(ctx context.Context, id string) (Subscription, error)
+	// Verified is called once the hub has confirmed a subscribe or
+	// unsubscribe, or reported that a subscription was denied. lease is only
+	// set for subscribe.
+	Verified(ctx context.Context, id, mode string, lease time.Duration) error
+}
+
+// Handler serves subscription callbacks. Requests are routed by the last
+// path segment, which is the subscription ID.
+type Handler struct {
+	Store Store
+	// Deliver receives authenticated content pushed by the hub.
+	Deliver func(ctx context.Context, sub Subscription, contentType string, body []byte) error
+	// Logger reports problems that can't be returned to the hub. Optional.
+	Logger *slog.Logger
+}
+
+func (h *Handler) logError(msg, id string, err error) {
+	if h.Logger != nil {
+		h.Logger.Error(msg, "subscription_id", id, "error", err)
+	}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	id := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+	sub, err := h.Store.Lookup(r.Context(), id)
+	if errors.Is(err, ErrUnknownSubscription) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		h.logError("websub: couldn't look up subscription", id, err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		h.verify(w, r, sub)
+	case http.MethodPost:
+		h.distribute(w, r, sub)
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// verify answers the hub's verification of intent by echoing the challenge
+// when the request matches what we asked for.
+func (h *Handler) verify(w http.ResponseWriter, r *http.Request, sub Subscription) {
+	q := r.URL.Query()
+	mode := q.Get("hub.mode")
+	topic := q.Get("hub.topic")
+
+	if topic != sub.Topic {
+		http.NotFound(w, r)
+		return
+	}
+
+	switch mode {
+	case ModeDenied:
+		if err := h.Store.Verified(r.Context(), sub.ID, mode, 0); err != nil {
+			h.logError("websub: couldn't record denial", sub.ID, err)
+		}
+		w.WriteHeader(http.StatusOK)
+		return
+	case ModeSubscribe, ModeUnsubscribe:
+		if mode != sub.Pending {
+			http.NotFound(w, r)
+			return
+		}
+	default:
+		http.Error(w, "unknown hub.mode", http.StatusBadRequest)
+		return
+	}
+
+	challenge := q.Get("hub.challenge")
+	if challenge == "" {
+		http.Error(w, "missing hub.challenge", http.StatusBadRequest)
+		return
+	}
+
+	var lease time.Duration
+	if mode == ModeSubscribe {
+		seconds, err := strconv.Atoi(q.Get("hub.lease_seconds"))
+		if err != nil || seconds <= 0 {
+			http.Error(w, "invalid hub.lease_seconds", http.StatusBadRequest)
+			return
+		}
+		lease = time.Duration(seconds) * time.Second
+	}
+
+	if err := h.Store.Verified(r.Context(), sub.ID, mode, lease); err != nil {
+		h.logError("websub: couldn't record verification", sub.ID, err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain")
+	w.WriteHeader(http.StatusOK)
+	io.WriteString(w, challenge)
+}
+
+// distribute accepts content pushed by the hub. Content with a missing or
+// wrong signature is acknowledged but dropped, as the spec requires, so a
+// forger can't tell whether it guessed right.
+func (h *Handler) distribute(w http.ResponseWriter, r *http.Request, sub Subscription) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, MaxContentBytes+1))
+	if err != nil {
+		http.Error(w, "could not read body", http.StatusBadRequest)
+		return
+	}
+	if len(body) > MaxContentBytes {
+		http.Error(w, "content too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	if sub.Secret != "" {
+		if err := sub.verifySignature(r.Header.Get("X-Hub-Signature"), body); err != nil {
+			h.logError("websub: dropping unauthenticated content", sub.ID, err)
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+	}
+
+	if err := h.Deliver(r.Context(), sub, r.Header.Get("Content-Type"), body); err != nil {
+		h.logError("websub: couldn't deliver content", sub.ID, err)
+		http.Error(w, "could not process content", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// verifySignature checks content against the subscription's secret, then
+// its pending secret.
+func (sub Subscription) verifySignature(header string, body []byte) error {
+	err := VerifySignature(sub.Secret, header, body)
+	if err != nil && sub.PendingSecret != "" && sub.PendingSecret != sub.Secret {
+		if VerifySignature(sub.PendingSecret, header, body) == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+var signatureHashes = map[string]func() hash.Hash{
+	"sha1":   sha1.New,
+	"sha256": sha256.New,
+	"sha384": sha512.New384,
+	"sha512": sha512.New,
+}
+
+// VerifySignature checks an X-Hub-Signature header of the form
+// "method=hexdigest" against the HMAC of body under secret.
+func VerifySignature(secret, header string, body []byte) error {
+	method, digest, ok := strings.Cut(header, "=")
+	if !ok {
+		return errors.New("missing or malformed X-Hub-Signature")
+	}
+	newHash, ok := signatureHashes[strings.ToLower(method)]
+	if !ok {
+		return fmt.Errorf("unsupported signature method %q", method)
+	}
+	want, err := hex.DecodeString(digest)
+	if err != nil {
+		return errors.New("malformed signature digest")
+	}
+	mac := hmac.New(newHash, []byte(secret))
+	mac.Write(body)
+	if !hmac.Equal(mac.Sum(nil), want) {
+		return errors.New("signature mismatch")
+	}
+	return nil
+}
@@ -0,0 +1,286 @@
+package websub
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memStore is a Store that promotes a pending secret on verification, as
+// the subscriptions table does.
+type memStore struct {
+	mu       sync.Mutex
+	subs     map[string]*Subscription
+	verified []string
+	lease    time.Duration
+}
+
+func (m *memStore) Lookup(ctx context.Context, id string) (Subscription, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	sub, ok := m.subs[id]
+	if !ok {
+		return Subscription{}, ErrUnknownSubscription
+	}
+	return *sub, nil
+}
+
+func (m *memStore) Verified(ctx context.Context, id, mode string, lease time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.verified = append(m.verified, mode)
+	m.lease = lease
+	if sub := m.subs[id]; mode == ModeSubscribe && sub.PendingSecret != "" {
+		sub.Secret, sub.PendingSecret = sub.PendingSecret, ""
+	}
+	return nil
+}
+
+func sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+type delivery struct {
+	contentType string
+	body        string
+}
+
+func newCallback(t *testing.T, store *memStore) (*httptest.Server, *[]delivery) {
+	t.Helper()
+	var delivered []delivery
+	h := &Handler{
+		Store: store,
+		Deliver: func(ctx context.Context, sub Subscription, contentType string, body []byte) error {
+			delivered = append(delivered, delivery{contentType, string(body)})
+			return nil
+		},
+	}
+	srv := httptest.NewServer(h)
+	t.Cleanup(srv.Close)
+	return srv, &delivered
+}
+
+// TestSubscribe runs a subscription against a stand-in hub that verifies
+// intent and then pushes signed content, the way a real hub does.
+func TestSubscribe(t *testing.T) {
+	const (
+		topic   = "https://example.com/feed.xml"
+		content = "<rss><channel><title>pushed</title></channel></rss>"
+	)
+	store := &memStore{subs: map[string]*Subscription{
+		"sub1": {ID: "sub1", Topic: topic, Secret: "old", PendingSecret: "new", Pending: ModeSubscribe},
+	}}
+	callback, delivered := newCallback(t, store)
+
+	var hubErr error
+	hub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			hubErr = err
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if got := r.PostForm.Get("hub.mode"); got != ModeSubscribe {
+			t.Errorf("hub.mode = %q, want %q", got, ModeSubscribe)
+		}
+		if got := r.PostForm.Get("hub.lease_seconds"); got != "3600" {
+			t.Errorf("hub.lease_seconds = %q, want 3600", got)
+		}
+
+		q := url.Values{
+			"hub.mode":          {ModeSubscribe},
+			"hub.topic":         {r.PostForm.Get("hub.topic")},
+			"hub.challenge":     {"c4a11e"},
+			"hub.lease_seconds": {"3600"},
+		}
+		resp, err := http.Get(r.PostForm.Get("hub.callback") + "?" + q.Encode())
+		if err != nil {
+			hubErr = err
+			return
+		}
+		echo, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK || string(echo) != "c4a11e" {
+			t.Errorf("verification = %d %q, want 200 with the challenge", resp.StatusCode, echo)
+		}
+
+		req, _ := http.NewRequest(http.MethodPost, r.PostForm.Get("hub.callback"), strings.NewReader(content))
+		req.Header.Set("Content-Type", "application/rss+xml")
+		req.Header.Set("X-Hub-Signature", sign(r.PostForm.Get("hub.secret"), []byte(content)))
+		resp, err = http.DefaultClient.Do(req)
+		if err != nil {
+			hubErr = err
+			return
+		}
+		resp.Body.Close()
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer hub.Close()
+
+	err := Subscribe(context.Background(), hub.Client(), Request{
+		Hub:      hub.URL,
+		Topic:    topic,
+		Callback: callback.URL + "/websub/sub1",
+		Secret:   "new",
+		Lease:    time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+	if hubErr != nil {
+		t.Fatalf("hub: %v", hubErr)
+	}
+
+	if len(store.verified) != 1 || store.verified[0] != ModeSubscribe {
+		t.Errorf("verified = %v, want [subscribe]", store.verified)
+	}
+	if store.lease != time.Hour {
+		t.Errorf("lease = %v, want 1h", store.lease)
+	}
+	if got := store.subs["sub1"].Secret; got != "new" {
+		t.Errorf("secret after verification = %q, want the new one", got)
+	}
+	want := []delivery{{"application/rss+xml", content}}
+	if len(*delivered) != 1 || (*delivered)[0] != want[0] {
+		t.Errorf("delivered = %v, want %v", *delivered, want)
+	}
+}
+
+func TestSubscribeRefused(t *testing.T) {
+	hub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "no such topic", http.StatusBadRequest)
+	}))
+	defer hub.Close()
+
+	err := Subscribe(context.Background(), hub.Client(), Request{Hub: hub.URL, Topic: "t", Callback: "c"})
+	if err == nil || !strings.Contains(err.Error(), "no such topic") {
+		t.Errorf("Subscribe error = %v, want the hub's refusal", err)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	const topic = "https://example.com/feed.xml"
+	tests := []struct {
+		name     string
+		path     string
+		query    url.Values
+		status   int
+		verified []string
+	}{
+		{
+			name:     "subscribe",
+			path:     "/websub/sub1",
+			query:    url.Values{"hub.mode": {ModeSubscribe}, "hub.topic": {topic}, "hub.challenge": {"x"}, "hub.lease_seconds": {"60"}},
+			status:   http.StatusOK,
+			verified: []string{ModeSubscribe},
+		},
+		{
+			name:   "unknown subscription",
+			path:   "/websub/nope",
+			query:  url.Values{"hub.mode": {ModeSubscribe}, "hub.topic": {topic}, "hub.challenge": {"x"}, "hub.lease_seconds": {"60"}},
+			status: http.StatusNotFound,
+		},
+		{
+			name:   "wrong topic",
+			path:   "/websub/sub1",
+			query:  url.Values{"hub.mode": {ModeSubscribe}, "hub.topic": {"https://other.example/"}, "hub.challenge": {"x"}, "hub.lease_seconds": {"60"}},
+			status: http.StatusNotFound,
+		},
+		{
+			name:   "mode we didn't ask for",
+			path:   "/websub/sub1",
+			query:  url.Values{"hub.mode": {ModeUnsubscribe}, "hub.topic": {topic}, "hub.challenge": {"x"}},
+			status: http.StatusNotFound,
+		},
+		{
+			name:   "missing challenge",
+			path:   "/websub/sub1",
+			query:  url.Values{"hub.mode": {ModeSubscribe}, "hub.topic": {topic}, "hub.lease_seconds": {"60"}},
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "missing lease",
+			path:   "/websub/sub1",
+			query:  url.Values{"hub.mode": {ModeSubscribe}, "hub.topic": {topic}, "hub.challenge": {"x"}},
+			status: http.StatusBadRequest,
+		},
+		{
+			name:     "denied",
+			path:     "/websub/sub1",
+			query:    url.Values{"hub.mode": {ModeDenied}, "hub.topic": {topic}},
+			status:   http.StatusOK,
+			verified: []string{ModeDenied},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &memStore{subs: map[string]*Subscription{
+				"sub1": {ID: "sub1", Topic: topic, Secret: "s", Pending: ModeSubscribe},
+			}}
+			callback, _ := newCallback(t, store)
+
+			resp, err := http.Get(callback.URL + tt.path + "?" + tt.query.Encode())
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.status {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.status)
+			}
+			if strings.Join(store.verified, ",") != strings.Join(tt.verified, ",") {
+				t.Errorf("verified = %v, want %v", store.verified, tt.verified)
+			}
+		})
+	}
+}
+
+func TestDistribute(t *testing.T) {
+	const body = "<rss/>"
+	tests := []struct {
+		name      string
+		sub       Subscription
+		signature string
+		delivered bool
+	}{
+		{"current secret", Subscription{Secret: "old", PendingSecret: "new"}, sign("old", []byte(body)), true},
+		{"pending secret", Subscription{Secret: "old", PendingSecret: "new"}, sign("new", []byte(body)), true},
+		{"wrong secret", Subscription{Secret: "old", PendingSecret: "new"}, sign("guess", []byte(body)), false},
+		{"unsigned", Subscription{Secret: "old"}, "", false},
+		{"unsupported method", Subscription{Secret: "old"}, "md5=00", false},
+		{"no secret", Subscription{}, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.sub.ID = "sub1"
+			store := &memStore{subs: map[string]*Subscription{"sub1": &tt.sub}}
+			callback, delivered := newCallback(t, store)
+
+			req, _ := http.NewRequest(http.MethodPost, callback.URL+"/websub/sub1", strings.NewReader(body))
+			if tt.signature != "" {
+				req.Header.Set("X-Hub-Signature", tt.signature)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			// Dropped content is still acknowledged.
+			if resp.StatusCode != http.StatusAccepted {
+				t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusAccepted)
+			}
+			if got := len(*delivered) == 1; got != tt.delivered {
+				t.Errorf("delivered = %v, want %v", got, tt.delivered)
+			}
+		})
+	}
+}
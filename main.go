@@ -1,74 +1,649 @@
 package main
 
 import (
-	"database/sql"
+	"context"
+	"errors"
+	"flag"
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
+	"time"
 
 	_ "github.com/lib/pq"
 
 	"github.com/isaacjstriker/gatorapp/internal/config"
 	"github.com/isaacjstriker/gatorapp/internal/database"
+	"github.com/isaacjstriker/gatorapp/internal/logging"
+	"github.com/isaacjstriker/gatorapp/internal/metrics"
 )
 
 func main() {
-	cfg, err := config.Read()
+	os.Exit(run())
+}
+
+// run executes the command named on the command line and returns the
+// process exit code. It is separate from main so deferred cleanup runs
+// before the process exits.
+func run() int {
+	// Global flags come before the command name
+	globalFlags := flag.NewFlagSet("gatorapp", flag.ContinueOnError)
+	logLevel := globalFlags.String("log-level", "", "diagnostic log level: debug, info, warn or error")
+	logFormat := globalFlags.String("log-format", "", "diagnostic log format: text or json")
+	profile := globalFlags.String("profile", "", "config profile to use instead of the current one")
+
+	commands := newCommands(globalFlags)
+	registerCommands(commands)
+
+	globalFlags.Usage = func() { commands.printOverview(globalFlags.Output()) }
+	if err := globalFlags.Parse(os.Args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+
+	//Get command-line arguments passed in by the user
+	args := globalFlags.Args()
+	if len(args) < 1 {
+		commands.printOverview(os.Stderr)
+		return exitUsage
+	}
+	cmd := Command{
+		name: args[0],
+		args: args[1:],
+	}
+
+	// Check the command line before connecting to anything, so mistakes are
+	// reported even without a working config.
+	spec, cmd, err := commands.parse(cmd)
+	if errors.Is(err, flag.ErrHelp) {
+		return exitOK
+	}
 	if err != nil {
-		log.Fatalf("Error reading config: %v", err)
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		return exitCode(err)
 	}
 
-	// Open database connection
-	db, err := sql.Open("postgres", cfg.DbURL)
+	state := &State{}
+	if !spec.offline {
+		var closeState func()
+		state, closeState, err = openState(*logLevel, *logFormat, *profile, !spec.configOnly)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error: couldn't start:", err)
+			return exitCode(err)
+		}
+		defer closeState()
+	}
+
+	if err := spec.handler(state, cmd); err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		return exitCode(err)
+	}
+	return exitOK
+}
+
+// openState reads the config, sets up logging and, when connect is set,
+// connects to the database. The returned func closes the connection.
+func openState(logLevel, logFormat, profile string, connect bool) (*State, func(), error) {
+	cfg, err := config.Read(profile)
+	if errors.Is(err, config.ErrNoConfig) {
+		return nil, nil, fmt.Errorf("%w; run 'gatorapp init' to create one", err)
+	}
+	if profile != "" && errors.Is(err, config.ErrUnknownProfile) {
+		return nil, nil, &NotFoundError{Kind: "profile", Name: profile}
+	}
 	if err != nil {
-		log.Fatalf("Error opening database: %v", err)
+		return nil, nil, fmt.Errorf("couldn't read config: %w", err)
 	}
-	defer db.Close()
 
-	// Verify connection is valid
-	if err := db.Ping(); err != nil {
-		log.Fatalf("Error connecting to database: %v", err)
+	if logLevel == "" {
+		logLevel = cfg.Log.Level
+	}
+	if logFormat == "" {
+		logFormat = cfg.Log.Format
 	}
+	logger, err := logging.New(os.Stderr, logLevel, logFormat)
+	if err != nil {
+		return nil, nil, &UsageError{Usage: "gatorapp [global flags] <command> [arguments...]", Reason: "couldn't configure logging: " + err.Error()}
+	}
+	slog.SetDefault(logger)
 
-	queries := database.New(db)
+	// A current profile that no longer exists only stops commands that need
+	// its database, so the profile commands can still fix it.
+	if name := cfg.MissingProfile(); name != "" {
+		if connect {
+			return nil, nil, fmt.Errorf("current_profile: %w; switch with 'gatorapp profile use <name>'", &NotFoundError{Kind: "profile", Name: name})
+		}
+		slog.Warn("current profile doesn't exist, using the default profile", "profile", name)
+	}
 
-	state := &State{
-		Config:  cfg,
-		DB:      db,
-		Queries: queries,
+	if !connect {
+		return &State{Config: cfg}, func() {}, nil
 	}
 
-	commands := &Commands{
-		handlers: make(map[string]func(*State, Command) error),
+	// Open database connection
+	db, err := openDB(cfg.DbURL)
+	if err != nil {
+		return nil, nil, fmt.Errorf("couldn't open database: %w", err)
 	}
 
-	// Initialize commands
-	commands.register("login", handlerLogin)
-	commands.register("register", handlerRegister)
-	commands.register("reset", handlerReset)
-	commands.register("users", handlerUsers)
-	commands.register("agg", handlerAgg)
-	commands.register("addfeed", requireLogin(handlerAddFeed))
-	commands.register("feeds", handlerFeeds)
-	commands.register("follow", requireLogin(handlerFollow))
-	commands.register("following", requireLogin(handlerFollowing))
-	commands.register("unfollow", requireLogin(handlerUnfollow))
-	commands.register("browse", requireLogin(handlerBrowse))
+	// Verify connection is valid
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, nil, fmt.Errorf("couldn't connect to database: %w", err)
+	}
 
-	//Get command-line arguments passed in by the user
-	if len(os.Args) < 2 {
-		fmt.Println(err)
-		os.Exit(1)
+	var store database.Store
+	m := metrics.New(func() (int64, error) {
+		return store.CountOverdueFeeds(context.Background(), time.Now().UTC())
+	})
+	store, err = newStore(context.Background(), cfg.DbURL, db, m.WrapDB(db))
+	if err != nil {
+		db.Close()
+		return nil, nil, fmt.Errorf("couldn't set up database: %w", err)
 	}
-	cmdName := os.Args[1]
-	cmdArgs := os.Args[2:]
 
-	cmd := Command{
-		name: cmdName,
-		args: cmdArgs,
+	fetcher, err := NewFetcher(cfg.Fetcher, m)
+	if err != nil {
+		db.Close()
+		return nil, nil, fmt.Errorf("couldn't configure fetcher: %w", err)
 	}
 
-	if err := commands.run(state, cmd); err != nil {
-		fmt.Println("Error:", err)
+	state := &State{
+		Config:  cfg,
+		Queries: store,
+		Fetcher: fetcher,
+		Metrics: m,
 	}
+	return state, func() { db.Close() }, nil
+}
+
+// registerCommands defines every command gatorapp understands.
+func registerCommands(c *Commands) {
+	c.register(commandSpec{
+		name:        "help",
+		description: "Show the list of commands, or details about one command.",
+		usage:       "[command [subcommand]]",
+		maxArgs:     2,
+		handler:     handlerHelp(c),
+		completeArgs: func() []string {
+			return c.order
+		},
+		offline: true,
+	})
+	c.register(commandSpec{
+		name:         "completion",
+		description:  "Print a shell completion script for bash, zsh or fish.",
+		usage:        "<bash|zsh|fish>",
+		minArgs:      1,
+		maxArgs:      1,
+		handler:      handlerCompletion(c),
+		completeArgs: func() []string { return completionShells },
+		offline:      true,
+	})
+	c.register(commandSpec{
+		name:        "init",
+		description: "Create the config file, checking that the database can be reached.",
+		flags:       initFlags,
+		handler:     handlerInit,
+		offline:     true,
+	})
+	c.register(commandSpec{
+		name:        "profile",
+		description: "Switch between config profiles, each with its own database, user and fetcher settings.",
+		configOnly:  true,
+		subcommands: []commandSpec{
+			{
+				name:        "list",
+				description: "List the profiles, marking the one in use.",
+				handler:     handlerProfileList,
+			},
+			{
+				name:        "add",
+				description: "Add a profile.",
+				usage:       "<name>",
+				minArgs:     1,
+				maxArgs:     1,
+				flags:       addProfileFlags,
+				handler:     handlerProfileAdd,
+			},
+			{
+				name:        "use",
+				description: "Make a profile the current one. --profile picks one for a single command.",
+				usage:       "<name>",
+				minArgs:     1,
+				maxArgs:     1,
+				handler:     handlerProfileUse,
+			},
+		},
+	})
+	c.register(commandSpec{
+		name:        "register",
+		description: "Register a new user and log in as them.",
+		usage:       "<username>",
+		minArgs:     1,
+		maxArgs:     1,
+		handler:     handlerRegister,
+	})
+	c.register(commandSpec{
+		name:        "login",
+		description: "Log in as an existing user.",
+		usage:       "<username>",
+		minArgs:     1,
+		maxArgs:     1,
+		handler:     handlerLogin,
+	})
+	c.register(commandSpec{
+		name:        "users",
+		description: "List users with their follows, feeds, unread posts and last activity. Admins only.",
+		flags:       usersFlags,
+		handler:     requireAdmin(handlerUsers),
+	})
+	c.register(commandSpec{
+		name:        "deluser",
+		description: "Delete a user, along with the feeds they added and their follows. Admins only.",
+		usage:       "<username>",
+		minArgs:     1,
+		maxArgs:     1,
+		flags:       deleteUserFlags,
+		handler:     requireAdmin(handlerDeleteUser),
+	})
+	c.register(commandSpec{
+		name:        "admin",
+		description: "Manage users and feed ownership. Admins only.",
+		subcommands: []commandSpec{
+			{
+				name:        "rename",
+				description: "Rename a user.",
+				usage:       "<username> <new_name>",
+				minArgs:     2,
+				maxArgs:     2,
+				handler:     requireAdmin(handlerAdminRename),
+			},
+			{
+				name:        "disable",
+				description: "Stop a user from logging in, running commands and getting notifications. Their data is kept.",
+				usage:       "<username>",
+				minArgs:     1,
+				maxArgs:     1,
+				handler:     requireAdmin(handlerAdminDisable),
+			},
+			{
+				name:        "enable",
+				description: "Let a disabled user back in.",
+				usage:       "<username>",
+				minArgs:     1,
+				maxArgs:     1,
+				handler:     requireAdmin(handlerAdminEnable),
+			},
+			{
+				name:        "role",
+				description: "Make a user an admin or an ordinary user.",
+				usage:       "<username> <admin|user>",
+				minArgs:     2,
+				maxArgs:     2,
+				handler:     requireAdmin(handlerAdminRole),
+			},
+			{
+				name:        "transfer",
+				description: "Give a feed to another user, who follows it if they didn't already.",
+				usage:       "<feed_url> <username>",
+				minArgs:     2,
+				maxArgs:     2,
+				handler:     requireAdmin(handlerAdminTransfer),
+			},
+		},
+	})
+	c.register(commandSpec{
+		name:        "reset",
+		description: "Delete all users, feeds, follows and posts. Requires " + allowResetEnv + "=1. Admins only.",
+		flags:       destructiveFlags,
+		handler:     requireAdmin(handlerReset),
+	})
+	c.register(commandSpec{
+		name:        "backup",
+		description: "Write every user's feeds, follows, posts and settings to a compressed archive (- for stdout). Admins only.",
+		usage:       "<file>",
+		minArgs:     1,
+		maxArgs:     1,
+		handler:     requireAdmin(handlerBackup),
+	})
+	c.register(commandSpec{
+		name:        "restore",
+		description: "Add the contents of a backup archive (- for stdin), skipping what is already here. Admins only.",
+		usage:       "<file>",
+		minArgs:     1,
+		maxArgs:     1,
+		flags:       restoreFlags,
+		handler:     requireAdminUnlessEmpty(handlerRestore),
+	})
+	c.register(commandSpec{
+		name:        "addfeed",
+		description: "Add a feed and follow it as the current user.",
+		usage:       "<name> <url>",
+		minArgs:     2,
+		maxArgs:     2,
+		handler:     requireLogin(handlerAddFeed),
+	})
+	c.register(commandSpec{
+		name:        "feeds",
+		description: "List all feeds.",
+		handler:     handlerFeeds,
+	})
+	c.register(commandSpec{
+		name:        "editfeed",
+		description: "Rename a feed or correct its URL. Only the feed's owner or an admin may edit it.",
+		usage:       "<feed_url>",
+		minArgs:     1,
+		maxArgs:     1,
+		flags:       editFeedFlags,
+		handler:     requireLogin(handlerEditFeed),
+	})
+	c.register(commandSpec{
+		name:        "delfeed",
+		description: "Delete a feed, along with its posts and follows. Only the feed's owner or an admin may delete it.",
+		usage:       "<feed_url>",
+		minArgs:     1,
+		maxArgs:     1,
+		flags:       deleteFeedFlags,
+		handler:     requireLogin(handlerDeleteFeed),
+	})
+	c.register(commandSpec{
+		name:        "purge",
+		description: "Delete posts published before a date (2024-01-31) or RFC 3339 timestamp. Admins only.",
+		usage:       "<before>",
+		minArgs:     1,
+		maxArgs:     1,
+		flags:       destructiveFlags,
+		handler:     requireAdmin(handlerPurgePosts),
+	})
+	c.register(commandSpec{
+		name:        "prune",
+		description: "Delete posts past their feed's retention limits. Admins only.",
+		flags:       destructiveFlags,
+		handler:     requireAdmin(handlerPrune),
+	})
+	c.register(commandSpec{
+		name:        "retention",
+		description: "Show or set a feed's own retention limits, which override the configured ones.",
+		usage:       "<feed_url>",
+		minArgs:     1,
+		maxArgs:     1,
+		flags:       retentionFlags,
+		handler:     requireLogin(handlerRetention),
+	})
+	c.register(commandSpec{
+		name:        "follow",
+		description: "Follow a feed by its URL.",
+		usage:       "<feed_url>",
+		minArgs:     1,
+		maxArgs:     1,
+		handler:     requireLogin(handlerFollow),
+	})
+	c.register(commandSpec{
+		name:        "following",
+		description: "List the feeds the current user follows, by folder and with their tags.",
+		flags:       filterFlags,
+		handler:     requireLogin(handlerFollowing),
+	})
+	c.register(commandSpec{
+		name:        "editfollow",
+		description: "Show or change your settings for a followed feed: title, mute, priority and notifications.",
+		usage:       "<feed_url>",
+		minArgs:     1,
+		maxArgs:     1,
+		flags:       editFollowFlags,
+		handler:     requireLogin(handlerEditFollow),
+	})
+	c.register(commandSpec{
+		name:        "folder",
+		description: "Organize the feeds you follow into folders.",
+		subcommands: []commandSpec{
+			{
+				name:        "add",
+				description: "Create a folder.",
+				usage:       "<name>",
+				minArgs:     1,
+				maxArgs:     1,
+				handler:     requireLogin(handlerFolderAdd),
+			},
+			{
+				name:        "list",
+				description: "List your folders.",
+				handler:     requireLogin(handlerFolderList),
+			},
+			{
+				name:        "rm",
+				description: "Delete a folder. Its feeds stay followed, unfiled.",
+				usage:       "<name>",
+				minArgs:     1,
+				maxArgs:     1,
+				handler:     requireLogin(handlerFolderRemove),
+			},
+			{
+				name:        "move",
+				description: "Put a followed feed in a folder, or take it out of its folder if none is given.",
+				usage:       "<feed_url> [folder]",
+				minArgs:     1,
+				maxArgs:     2,
+				handler:     requireLogin(handlerFolderMove),
+			},
+		},
+	})
+	c.register(commandSpec{
+		name:        "tag",
+		description: "Label the feeds you follow with tags.",
+		subcommands: []commandSpec{
+			{
+				name:        "add",
+				description: "Tag a followed feed.",
+				usage:       "<feed_url> <tag>...",
+				minArgs:     2,
+				maxArgs:     -1,
+				handler:     requireLogin(handlerTagAdd),
+			},
+			{
+				name:        "rm",
+				description: "Remove tags from a followed feed.",
+				usage:       "<feed_url> <tag>...",
+				minArgs:     2,
+				maxArgs:     -1,
+				handler:     requireLogin(handlerTagRemove),
+			},
+			{
+				name:        "list",
+				description: "List your tags.",
+				handler:     requireLogin(handlerTagList),
+			},
+		},
+	})
+	c.register(commandSpec{
+		name:        "export",
+		description: "Write the feeds the current user follows to stdout as OPML.",
+		flags:       filterFlags,
+		handler:     requireLogin(handlerExport),
+	})
+	c.register(commandSpec{
+		name:        "unfollow",
+		description: "Stop following a feed by its URL.",
+		usage:       "<feed_url>",
+		minArgs:     1,
+		maxArgs:     1,
+		handler:     requireLogin(handlerUnfollow),
+	})
+	c.register(commandSpec{
+		name:        "browse",
+		description: "Show recent posts from the feeds the current user follows.",
+		usage:       "[limit]",
+		maxArgs:     1,
+		flags:       filterFlags,
+		handler:     requireLogin(handlerBrowse),
+	})
+	c.register(commandSpec{
+		name:        "star",
+		description: "Star a post for the current user. Starred posts are kept when pruning.",
+		usage:       "<post_url>",
+		minArgs:     1,
+		maxArgs:     1,
+		handler:     requireLogin(handlerSetStarred(true)),
+	})
+	c.register(commandSpec{
+		name:        "unstar",
+		description: "Unstar a post for the current user.",
+		usage:       "<post_url>",
+		minArgs:     1,
+		maxArgs:     1,
+		handler:     requireLogin(handlerSetStarred(false)),
+	})
+	c.register(commandSpec{
+		name:        "markread",
+		description: "Mark a post read for the current user.",
+		usage:       "<post_url>",
+		minArgs:     1,
+		maxArgs:     1,
+		handler:     requireLogin(handlerSetRead(true)),
+	})
+	c.register(commandSpec{
+		name:        "markunread",
+		description: "Mark a post unread for the current user.",
+		usage:       "<post_url>",
+		minArgs:     1,
+		maxArgs:     1,
+		handler:     requireLogin(handlerSetRead(false)),
+	})
+	c.register(commandSpec{
+		name:        "rule",
+		description: "Hide, mark read, star or tag posts whose title, description, author or category matches a pattern.",
+		subcommands: []commandSpec{
+			{
+				name:        "add",
+				description: "Add a filter rule. It applies to posts as you browse them.",
+				usage:       "<hide|read|star|tag> <pattern>",
+				minArgs:     2,
+				maxArgs:     2,
+				flags:       addRuleFlags,
+				handler:     requireLogin(handlerRuleAdd),
+			},
+			{
+				name:        "list",
+				description: "List your filter rules.",
+				handler:     requireLogin(handlerRuleList),
+			},
+			{
+				name:        "rm",
+				description: "Delete a filter rule by the ID shown in rule list.",
+				usage:       "<id>",
+				minArgs:     1,
+				maxArgs:     1,
+				handler:     requireLogin(handlerRuleRemove),
+			},
+			{
+				name:        "apply",
+				description: "Run your filter rules over the posts already saved, recording their results.",
+				flags:       applyRuleFlags,
+				handler:     requireLogin(handlerRuleApply),
+			},
+		},
+	})
+	c.register(commandSpec{
+		name:        "notify",
+		description: "Get notified through a configured sink when a feed or keyword gets a new post.",
+		subcommands: []commandSpec{
+			{
+				name:        "add",
+				description: "Watch a feed, posts matching a pattern, or both. Notifications are sent while agg runs.",
+				usage:       "[pattern]",
+				maxArgs:     1,
+				flags:       addNotifyFlags,
+				handler:     requireLogin(handlerNotifyAdd),
+			},
+			{
+				name:        "list",
+				description: "List your notifications and recently failed deliveries.",
+				handler:     requireLogin(handlerNotifyList),
+			},
+			{
+				name:        "rm",
+				description: "Delete a notification by the ID shown in notify list.",
+				usage:       "<id>",
+				minArgs:     1,
+				maxArgs:     1,
+				handler:     requireLogin(handlerNotifyRemove),
+			},
+			{
+				name:        "test",
+				description: "Send a sample notification to a sink to check its configuration.",
+				usage:       "<sink>",
+				minArgs:     1,
+				maxArgs:     1,
+				handler:     requireLogin(handlerNotifyTest),
+			},
+		},
+	})
+	c.register(commandSpec{
+		name:        "digest",
+		description: "Get unread posts emailed to you as a periodic digest, sent while agg runs.",
+		subcommands: []commandSpec{
+			{
+				name:        "enable",
+				description: "Turn digests on, or change their address or the feeds they cover.",
+				flags:       enableDigestFlags,
+				handler:     requireLogin(handlerDigestEnable),
+			},
+			{
+				name:        "disable",
+				description: "Turn digests off.",
+				handler:     requireLogin(handlerDigestDisable),
+			},
+			{
+				name:        "show",
+				description: "Show your digest settings and when the last digest was sent.",
+				handler:     requireLogin(handlerDigestShow),
+			},
+			{
+				name:        "send",
+				description: "Send your digest now instead of waiting for the next one.",
+				flags:       sendDigestFlags,
+				handler:     requireLogin(handlerDigestSend),
+			},
+		},
+	})
+	c.register(commandSpec{
+		name:        "fever",
+		description: "Let feed reader apps that speak the Fever API sync with your feeds, through serve or agg.",
+		subcommands: []commandSpec{
+			{
+				name:        "enable",
+				description: "Get a new password for Fever clients, replacing any old one.",
+				handler:     requireLogin(handlerFeverEnable),
+			},
+			{
+				name:        "disable",
+				description: "Stop Fever clients from signing in as you.",
+				handler:     requireLogin(handlerFeverDisable),
+			},
+			{
+				name:        "show",
+				description: "Show whether Fever is enabled and where clients connect.",
+				handler:     requireLogin(handlerFeverShow),
+			},
+		},
+	})
+	c.register(commandSpec{
+		name:        "agg",
+		description: "Fetch feeds continuously as they come due. The optional duration overrides schedule.min_interval.",
+		usage:       "[min_time_between_reqs]",
+		maxArgs:     1,
+		handler:     handlerAgg,
+	})
+	c.register(commandSpec{
+		name:        "serve",
+		description: "Serve the Fever API for feed reader apps on server.listen_addr, without fetching feeds.",
+		handler:     handlerServe,
+	})
+	c.register(commandSpec{
+		name:        "canonicalize",
+		description: "Rewrite stored feed and post URLs into canonical form and merge duplicate feeds. Admins only.",
+		handler:     requireAdmin(handlerCanonicalize),
+	})
 }
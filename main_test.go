@@ -0,0 +1,103 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/isaacjstriker/gatorapp/internal/config"
+)
+
+// testBackend is a database the handler tests run against.
+type testBackend struct {
+	name string
+	// dbURL is the Postgres database each test empties and uses, or "" for
+	// a fresh SQLite file per test.
+	dbURL string
+}
+
+// testBackends lists SQLite, and Postgres when postgresTestEnv is set.
+func testBackends(t *testing.T) []testBackend {
+	t.Helper()
+	backends := []testBackend{{name: "sqlite"}}
+	if dbURL := os.Getenv(postgresTestEnv); dbURL != "" {
+		backends = append(backends, testBackend{name: "postgres", dbURL: dbURL})
+	} else {
+		t.Logf("%s not set, testing SQLite only", postgresTestEnv)
+	}
+	return backends
+}
+
+// newTestState opens a State on an empty database of backend b, with its
+// config in a temporary file, the way run does for a command.
+func newTestState(t *testing.T, b testBackend) *State {
+	t.Helper()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.json")
+	t.Setenv(config.ConfigEnv, path)
+	t.Setenv(config.DbURLEnv, "")
+
+	dbURL := b.dbURL
+	if dbURL == "" {
+		dbURL = "sqlite:" + filepath.Join(dir, "gator.db")
+	} else {
+		emptyPostgres(t, dbURL)
+	}
+	cfg := config.Config{DbURL: dbURL}
+	if err := config.Create(path, cfg, false); err != nil {
+		t.Fatalf("couldn't write config: %v", err)
+	}
+	s, closeState, err := openState("error", "", "", true)
+	if err != nil {
+		t.Fatalf("couldn't open state: %v", err)
+	}
+	t.Cleanup(closeState)
+	return s
+}
+
+// emptyPostgres deletes every row from the tables of the Postgres database
+// at dbURL, leaving its migrations in place.
+func emptyPostgres(t *testing.T, dbURL string) {
+	t.Helper()
+	db, err := openDB(dbURL)
+	if err != nil {
+		t.Fatalf("couldn't open database: %v", err)
+	}
+	defer db.Close()
+	var tables string
+	err = db.QueryRow(`
+		SELECT string_agg(quote_ident(table_name), ', ')
+		FROM information_schema.tables
+		WHERE table_schema = current_schema()
+			AND table_type = 'BASE TABLE'
+			AND table_name <> 'goose_db_version'`).Scan(&tables)
+	if err != nil {
+		t.Fatalf("couldn't list tables: %v", err)
+	}
+	if _, err := db.Exec("TRUNCATE " + tables + " RESTART IDENTITY CASCADE"); err != nil {
+		t.Fatalf("couldn't empty database: %v", err)
+	}
+}
+
+// runCommand parses args as the command line would be and runs the command
+// against s.
+func runCommand(s *State, args ...string) error {
+	c := newCommands(flag.NewFlagSet("gatorapp", flag.ContinueOnError))
+	registerCommands(c)
+	spec, cmd, err := c.parse(Command{name: args[0], args: args[1:]})
+	if err != nil {
+		return err
+	}
+	return spec.handler(s, cmd)
+}
+
+// mustRun runs each command line in turn, failing the test on any error.
+func mustRun(t *testing.T, s *State, commands ...[]string) {
+	t.Helper()
+	for _, args := range commands {
+		if err := runCommand(s, args...); err != nil {
+			t.Fatalf("%v: %v", args, err)
+		}
+	}
+}
@@ -0,0 +1,344 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/isaacjstriker/gatorapp/internal/database"
+	"github.com/isaacjstriker/gatorapp/internal/filter"
+	"github.com/isaacjstriker/gatorapp/internal/mail"
+	"github.com/isaacjstriker/gatorapp/internal/metrics"
+	"github.com/isaacjstriker/gatorapp/internal/notify"
+)
+
+// maxNotifyBackoff caps the wait between retries of a failed delivery.
+const maxNotifyBackoff = time.Hour
+
+// maxDeliveriesPerRound caps how many notifications agg sends per round.
+const maxDeliveriesPerRound = 100
+
+// notificationSink builds the named sink from the config. It also returns
+// the sink's type, for metrics.
+func notificationSink(s *State, name string) (notify.Sink, string, error) {
+	sc, ok := s.Config.Notifications.Sinks[name]
+	if !ok {
+		return nil, "", &NotFoundError{Kind: "notification sink", Name: name}
+	}
+	client := &http.Client{Timeout: s.Config.Notifications.TimeoutOrDefault()}
+	switch sc.Type {
+	case notify.TypeWebhook:
+		return &notify.Webhook{URL: sc.URL, Secret: sc.Secret, Client: client}, sc.Type, nil
+	case notify.TypeSlack:
+		return &notify.Slack{URL: sc.URL, Client: client}, sc.Type, nil
+	case notify.TypeEmail:
+		return &notify.Email{Server: smtpServer(s), To: sc.To}, sc.Type, nil
+	case notify.TypeExec:
+		return &notify.Exec{Command: sc.Command}, sc.Type, nil
+	}
+	return nil, "", fmt.Errorf("sink %s has unknown type %q, want one of %s",
+		name, sc.Type, strings.Join(notify.Types, ", "))
+}
+
+func smtpServer(s *State) mail.Server {
+	return mail.Server{
+		Addr:     s.Config.SMTP.Addr,
+		Username: s.Config.SMTP.Username,
+		Password: s.Config.SMTP.Password,
+		From:     s.Config.SMTP.From,
+		TLS:      s.Config.SMTP.TLS,
+	}
+}
+
+// notificationMatcher compiles a notification rule. A rule without a pattern
+// matches every post, and gets a nil matcher.
+func notificationMatcher(field, match, pattern string) (*filter.Rule, error) {
+	if pattern == "" {
+		return nil, nil
+	}
+	return filter.Compile(field, match, pattern)
+}
+
+// queueNotifications records a delivery for each new post of feed that one
+// of its followers' notification rules matches. A post is queued once per
+// user and sink, however many rules match it.
+func queueNotifications(s *State, feed database.Feed, posts []database.Post) {
+	ctx := context.Background()
+	rows, err := s.Queries.GetNotificationRulesForFeed(ctx, feed.ID)
+	if err != nil {
+		feedLog(feed).Error("couldn't get notification rules", "error", err)
+		return
+	}
+
+	type rule struct {
+		database.NotificationRule
+		matcher *filter.Rule
+	}
+	rules := make([]rule, 0, len(rows))
+	for _, row := range rows {
+		matcher, err := notificationMatcher(row.Field, row.MatchType, row.Pattern)
+		if err != nil {
+			feedLog(feed).Warn("skipping invalid notification rule", "rule_id", row.ID, "error", err)
+			continue
+		}
+		rules = append(rules, rule{NotificationRule: row, matcher: matcher})
+	}
+
+	now := time.Now().UTC()
+	for _, post := range posts {
+		p := filter.Post{
+			Title:       post.Title,
+			Description: post.Description.String,
+			Author:      post.Author,
+			Categories:  post.Categories,
+		}
+		for _, r := range rules {
+			if r.matcher != nil && !r.matcher.Matches(p) {
+				continue
+			}
+			if err := s.Queries.QueueNotification(ctx, database.QueueNotificationParams{
+				ID:        uuid.New(),
+				CreatedAt: now,
+				UserID:    r.UserID,
+				PostID:    uuid.NullUUID{UUID: post.ID, Valid: true},
+				PostUrl:   post.Url,
+				Sink:      r.Sink,
+			}); err != nil {
+				feedLog(feed).Error("couldn't queue notification", "post_url", post.Url, "sink", r.Sink, "error", err)
+			}
+		}
+	}
+}
+
+// deliverNotifications sends the notifications that are due, backing off
+// exponentially after each failure until the attempts run out.
+func deliverNotifications(s *State) {
+	cfg := s.Config.Notifications
+	maxAttempts := cfg.MaxAttemptsOrDefault()
+	orphaned, err := s.Queries.FailOrphanedNotifications(context.Background(), database.FailOrphanedNotificationsParams{
+		MaxAttempts: int32(maxAttempts),
+		LastError:   "post was deleted before it could be sent",
+	})
+	if err != nil {
+		slog.Error("couldn't give up on notifications for deleted posts", "error", err)
+	} else if orphaned > 0 {
+		slog.Warn("gave up on notifications for deleted posts", "count", orphaned)
+	}
+
+	due, err := s.Queries.GetDueNotifications(context.Background(), database.GetDueNotificationsParams{
+		MaxAttempts:   int32(maxAttempts),
+		Now:           time.Now().UTC(),
+		MaxDeliveries: maxDeliveriesPerRound,
+	})
+	if err != nil {
+		slog.Error("couldn't get due notifications", "error", err)
+		return
+	}
+
+	for _, d := range due {
+		log := slog.With("delivery_id", d.ID, "sink", d.Sink, "user", d.UserName, "post_url", d.Url)
+		sink, sinkType, err := notificationSink(s, d.Sink)
+		if err == nil {
+			ctx, cancel := context.WithTimeout(context.Background(), cfg.TimeoutOrDefault())
+			err = sink.Send(ctx, notify.Message{
+				User:        d.UserName,
+				Feed:        d.FeedName,
+				FeedURL:     d.FeedUrl,
+				Title:       d.Title,
+				URL:         d.Url,
+				Description: d.Description.String,
+				Author:      d.Author,
+				PublishedAt: d.PublishedAt.Time,
+			})
+			cancel()
+		} else {
+			sinkType = "unknown"
+		}
+
+		now := time.Now().UTC()
+		if err == nil {
+			s.Metrics.ObserveNotification(sinkType, metrics.NotificationDelivered)
+			log.Debug("delivered notification")
+			if err := s.Queries.MarkNotificationDelivered(context.Background(), database.MarkNotificationDeliveredParams{
+				ID:          d.ID,
+				DeliveredAt: sql.NullTime{Time: now, Valid: true},
+			}); err != nil {
+				log.Error("couldn't mark notification delivered", "error", err)
+			}
+			continue
+		}
+
+		s.Metrics.ObserveNotification(sinkType, metrics.NotificationFailed)
+		attempts := int(d.Attempts) + 1
+		if attempts >= maxAttempts {
+			log.Error("giving up on notification", "attempts", attempts, "error", err)
+		} else {
+			log.Warn("couldn't deliver notification", "attempts", attempts, "error", err)
+		}
+		if err := s.Queries.MarkNotificationFailed(context.Background(), database.MarkNotificationFailedParams{
+			ID:            d.ID,
+			NextAttemptAt: now.Add(notify.Backoff(attempts, cfg.RetryBackoffOrDefault(), maxNotifyBackoff)),
+			LastError:     err.Error(),
+		}); err != nil {
+			log.Error("couldn't record notification failure", "error", err)
+		}
+	}
+}
+
+func addNotifyFlags(fs *flag.FlagSet) {
+	fs.String("sink", "", "name of the configured sink to notify (required)")
+	fs.String("feed", "", "only watch this feed; by default every feed you follow is watched")
+	fs.String("field", filter.FieldTitle, "post field the pattern matches: "+strings.Join(filter.Fields, ", "))
+	fs.Bool("regex", false, "treat the pattern as a regular expression instead of a keyword")
+}
+
+func handlerNotifyAdd(s *State, user database.User, cmd Command) error {
+	usage := "notify add [flags] [pattern]"
+	sink := cmd.flagString("sink")
+	if sink == "" {
+		return &UsageError{Usage: usage, Reason: "-sink is required"}
+	}
+	if _, _, err := notificationSink(s, sink); err != nil {
+		return err
+	}
+
+	var pattern string
+	if len(cmd.args) == 1 {
+		pattern = cmd.args[0]
+	}
+	rawURL := cmd.flagString("feed")
+	if pattern == "" && rawURL == "" {
+		return &UsageError{Usage: usage, Reason: "give a pattern, -feed, or both"}
+	}
+
+	match := filter.MatchKeyword
+	if cmd.flagBool("regex") {
+		match = filter.MatchRegex
+	}
+	field := cmd.flagString("field")
+	if !slices.Contains(filter.Fields, field) {
+		return &UsageError{Usage: usage, Reason: fmt.Sprintf("unknown field %q", field)}
+	}
+	if _, err := notificationMatcher(field, match, pattern); err != nil {
+		return &UsageError{Usage: usage, Reason: err.Error()}
+	}
+
+	var feedID uuid.NullUUID
+	if rawURL != "" {
+		feed, _, err := findFollow(s, user, rawURL)
+		if err != nil {
+			return err
+		}
+		feedID = uuid.NullUUID{UUID: feed.ID, Valid: true}
+	}
+
+	rule, err := s.Queries.CreateNotificationRule(context.Background(), database.CreateNotificationRuleParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now().UTC(),
+		UserID:    user.ID,
+		FeedID:    feedID,
+		Field:     field,
+		MatchType: match,
+		Pattern:   pattern,
+		Sink:      sink,
+	})
+	if err != nil {
+		return fmt.Errorf("could not create notification rule: %w", err)
+	}
+	fmt.Printf("Added notification %s\n", shortID(rule.ID))
+	return nil
+}
+
+func handlerNotifyList(s *State, user database.User, cmd Command) error {
+	rules, err := s.Queries.GetNotificationRulesForUser(context.Background(), user.ID)
+	if err != nil {
+		return fmt.Errorf("could not get notification rules: %w", err)
+	}
+	if len(rules) == 0 {
+		fmt.Println("You have no notifications.")
+	} else {
+		fmt.Println("Notifications:")
+	}
+	for _, rule := range rules {
+		what := "every new post"
+		if rule.Pattern != "" {
+			what = fmt.Sprintf("posts whose %s %s %q", rule.Field, rule.MatchType, rule.Pattern)
+		}
+		scope := "all feeds"
+		if rule.FeedUrl != "" {
+			scope = rule.FeedUrl
+		}
+		fmt.Printf("- %s: %s to %s (%s)\n", shortID(rule.ID), what, rule.Sink, scope)
+	}
+
+	failed, err := s.Queries.GetFailedNotificationsForUser(context.Background(), database.GetFailedNotificationsForUserParams{
+		UserID:      user.ID,
+		MaxAttempts: int32(s.Config.Notifications.MaxAttemptsOrDefault()),
+	})
+	if err != nil {
+		return fmt.Errorf("could not get failed notifications: %w", err)
+	}
+	if len(failed) > 0 {
+		fmt.Println("Recently failed:")
+		for _, d := range failed {
+			fmt.Printf("- %s to %s: %s\n", d.PostUrl, d.Sink, d.LastError)
+		}
+	}
+	return nil
+}
+
+func handlerNotifyRemove(s *State, user database.User, cmd Command) error {
+	rules, err := s.Queries.GetNotificationRulesForUser(context.Background(), user.ID)
+	if err != nil {
+		return fmt.Errorf("could not get notification rules: %w", err)
+	}
+	ids := make([]uuid.UUID, len(rules))
+	for i, rule := range rules {
+		ids[i] = rule.ID
+	}
+	id, err := resolveID("notification", "notify rm <id>", cmd.args[0], ids)
+	if err != nil {
+		return err
+	}
+
+	if _, err := s.Queries.DeleteNotificationRule(context.Background(), database.DeleteNotificationRuleParams{
+		UserID: user.ID,
+		ID:     id,
+	}); err != nil {
+		return fmt.Errorf("could not delete notification rule: %w", err)
+	}
+	fmt.Printf("Deleted notification %s\n", shortID(id))
+	return nil
+}
+
+// handlerNotifyTest sends a sample notification straight to a sink, so its
+// configuration can be checked without waiting for a matching post.
+func handlerNotifyTest(s *State, user database.User, cmd Command) error {
+	sink, _, err := notificationSink(s, cmd.args[0])
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), s.Config.Notifications.TimeoutOrDefault())
+	defer cancel()
+	if err := sink.Send(ctx, notify.Message{
+		User:        user.Name,
+		Feed:        "gator",
+		FeedURL:     "https://example.com/feed.xml",
+		Title:       "Test notification",
+		URL:         "https://example.com/test",
+		Description: "If you can read this, the sink works.",
+		PublishedAt: time.Now().UTC(),
+	}); err != nil {
+		return fmt.Errorf("could not notify %s: %w", cmd.args[0], err)
+	}
+	fmt.Printf("Sent a test notification to %s\n", cmd.args[0])
+	return nil
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/isaacjstriker/gatorapp/internal/config"
+	"github.com/isaacjstriker/gatorapp/internal/database"
+	"github.com/isaacjstriker/gatorapp/internal/notify"
+)
+
+// addWebhookSink points the "hook" sink at a stand-in webhook and returns
+// the URLs of the posts it is sent.
+func addWebhookSink(t *testing.T, s *State) *[]string {
+	t.Helper()
+	var sent []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var m notify.Message
+		if err := json.NewDecoder(r.Body).Decode(&m); err != nil {
+			t.Errorf("webhook body: %v", err)
+		}
+		sent = append(sent, m.URL)
+	}))
+	t.Cleanup(srv.Close)
+	s.Config.Notifications.Sinks = map[string]config.SinkConfig{
+		"hook": {Type: notify.TypeWebhook, URL: srv.URL},
+	}
+	return &sent
+}
+
+func TestPrunedPostsNotifyOnce(t *testing.T) {
+	for _, b := range testBackends(t) {
+		t.Run(b.name, func(t *testing.T) {
+			s := newTestState(t, b)
+			sent := addWebhookSink(t, s)
+			mustRun(t, s,
+				[]string{"register", "alice"},
+				[]string{"addfeed", "Example", testFeedURL},
+				[]string{"notify", "add", "-sink", "hook", "-feed", testFeedURL},
+				[]string{"retention", "-max-posts", "1", testFeedURL},
+			)
+			feed, err := s.Queries.GetFeedByURL(context.Background(), testFeedURL)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			savePosts(s, feed, testRSS(1, 2))
+			deliverNotifications(s)
+			mustRun(t, s, []string{"prune", "-yes"})
+
+			// The feed drops post 1, so it's forgotten, and then lists it again.
+			forgetPrunedPosts(s, feed, testRSS(2))
+			savePosts(s, feed, testRSS(1, 2))
+			deliverNotifications(s)
+
+			want := []string{"https://example.com/posts/1", "https://example.com/posts/2"}
+			if len(*sent) != len(want) {
+				t.Fatalf("webhook was sent %v, want each post once: %v", *sent, want)
+			}
+			for _, url := range want {
+				count := 0
+				for _, got := range *sent {
+					if got == url {
+						count++
+					}
+				}
+				if count != 1 {
+					t.Errorf("%s sent %d times, want once", url, count)
+				}
+			}
+		})
+	}
+}
+
+// TestPrunedBeforeSent checks that a delivery whose post is deleted before
+// it is sent is given up on rather than left pending.
+func TestPrunedBeforeSent(t *testing.T) {
+	for _, b := range testBackends(t) {
+		t.Run(b.name, func(t *testing.T) {
+			s := newTestState(t, b)
+			sent := addWebhookSink(t, s)
+			mustRun(t, s,
+				[]string{"register", "alice"},
+				[]string{"addfeed", "Example", testFeedURL},
+				[]string{"notify", "add", "-sink", "hook", "-feed", testFeedURL},
+				[]string{"retention", "-max-posts", "1", testFeedURL},
+			)
+			ctx := context.Background()
+			feed, err := s.Queries.GetFeedByURL(ctx, testFeedURL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			alice, err := s.Queries.GetUser(ctx, "alice")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			savePosts(s, feed, testRSS(1, 2))
+			mustRun(t, s, []string{"prune", "-yes"})
+			deliverNotifications(s)
+
+			if len(*sent) != 1 || (*sent)[0] != "https://example.com/posts/2" {
+				t.Errorf("webhook was sent %v, want only the post that is left", *sent)
+			}
+			failed, err := s.Queries.GetFailedNotificationsForUser(ctx, database.GetFailedNotificationsForUserParams{
+				UserID:      alice.ID,
+				MaxAttempts: int32(s.Config.Notifications.MaxAttemptsOrDefault()),
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(failed) != 1 || failed[0].PostUrl != "https://example.com/posts/1" {
+				t.Errorf("failed deliveries = %v, want the pruned post's", failed)
+			}
+		})
+	}
+}
+
+func TestCanonicalizeKeepsNotificationRules(t *testing.T) {
+	for _, b := range testBackends(t) {
+		t.Run(b.name, func(t *testing.T) {
+			s := newTestState(t, b)
+			addWebhookSink(t, s)
+			mustRun(t, s, []string{"register", "alice"}, []string{"addfeed", "Example", testFeedURL})
+			ctx := context.Background()
+			alice, err := s.Queries.GetUser(ctx, "alice")
+			if err != nil {
+				t.Fatal(err)
+			}
+			survivor, err := s.Queries.GetFeedByURL(ctx, testFeedURL)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			now := time.Now().UTC()
+			dup, err := s.Queries.CreateFeed(ctx, database.CreateFeedParams{
+				ID:        uuid.New(),
+				CreatedAt: now.Add(time.Second),
+				UpdatedAt: now.Add(time.Second),
+				Name:      "Example again",
+				Url:       "HTTP://EXAMPLE.COM/feed.xml",
+				UserID:    alice.ID,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := s.Queries.CreateNotificationRule(ctx, database.CreateNotificationRuleParams{
+				ID:        uuid.New(),
+				CreatedAt: now,
+				UserID:    alice.ID,
+				FeedID:    uuid.NullUUID{UUID: dup.ID, Valid: true},
+				Field:     "title",
+				MatchType: "keyword",
+				Sink:      "hook",
+			}); err != nil {
+				t.Fatal(err)
+			}
+
+			mustRun(t, s, []string{"canonicalize"})
+
+			rules, err := s.Queries.GetNotificationRulesForUser(ctx, alice.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(rules) != 1 {
+				t.Fatalf("%d notification rules after merging, want 1", len(rules))
+			}
+			if rules[0].FeedID.UUID != survivor.ID {
+				t.Errorf("rule points at feed %v, want the surviving feed %v", rules[0].FeedID.UUID, survivor.ID)
+			}
+		})
+	}
+}
@@ -0,0 +1,286 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/isaacjstriker/gatorapp/internal/database"
+)
+
+// filterFlags defines the flags that narrow a command to some of the
+// current user's feeds.
+func filterFlags(fs *flag.FlagSet) {
+	fs.String("folder", "", "only feeds in this folder")
+	fs.String("tag", "", "only feeds with this tag")
+}
+
+// followFilter reads the -folder and -tag flags. Naming a folder the user
+// doesn't have is an error; an unused tag just matches nothing.
+func followFilter(s *State, user database.User, cmd Command) (folder, tag sql.NullString, err error) {
+	if name := cmd.flagString("folder"); name != "" {
+		if _, err := findFolder(s, user, name); err != nil {
+			return folder, tag, err
+		}
+		folder = sql.NullString{String: name, Valid: true}
+	}
+	if name := normalizeTag(cmd.flagString("tag")); name != "" {
+		tag = sql.NullString{String: name, Valid: true}
+	}
+	return folder, tag, nil
+}
+
+// normalizeTag makes tags case-insensitive.
+func normalizeTag(tag string) string {
+	return strings.ToLower(strings.TrimSpace(tag))
+}
+
+func findFolder(s *State, user database.User, name string) (database.Folder, error) {
+	folder, err := s.Queries.GetFolderByName(context.Background(), database.GetFolderByNameParams{
+		UserID: user.ID,
+		Name:   name,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return database.Folder{}, &NotFoundError{Kind: "folder", Name: name}
+	}
+	if err != nil {
+		return database.Folder{}, fmt.Errorf("could not find folder %s: %w", name, err)
+	}
+	return folder, nil
+}
+
+// findFollow looks up the feed at rawURL and the current user's follow of
+// it.
+func findFollow(s *State, user database.User, rawURL string) (database.Feed, database.FeedFollow, error) {
+	feed, err := findFeed(s, rawURL)
+	if err != nil {
+		return database.Feed{}, database.FeedFollow{}, err
+	}
+	follow, err := s.Queries.GetFeedFollow(context.Background(), database.GetFeedFollowParams{
+		UserID: user.ID,
+		FeedID: feed.ID,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return database.Feed{}, database.FeedFollow{}, &NotFoundError{Kind: "followed feed", Name: feed.Url}
+	}
+	if err != nil {
+		return database.Feed{}, database.FeedFollow{}, fmt.Errorf("could not check feed follow: %w", err)
+	}
+	return feed, follow, nil
+}
+
+func handlerFolderAdd(s *State, user database.User, cmd Command) error {
+	name := strings.TrimSpace(cmd.args[0])
+	if name == "" {
+		return &UsageError{Usage: "folder add <name>", Reason: "folder name can't be empty"}
+	}
+
+	now := time.Now().UTC()
+	_, err := s.Queries.CreateFolder(context.Background(), database.CreateFolderParams{
+		ID:        uuid.New(),
+		CreatedAt: now,
+		UpdatedAt: now,
+		UserID:    user.ID,
+		Name:      name,
+	})
+	if isUniqueViolation(err) {
+		return &ConflictError{Message: fmt.Sprintf("folder %q already exists", name)}
+	}
+	if err != nil {
+		return fmt.Errorf("could not create folder: %w", err)
+	}
+	fmt.Printf("Created folder %s\n", name)
+	return nil
+}
+
+func handlerFolderList(s *State, user database.User, cmd Command) error {
+	folders, err := s.Queries.GetFoldersForUser(context.Background(), user.ID)
+	if err != nil {
+		return fmt.Errorf("could not get folders: %w", err)
+	}
+	if len(folders) == 0 {
+		fmt.Println("You have no folders.")
+		return nil
+	}
+	fmt.Println("Folders:")
+	for _, folder := range folders {
+		fmt.Printf("- %s (%s)\n", folder.Name, count(folder.FeedCount, "feed"))
+	}
+	return nil
+}
+
+func handlerFolderRemove(s *State, user database.User, cmd Command) error {
+	name := cmd.args[0]
+	deleted, err := s.Queries.DeleteFolder(context.Background(), database.DeleteFolderParams{
+		UserID: user.ID,
+		Name:   name,
+	})
+	if err != nil {
+		return fmt.Errorf("could not delete folder: %w", err)
+	}
+	if deleted == 0 {
+		return &NotFoundError{Kind: "folder", Name: name}
+	}
+	fmt.Printf("Deleted folder %s; its feeds are now unfiled\n", name)
+	return nil
+}
+
+// handlerFolderMove files a followed feed in a folder, or takes it out of
+// its folder when none is named.
+func handlerFolderMove(s *State, user database.User, cmd Command) error {
+	feed, _, err := findFollow(s, user, cmd.args[0])
+	if err != nil {
+		return err
+	}
+
+	var folderID uuid.NullUUID
+	if len(cmd.args) == 2 {
+		folder, err := findFolder(s, user, cmd.args[1])
+		if err != nil {
+			return err
+		}
+		folderID = uuid.NullUUID{UUID: folder.ID, Valid: true}
+	}
+
+	if _, err := s.Queries.SetFeedFollowFolder(context.Background(), database.SetFeedFollowFolderParams{
+		UserID:    user.ID,
+		FeedID:    feed.ID,
+		FolderID:  folderID,
+		UpdatedAt: time.Now().UTC(),
+	}); err != nil {
+		return fmt.Errorf("could not move feed: %w", err)
+	}
+	if folderID.Valid {
+		fmt.Printf("Moved %s to folder %s\n", feed.Name, cmd.args[1])
+	} else {
+		fmt.Printf("Moved %s out of its folder\n", feed.Name)
+	}
+	return nil
+}
+
+func handlerTagAdd(s *State, user database.User, cmd Command) error {
+	feed, _, err := findFollow(s, user, cmd.args[0])
+	if err != nil {
+		return err
+	}
+	for _, raw := range cmd.args[1:] {
+		tag := normalizeTag(raw)
+		if tag == "" {
+			return &UsageError{Usage: "tag add <feed_url> <tag>...", Reason: "tags can't be empty"}
+		}
+		if _, err := s.Queries.AddFeedFollowTag(context.Background(), database.AddFeedFollowTagParams{
+			Tag:    tag,
+			UserID: user.ID,
+			FeedID: feed.ID,
+		}); err != nil {
+			return fmt.Errorf("could not tag feed: %w", err)
+		}
+	}
+	fmt.Printf("Tagged %s\n", feed.Name)
+	return nil
+}
+
+func handlerTagRemove(s *State, user database.User, cmd Command) error {
+	feed, _, err := findFollow(s, user, cmd.args[0])
+	if err != nil {
+		return err
+	}
+	for _, raw := range cmd.args[1:] {
+		tag := normalizeTag(raw)
+		removed, err := s.Queries.RemoveFeedFollowTag(context.Background(), database.RemoveFeedFollowTagParams{
+			UserID: user.ID,
+			FeedID: feed.ID,
+			Tag:    tag,
+		})
+		if err != nil {
+			return fmt.Errorf("could not untag feed: %w", err)
+		}
+		if removed == 0 {
+			return &NotFoundError{Kind: "tag", Name: tag}
+		}
+	}
+	fmt.Printf("Untagged %s\n", feed.Name)
+	return nil
+}
+
+func handlerTagList(s *State, user database.User, cmd Command) error {
+	tags, err := s.Queries.GetTagsForUser(context.Background(), user.ID)
+	if err != nil {
+		return fmt.Errorf("could not get tags: %w", err)
+	}
+	if len(tags) == 0 {
+		fmt.Println("You have no tags.")
+		return nil
+	}
+	fmt.Println("Tags:")
+	for _, tag := range tags {
+		fmt.Printf("- %s (%s)\n", tag.Tag, count(tag.FeedCount, "feed"))
+	}
+	return nil
+}
+
+func editFollowFlags(fs *flag.FlagSet) {
+	fs.String("title", "", "your own title for the feed; empty goes back to the feed's name")
+	fs.Bool("mute", false, "hide the feed's posts from browse while staying subscribed")
+	fs.Int("priority", 0, "feeds with higher priority come first in browse")
+	fs.Bool("notify", true, "send notifications for the feed's new posts")
+}
+
+// handlerEditFollow shows or changes the current user's settings for one
+// followed feed.
+func handlerEditFollow(s *State, user database.User, cmd Command) error {
+	feed, follow, err := findFollow(s, user, cmd.args[0])
+	if err != nil {
+		return err
+	}
+
+	params := database.UpdateFeedFollowSettingsParams{
+		UserID:   user.ID,
+		FeedID:   feed.ID,
+		Title:    follow.Title,
+		Muted:    follow.Muted,
+		Priority: follow.Priority,
+		Notify:   follow.Notify,
+	}
+	changed := false
+	if cmd.flagGiven("title") {
+		title := strings.TrimSpace(cmd.flagString("title"))
+		params.Title = sql.NullString{String: title, Valid: title != ""}
+		changed = true
+	}
+	if cmd.flagGiven("mute") {
+		params.Muted = cmd.flagBool("mute")
+		changed = true
+	}
+	if cmd.flagGiven("priority") {
+		params.Priority = int32(cmd.flagInt("priority"))
+		changed = true
+	}
+	if cmd.flagGiven("notify") {
+		params.Notify = cmd.flagBool("notify")
+		changed = true
+	}
+
+	if changed {
+		params.UpdatedAt = time.Now().UTC()
+		if _, err := s.Queries.UpdateFeedFollowSettings(context.Background(), params); err != nil {
+			return fmt.Errorf("could not update follow settings: %w", err)
+		}
+	}
+
+	title := feed.Name
+	if params.Title.Valid {
+		title = params.Title.String
+	}
+	fmt.Printf("Settings for %s (%s):\n", title, feed.Url)
+	fmt.Printf("- muted: %t\n", params.Muted)
+	fmt.Printf("- priority: %d\n", params.Priority)
+	fmt.Printf("- notify: %t\n", params.Notify)
+	return nil
+}
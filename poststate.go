@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/isaacjstriker/gatorapp/internal/canonurl"
+	"github.com/isaacjstriker/gatorapp/internal/database"
+)
+
+// findPost looks a post up by any spelling of its URL.
+func findPost(s *State, rawURL string) (database.Post, error) {
+	postURL, err := canonurl.Canonicalize(rawURL)
+	if err != nil {
+		postURL = strings.TrimSpace(rawURL)
+	}
+	post, err := s.Queries.GetPostByURL(context.Background(), postURL)
+	if errors.Is(err, sql.ErrNoRows) {
+		return database.Post{}, &NotFoundError{Kind: "post", Name: rawURL}
+	}
+	if err != nil {
+		return database.Post{}, fmt.Errorf("could not find post: %w", err)
+	}
+	return post, nil
+}
+
+// stateTime is the timestamp stored for a post state that is being set, or
+// NULL when it is being cleared.
+func stateTime(set bool) sql.NullTime {
+	if !set {
+		return sql.NullTime{}
+	}
+	return sql.NullTime{Time: time.Now().UTC(), Valid: true}
+}
+
+// handlerSetStarred stars or unstars a post for the current user.
+func handlerSetStarred(starred bool) UserHandler {
+	return func(s *State, user database.User, cmd Command) error {
+		post, err := findPost(s, cmd.args[0])
+		if err != nil {
+			return err
+		}
+		if err := s.Queries.SetPostStarred(context.Background(), database.SetPostStarredParams{
+			UserID:    user.ID,
+			PostID:    post.ID,
+			StarredAt: stateTime(starred),
+		}); err != nil {
+			return fmt.Errorf("could not update post: %w", err)
+		}
+		if starred {
+			fmt.Printf("Starred %s\n", post.Title)
+		} else {
+			fmt.Printf("Unstarred %s\n", post.Title)
+		}
+		return nil
+	}
+}
+
+// handlerSetRead marks a post read or unread for the current user.
+func handlerSetRead(read bool) UserHandler {
+	return func(s *State, user database.User, cmd Command) error {
+		post, err := findPost(s, cmd.args[0])
+		if err != nil {
+			return err
+		}
+		if err := s.Queries.SetPostRead(context.Background(), database.SetPostReadParams{
+			UserID: user.ID,
+			PostID: post.ID,
+			ReadAt: stateTime(read),
+		}); err != nil {
+			return fmt.Errorf("could not update post: %w", err)
+		}
+		if read {
+			fmt.Printf("Marked %s as read\n", post.Title)
+		} else {
+			fmt.Printf("Marked %s as unread\n", post.Title)
+		}
+		return nil
+	}
+}
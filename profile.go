@@ -0,0 +1,76 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/isaacjstriker/gatorapp/internal/config"
+)
+
+// redactDbURL hides the password in a database URL for display.
+func redactDbURL(dbURL string) string {
+	u, err := url.Parse(dbURL)
+	if err != nil || u.User == nil {
+		return dbURL
+	}
+	return u.Redacted()
+}
+
+func handlerProfileList(s *State, cmd Command) error {
+	fmt.Printf("Profiles in %s:\n", s.Config.File())
+	for _, name := range s.Config.ProfileNames() {
+		p, _ := s.Config.LookupProfile(name)
+		marker := " "
+		if name == s.Config.Profile() {
+			marker = "*"
+		}
+		user := p.CurrentUsername
+		if user == "" {
+			user = "not logged in"
+		}
+		fmt.Printf("%s %s: %s (%s)\n", marker, name, redactDbURL(p.DbURL), user)
+	}
+	if os.Getenv(config.DbURLEnv) != "" {
+		fmt.Printf("%s is set and overrides the database URL.\n", config.DbURLEnv)
+	}
+	return nil
+}
+
+func addProfileFlags(fs *flag.FlagSet) {
+	fs.String("db-url", "", "the profile's database connection string (required)")
+}
+
+func handlerProfileAdd(s *State, cmd Command) error {
+	name := strings.TrimSpace(cmd.args[0])
+	dbURL := cmd.flagString("db-url")
+	if name == "" {
+		return &UsageError{Usage: "profile add -db-url <url> <name>", Reason: "profile name can't be empty"}
+	}
+	if dbURL == "" {
+		return &UsageError{Usage: "profile add -db-url <url> <name>", Reason: "-db-url is required"}
+	}
+	if _, ok := s.Config.LookupProfile(name); ok {
+		return &ConflictError{Message: fmt.Sprintf("profile %q already exists", name)}
+	}
+
+	if err := s.Config.AddProfile(name, config.Profile{DbURL: dbURL}); err != nil {
+		return fmt.Errorf("could not add profile: %w", err)
+	}
+	fmt.Printf("Added profile %s; switch to it with 'profile use %s'\n", name, name)
+	return nil
+}
+
+func handlerProfileUse(s *State, cmd Command) error {
+	name := cmd.args[0]
+	if _, ok := s.Config.LookupProfile(name); !ok {
+		return &NotFoundError{Kind: "profile", Name: name}
+	}
+	if err := s.Config.UseProfile(name); err != nil {
+		return fmt.Errorf("could not switch profile: %w", err)
+	}
+	fmt.Printf("Now using profile %s\n", name)
+	return nil
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/isaacjstriker/gatorapp/internal/config"
+)
+
+// TestMissingCurrentProfile checks that a current_profile naming no profile
+// blocks database commands but not the profile commands that fix it.
+func TestMissingCurrentProfile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.json")
+	t.Setenv(config.ConfigEnv, path)
+	t.Setenv(config.DbURLEnv, "")
+	cfg := config.Config{DbURL: "sqlite:" + filepath.Join(dir, "gator.db"), CurrentProfile: "gone"}
+	if err := config.Create(path, cfg, false); err != nil {
+		t.Fatalf("couldn't write config: %v", err)
+	}
+
+	if _, _, err := openState("error", "", "", true); exitCode(err) != exitNotFound {
+		t.Fatalf("opening the database: exit code %d (%v), want %d", exitCode(err), err, exitNotFound)
+	}
+
+	s, closeState, err := openState("error", "", "", false)
+	if err != nil {
+		t.Fatalf("couldn't open state for a profile command: %v", err)
+	}
+	defer closeState()
+	if got := s.Config.Profile(); got != config.DefaultProfile {
+		t.Errorf("profile in use = %q, want %q", got, config.DefaultProfile)
+	}
+	mustRun(t, s, []string{"profile", "use", config.DefaultProfile})
+
+	s, closeState, err = openState("error", "", "", true)
+	if err != nil {
+		t.Fatalf("after switching to the default profile: %v", err)
+	}
+	closeState()
+}
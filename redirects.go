@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/isaacjstriker/gatorapp/internal/canonurl"
+	"github.com/isaacjstriker/gatorapp/internal/database"
+)
+
+// trackFeedMove records the permanent redirect observed while fetching feed.
+// Once the same target has been seen MovedThreshold times in a row the feed
+// is moved there, keeping its old URL in the history so existing spellings
+// still resolve. The returned feed reflects any changes made.
+func trackFeedMove(s *State, feed database.Feed, movedTo string) (database.Feed, error) {
+	ctx := context.Background()
+
+	target := ""
+	if movedTo != "" {
+		canonical, err := canonurl.Canonicalize(movedTo)
+		if err != nil {
+			return feed, fmt.Errorf("invalid redirect target: %w", err)
+		}
+		if canonical != feed.Url {
+			target = canonical
+		}
+	}
+
+	if target == "" {
+		if !feed.RedirectUrl.Valid {
+			return feed, nil
+		}
+		if err := s.Queries.ClearFeedRedirect(ctx, database.ClearFeedRedirectParams{ID: feed.ID, UpdatedAt: time.Now().UTC()}); err != nil {
+			return feed, err
+		}
+		feed.RedirectUrl = sql.NullString{}
+		feed.RedirectCount = 0
+		return feed, nil
+	}
+
+	updated, err := s.Queries.RecordFeedRedirect(ctx, database.RecordFeedRedirectParams{
+		ID:          feed.ID,
+		RedirectUrl: sql.NullString{String: target, Valid: true},
+		UpdatedAt:   time.Now().UTC(),
+	})
+	if err != nil {
+		return feed, err
+	}
+
+	threshold := s.Config.MovedThreshold()
+	if int(updated.RedirectCount) < threshold {
+		feedLog(feed).Info("feed permanently redirects", "moved_to", target, "observations", updated.RedirectCount, "threshold", threshold)
+		return updated, nil
+	}
+	return moveFeed(ctx, s, updated, target)
+}
+
+// moveFeed points feed at newURL. If another feed already lives there the
+// two are merged and the surviving feed is returned.
+func moveFeed(ctx context.Context, s *State, feed database.Feed, newURL string) (database.Feed, error) {
+	var merged *database.Feed
+	err := s.Queries.InTx(ctx, func(qtx database.Querier) error {
+		existing, err := qtx.GetFeedByURL(ctx, newURL)
+		switch {
+		case err == nil:
+			merged = &existing
+			return mergeFeed(ctx, qtx, feed, existing)
+		case !errors.Is(err, sql.ErrNoRows):
+			return err
+		}
+
+		now := time.Now().UTC()
+		if err := qtx.AddFeedURLHistory(ctx, database.AddFeedURLHistoryParams{
+			ID:        uuid.New(),
+			CreatedAt: now,
+			Url:       feed.Url,
+			FeedID:    feed.ID,
+		}); err != nil {
+			return err
+		}
+		if err := qtx.UpdateFeedURL(ctx, database.UpdateFeedURLParams{
+			ID:        feed.ID,
+			Url:       newURL,
+			UpdatedAt: now,
+		}); err != nil {
+			return err
+		}
+		return qtx.ClearFeedRedirect(ctx, database.ClearFeedRedirectParams{ID: feed.ID, UpdatedAt: now})
+	})
+	if err != nil {
+		return feed, err
+	}
+
+	if merged != nil {
+		feedLog(feed).Info("feed moved, merged into existing feed", "moved_to", newURL, "merged_into_id", merged.ID)
+		return *merged, nil
+	}
+	feedLog(feed).Info("feed moved", "moved_to", newURL)
+	feed.Url = newURL
+	feed.RedirectUrl = sql.NullString{}
+	feed.RedirectCount = 0
+	return feed, nil
+}
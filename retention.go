@@ -0,0 +1,263 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/isaacjstriker/gatorapp/internal/config"
+	"github.com/isaacjstriker/gatorapp/internal/database"
+)
+
+// retentionLimits are the limits that apply to one feed's posts. Zero means
+// no limit.
+type retentionLimits struct {
+	maxAge   time.Duration
+	maxPosts int
+}
+
+// feedRetention returns the feed's own limits where it has them and the
+// configured ones otherwise.
+func feedRetention(cfg config.RetentionConfig, feed database.Feed) retentionLimits {
+	limits := retentionLimits{
+		maxAge:   cfg.MaxAge.Duration,
+		maxPosts: cfg.MaxPostsPerFeed,
+	}
+	if feed.RetentionMaxAgeSeconds.Valid {
+		limits.maxAge = time.Duration(feed.RetentionMaxAgeSeconds.Int64) * time.Second
+	}
+	if feed.RetentionMaxPosts.Valid {
+		limits.maxPosts = int(feed.RetentionMaxPosts.Int32)
+	}
+	return limits
+}
+
+// prunePlan lists the posts of one feed that the retention policy removes.
+type prunePlan struct {
+	feed    database.Feed
+	postIDs []uuid.UUID
+}
+
+// planPrune works out which posts are past their retention as of now.
+func planPrune(ctx context.Context, s *State, now time.Time) ([]prunePlan, error) {
+	keepStarred, keepUnread, err := s.Config.Retention.Exemptions()
+	if err != nil {
+		return nil, fmt.Errorf("invalid retention config: %w", err)
+	}
+
+	feeds, err := s.Queries.GetFeeds(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("could not get feeds: %w", err)
+	}
+
+	var plans []prunePlan
+	for _, feed := range feeds {
+		limits := feedRetention(s.Config.Retention, feed)
+		if limits.maxAge <= 0 && limits.maxPosts <= 0 {
+			continue
+		}
+
+		params := database.GetPrunablePostsParams{
+			FeedID:      feed.ID,
+			MaxPosts:    int32(limits.maxPosts),
+			KeepStarred: keepStarred,
+			KeepUnread:  keepUnread,
+		}
+		if limits.maxAge > 0 {
+			params.Cutoff = sql.NullTime{Time: now.Add(-limits.maxAge), Valid: true}
+		}
+		ids, err := s.Queries.GetPrunablePosts(ctx, params)
+		if err != nil {
+			return nil, fmt.Errorf("could not find posts to prune for feed %s: %w", feed.Url, err)
+		}
+		if len(ids) > 0 {
+			plans = append(plans, prunePlan{feed: feed, postIDs: ids})
+		}
+	}
+	return plans, nil
+}
+
+// applyPrune deletes the planned posts and returns how many were deleted.
+// Their URLs are remembered so savePosts doesn't save them again while the
+// feed still lists them.
+func applyPrune(ctx context.Context, s *State, plans []prunePlan) (int64, error) {
+	var total int64
+	for _, plan := range plans {
+		var n int64
+		err := s.Queries.InTx(ctx, func(qtx database.Querier) error {
+			err := qtx.RecordPrunedPosts(ctx, database.RecordPrunedPostsParams{
+				PrunedAt: time.Now().UTC(),
+				Ids:      plan.postIDs,
+			})
+			if err != nil {
+				return err
+			}
+			n, err = qtx.DeletePostsByID(ctx, plan.postIDs)
+			return err
+		})
+		if err != nil {
+			return total, fmt.Errorf("could not prune posts for feed %s: %w", plan.feed.Url, err)
+		}
+		total += n
+		feedLog(plan.feed).Debug("pruned posts", "posts", n)
+	}
+	return total, nil
+}
+
+// pruneIfDue prunes posts from agg once the configured prune interval has
+// passed since last. It returns the time of the latest prune.
+func pruneIfDue(s *State, last time.Time) time.Time {
+	interval := s.Config.Retention.PruneInterval.Duration
+	if interval <= 0 || time.Since(last) < interval {
+		return last
+	}
+
+	ctx := context.Background()
+	now := time.Now().UTC()
+	plans, err := planPrune(ctx, s, now)
+	if err != nil {
+		slog.Error("couldn't plan prune", "error", err)
+		return now
+	}
+	n, err := applyPrune(ctx, s, plans)
+	if err != nil {
+		slog.Error("couldn't prune posts", "error", err)
+	}
+	if n > 0 {
+		slog.Info("pruned posts", "posts", n, "feeds", len(plans))
+	}
+	return now
+}
+
+// forgetPrunedPosts drops the pruned posts a freshly polled feed no longer
+// lists. Pushed content may be partial, so only a full fetch can tell.
+func forgetPrunedPosts(s *State, feed database.Feed, feedData *RSSFeed) {
+	if len(feedData.Channel.Item) == 0 {
+		return
+	}
+	listed := make([]string, 0, len(feedData.Channel.Item))
+	for _, item := range feedData.Channel.Item {
+		listed = append(listed, itemURL(item))
+	}
+	_, err := s.Queries.ForgetPrunedPosts(context.Background(), database.ForgetPrunedPostsParams{
+		FeedID: feed.ID,
+		Listed: listed,
+	})
+	if err != nil {
+		feedLog(feed).Error("couldn't forget pruned posts", "error", err)
+	}
+}
+
+// tooOldToKeep reports whether a post published at publishedAt would be
+// pruned straight away for its age, so savePosts needn't insert it.
+func tooOldToKeep(s *State, feed database.Feed, publishedAt sql.NullTime) bool {
+	maxAge := feedRetention(s.Config.Retention, feed).maxAge
+	return maxAge > 0 && publishedAt.Valid && time.Since(publishedAt.Time) > maxAge
+}
+
+func handlerPrune(s *State, _ database.User, cmd Command) error {
+	ctx := context.Background()
+
+	plans, err := planPrune(ctx, s, time.Now().UTC())
+	if err != nil {
+		return err
+	}
+	if len(plans) == 0 {
+		fmt.Println("Nothing to prune")
+		return nil
+	}
+
+	var total int64
+	for _, plan := range plans {
+		fmt.Printf("- %s: %s\n", plan.feed.Name, count(int64(len(plan.postIDs)), "post"))
+		total += int64(len(plan.postIDs))
+	}
+	what := fmt.Sprintf("%s from %s", count(total, "post"), count(int64(len(plans)), "feed"))
+	if proceed, err := confirmDestructive(cmd, what); err != nil || !proceed {
+		return err
+	}
+
+	n, err := applyPrune(ctx, s, plans)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Pruned %s\n", count(n, "post"))
+	return nil
+}
+
+func retentionFlags(fs *flag.FlagSet) {
+	fs.Duration("max-age", 0, "prune posts published longer ago than this, e.g. 720h; 0 keeps them forever")
+	fs.Int("max-posts", 0, "keep only this many of the feed's newest posts; 0 keeps them all")
+	fs.Bool("inherit", false, "drop the feed's own limits and use the configured ones")
+}
+
+// handlerRetention shows or sets a feed's own retention limits.
+func handlerRetention(s *State, user database.User, cmd Command) error {
+	ctx := context.Background()
+
+	feed, err := findFeed(s, cmd.args[0])
+	if err != nil {
+		return err
+	}
+
+	maxAge, maxPosts := feed.RetentionMaxAgeSeconds, feed.RetentionMaxPosts
+	switch {
+	case cmd.flagBool("inherit"):
+		maxAge, maxPosts = sql.NullInt64{}, sql.NullInt32{}
+	case cmd.flagGiven("max-age") || cmd.flagGiven("max-posts"):
+		if cmd.flagDuration("max-age") < 0 || cmd.flagInt("max-posts") < 0 {
+			return &UsageError{Usage: "retention [flags] <feed_url>", Reason: "limits can't be negative"}
+		}
+		if cmd.flagGiven("max-age") {
+			maxAge = sql.NullInt64{Int64: int64(cmd.flagDuration("max-age") / time.Second), Valid: true}
+		}
+		if cmd.flagGiven("max-posts") {
+			maxPosts = sql.NullInt32{Int32: int32(cmd.flagInt("max-posts")), Valid: true}
+		}
+	default:
+		printRetention(s, feed)
+		return nil
+	}
+	if err := authorizeFeed(s, user, feed); err != nil {
+		return err
+	}
+
+	if err := s.Queries.SetFeedRetention(ctx, database.SetFeedRetentionParams{
+		ID:                     feed.ID,
+		RetentionMaxAgeSeconds: maxAge,
+		RetentionMaxPosts:      maxPosts,
+		UpdatedAt:              time.Now().UTC(),
+	}); err != nil {
+		return fmt.Errorf("could not update feed retention: %w", err)
+	}
+	feed.RetentionMaxAgeSeconds, feed.RetentionMaxPosts = maxAge, maxPosts
+	printRetention(s, feed)
+	return nil
+}
+
+func printRetention(s *State, feed database.Feed) {
+	limits := feedRetention(s.Config.Retention, feed)
+	describe := func(own bool, limit string) string {
+		if own {
+			return limit
+		}
+		return limit + " (configured default)"
+	}
+
+	maxAge := "forever"
+	if limits.maxAge > 0 {
+		maxAge = limits.maxAge.String()
+	}
+	maxPosts := "unlimited"
+	if limits.maxPosts > 0 {
+		maxPosts = fmt.Sprint(limits.maxPosts)
+	}
+	fmt.Printf("Retention for %s:\n", feed.Name)
+	fmt.Printf("- max age: %s\n", describe(feed.RetentionMaxAgeSeconds.Valid, maxAge))
+	fmt.Printf("- max posts: %s\n", describe(feed.RetentionMaxPosts.Valid, maxPosts))
+}
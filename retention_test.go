@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/isaacjstriker/gatorapp/internal/database"
+)
+
+// testRSS returns a feed listing a post for each of the given numbers,
+// published an hour apart with the highest number newest.
+func testRSS(numbers ...int) *RSSFeed {
+	feedData := &RSSFeed{}
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, n := range numbers {
+		feedData.Channel.Item = append(feedData.Channel.Item, RSSItem{
+			Title:   fmt.Sprintf("Post %d", n),
+			Link:    fmt.Sprintf("https://example.com/posts/%d", n),
+			PubDate: base.Add(time.Duration(n) * time.Hour).Format(time.RFC1123Z),
+		})
+	}
+	return feedData
+}
+
+func countPosts(t *testing.T, s *State, feed database.Feed) int64 {
+	t.Helper()
+	counts, err := s.Queries.CountFeedData(context.Background(), feed.ID)
+	if err != nil {
+		t.Fatalf("CountFeedData: %v", err)
+	}
+	return counts.Posts
+}
+
+func TestPrunedPostsStayPruned(t *testing.T) {
+	for _, b := range testBackends(t) {
+		t.Run(b.name, func(t *testing.T) {
+			s := newTestState(t, b)
+			mustRun(t, s,
+				[]string{"register", "alice"},
+				[]string{"addfeed", "Example", testFeedURL},
+				[]string{"retention", "-max-posts", "2", testFeedURL},
+			)
+			feed, err := s.Queries.GetFeedByURL(context.Background(), testFeedURL)
+			if err != nil {
+				t.Fatalf("GetFeedByURL: %v", err)
+			}
+
+			savePosts(s, feed, testRSS(1, 2, 3, 4))
+			mustRun(t, s, []string{"prune", "-yes"})
+			if got := countPosts(t, s, feed); got != 2 {
+				t.Fatalf("after prune: %d posts, want 2", got)
+			}
+
+			// The next fetch still lists the pruned posts, plus a new one.
+			feedData := testRSS(1, 2, 3, 4, 5)
+			savePosts(s, feed, feedData)
+			forgetPrunedPosts(s, feed, feedData)
+			if got := countPosts(t, s, feed); got != 3 {
+				t.Errorf("after refetch: %d posts, want 3", got)
+			}
+
+			// Once the feed stops listing a pruned post it is forgotten.
+			forgetPrunedPosts(s, feed, testRSS(2, 3, 4, 5))
+			for n, want := range map[int]bool{1: false, 2: true} {
+				url := fmt.Sprintf("https://example.com/posts/%d", n)
+				pruned, err := s.Queries.PostWasPruned(context.Background(), url)
+				if err != nil {
+					t.Fatalf("PostWasPruned: %v", err)
+				}
+				if pruned != want {
+					t.Errorf("PostWasPruned(%s) = %v, want %v", url, pruned, want)
+				}
+			}
+		})
+	}
+}
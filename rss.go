@@ -1,53 +1,78 @@
 package main
 
 import (
-	"context"
 	"encoding/xml"
-	"errors"
 	"html"
-	"io"
-	"net/http"
+
+	"github.com/isaacjstriker/gatorapp/internal/schedule"
 )
 
 type RSSFeed struct {
 	Channel struct {
-		Title       string    `xml:"title"`
-		Link        string    `xml:"link"`
-		Description string    `xml:"description"`
-		Item        []RSSItem `xml:"item"`
+		Title           string     `xml:"title"`
+		Link            string     `xml:"link"`
+		Description     string     `xml:"description"`
+		TTL             string     `xml:"ttl"`
+		UpdatePeriod    string     `xml:"http://purl.org/rss/1.0/modules/syndication/ updatePeriod"`
+		UpdateFrequency string     `xml:"http://purl.org/rss/1.0/modules/syndication/ updateFrequency"`
+		SkipHours       []string   `xml:"skipHours>hour"`
+		SkipDays        []string   `xml:"skipDays>day"`
+		AtomLinks       []AtomLink `xml:"http://www.w3.org/2005/Atom link"`
+		Item            []RSSItem  `xml:"item"`
 	} `xml:"channel"`
 }
 
+type AtomLink struct {
+	Rel  string `xml:"rel,attr"`
+	Href string `xml:"href,attr"`
+}
+
 type RSSItem struct {
-	Title       string `xml:"title"`
-	Link        string `xml:"link"`
-	Description string `xml:"description"`
-	PubDate     string `xml:"pubDate"`
+	Title       string   `xml:"title"`
+	Link        string   `xml:"link"`
+	Description string   `xml:"description"`
+	PubDate     string   `xml:"pubDate"`
+	Author      string   `xml:"author"`
+	Creator     string   `xml:"http://purl.org/dc/elements/1.1/ creator"`
+	Categories  []string `xml:"category"`
 }
 
-func fetchFeed(ctx context.Context, feedURL string) (*RSSFeed, error) {
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, feedURL, nil)
-	if err != nil {
-		return nil, err
+// AuthorName returns the item's author, preferring dc:creator, which holds
+// a name, over author, which RSS 2.0 defines as an email address.
+func (item RSSItem) AuthorName() string {
+	if item.Creator != "" {
+		return item.Creator
 	}
+	return item.Author
+}
 
-	req.Header.Set("User-Agent", "gator")
+// Hints returns the polling hints the feed publishes.
+func (f *RSSFeed) Hints() schedule.Hints {
+	c := f.Channel
+	return schedule.ParseHints(c.TTL, c.UpdatePeriod, c.UpdateFrequency, c.SkipHours, c.SkipDays)
+}
 
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return nil, err
+// link returns the href of the first <atom:link> with the given rel.
+func (f *RSSFeed) link(rel string) string {
+	for _, l := range f.Channel.AtomLinks {
+		if l.Rel == rel && l.Href != "" {
+			return l.Href
+		}
 	}
-	defer resp.Body.Close()
+	return ""
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, errors.New("failed to fetch feed: " + resp.Status)
-	}
+// HubURL is the WebSub hub the feed advertises, if any.
+func (f *RSSFeed) HubURL() string {
+	return f.link("hub")
+}
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
-	}
+// SelfURL is the feed's own URL as the publisher announces it to its hub.
+func (f *RSSFeed) SelfURL() string {
+	return f.link("self")
+}
 
+func parseFeed(body []byte) (*RSSFeed, error) {
 	var feed RSSFeed
 	if err := xml.Unmarshal(body, &feed); err != nil {
 		return nil, err
@@ -0,0 +1,325 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/isaacjstriker/gatorapp/internal/database"
+	"github.com/isaacjstriker/gatorapp/internal/filter"
+)
+
+// What a filter rule does to the posts it matches.
+const (
+	ruleHide = "hide"
+	ruleRead = "read"
+	ruleStar = "star"
+	ruleTag  = "tag"
+)
+
+var ruleActions = []string{ruleHide, ruleRead, ruleStar, ruleTag}
+
+// userRule is a stored filter rule ready to match posts.
+type userRule struct {
+	database.GetFilterRulesForUserRow
+	matcher *filter.Rule
+}
+
+// appliesTo reports whether the rule covers posts of the given feed.
+func (r userRule) appliesTo(feedID uuid.UUID) bool {
+	return !r.FeedID.Valid || r.FeedID.UUID == feedID
+}
+
+// loadRules returns the user's filter rules. Rules that no longer compile
+// are skipped with a warning rather than failing the command.
+func loadRules(s *State, user database.User) ([]userRule, error) {
+	rows, err := s.Queries.GetFilterRulesForUser(context.Background(), user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("could not get filter rules: %w", err)
+	}
+	rules := make([]userRule, 0, len(rows))
+	for _, row := range rows {
+		matcher, err := filter.Compile(row.Field, row.MatchType, row.Pattern)
+		if err != nil {
+			slog.Warn("skipping invalid filter rule", "rule_id", row.ID, "error", err)
+			continue
+		}
+		rules = append(rules, userRule{GetFilterRulesForUserRow: row, matcher: matcher})
+	}
+	return rules, nil
+}
+
+// matchingRules returns the rules that match a post of the given feed.
+func matchingRules(rules []userRule, feedID uuid.UUID, post filter.Post) []userRule {
+	var matched []userRule
+	for _, rule := range rules {
+		if rule.appliesTo(feedID) && rule.matcher.Matches(post) {
+			matched = append(matched, rule)
+		}
+	}
+	return matched
+}
+
+// ruleMarks describes what matching rules do to a post shown in browse, and
+// whether any of them hides it.
+func ruleMarks(matched []userRule) (marks []string, hidden bool) {
+	for _, rule := range matched {
+		var mark string
+		switch rule.Action {
+		case ruleHide:
+			hidden = true
+			continue
+		case ruleRead:
+			mark = "read"
+		case ruleStar:
+			mark = "starred"
+		case ruleTag:
+			mark = "#" + rule.Tag
+		}
+		if !slices.Contains(marks, mark) {
+			marks = append(marks, mark)
+		}
+	}
+	return marks, hidden
+}
+
+func addRuleFlags(fs *flag.FlagSet) {
+	fs.String("field", filter.FieldTitle, "post field to match: "+strings.Join(filter.Fields, ", "))
+	fs.Bool("regex", false, "treat the pattern as a regular expression instead of a keyword")
+	fs.String("feed", "", "only apply the rule to this feed; by default it applies to every feed you follow")
+	fs.String("tag", "", "tag to add to matching posts, for the tag action")
+}
+
+func handlerRuleAdd(s *State, user database.User, cmd Command) error {
+	usage := "rule add [flags] <hide|read|star|tag> <pattern>"
+	action, pattern := cmd.args[0], cmd.args[1]
+
+	if !slices.Contains(ruleActions, action) {
+		return &UsageError{Usage: usage, Reason: fmt.Sprintf("unknown action %q", action)}
+	}
+	tag := normalizeTag(cmd.flagString("tag"))
+	if (action == ruleTag) != (tag != "") {
+		return &UsageError{Usage: usage, Reason: "-tag is required for the tag action, and only for it"}
+	}
+
+	match := filter.MatchKeyword
+	if cmd.flagBool("regex") {
+		match = filter.MatchRegex
+	}
+	field := cmd.flagString("field")
+	if _, err := filter.Compile(field, match, pattern); err != nil {
+		return &UsageError{Usage: usage, Reason: err.Error()}
+	}
+
+	var feedID uuid.NullUUID
+	if rawURL := cmd.flagString("feed"); rawURL != "" {
+		feed, _, err := findFollow(s, user, rawURL)
+		if err != nil {
+			return err
+		}
+		feedID = uuid.NullUUID{UUID: feed.ID, Valid: true}
+	}
+
+	rule, err := s.Queries.CreateFilterRule(context.Background(), database.CreateFilterRuleParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now().UTC(),
+		UserID:    user.ID,
+		FeedID:    feedID,
+		Field:     field,
+		MatchType: match,
+		Pattern:   pattern,
+		Action:    action,
+		Tag:       tag,
+	})
+	if err != nil {
+		return fmt.Errorf("could not create filter rule: %w", err)
+	}
+	fmt.Printf("Added rule %s\n", shortID(rule.ID))
+	return nil
+}
+
+func handlerRuleList(s *State, user database.User, cmd Command) error {
+	rules, err := s.Queries.GetFilterRulesForUser(context.Background(), user.ID)
+	if err != nil {
+		return fmt.Errorf("could not get filter rules: %w", err)
+	}
+	if len(rules) == 0 {
+		fmt.Println("You have no filter rules.")
+		return nil
+	}
+	fmt.Println("Filter rules:")
+	for _, rule := range rules {
+		action := rule.Action
+		if rule.Action == ruleTag {
+			action += " #" + rule.Tag
+		}
+		scope := "all feeds"
+		if rule.FeedUrl != "" {
+			scope = rule.FeedUrl
+		}
+		fmt.Printf("- %s: %s when %s %s %q (%s)\n",
+			shortID(rule.ID), action, rule.Field, rule.MatchType, rule.Pattern, scope)
+	}
+	return nil
+}
+
+// handlerRuleRemove deletes a rule by ID or by a unique prefix of its ID, as
+// shown by rule list.
+func handlerRuleRemove(s *State, user database.User, cmd Command) error {
+	rules, err := s.Queries.GetFilterRulesForUser(context.Background(), user.ID)
+	if err != nil {
+		return fmt.Errorf("could not get filter rules: %w", err)
+	}
+	ids := make([]uuid.UUID, len(rules))
+	for i, rule := range rules {
+		ids[i] = rule.ID
+	}
+	id, err := resolveID("filter rule", "rule rm <id>", cmd.args[0], ids)
+	if err != nil {
+		return err
+	}
+
+	if _, err := s.Queries.DeleteFilterRule(context.Background(), database.DeleteFilterRuleParams{
+		UserID: user.ID,
+		ID:     id,
+	}); err != nil {
+		return fmt.Errorf("could not delete filter rule: %w", err)
+	}
+	fmt.Printf("Deleted rule %s\n", shortID(id))
+	return nil
+}
+
+func applyRuleFlags(fs *flag.FlagSet) {
+	fs.Bool("dry-run", false, "report what the rules would do without changing any posts")
+}
+
+// handlerRuleApply runs the user's rules over every post already saved for
+// the feeds they follow, recording the results.
+func handlerRuleApply(s *State, user database.User, cmd Command) error {
+	ctx := context.Background()
+	dryRun := cmd.flagBool("dry-run")
+
+	rules, err := loadRules(s, user)
+	if err != nil {
+		return err
+	}
+	if len(rules) == 0 {
+		fmt.Println("You have no filter rules.")
+		return nil
+	}
+	posts, err := s.Queries.GetAllPostsForUser(ctx, user.ID)
+	if err != nil {
+		return fmt.Errorf("could not get posts: %w", err)
+	}
+
+	now := time.Now().UTC()
+	applied := make(map[string]int)
+	for _, post := range posts {
+		matched := matchingRules(rules, post.FeedID, filter.Post{
+			Title:       post.Title,
+			Description: post.Description.String,
+			Author:      post.Author,
+			Categories:  post.Categories,
+		})
+		for _, rule := range matched {
+			applied[rule.Action]++
+			if dryRun {
+				continue
+			}
+			if err := applyRule(ctx, s.Queries, user.ID, post.ID, rule.Action, rule.Tag, now); err != nil {
+				return fmt.Errorf("could not apply rule %s to %s: %w", shortID(rule.ID), post.Url, err)
+			}
+		}
+	}
+
+	verb := "Applied"
+	if dryRun {
+		verb = "Dry run: would apply"
+	}
+	fmt.Printf("%s rules to %s:\n", verb, count(int64(len(posts)), "post"))
+	for _, action := range ruleActions {
+		fmt.Printf("- %s: %d\n", action, applied[action])
+	}
+	return nil
+}
+
+// applyRule records what a rule with the given action and tag does to one
+// user's view of a post.
+func applyRule(ctx context.Context, q database.Querier, userID, postID uuid.UUID, action, tag string, now time.Time) error {
+	at := sql.NullTime{Time: now, Valid: true}
+	switch action {
+	case ruleHide:
+		return q.SetPostHidden(ctx, database.SetPostHiddenParams{UserID: userID, PostID: postID, HiddenAt: at})
+	case ruleRead:
+		return q.SetPostRead(ctx, database.SetPostReadParams{UserID: userID, PostID: postID, ReadAt: at})
+	case ruleStar:
+		return q.SetPostStarred(ctx, database.SetPostStarredParams{UserID: userID, PostID: postID, StarredAt: at})
+	case ruleTag:
+		_, err := q.AddPostTag(ctx, database.AddPostTagParams{UserID: userID, PostID: postID, Tag: tag})
+		return err
+	}
+	return fmt.Errorf("unknown action %q", action)
+}
+
+// applyRulesToNewPosts runs the feed's followers' rules over its newly saved
+// posts and records the results, as rule apply would, so matching posts are
+// hidden, read, starred or tagged as they arrive.
+func applyRulesToNewPosts(s *State, feed database.Feed, posts []database.Post) {
+	ctx := context.Background()
+	rules, err := s.Queries.GetFilterRulesForFeed(ctx, feed.ID)
+	if err != nil {
+		feedLog(feed).Error("couldn't get filter rules", "error", err)
+		return
+	}
+
+	now := time.Now().UTC()
+	for _, rule := range rules {
+		matcher, err := filter.Compile(rule.Field, rule.MatchType, rule.Pattern)
+		if err != nil {
+			feedLog(feed).Warn("skipping invalid filter rule", "rule_id", rule.ID, "error", err)
+			continue
+		}
+		for _, post := range posts {
+			if !matcher.Matches(filter.Post{
+				Title:       post.Title,
+				Description: post.Description.String,
+				Author:      post.Author,
+				Categories:  post.Categories,
+			}) {
+				continue
+			}
+			if err := applyRule(ctx, s.Queries, rule.UserID, post.ID, rule.Action, rule.Tag, now); err != nil {
+				feedLog(feed).Error("couldn't apply filter rule", "rule_id", rule.ID, "post_url", post.Url, "error", err)
+			}
+		}
+	}
+}
+
+// shortID abbreviates an ID for display; commands accept the prefix back.
+func shortID(id uuid.UUID) string {
+	return id.String()[:8]
+}
+
+// resolveID returns the one ID in ids that starts with prefix.
+func resolveID(kind, usage, prefix string, ids []uuid.UUID) (uuid.UUID, error) {
+	var found []uuid.UUID
+	for _, id := range ids {
+		if prefix != "" && strings.HasPrefix(id.String(), strings.ToLower(prefix)) {
+			found = append(found, id)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return uuid.Nil, &NotFoundError{Kind: kind, Name: prefix}
+	case 1:
+		return found[0], nil
+	}
+	return uuid.Nil, &UsageError{Usage: usage, Reason: fmt.Sprintf("%q matches %d %ss; give more of the ID", prefix, len(found), kind)}
+}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/isaacjstriker/gatorapp/internal/database"
+)
+
+func TestRulesApplyToNewPosts(t *testing.T) {
+	for _, b := range testBackends(t) {
+		t.Run(b.name, func(t *testing.T) {
+			s := newTestState(t, b)
+			mustRun(t, s,
+				[]string{"register", "alice"},
+				[]string{"addfeed", "Example", testFeedURL},
+				[]string{"rule", "add", "star", "gopher"},
+				[]string{"rule", "add", "hide", "sponsored"},
+				[]string{"register", "bob"},
+				[]string{"follow", testFeedURL},
+			)
+			ctx := context.Background()
+			feed, err := s.Queries.GetFeedByURL(ctx, testFeedURL)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			feedData := &RSSFeed{}
+			feedData.Channel.Item = []RSSItem{
+				{Title: "A gopher appears", Link: "https://example.com/gopher"},
+				{Title: "Sponsored: buy things", Link: "https://example.com/ad"},
+				{Title: "Nothing to see", Link: "https://example.com/plain"},
+			}
+			savePosts(s, feed, feedData)
+
+			alice, err := s.Queries.GetUser(ctx, "alice")
+			if err != nil {
+				t.Fatal(err)
+			}
+			marks := make(map[uuid.UUID]string)
+			states, err := s.Queries.GetAllPostStates(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, state := range states {
+				if state.UserID != alice.ID {
+					t.Errorf("post state recorded for a user without rules: %+v", state)
+					continue
+				}
+				switch {
+				case state.StarredAt.Valid:
+					marks[state.PostID] = "starred"
+				case state.HiddenAt.Valid:
+					marks[state.PostID] = "hidden"
+				}
+			}
+
+			for url, want := range map[string]string{
+				"https://example.com/gopher": "starred",
+				"https://example.com/ad":     "hidden",
+				"https://example.com/plain":  "",
+			} {
+				post, err := s.Queries.GetPostByURL(ctx, url)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got := marks[post.ID]; got != want {
+					t.Errorf("%s: marked %q, want %q", url, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestCanonicalizeKeepsFeedRules(t *testing.T) {
+	for _, b := range testBackends(t) {
+		t.Run(b.name, func(t *testing.T) {
+			s := newTestState(t, b)
+			mustRun(t, s, []string{"register", "alice"}, []string{"addfeed", "Example", testFeedURL})
+			ctx := context.Background()
+			alice, err := s.Queries.GetUser(ctx, "alice")
+			if err != nil {
+				t.Fatal(err)
+			}
+			survivor, err := s.Queries.GetFeedByURL(ctx, testFeedURL)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// A later copy of the same feed, saved before URLs were canonicalized.
+			now := time.Now().UTC()
+			dup, err := s.Queries.CreateFeed(ctx, database.CreateFeedParams{
+				ID:        uuid.New(),
+				CreatedAt: now.Add(time.Second),
+				UpdatedAt: now.Add(time.Second),
+				Name:      "Example again",
+				Url:       "HTTP://EXAMPLE.COM/feed.xml",
+				UserID:    alice.ID,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := s.Queries.CreateFilterRule(ctx, database.CreateFilterRuleParams{
+				ID:        uuid.New(),
+				CreatedAt: now,
+				UserID:    alice.ID,
+				FeedID:    uuid.NullUUID{UUID: dup.ID, Valid: true},
+				Field:     "title",
+				MatchType: "keyword",
+				Pattern:   "gopher",
+				Action:    ruleStar,
+			}); err != nil {
+				t.Fatal(err)
+			}
+
+			mustRun(t, s, []string{"canonicalize"})
+
+			rules, err := s.Queries.GetFilterRulesForUser(ctx, alice.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(rules) != 1 {
+				t.Fatalf("%d rules after merging, want 1", len(rules))
+			}
+			if rules[0].FeedID.UUID != survivor.ID {
+				t.Errorf("rule points at feed %v, want the surviving feed %v", rules[0].FeedID.UUID, survivor.ID)
+			}
+		})
+	}
+}
+
+func TestCanonicalizeKeepsFollowSettings(t *testing.T) {
+	for _, b := range testBackends(t) {
+		t.Run(b.name, func(t *testing.T) {
+			s := newTestState(t, b)
+			mustRun(t, s, []string{"register", "alice"}, []string{"addfeed", "Example", testFeedURL})
+			ctx := context.Background()
+			alice, err := s.Queries.GetUser(ctx, "alice")
+			if err != nil {
+				t.Fatal(err)
+			}
+			survivor, err := s.Queries.GetFeedByURL(ctx, testFeedURL)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// alice follows a later copy of the same feed too, and has set up that
+			// follow rather than the one that survives.
+			now := time.Now().UTC()
+			dup, err := s.Queries.CreateFeed(ctx, database.CreateFeedParams{
+				ID:        uuid.New(),
+				CreatedAt: now.Add(time.Second),
+				UpdatedAt: now.Add(time.Second),
+				Name:      "Example again",
+				Url:       "HTTP://EXAMPLE.COM/feed.xml",
+				UserID:    alice.ID,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := s.Queries.CreateFeedFollow(ctx, database.CreateFeedFollowParams{
+				ID:        uuid.New(),
+				CreatedAt: now,
+				UpdatedAt: now,
+				UserID:    alice.ID,
+				FeedID:    dup.ID,
+			}); err != nil {
+				t.Fatal(err)
+			}
+			folder, err := s.Queries.CreateFolder(ctx, database.CreateFolderParams{
+				ID:        uuid.New(),
+				CreatedAt: now,
+				UpdatedAt: now,
+				UserID:    alice.ID,
+				Name:      "news",
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := s.Queries.SetFeedFollowFolder(ctx, database.SetFeedFollowFolderParams{
+				UserID:   alice.ID,
+				FeedID:   dup.ID,
+				FolderID: uuid.NullUUID{UUID: folder.ID, Valid: true},
+			}); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := s.Queries.UpdateFeedFollowSettings(ctx, database.UpdateFeedFollowSettingsParams{
+				UserID:   alice.ID,
+				FeedID:   dup.ID,
+				Title:    sql.NullString{String: "My example", Valid: true},
+				Muted:    true,
+				Priority: 2,
+				Notify:   false,
+			}); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := s.Queries.AddFeedFollowTag(ctx, database.AddFeedFollowTagParams{
+				Tag:    "go",
+				UserID: alice.ID,
+				FeedID: dup.ID,
+			}); err != nil {
+				t.Fatal(err)
+			}
+			if err := s.Queries.SetFeedRetention(ctx, database.SetFeedRetentionParams{
+				ID:                dup.ID,
+				RetentionMaxPosts: sql.NullInt32{Int32: 20, Valid: true},
+			}); err != nil {
+				t.Fatal(err)
+			}
+
+			mustRun(t, s, []string{"canonicalize"})
+
+			follows, err := s.Queries.GetFeedFollowsForUser(ctx, database.GetFeedFollowsForUserParams{UserID: alice.ID})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(follows) != 1 {
+				t.Fatalf("alice has %d follows after merging, want 1", len(follows))
+			}
+			follow := follows[0]
+			if follow.FeedID != survivor.ID {
+				t.Errorf("follow points at feed %v, want the surviving feed %v", follow.FeedID, survivor.ID)
+			}
+			if follow.FeedName != "My example" || follow.FolderName != "news" || !follow.Muted || follow.Notify || follow.Priority != 2 {
+				t.Errorf("follow after merging = %+v, want the merged follow's title, folder, priority, muting and notifications", follow)
+			}
+			if len(follow.Tags) != 1 || follow.Tags[0] != "go" {
+				t.Errorf("follow tags = %v, want [go]", follow.Tags)
+			}
+
+			feed, err := s.Queries.GetFeedByURL(ctx, testFeedURL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if feed.RetentionMaxPosts != (sql.NullInt32{Int32: 20, Valid: true}) {
+				t.Errorf("surviving feed keeps %v posts, want the merged feed's 20", feed.RetentionMaxPosts)
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// newAggMux builds the routes served while agg runs.
+func newAggMux(s *State) *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", s.Metrics.Handler())
+	if s.Config.WebSub.Enabled() {
+		mux.Handle("/websub/", newWebSubHandler(s))
+	}
+	handleFever(mux, s)
+	return mux
+}
+
+// newServeMux builds the routes served by serve.
+func newServeMux(s *State) *http.ServeMux {
+	mux := http.NewServeMux()
+	handleFever(mux, s)
+	return mux
+}
+
+// handleFever serves the Fever API. Clients are told to use /fever/, but
+// some drop the trailing slash, and redirecting them would lose the api_key
+// they POST.
+func handleFever(mux *http.ServeMux, s *State) {
+	h := newFeverHandler(s)
+	mux.Handle("/fever/", h)
+	mux.Handle("/fever", h)
+}
+
+// serveAgg runs agg's HTTP server until it fails.
+func serveAgg(s *State) {
+	server := &http.Server{
+		Addr:              s.Config.Server.ListenAddrOrDefault(),
+		Handler:           newAggMux(s),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	slog.Info("listening for http", "addr", server.Addr)
+	if err := server.ListenAndServe(); err != nil {
+		slog.Error("http server stopped", "addr", server.Addr, "error", err)
+	}
+}
+
+// handlerServe runs the HTTP API for feed reader clients without fetching
+// feeds, for when agg runs elsewhere or without its HTTP server.
+func handlerServe(s *State, cmd Command) error {
+	server := &http.Server{
+		Addr:              s.Config.Server.ListenAddrOrDefault(),
+		Handler:           newServeMux(s),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	slog.Info("listening for http", "addr", server.Addr)
+	fmt.Printf("Serving the Fever API at %s\n", feverURL(s))
+	if err := server.ListenAndServe(); err != nil {
+		return fmt.Errorf("http server stopped: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,24 @@
+-- name: GetPostsAfter :many
+-- A page of every post in id order, for backups. Pass the nil UUID for the
+-- first page and the last id of each page for the next.
+SELECT * FROM posts
+WHERE id > sqlc.arg(after)
+ORDER BY id
+LIMIT sqlc.arg(max_posts);
+
+-- name: GetAllPostStates :many
+-- Every user's read, starred and hidden marks, leaving out rows with none.
+SELECT * FROM post_states
+WHERE read_at IS NOT NULL OR starred_at IS NOT NULL OR hidden_at IS NOT NULL
+ORDER BY user_id, post_id;
+
+-- name: GetAllPostTags :many
+SELECT * FROM post_tags
+ORDER BY user_id, post_id, tag;
+
+-- name: RestorePostState :execrows
+-- Adds a user's marks on a post from a backup, leaving any the user already
+-- has alone.
+INSERT INTO post_states (user_id, post_id, read_at, starred_at, hidden_at)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (user_id, post_id) DO NOTHING;
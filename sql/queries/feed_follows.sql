@@ -13,18 +13,78 @@ INNER JOIN users u ON f.user_id = u.id
 INNER JOIN feeds fe ON f.feed_id = fe.id;
 
 -- name: GetFeedFollowsForUser :many
+-- Optionally narrowed to one folder and/or tag. feed_name is the user's own
+-- title for the feed when they have set one.
 SELECT
     ff.*,
     u.name AS user_name,
-    f.name AS feed_name
+    COALESCE(ff.title, f.name)::text AS feed_name,
+    f.url AS feed_url,
+    COALESCE(folders.name, '')::text AS folder_name,
+    COALESCE(
+        (SELECT array_agg(t.tag ORDER BY t.tag) FROM feed_follow_tags t WHERE t.feed_follow_id = ff.id),
+        '{}'
+    )::text[] AS tags
 FROM feed_follows ff 
 INNER JOIN users u ON ff.user_id = u.id 
 INNER JOIN feeds f ON ff.feed_id = f.id 
-WHERE ff.user_id = $1;
+LEFT JOIN folders ON ff.folder_id = folders.id
+WHERE ff.user_id = sqlc.arg(user_id)
+    AND (sqlc.narg(folder)::text IS NULL OR folders.name = sqlc.narg(folder)::text)
+    AND (sqlc.narg(tag)::text IS NULL OR EXISTS (
+        SELECT 1 FROM feed_follow_tags t
+        WHERE t.feed_follow_id = ff.id AND t.tag = sqlc.narg(tag)::text
+    ))
+ORDER BY folder_name, feed_name;
 
--- name: DelFeedFollow :exec
+-- name: DelFeedFollow :execrows
 DELETE FROM feed_follows
 USING feeds
 WHERE feed_follows.user_id = $1
     AND feed_follows.feed_id = feeds.id 
     AND feeds.url = $2;
+
+-- name: MoveFeedFollows :exec
+UPDATE feed_follows
+SET feed_id = sqlc.arg(to_feed_id),
+    updated_at = sqlc.arg(updated_at)
+WHERE feed_follows.feed_id = sqlc.arg(from_feed_id)
+    AND feed_follows.user_id NOT IN (
+        SELECT existing.user_id FROM feed_follows existing
+        WHERE existing.feed_id = sqlc.arg(to_feed_id)
+    );
+
+-- name: MergeFeedFollowSettings :exec
+-- For users who follow both feeds, fills in their follow of to_feed_id from
+-- their follow of from_feed_id: a title or folder it lacks, a priority when
+-- it has none, and muting or turning notifications off.
+UPDATE feed_follows
+SET title = COALESCE(feed_follows.title, merged.title),
+    folder_id = COALESCE(feed_follows.folder_id, merged.folder_id),
+    priority = CASE WHEN feed_follows.priority <> 0 THEN feed_follows.priority ELSE merged.priority END,
+    muted = feed_follows.muted OR merged.muted,
+    notify = feed_follows.notify AND merged.notify,
+    updated_at = sqlc.arg(updated_at)
+FROM feed_follows AS merged
+WHERE feed_follows.feed_id = sqlc.arg(to_feed_id)
+    AND merged.feed_id = sqlc.arg(from_feed_id)
+    AND merged.user_id = feed_follows.user_id;
+
+-- name: GetFeedFollowers :many
+SELECT users.* FROM users
+JOIN feed_follows ON feed_follows.user_id = users.id
+WHERE feed_follows.feed_id = $1
+ORDER BY feed_follows.created_at ASC;
+
+-- name: GetFeedFollow :one
+SELECT * FROM feed_follows
+WHERE user_id = $1 AND feed_id = $2;
+
+-- name: UpdateFeedFollowSettings :execrows
+UPDATE feed_follows
+SET title = $3,
+    muted = $4,
+    priority = $5,
+    notify = $6,
+    updated_at = $7
+WHERE user_id = $1 AND feed_id = $2;
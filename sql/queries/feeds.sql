@@ -20,12 +20,149 @@ SELECT * FROM feeds WHERE url = $1;
 
 -- name: MarkFeedFetched :one
 UPDATE feeds
-SET last_fetched_at = NOW(),
-updated_at = NOW()
+SET last_fetched_at = $2,
+updated_at = $2
 WHERE id = $1
 RETURNING *;
 
--- name: GetNextFeedToFetch :one
+-- name: GetNextFeedsToFetch :many
 SELECT * FROM feeds
-ORDER BY last_fetched_at ASC NULLS FIRST
-LIMIT 1;
\ No newline at end of file
+WHERE deactivated_at IS NULL
+    AND (next_fetch_at IS NULL OR next_fetch_at <= sqlc.arg(now)::timestamp)
+ORDER BY next_fetch_at ASC NULLS FIRST, last_fetched_at ASC NULLS FIRST
+LIMIT sqlc.arg(max_feeds);
+
+-- name: GetEarliestNextFetch :one
+SELECT next_fetch_at FROM feeds
+WHERE deactivated_at IS NULL
+ORDER BY next_fetch_at ASC NULLS FIRST
+LIMIT 1;
+
+-- name: RecordFeedFetchError :one
+UPDATE feeds
+SET fetch_error_count = fetch_error_count + 1
+WHERE id = $1
+RETURNING fetch_error_count;
+
+-- name: ResetFeedFetchErrors :exec
+UPDATE feeds
+SET fetch_error_count = 0
+WHERE id = $1 AND fetch_error_count <> 0;
+
+-- name: SetFeedNextFetch :exec
+UPDATE feeds
+SET next_fetch_at = $2
+WHERE id = $1;
+
+-- name: GetFeeds :many
+SELECT * FROM feeds
+ORDER BY created_at ASC;
+
+-- name: GetFeedsByOwner :many
+SELECT * FROM feeds
+WHERE user_id = $1
+ORDER BY created_at ASC;
+
+-- name: UpdateFeedURL :exec
+UPDATE feeds
+SET url = $2,
+updated_at = $3
+WHERE id = $1;
+
+-- name: DeleteFeed :exec
+DELETE FROM feeds WHERE id = $1;
+
+-- name: RecordFeedRedirect :one
+UPDATE feeds
+SET redirect_count = CASE WHEN redirect_url = sqlc.arg(redirect_url) THEN redirect_count + 1 ELSE 1 END,
+redirect_url = sqlc.arg(redirect_url),
+updated_at = sqlc.arg(updated_at)
+WHERE id = sqlc.arg(id)
+RETURNING *;
+
+-- name: ClearFeedRedirect :exec
+UPDATE feeds
+SET redirect_url = NULL,
+redirect_count = 0,
+updated_at = $2
+WHERE id = $1 AND redirect_url IS NOT NULL;
+
+-- name: DeactivateFeed :exec
+UPDATE feeds
+SET deactivated_at = $2,
+updated_at = $2
+WHERE id = $1;
+
+-- name: AddFeedURLHistory :exec
+INSERT INTO feed_url_history (id, created_at, url, feed_id)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (url) DO UPDATE SET feed_id = EXCLUDED.feed_id;
+
+-- name: MoveFeedURLHistory :exec
+UPDATE feed_url_history
+SET feed_id = sqlc.arg(to_feed_id)
+WHERE feed_id = sqlc.arg(from_feed_id);
+
+-- name: GetFeedURLHistory :many
+SELECT * FROM feed_url_history
+WHERE feed_id = $1
+ORDER BY created_at;
+
+-- name: GetFeedByPreviousURL :one
+SELECT feeds.* FROM feeds
+JOIN feed_url_history ON feed_url_history.feed_id = feeds.id
+WHERE feed_url_history.url = $1;
+
+-- name: GetFeedByID :one
+SELECT * FROM feeds WHERE id = $1;
+
+-- name: CountOverdueFeeds :one
+SELECT COUNT(*) FROM feeds
+WHERE deactivated_at IS NULL
+    AND (next_fetch_at IS NULL OR next_fetch_at <= sqlc.arg(now)::timestamp);
+
+-- name: CountFeedData :one
+SELECT
+    (SELECT COUNT(*) FROM feed_follows WHERE feed_follows.feed_id = $1) AS follows,
+    (SELECT COUNT(*) FROM posts WHERE posts.feed_id = $1) AS posts;
+
+-- name: SetFeedRetention :exec
+UPDATE feeds
+SET retention_max_age_seconds = $2,
+retention_max_posts = $3,
+updated_at = $4
+WHERE id = $1;
+
+-- name: MergeFeedRetention :exec
+-- Gives to_feed_id the retention limits of from_feed_id that it hasn't set
+-- itself.
+UPDATE feeds
+SET retention_max_age_seconds = COALESCE(feeds.retention_max_age_seconds, merged.retention_max_age_seconds),
+    retention_max_posts = COALESCE(feeds.retention_max_posts, merged.retention_max_posts),
+    updated_at = sqlc.arg(updated_at)
+FROM feeds AS merged
+WHERE feeds.id = sqlc.arg(to_feed_id) AND merged.id = sqlc.arg(from_feed_id);
+
+-- name: RenameFeed :exec
+UPDATE feeds
+SET name = $2,
+updated_at = $3
+WHERE id = $1;
+
+-- name: ResetFeedFetchState :exec
+-- Forgets what fetching the feed's previous URL taught us, so a corrected
+-- URL is fetched straight away.
+UPDATE feeds
+SET redirect_url = NULL,
+redirect_count = 0,
+deactivated_at = NULL,
+fetch_error_count = 0,
+next_fetch_at = NULL,
+updated_at = $2
+WHERE id = $1;
+
+-- name: SetFeedOwner :exec
+UPDATE feeds
+SET user_id = $2,
+updated_at = $3
+WHERE id = $1;
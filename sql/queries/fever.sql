@@ -0,0 +1,198 @@
+-- name: GetUserByFeverAPIKey :one
+SELECT * FROM users
+WHERE fever_api_key = $1;
+
+-- name: SetFeverAPIKey :execrows
+-- Pass a NULL fever_api_key to turn Fever off for the user.
+UPDATE users
+SET fever_api_key = sqlc.narg(fever_api_key),
+updated_at = sqlc.arg(updated_at)
+WHERE id = sqlc.arg(id);
+
+-- name: GetFeverGroups :many
+SELECT fever_id, name FROM folders
+WHERE user_id = $1
+ORDER BY name;
+
+-- name: GetFeverFeeds :many
+-- The feeds the user follows, with the folder each is in, if any, and
+-- whether we have its icon.
+SELECT
+    feeds.fever_id,
+    COALESCE(feed_follows.title, feeds.name)::text AS title,
+    feeds.url,
+    feeds.last_fetched_at,
+    feed_follows.muted,
+    folders.fever_id AS group_id,
+    (favicons.data IS NOT NULL)::boolean AS has_favicon
+FROM feed_follows
+JOIN feeds ON feed_follows.feed_id = feeds.id
+LEFT JOIN folders ON feed_follows.folder_id = folders.id
+LEFT JOIN favicons ON favicons.feed_id = feeds.id
+WHERE feed_follows.user_id = $1
+ORDER BY feeds.fever_id;
+
+-- name: GetFeverLastRefreshed :one
+-- When the most recently fetched of the user's feeds was fetched.
+SELECT feeds.last_fetched_at FROM feeds
+JOIN feed_follows ON feed_follows.feed_id = feeds.id
+WHERE feed_follows.user_id = $1 AND feeds.last_fetched_at IS NOT NULL
+ORDER BY feeds.last_fetched_at DESC
+LIMIT 1;
+
+-- name: GetFeverItemsAfter :many
+-- The oldest posts in the user's feeds after since_id. Hidden posts are
+-- left out here and in the other item queries.
+SELECT
+    posts.fever_id,
+    feeds.fever_id AS feed_fever_id,
+    posts.title,
+    posts.author,
+    posts.description,
+    posts.url,
+    posts.published_at,
+    posts.created_at,
+    post_states.read_at,
+    post_states.starred_at
+FROM posts
+JOIN feeds ON posts.feed_id = feeds.id
+JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+LEFT JOIN post_states ON post_states.post_id = posts.id AND post_states.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = sqlc.arg(user_id)
+    AND post_states.hidden_at IS NULL
+    AND posts.fever_id > sqlc.arg(since_id)
+ORDER BY posts.fever_id
+LIMIT sqlc.arg(max_items);
+
+-- name: GetFeverItemsBefore :many
+-- The newest posts in the user's feeds before max_id.
+SELECT
+    posts.fever_id,
+    feeds.fever_id AS feed_fever_id,
+    posts.title,
+    posts.author,
+    posts.description,
+    posts.url,
+    posts.published_at,
+    posts.created_at,
+    post_states.read_at,
+    post_states.starred_at
+FROM posts
+JOIN feeds ON posts.feed_id = feeds.id
+JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+LEFT JOIN post_states ON post_states.post_id = posts.id AND post_states.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = sqlc.arg(user_id)
+    AND post_states.hidden_at IS NULL
+    AND posts.fever_id < sqlc.arg(max_id)
+ORDER BY posts.fever_id DESC
+LIMIT sqlc.arg(max_items);
+
+-- name: GetFeverItemsByID :many
+SELECT
+    posts.fever_id,
+    feeds.fever_id AS feed_fever_id,
+    posts.title,
+    posts.author,
+    posts.description,
+    posts.url,
+    posts.published_at,
+    posts.created_at,
+    post_states.read_at,
+    post_states.starred_at
+FROM posts
+JOIN feeds ON posts.feed_id = feeds.id
+JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+LEFT JOIN post_states ON post_states.post_id = posts.id AND post_states.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = sqlc.arg(user_id)
+    AND post_states.hidden_at IS NULL
+    AND posts.fever_id = ANY(sqlc.arg(ids)::bigint[])
+ORDER BY posts.fever_id;
+
+-- name: CountFeverItems :one
+SELECT COUNT(*) FROM posts
+JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+LEFT JOIN post_states ON post_states.post_id = posts.id AND post_states.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = $1
+    AND post_states.hidden_at IS NULL;
+
+-- name: GetFeverUnreadItemIDs :many
+SELECT posts.fever_id FROM posts
+JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+LEFT JOIN post_states ON post_states.post_id = posts.id AND post_states.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = $1
+    AND post_states.read_at IS NULL
+    AND post_states.hidden_at IS NULL
+ORDER BY posts.fever_id;
+
+-- name: GetFeverSavedItemIDs :many
+SELECT posts.fever_id FROM posts
+JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+JOIN post_states ON post_states.post_id = posts.id AND post_states.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = $1
+    AND post_states.starred_at IS NOT NULL
+    AND post_states.hidden_at IS NULL
+ORDER BY posts.fever_id;
+
+-- name: GetFeverPostID :one
+-- The post with this Fever ID, if it is in one of the user's feeds.
+SELECT posts.id FROM posts
+JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+WHERE posts.fever_id = sqlc.arg(fever_id) AND feed_follows.user_id = sqlc.arg(user_id);
+
+-- name: MarkFeverFeedRead :execrows
+-- Marks the feed's posts saved before before as read, keeping the read time
+-- of ones already read.
+INSERT INTO post_states (user_id, post_id, read_at)
+SELECT feed_follows.user_id, posts.id, sqlc.arg(read_at)::timestamp
+FROM posts
+JOIN feeds ON posts.feed_id = feeds.id
+JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+WHERE feed_follows.user_id = sqlc.arg(user_id)
+    AND feeds.fever_id = sqlc.arg(feed_id)
+    AND posts.created_at < sqlc.arg(before)
+ON CONFLICT (user_id, post_id) DO UPDATE SET read_at = COALESCE(post_states.read_at, EXCLUDED.read_at);
+
+-- name: MarkFeverGroupRead :execrows
+-- Like MarkFeverFeedRead, for every feed in the folder.
+INSERT INTO post_states (user_id, post_id, read_at)
+SELECT feed_follows.user_id, posts.id, sqlc.arg(read_at)::timestamp
+FROM posts
+JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+JOIN folders ON feed_follows.folder_id = folders.id
+WHERE feed_follows.user_id = sqlc.arg(user_id)
+    AND folders.fever_id = sqlc.arg(group_id)
+    AND posts.created_at < sqlc.arg(before)
+ON CONFLICT (user_id, post_id) DO UPDATE SET read_at = COALESCE(post_states.read_at, EXCLUDED.read_at);
+
+-- name: MarkFeverSuperGroupRead :execrows
+-- Like MarkFeverFeedRead, for every feed the user follows that is muted when
+-- sparks is set, which Fever calls Sparks, or isn't muted otherwise, which it
+-- calls Kindling.
+INSERT INTO post_states (user_id, post_id, read_at)
+SELECT feed_follows.user_id, posts.id, sqlc.arg(read_at)::timestamp
+FROM posts
+JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+WHERE feed_follows.user_id = sqlc.arg(user_id)
+    AND feed_follows.muted = sqlc.arg(sparks)
+    AND posts.created_at < sqlc.arg(before)
+ON CONFLICT (user_id, post_id) DO UPDATE SET read_at = COALESCE(post_states.read_at, EXCLUDED.read_at);
+
+-- name: GetFeverFavicons :many
+SELECT feeds.fever_id, favicons.content_type, favicons.data FROM favicons
+JOIN feeds ON favicons.feed_id = feeds.id
+JOIN feed_follows ON feed_follows.feed_id = feeds.id
+WHERE feed_follows.user_id = $1 AND favicons.data IS NOT NULL
+ORDER BY feeds.fever_id;
+
+-- name: GetFaviconCheckedAt :one
+SELECT checked_at FROM favicons
+WHERE feed_id = $1;
+
+-- name: UpsertFavicon :exec
+-- data is NULL when the site has no icon.
+INSERT INTO favicons (feed_id, checked_at, content_type, data)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (feed_id) DO UPDATE SET
+    checked_at = EXCLUDED.checked_at,
+    content_type = EXCLUDED.content_type,
+    data = EXCLUDED.data;
@@ -0,0 +1,29 @@
+-- name: CreateFilterRule :one
+INSERT INTO filter_rules (id, created_at, user_id, feed_id, field, match_type, pattern, action, tag)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+RETURNING *;
+
+-- name: GetFilterRulesForUser :many
+SELECT filter_rules.*, COALESCE(feeds.url, '')::text AS feed_url
+FROM filter_rules
+LEFT JOIN feeds ON filter_rules.feed_id = feeds.id
+WHERE filter_rules.user_id = $1
+ORDER BY filter_rules.created_at;
+
+-- name: DeleteFilterRule :execrows
+DELETE FROM filter_rules
+WHERE user_id = $1 AND id = $2;
+
+-- name: GetFilterRulesForFeed :many
+-- The rules of the feed's followers that cover its posts: each follower's
+-- rules for every feed and their rules for this one.
+SELECT filter_rules.* FROM filter_rules
+JOIN feed_follows ON feed_follows.user_id = filter_rules.user_id
+WHERE feed_follows.feed_id = sqlc.arg(feed_id)
+    AND (filter_rules.feed_id IS NULL OR filter_rules.feed_id = sqlc.arg(feed_id))
+ORDER BY filter_rules.created_at;
+
+-- name: MoveFilterRules :exec
+UPDATE filter_rules
+SET feed_id = sqlc.arg(to_feed_id)
+WHERE feed_id = sqlc.arg(from_feed_id);
@@ -1,14 +1,125 @@
 -- name: CreatePost :one
-INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id)
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id, author, categories)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+ON CONFLICT (url) DO NOTHING
 RETURNING *;
 --
 
 -- name: GetPostsForUser :many
-SELECT posts.*, feeds.name AS feed_name FROM posts
+-- Optionally narrowed to the feeds in one folder and/or with one tag. Muted
+-- feeds and hidden posts are left out. Newest days come first; within a day,
+-- posts from higher priority feeds come first.
+SELECT posts.*, COALESCE(feed_follows.title, feeds.name)::text AS feed_name FROM posts
 JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
 JOIN feeds ON posts.feed_id = feeds.id
-WHERE feed_follows.user_id = $1
-ORDER BY posts.published_at DESC
-LIMIT $2;
+LEFT JOIN folders ON feed_follows.folder_id = folders.id
+WHERE feed_follows.user_id = sqlc.arg(user_id)
+    AND NOT feed_follows.muted
+    AND NOT EXISTS (
+        SELECT 1 FROM post_states
+        WHERE post_states.user_id = sqlc.arg(user_id)
+            AND post_states.post_id = posts.id
+            AND post_states.hidden_at IS NOT NULL
+    )
+    AND (sqlc.narg(folder)::text IS NULL OR folders.name = sqlc.narg(folder)::text)
+    AND (sqlc.narg(tag)::text IS NULL OR EXISTS (
+        SELECT 1 FROM feed_follow_tags
+        WHERE feed_follow_tags.feed_follow_id = feed_follows.id
+            AND feed_follow_tags.tag = sqlc.narg(tag)::text
+    ))
+ORDER BY date_trunc('day', COALESCE(posts.published_at, posts.created_at)) DESC,
+    feed_follows.priority DESC,
+    COALESCE(posts.published_at, posts.created_at) DESC
+LIMIT sqlc.arg(max_posts) OFFSET sqlc.arg(skip);
 --
+
+-- name: MovePosts :exec
+UPDATE posts
+SET feed_id = sqlc.arg(to_feed_id),
+    updated_at = sqlc.arg(updated_at)
+WHERE feed_id = sqlc.arg(from_feed_id);
+
+-- name: GetPostURLs :many
+SELECT id, url FROM posts
+ORDER BY created_at ASC;
+
+-- name: UpdatePostURL :exec
+UPDATE posts
+SET url = $2,
+updated_at = $3
+WHERE id = $1;
+
+-- name: DeletePost :exec
+DELETE FROM posts WHERE id = $1;
+
+-- name: GetRecentPostTimes :many
+SELECT COALESCE(published_at, created_at)::timestamp AS posted_at
+FROM posts
+WHERE feed_id = $1
+ORDER BY posted_at DESC
+LIMIT $2;
+
+-- name: CountPostsBefore :one
+SELECT COUNT(*) FROM posts
+WHERE COALESCE(published_at, created_at) < sqlc.arg(before)::timestamp;
+
+-- name: DeletePostsBefore :execrows
+DELETE FROM posts
+WHERE COALESCE(published_at, created_at) < sqlc.arg(before)::timestamp;
+
+-- name: GetPostByURL :one
+SELECT * FROM posts WHERE url = $1;
+
+-- name: GetPrunablePosts :many
+-- Posts of a feed that are older than the cutoff or beyond the newest
+-- max_posts, less those kept for being starred or unread by a follower.
+SELECT ranked.id FROM (
+    SELECT posts.id,
+        COALESCE(posts.published_at, posts.created_at) AS posted_at,
+        ROW_NUMBER() OVER (ORDER BY COALESCE(posts.published_at, posts.created_at) DESC) AS rank
+    FROM posts
+    WHERE posts.feed_id = sqlc.arg(feed_id)
+) ranked
+WHERE (
+        ranked.posted_at < sqlc.narg(cutoff)::timestamp
+        OR (sqlc.arg(max_posts)::int > 0 AND ranked.rank > sqlc.arg(max_posts)::int)
+    )
+    AND NOT (sqlc.arg(keep_starred)::bool AND EXISTS (
+        SELECT 1 FROM post_states
+        WHERE post_states.post_id = ranked.id AND post_states.starred_at IS NOT NULL
+    ))
+    AND NOT (sqlc.arg(keep_unread)::bool AND EXISTS (
+        SELECT 1 FROM feed_follows
+        WHERE feed_follows.feed_id = sqlc.arg(feed_id)
+            AND NOT EXISTS (
+                SELECT 1 FROM post_states
+                WHERE post_states.post_id = ranked.id
+                    AND post_states.user_id = feed_follows.user_id
+                    AND post_states.read_at IS NOT NULL
+            )
+    ));
+
+-- name: DeletePostsByID :execrows
+DELETE FROM posts WHERE id = ANY(sqlc.arg(ids)::uuid[]);
+
+-- name: GetAllPostsForUser :many
+-- Every post of every feed the user follows, muted or not, for applying
+-- filter rules.
+SELECT posts.* FROM posts
+JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+WHERE feed_follows.user_id = $1
+ORDER BY posts.created_at;
+
+-- name: RecordPrunedPosts :exec
+INSERT INTO pruned_posts (url, feed_id, pruned_at)
+SELECT url, feed_id, sqlc.arg(pruned_at)::timestamp FROM posts
+WHERE id = ANY(sqlc.arg(ids)::uuid[])
+ON CONFLICT (url) DO NOTHING;
+
+-- name: PostWasPruned :one
+SELECT EXISTS (SELECT 1 FROM pruned_posts WHERE url = $1);
+
+-- name: ForgetPrunedPosts :execrows
+-- Forgets the feed's pruned posts it no longer lists, which can't come back.
+DELETE FROM pruned_posts
+WHERE feed_id = sqlc.arg(feed_id) AND url <> ALL(sqlc.arg(listed)::text[]);
@@ -0,0 +1,37 @@
+-- name: UpsertWebSubSubscription :one
+INSERT INTO websub_subscriptions (id, created_at, updated_at, feed_id, hub_url, topic_url, secret, pending_secret, state)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, 'pending')
+ON CONFLICT (feed_id) DO UPDATE
+SET updated_at = EXCLUDED.updated_at,
+    hub_url = EXCLUDED.hub_url,
+    topic_url = EXCLUDED.topic_url,
+    pending_secret = EXCLUDED.pending_secret,
+    state = 'pending'
+RETURNING *;
+
+-- name: GetWebSubSubscription :one
+SELECT * FROM websub_subscriptions WHERE id = $1;
+
+-- name: GetWebSubSubscriptionForFeed :one
+SELECT * FROM websub_subscriptions WHERE feed_id = $1;
+
+-- name: ActivateWebSubSubscription :exec
+UPDATE websub_subscriptions
+SET state = 'active',
+    secret = COALESCE(pending_secret, secret),
+    pending_secret = NULL,
+    lease_expires_at = $2,
+    renew_at = $3,
+    updated_at = $4
+WHERE id = $1;
+
+-- name: SetWebSubSubscriptionState :exec
+UPDATE websub_subscriptions
+SET state = $2,
+    updated_at = $3
+WHERE id = $1;
+
+-- name: GetWebSubSubscriptionsToRenew :many
+SELECT * FROM websub_subscriptions
+WHERE state = 'active' AND renew_at <= sqlc.arg(before)::timestamp
+ORDER BY renew_at ASC;
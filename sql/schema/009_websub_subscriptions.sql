@@ -0,0 +1,21 @@
+-- +goose Up
+CREATE TABLE websub_subscriptions (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    feed_id UUID NOT NULL UNIQUE REFERENCES feeds(id) ON DELETE CASCADE,
+    hub_url TEXT NOT NULL,
+    topic_url TEXT NOT NULL,
+    secret TEXT NOT NULL,
+    -- The secret of a subscription request the hub hasn't verified yet. The
+    -- hub keeps signing with secret until it does.
+    pending_secret TEXT,
+    -- pending, active, denied, unsubscribing or unsubscribed
+    state TEXT NOT NULL,
+    lease_expires_at TIMESTAMP,
+    -- When to renew the lease, set from the lease the hub granted.
+    renew_at TIMESTAMP
+);
+
+-- +goose Down
+DROP TABLE websub_subscriptions;
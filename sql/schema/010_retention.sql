@@ -0,0 +1,25 @@
+-- +goose Up
+ALTER TABLE feeds ADD COLUMN retention_max_age_seconds BIGINT;
+ALTER TABLE feeds ADD COLUMN retention_max_posts INTEGER;
+
+CREATE TABLE post_states (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    post_id UUID NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    read_at TIMESTAMP,
+    starred_at TIMESTAMP,
+    PRIMARY KEY (user_id, post_id)
+);
+
+-- URLs of posts removed by retention, so they aren't saved again while
+-- their feed still lists them.
+CREATE TABLE pruned_posts (
+    url TEXT PRIMARY KEY,
+    feed_id UUID NOT NULL REFERENCES feeds(id) ON DELETE CASCADE,
+    pruned_at TIMESTAMP NOT NULL
+);
+
+-- +goose Down
+DROP TABLE pruned_posts;
+DROP TABLE post_states;
+ALTER TABLE feeds DROP COLUMN retention_max_posts;
+ALTER TABLE feeds DROP COLUMN retention_max_age_seconds;
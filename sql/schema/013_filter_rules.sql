@@ -0,0 +1,36 @@
+-- +goose Up
+ALTER TABLE posts ADD COLUMN author TEXT NOT NULL DEFAULT '';
+ALTER TABLE posts ADD COLUMN categories TEXT[] NOT NULL DEFAULT '{}';
+
+ALTER TABLE post_states ADD COLUMN hidden_at TIMESTAMP;
+
+CREATE TABLE post_tags (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    post_id UUID NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    tag TEXT NOT NULL,
+    PRIMARY KEY (user_id, post_id, tag)
+);
+
+CREATE TABLE filter_rules (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    -- NULL applies the rule to every feed the user follows.
+    feed_id UUID REFERENCES feeds(id) ON DELETE CASCADE,
+    -- title, description, author or category
+    field TEXT NOT NULL,
+    -- keyword or regex
+    match_type TEXT NOT NULL,
+    pattern TEXT NOT NULL,
+    -- hide, read, star or tag
+    action TEXT NOT NULL,
+    -- The tag to add when action is tag.
+    tag TEXT NOT NULL DEFAULT ''
+);
+
+-- +goose Down
+DROP TABLE filter_rules;
+DROP TABLE post_tags;
+ALTER TABLE post_states DROP COLUMN hidden_at;
+ALTER TABLE posts DROP COLUMN categories;
+ALTER TABLE posts DROP COLUMN author;
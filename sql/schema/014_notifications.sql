@@ -0,0 +1,42 @@
+-- +goose Up
+CREATE TABLE notification_rules (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    -- NULL watches every feed the user follows.
+    feed_id UUID REFERENCES feeds(id) ON DELETE CASCADE,
+    -- title, description, author or category
+    field TEXT NOT NULL,
+    -- keyword or regex
+    match_type TEXT NOT NULL,
+    -- An empty pattern matches every new post.
+    pattern TEXT NOT NULL,
+    -- The name of a sink in the notifications config.
+    sink TEXT NOT NULL
+);
+
+-- Each post is sent to each of a user's sinks at most once, however many of
+-- their rules match it. Deliveries are unique by post URL rather than post
+-- ID, and outlive their post, so a post that is pruned and saved again isn't
+-- sent twice.
+CREATE TABLE notification_deliveries (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    post_id UUID REFERENCES posts(id) ON DELETE SET NULL,
+    post_url TEXT NOT NULL,
+    sink TEXT NOT NULL,
+    attempts INT NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP NOT NULL,
+    delivered_at TIMESTAMP,
+    last_error TEXT NOT NULL DEFAULT '',
+    UNIQUE (user_id, post_url, sink)
+);
+
+CREATE INDEX notification_deliveries_pending_idx
+    ON notification_deliveries (next_attempt_at)
+    WHERE delivered_at IS NULL;
+
+-- +goose Down
+DROP TABLE notification_deliveries;
+DROP TABLE notification_rules;
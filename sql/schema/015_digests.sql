@@ -0,0 +1,17 @@
+-- +goose Up
+-- A user gets email digests while they have a row here.
+CREATE TABLE digest_settings (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    email TEXT NOT NULL,
+    -- Optionally narrow the digest to one folder and/or tag.
+    folder TEXT,
+    tag TEXT,
+    -- The end of the period the last digest covered. The next digest covers
+    -- posts saved after it.
+    last_sent_at TIMESTAMP
+);
+
+-- +goose Down
+DROP TABLE digest_settings;
@@ -0,0 +1,14 @@
+-- +goose Up
+-- admin or user. Admins manage other users and run the commands that affect
+-- everyone's data.
+ALTER TABLE users ADD COLUMN role TEXT NOT NULL DEFAULT 'user';
+-- A disabled user can't log in or run commands, but their data is kept.
+ALTER TABLE users ADD COLUMN disabled_at TIMESTAMP;
+
+-- Make the first user an admin, so existing databases have one.
+UPDATE users SET role = 'admin'
+WHERE id = (SELECT id FROM users ORDER BY created_at LIMIT 1);
+
+-- +goose Down
+ALTER TABLE users DROP COLUMN disabled_at;
+ALTER TABLE users DROP COLUMN role;
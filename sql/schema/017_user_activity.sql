@@ -0,0 +1,7 @@
+-- +goose Up
+-- When the user last ran a command. NULL if they never have since this
+-- was added.
+ALTER TABLE users ADD COLUMN last_active_at TIMESTAMP;
+
+-- +goose Down
+ALTER TABLE users DROP COLUMN last_active_at;
@@ -0,0 +1,27 @@
+-- +goose Up
+-- Fever clients identify feeds, groups (our folders) and items (our posts)
+-- by integer, and page through items by it, so each gets a number that
+-- only grows.
+ALTER TABLE feeds ADD COLUMN fever_id BIGSERIAL NOT NULL UNIQUE;
+ALTER TABLE folders ADD COLUMN fever_id BIGSERIAL NOT NULL UNIQUE;
+ALTER TABLE posts ADD COLUMN fever_id BIGSERIAL NOT NULL UNIQUE;
+
+-- The api_key the user's Fever client sends: the MD5 of
+-- "username:password". NULL if the user hasn't turned Fever on.
+ALTER TABLE users ADD COLUMN fever_api_key TEXT UNIQUE;
+
+-- The icon of each feed's site. A row without data records that there was
+-- none when we last looked.
+CREATE TABLE favicons (
+    feed_id UUID PRIMARY KEY REFERENCES feeds(id) ON DELETE CASCADE,
+    checked_at TIMESTAMP NOT NULL,
+    content_type TEXT NOT NULL DEFAULT '',
+    data BYTEA
+);
+
+-- +goose Down
+DROP TABLE favicons;
+ALTER TABLE users DROP COLUMN fever_api_key;
+ALTER TABLE posts DROP COLUMN fever_id;
+ALTER TABLE folders DROP COLUMN fever_id;
+ALTER TABLE feeds DROP COLUMN fever_id;
@@ -0,0 +1,64 @@
+-- name: UpsertDigestSettings :one
+INSERT INTO digest_settings (user_id, created_at, updated_at, email, folder, tag)
+VALUES (sqlc.arg(user_id), sqlc.arg(created_at), sqlc.arg(created_at), sqlc.arg(email), sqlc.narg(folder), sqlc.narg(tag))
+ON CONFLICT (user_id) DO UPDATE
+SET updated_at = excluded.updated_at,
+    email = excluded.email,
+    folder = excluded.folder,
+    tag = excluded.tag
+RETURNING *;
+
+-- name: GetDigestSettings :one
+SELECT * FROM digest_settings
+WHERE user_id = ?;
+
+-- name: DeleteDigestSettings :execrows
+DELETE FROM digest_settings
+WHERE user_id = ?;
+
+-- name: GetDueDigests :many
+-- Users whose last digest covered up to before the cutoff, or who have never
+-- had one. Disabled users get none.
+SELECT sqlc.embed(digest_settings), sqlc.embed(users)
+FROM digest_settings
+JOIN users ON digest_settings.user_id = users.id
+WHERE users.disabled_at IS NULL
+    AND (digest_settings.last_sent_at IS NULL
+        OR digest_settings.last_sent_at <= sqlc.arg(cutoff))
+ORDER BY digest_settings.last_sent_at NULLS FIRST;
+
+-- name: ClaimDigest :execrows
+-- Moves last_sent_at from one value to another, unless someone else already
+-- moved it. Claiming a period before sending its digest keeps two senders
+-- from sending it twice.
+UPDATE digest_settings
+SET last_sent_at = sqlc.narg(new_sent_at)
+WHERE user_id = sqlc.arg(user_id)
+    AND last_sent_at IS sqlc.narg(old_sent_at);
+
+-- name: GetDigestPosts :many
+-- Unread posts saved in (since, until] for the user's followed feeds, leaving
+-- out muted feeds and hidden posts. The oldest come first, so a digest cut
+-- off at max_posts covers everything saved up to its last post.
+SELECT posts.*, CAST(COALESCE(feed_follows.title, feeds.name) AS TEXT) AS feed_name,
+    feed_follows.priority
+FROM posts
+JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+JOIN feeds ON posts.feed_id = feeds.id
+LEFT JOIN folders ON feed_follows.folder_id = folders.id
+LEFT JOIN post_states ON post_states.post_id = posts.id
+    AND post_states.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = sqlc.arg(user_id)
+    AND NOT feed_follows.muted
+    AND posts.created_at > sqlc.arg(since)
+    AND posts.created_at <= sqlc.arg(until)
+    AND post_states.read_at IS NULL
+    AND post_states.hidden_at IS NULL
+    AND (CAST(sqlc.narg(folder) AS TEXT) IS NULL OR folders.name = CAST(sqlc.narg(folder) AS TEXT))
+    AND (CAST(sqlc.narg(tag) AS TEXT) IS NULL OR EXISTS (
+        SELECT 1 FROM feed_follow_tags
+        WHERE feed_follow_tags.feed_follow_id = feed_follows.id
+            AND feed_follow_tags.tag = CAST(sqlc.narg(tag) AS TEXT)
+    ))
+ORDER BY posts.created_at, posts.id
+LIMIT sqlc.arg(max_posts);
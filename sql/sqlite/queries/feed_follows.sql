@@ -0,0 +1,109 @@
+-- name: CreateFeedFollow :exec
+-- SQLite can't insert in a WITH clause, so unlike the Postgres query this
+-- only inserts; GetFeedFollowWithNames reads the follow back.
+INSERT INTO feed_follows (id, created_at, updated_at, user_id, feed_id)
+VALUES (?, ?, ?, ?, ?);
+
+-- name: GetFeedFollowWithNames :one
+SELECT
+    f.*,
+    u.name AS user_name,
+    fe.name AS feed_name
+FROM feed_follows f
+INNER JOIN users u ON f.user_id = u.id
+INNER JOIN feeds fe ON f.feed_id = fe.id
+WHERE f.id = ?;
+
+-- name: GetFeedFollowsForUser :many
+-- Optionally narrowed to one folder and/or tag. feed_name is the user's own
+-- title for the feed when they have set one, and tags is a JSON array.
+SELECT
+    ff.*,
+    u.name AS user_name,
+    CAST(COALESCE(ff.title, f.name) AS TEXT) AS feed_name,
+    f.url AS feed_url,
+    CAST(COALESCE(folders.name, '') AS TEXT) AS folder_name,
+    CAST((
+        SELECT json_group_array(t.tag) FROM (
+            SELECT feed_follow_tags.tag FROM feed_follow_tags
+            WHERE feed_follow_tags.feed_follow_id = ff.id
+            ORDER BY feed_follow_tags.tag
+        ) t
+    ) AS TEXT) AS tags
+FROM feed_follows ff
+INNER JOIN users u ON ff.user_id = u.id
+INNER JOIN feeds f ON ff.feed_id = f.id
+LEFT JOIN folders ON ff.folder_id = folders.id
+WHERE ff.user_id = sqlc.arg(user_id)
+    AND (CAST(sqlc.narg(folder) AS TEXT) IS NULL OR folders.name = CAST(sqlc.narg(folder) AS TEXT))
+    AND (CAST(sqlc.narg(tag) AS TEXT) IS NULL OR EXISTS (
+        SELECT 1 FROM feed_follow_tags t
+        WHERE t.feed_follow_id = ff.id AND t.tag = CAST(sqlc.narg(tag) AS TEXT)
+    ))
+ORDER BY folder_name, feed_name;
+
+-- name: DelFeedFollow :execrows
+DELETE FROM feed_follows
+WHERE feed_follows.user_id = sqlc.arg(user_id)
+    AND feed_follows.feed_id IN (SELECT feeds.id FROM feeds WHERE feeds.url = sqlc.arg(url));
+
+-- name: MoveFeedFollows :exec
+UPDATE feed_follows
+SET feed_id = sqlc.arg(to_feed_id),
+    updated_at = sqlc.arg(updated_at)
+WHERE feed_follows.feed_id = sqlc.arg(from_feed_id)
+    AND feed_follows.user_id NOT IN (
+        SELECT existing.user_id FROM feed_follows existing
+        WHERE existing.feed_id = sqlc.arg(to_feed_id)
+    );
+
+-- name: MergeFeedFollowSettings :exec
+-- For users who follow both feeds, fills in their follow of to_feed_id from
+-- their follow of from_feed_id: a title or folder it lacks, a priority when
+-- it has none, and muting or turning notifications off.
+UPDATE feed_follows
+SET title = COALESCE(title, (
+        SELECT merged.title FROM feed_follows AS merged
+        WHERE merged.feed_id = sqlc.arg(from_feed_id) AND merged.user_id = feed_follows.user_id
+    )),
+    folder_id = COALESCE(folder_id, (
+        SELECT merged.folder_id FROM feed_follows AS merged
+        WHERE merged.feed_id = sqlc.arg(from_feed_id) AND merged.user_id = feed_follows.user_id
+    )),
+    priority = CASE WHEN priority <> 0 THEN priority ELSE (
+        SELECT merged.priority FROM feed_follows AS merged
+        WHERE merged.feed_id = sqlc.arg(from_feed_id) AND merged.user_id = feed_follows.user_id
+    ) END,
+    muted = muted OR (
+        SELECT merged.muted FROM feed_follows AS merged
+        WHERE merged.feed_id = sqlc.arg(from_feed_id) AND merged.user_id = feed_follows.user_id
+    ),
+    notify = notify AND (
+        SELECT merged.notify FROM feed_follows AS merged
+        WHERE merged.feed_id = sqlc.arg(from_feed_id) AND merged.user_id = feed_follows.user_id
+    ),
+    updated_at = sqlc.arg(updated_at)
+WHERE feed_follows.feed_id = sqlc.arg(to_feed_id)
+    AND feed_follows.user_id IN (
+        SELECT merged.user_id FROM feed_follows AS merged
+        WHERE merged.feed_id = sqlc.arg(from_feed_id)
+    );
+
+-- name: GetFeedFollowers :many
+SELECT users.* FROM users
+JOIN feed_follows ON feed_follows.user_id = users.id
+WHERE feed_follows.feed_id = ?
+ORDER BY feed_follows.created_at ASC;
+
+-- name: GetFeedFollow :one
+SELECT * FROM feed_follows
+WHERE user_id = ? AND feed_id = ?;
+
+-- name: UpdateFeedFollowSettings :execrows
+UPDATE feed_follows
+SET title = sqlc.narg(title),
+    muted = sqlc.arg(muted),
+    priority = sqlc.arg(priority),
+    notify = sqlc.arg(notify),
+    updated_at = sqlc.arg(updated_at)
+WHERE user_id = sqlc.arg(user_id) AND feed_id = sqlc.arg(feed_id);
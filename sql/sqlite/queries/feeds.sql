@@ -0,0 +1,166 @@
+-- name: CreateFeed :one
+INSERT INTO feeds (id, created_at, updated_at, name, url, user_id, fever_id)
+VALUES (?, ?, ?, ?, ?, ?, (SELECT last_id + 1 FROM fever_sequences WHERE name = 'feeds'))
+RETURNING *;
+
+-- name: GetFeedsWithUser :many
+SELECT feeds.name, feeds.url, users.name AS user_name
+FROM feeds
+JOIN users ON feeds.user_id = users.id;
+
+-- name: GetFeedByURL :one
+SELECT * FROM feeds WHERE url = ?;
+
+-- name: MarkFeedFetched :one
+UPDATE feeds
+SET last_fetched_at = sqlc.arg(last_fetched_at),
+updated_at = sqlc.arg(last_fetched_at)
+WHERE id = sqlc.arg(id)
+RETURNING *;
+
+-- name: GetNextFeedsToFetch :many
+SELECT * FROM feeds
+WHERE deactivated_at IS NULL
+    AND (next_fetch_at IS NULL OR next_fetch_at <= sqlc.arg(now))
+ORDER BY next_fetch_at ASC NULLS FIRST, last_fetched_at ASC NULLS FIRST
+LIMIT sqlc.arg(max_feeds);
+
+-- name: GetEarliestNextFetch :one
+SELECT next_fetch_at FROM feeds
+WHERE deactivated_at IS NULL
+ORDER BY next_fetch_at ASC NULLS FIRST
+LIMIT 1;
+
+-- name: RecordFeedFetchError :one
+UPDATE feeds
+SET fetch_error_count = fetch_error_count + 1
+WHERE id = ?
+RETURNING fetch_error_count;
+
+-- name: ResetFeedFetchErrors :exec
+UPDATE feeds
+SET fetch_error_count = 0
+WHERE id = ? AND fetch_error_count <> 0;
+
+-- name: SetFeedNextFetch :exec
+UPDATE feeds
+SET next_fetch_at = sqlc.narg(next_fetch_at)
+WHERE id = sqlc.arg(id);
+
+-- name: GetFeeds :many
+SELECT * FROM feeds
+ORDER BY created_at ASC;
+
+-- name: GetFeedsByOwner :many
+SELECT * FROM feeds
+WHERE user_id = ?
+ORDER BY created_at ASC;
+
+-- name: UpdateFeedURL :exec
+UPDATE feeds
+SET url = sqlc.arg(url),
+updated_at = sqlc.arg(updated_at)
+WHERE id = sqlc.arg(id);
+
+-- name: DeleteFeed :exec
+DELETE FROM feeds WHERE id = ?;
+
+-- name: RecordFeedRedirect :one
+UPDATE feeds
+SET redirect_count = CASE WHEN redirect_url = sqlc.narg(redirect_url) THEN redirect_count + 1 ELSE 1 END,
+redirect_url = sqlc.narg(redirect_url),
+updated_at = sqlc.arg(updated_at)
+WHERE id = sqlc.arg(id)
+RETURNING *;
+
+-- name: ClearFeedRedirect :exec
+UPDATE feeds
+SET redirect_url = NULL,
+redirect_count = 0,
+updated_at = sqlc.arg(updated_at)
+WHERE id = sqlc.arg(id) AND redirect_url IS NOT NULL;
+
+-- name: DeactivateFeed :exec
+UPDATE feeds
+SET deactivated_at = sqlc.arg(deactivated_at),
+updated_at = sqlc.arg(deactivated_at)
+WHERE id = sqlc.arg(id);
+
+-- name: AddFeedURLHistory :exec
+INSERT INTO feed_url_history (id, created_at, url, feed_id)
+VALUES (?, ?, ?, ?)
+ON CONFLICT (url) DO UPDATE SET feed_id = excluded.feed_id;
+
+-- name: MoveFeedURLHistory :exec
+UPDATE feed_url_history
+SET feed_id = sqlc.arg(to_feed_id)
+WHERE feed_id = sqlc.arg(from_feed_id);
+
+-- name: GetFeedURLHistory :many
+SELECT * FROM feed_url_history
+WHERE feed_id = ?
+ORDER BY created_at;
+
+-- name: GetFeedByPreviousURL :one
+SELECT feeds.* FROM feeds
+JOIN feed_url_history ON feed_url_history.feed_id = feeds.id
+WHERE feed_url_history.url = ?;
+
+-- name: GetFeedByID :one
+SELECT * FROM feeds WHERE id = ?;
+
+-- name: CountOverdueFeeds :one
+SELECT COUNT(*) FROM feeds
+WHERE deactivated_at IS NULL
+    AND (next_fetch_at IS NULL OR next_fetch_at <= sqlc.arg(now));
+
+-- name: CountFeedData :one
+SELECT
+    (SELECT COUNT(*) FROM feed_follows WHERE feed_follows.feed_id = sqlc.arg(feed_id)) AS follows,
+    (SELECT COUNT(*) FROM posts WHERE posts.feed_id = sqlc.arg(feed_id)) AS posts;
+
+-- name: SetFeedRetention :exec
+UPDATE feeds
+SET retention_max_age_seconds = sqlc.narg(retention_max_age_seconds),
+retention_max_posts = sqlc.narg(retention_max_posts),
+updated_at = sqlc.arg(updated_at)
+WHERE id = sqlc.arg(id);
+
+-- name: MergeFeedRetention :exec
+-- Gives to_feed_id the retention limits of from_feed_id that it hasn't set
+-- itself.
+UPDATE feeds
+SET retention_max_age_seconds = COALESCE(retention_max_age_seconds, (
+        SELECT merged.retention_max_age_seconds FROM feeds AS merged
+        WHERE merged.id = sqlc.arg(from_feed_id)
+    )),
+    retention_max_posts = COALESCE(retention_max_posts, (
+        SELECT merged.retention_max_posts FROM feeds AS merged
+        WHERE merged.id = sqlc.arg(from_feed_id)
+    )),
+    updated_at = sqlc.arg(updated_at)
+WHERE feeds.id = sqlc.arg(to_feed_id);
+
+-- name: RenameFeed :exec
+UPDATE feeds
+SET name = sqlc.arg(name),
+updated_at = sqlc.arg(updated_at)
+WHERE id = sqlc.arg(id);
+
+-- name: ResetFeedFetchState :exec
+-- Forgets what fetching the feed's previous URL taught us, so a corrected
+-- URL is fetched straight away.
+UPDATE feeds
+SET redirect_url = NULL,
+redirect_count = 0,
+deactivated_at = NULL,
+fetch_error_count = 0,
+next_fetch_at = NULL,
+updated_at = sqlc.arg(updated_at)
+WHERE id = sqlc.arg(id);
+
+-- name: SetFeedOwner :exec
+UPDATE feeds
+SET user_id = sqlc.arg(user_id),
+updated_at = sqlc.arg(updated_at)
+WHERE id = sqlc.arg(id);
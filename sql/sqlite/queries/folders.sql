@@ -0,0 +1,61 @@
+-- name: CreateFolder :one
+INSERT INTO folders (id, created_at, updated_at, user_id, name, fever_id)
+VALUES (?, ?, ?, ?, ?, (SELECT last_id + 1 FROM fever_sequences WHERE name = 'folders'))
+RETURNING *;
+
+-- name: GetFolderByName :one
+SELECT * FROM folders
+WHERE user_id = ? AND name = ?;
+
+-- name: GetFoldersForUser :many
+SELECT folders.*, COUNT(feed_follows.id) AS feed_count
+FROM folders
+LEFT JOIN feed_follows ON feed_follows.folder_id = folders.id
+WHERE folders.user_id = ?
+GROUP BY folders.id
+ORDER BY folders.name;
+
+-- name: DeleteFolder :execrows
+DELETE FROM folders
+WHERE user_id = ? AND name = ?;
+
+-- name: SetFeedFollowFolder :execrows
+UPDATE feed_follows
+SET folder_id = sqlc.narg(folder_id),
+    updated_at = sqlc.arg(updated_at)
+WHERE user_id = sqlc.arg(user_id) AND feed_id = sqlc.arg(feed_id);
+
+-- name: AddFeedFollowTag :execrows
+INSERT INTO feed_follow_tags (feed_follow_id, tag)
+SELECT feed_follows.id, sqlc.arg(tag) FROM feed_follows
+WHERE feed_follows.user_id = sqlc.arg(user_id) AND feed_follows.feed_id = sqlc.arg(feed_id)
+ON CONFLICT DO NOTHING;
+
+-- name: RemoveFeedFollowTag :execrows
+DELETE FROM feed_follow_tags
+WHERE feed_follow_tags.feed_follow_id IN (
+        SELECT feed_follows.id FROM feed_follows
+        WHERE feed_follows.user_id = sqlc.arg(user_id)
+            AND feed_follows.feed_id = sqlc.arg(feed_id)
+    )
+    AND feed_follow_tags.tag = sqlc.arg(tag);
+
+-- name: MergeFeedFollowTags :exec
+-- For users who follow both feeds, adds the tags of their follow of
+-- from_feed_id to their follow of to_feed_id.
+INSERT INTO feed_follow_tags (feed_follow_id, tag)
+SELECT kept.id, feed_follow_tags.tag
+FROM feed_follow_tags
+JOIN feed_follows AS merged ON merged.id = feed_follow_tags.feed_follow_id
+JOIN feed_follows AS kept ON kept.user_id = merged.user_id
+WHERE merged.feed_id = sqlc.arg(from_feed_id)
+    AND kept.feed_id = sqlc.arg(to_feed_id)
+ON CONFLICT DO NOTHING;
+
+-- name: GetTagsForUser :many
+SELECT feed_follow_tags.tag, COUNT(*) AS feed_count
+FROM feed_follow_tags
+JOIN feed_follows ON feed_follow_tags.feed_follow_id = feed_follows.id
+WHERE feed_follows.user_id = ?
+GROUP BY feed_follow_tags.tag
+ORDER BY feed_follow_tags.tag;
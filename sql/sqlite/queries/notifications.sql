@@ -0,0 +1,95 @@
+-- name: CreateNotificationRule :one
+INSERT INTO notification_rules (id, created_at, user_id, feed_id, field, match_type, pattern, sink)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+RETURNING *;
+
+-- name: GetNotificationRulesForUser :many
+SELECT notification_rules.*, CAST(COALESCE(feeds.url, '') AS TEXT) AS feed_url
+FROM notification_rules
+LEFT JOIN feeds ON notification_rules.feed_id = feeds.id
+WHERE notification_rules.user_id = ?
+ORDER BY notification_rules.created_at;
+
+-- name: DeleteNotificationRule :execrows
+DELETE FROM notification_rules
+WHERE user_id = ? AND id = ?;
+
+-- name: GetNotificationRulesForFeed :many
+-- The rules that watch a feed, for followers who haven't muted it or turned
+-- its notifications off, and aren't disabled.
+SELECT notification_rules.* FROM notification_rules
+JOIN feed_follows ON feed_follows.user_id = notification_rules.user_id
+JOIN users ON users.id = notification_rules.user_id
+WHERE feed_follows.feed_id = sqlc.arg(feed_id)
+    AND users.disabled_at IS NULL
+    AND feed_follows.notify
+    AND NOT feed_follows.muted
+    AND (notification_rules.feed_id IS NULL OR notification_rules.feed_id = sqlc.arg(feed_id))
+ORDER BY notification_rules.created_at;
+
+-- name: QueueNotification :exec
+-- A post is queued once per user and sink by its URL, so saving it again
+-- after it was pruned doesn't send it again.
+INSERT INTO notification_deliveries (id, created_at, user_id, post_id, post_url, sink, next_attempt_at)
+VALUES (sqlc.arg(id), sqlc.arg(created_at), sqlc.arg(user_id), sqlc.arg(post_id), sqlc.arg(post_url), sqlc.arg(sink), sqlc.arg(created_at))
+ON CONFLICT (user_id, post_url, sink) DO NOTHING;
+
+-- name: GetDueNotifications :many
+SELECT
+    notification_deliveries.*,
+    users.name AS user_name,
+    CAST(COALESCE(feed_follows.title, feeds.name) AS TEXT) AS feed_name,
+    feeds.url AS feed_url,
+    posts.title,
+    posts.url,
+    posts.description,
+    posts.author,
+    posts.published_at
+FROM notification_deliveries
+JOIN users ON notification_deliveries.user_id = users.id
+JOIN posts ON notification_deliveries.post_id = posts.id
+JOIN feeds ON posts.feed_id = feeds.id
+LEFT JOIN feed_follows ON feed_follows.feed_id = feeds.id AND feed_follows.user_id = users.id
+WHERE notification_deliveries.delivered_at IS NULL
+    AND notification_deliveries.attempts < CAST(sqlc.arg(max_attempts) AS INTEGER)
+    AND notification_deliveries.next_attempt_at <= sqlc.arg(now)
+ORDER BY notification_deliveries.next_attempt_at
+LIMIT sqlc.arg(max_deliveries);
+
+-- name: FailOrphanedNotifications :execrows
+-- Gives up on pending deliveries whose post was deleted before they were
+-- sent, since there is nothing left to send.
+UPDATE notification_deliveries
+SET attempts = CAST(sqlc.arg(max_attempts) AS INTEGER),
+    last_error = sqlc.arg(last_error)
+WHERE post_id IS NULL
+    AND delivered_at IS NULL
+    AND attempts < CAST(sqlc.arg(max_attempts) AS INTEGER);
+
+-- name: MarkNotificationDelivered :exec
+UPDATE notification_deliveries
+SET delivered_at = sqlc.narg(delivered_at),
+    attempts = attempts + 1,
+    last_error = ''
+WHERE id = sqlc.arg(id);
+
+-- name: MarkNotificationFailed :exec
+UPDATE notification_deliveries
+SET attempts = attempts + 1,
+    next_attempt_at = sqlc.arg(next_attempt_at),
+    last_error = sqlc.arg(last_error)
+WHERE id = sqlc.arg(id);
+
+-- name: GetFailedNotificationsForUser :many
+-- Deliveries that have used up their attempts.
+SELECT * FROM notification_deliveries
+WHERE notification_deliveries.user_id = sqlc.arg(user_id)
+    AND notification_deliveries.delivered_at IS NULL
+    AND notification_deliveries.attempts >= CAST(sqlc.arg(max_attempts) AS INTEGER)
+ORDER BY notification_deliveries.created_at DESC
+LIMIT 20;
+
+-- name: MoveNotificationRules :exec
+UPDATE notification_rules
+SET feed_id = sqlc.arg(to_feed_id)
+WHERE feed_id = sqlc.arg(from_feed_id);
@@ -0,0 +1,19 @@
+-- name: SetPostStarred :exec
+INSERT INTO post_states (user_id, post_id, starred_at)
+VALUES (?, ?, ?)
+ON CONFLICT (user_id, post_id) DO UPDATE SET starred_at = excluded.starred_at;
+
+-- name: SetPostRead :exec
+INSERT INTO post_states (user_id, post_id, read_at)
+VALUES (?, ?, ?)
+ON CONFLICT (user_id, post_id) DO UPDATE SET read_at = excluded.read_at;
+
+-- name: SetPostHidden :exec
+INSERT INTO post_states (user_id, post_id, hidden_at)
+VALUES (?, ?, ?)
+ON CONFLICT (user_id, post_id) DO UPDATE SET hidden_at = excluded.hidden_at;
+
+-- name: AddPostTag :execrows
+INSERT INTO post_tags (user_id, post_id, tag)
+VALUES (?, ?, ?)
+ON CONFLICT DO NOTHING;
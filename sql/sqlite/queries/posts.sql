@@ -0,0 +1,127 @@
+-- name: CreatePost :one
+INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id, author, categories, fever_id)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, (SELECT last_id + 1 FROM fever_sequences WHERE name = 'posts'))
+ON CONFLICT (url) DO NOTHING
+RETURNING *;
+
+-- name: GetPostsForUser :many
+-- Optionally narrowed to the feeds in one folder and/or with one tag. Muted
+-- feeds and hidden posts are left out. Newest days come first; within a day,
+-- posts from higher priority feeds come first.
+SELECT posts.*, CAST(COALESCE(feed_follows.title, feeds.name) AS TEXT) AS feed_name FROM posts
+JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+JOIN feeds ON posts.feed_id = feeds.id
+LEFT JOIN folders ON feed_follows.folder_id = folders.id
+WHERE feed_follows.user_id = sqlc.arg(user_id)
+    AND NOT feed_follows.muted
+    AND NOT EXISTS (
+        SELECT 1 FROM post_states
+        WHERE post_states.user_id = feed_follows.user_id
+            AND post_states.post_id = posts.id
+            AND post_states.hidden_at IS NOT NULL
+    )
+    AND (CAST(sqlc.narg(folder) AS TEXT) IS NULL OR folders.name = CAST(sqlc.narg(folder) AS TEXT))
+    AND (CAST(sqlc.narg(tag) AS TEXT) IS NULL OR EXISTS (
+        SELECT 1 FROM feed_follow_tags
+        WHERE feed_follow_tags.feed_follow_id = feed_follows.id
+            AND feed_follow_tags.tag = CAST(sqlc.narg(tag) AS TEXT)
+    ))
+ORDER BY date(COALESCE(posts.published_at, posts.created_at)) DESC,
+    feed_follows.priority DESC,
+    COALESCE(posts.published_at, posts.created_at) DESC
+LIMIT sqlc.arg(max_posts) OFFSET sqlc.arg(skip);
+
+-- name: MovePosts :exec
+UPDATE posts
+SET feed_id = sqlc.arg(to_feed_id),
+    updated_at = sqlc.arg(updated_at)
+WHERE feed_id = sqlc.arg(from_feed_id);
+
+-- name: GetPostURLs :many
+SELECT id, url FROM posts
+ORDER BY created_at ASC;
+
+-- name: UpdatePostURL :exec
+UPDATE posts
+SET url = sqlc.arg(url),
+updated_at = sqlc.arg(updated_at)
+WHERE id = sqlc.arg(id);
+
+-- name: DeletePost :exec
+DELETE FROM posts WHERE id = ?;
+
+-- name: GetRecentPostTimes :many
+-- SQLite only reads a value back as a time when it comes straight from a
+-- TIMESTAMP column, so unlike the Postgres query this returns both columns
+-- for the caller to coalesce.
+SELECT published_at, created_at
+FROM posts
+WHERE feed_id = ?
+ORDER BY COALESCE(published_at, created_at) DESC
+LIMIT ?;
+
+-- name: CountPostsBefore :one
+SELECT COUNT(*) FROM posts
+WHERE COALESCE(published_at, created_at) < sqlc.arg(before);
+
+-- name: DeletePostsBefore :execrows
+DELETE FROM posts
+WHERE COALESCE(published_at, created_at) < sqlc.arg(before);
+
+-- name: GetPostByURL :one
+SELECT * FROM posts WHERE url = ?;
+
+-- name: GetPrunablePosts :many
+-- Posts of a feed that are older than the cutoff or beyond the newest
+-- max_posts, less those kept for being starred or unread by a follower.
+SELECT ranked.id FROM (
+    SELECT posts.id,
+        posts.feed_id,
+        COALESCE(posts.published_at, posts.created_at) < sqlc.narg(cutoff) AS too_old,
+        ROW_NUMBER() OVER (ORDER BY COALESCE(posts.published_at, posts.created_at) DESC) AS post_rank
+    FROM posts
+    WHERE posts.feed_id = sqlc.arg(feed_id)
+) ranked
+WHERE (
+        ranked.too_old
+        OR (CAST(sqlc.arg(max_posts) AS INTEGER) > 0 AND ranked.post_rank > CAST(sqlc.arg(max_posts) AS INTEGER))
+    )
+    AND (CAST(sqlc.arg(keep_starred) AS BOOLEAN) = FALSE OR NOT EXISTS (
+        SELECT 1 FROM post_states
+        WHERE post_states.post_id = ranked.id AND post_states.starred_at IS NOT NULL
+    ))
+    AND (CAST(sqlc.arg(keep_unread) AS BOOLEAN) = FALSE OR NOT EXISTS (
+        SELECT 1 FROM feed_follows
+        WHERE feed_follows.feed_id = ranked.feed_id
+            AND NOT EXISTS (
+                SELECT 1 FROM post_states
+                WHERE post_states.post_id = ranked.id
+                    AND post_states.user_id = feed_follows.user_id
+                    AND post_states.read_at IS NOT NULL
+            )
+    ));
+
+-- name: DeletePostsByID :execrows
+DELETE FROM posts WHERE id IN (sqlc.slice(ids));
+
+-- name: GetAllPostsForUser :many
+-- Every post of every feed the user follows, muted or not, for applying
+-- filter rules.
+SELECT posts.* FROM posts
+JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+WHERE feed_follows.user_id = ?
+ORDER BY posts.created_at;
+
+-- name: RecordPrunedPosts :exec
+INSERT INTO pruned_posts (url, feed_id, pruned_at)
+SELECT url, feed_id, sqlc.arg(pruned_at) FROM posts
+WHERE id IN (sqlc.slice(ids))
+ON CONFLICT (url) DO NOTHING;
+
+-- name: PostWasPruned :one
+SELECT CAST(EXISTS (SELECT 1 FROM pruned_posts WHERE url = ?) AS BOOLEAN);
+
+-- name: ForgetPrunedPosts :execrows
+-- Forgets the feed's pruned posts it no longer lists, which can't come back.
+DELETE FROM pruned_posts
+WHERE feed_id = sqlc.arg(feed_id) AND url NOT IN (sqlc.slice(listed));
@@ -0,0 +1,98 @@
+-- name: CreateUser :one
+INSERT INTO users (id, created_at, updated_at, name, role)
+VALUES (?, ?, ?, ?, ?)
+RETURNING *;
+
+-- name: LockUsers :exec
+-- SQLite runs one write at a time and RegisterUser checks and inserts in a
+-- single statement, so there is nothing to lock.
+SELECT 1;
+
+-- name: RegisterUser :one
+-- Adds a user, as an admin if they are the first one.
+INSERT INTO users (id, created_at, updated_at, name, role)
+SELECT
+    sqlc.arg(id),
+    sqlc.arg(created_at),
+    sqlc.arg(updated_at),
+    sqlc.arg(name),
+    CASE WHEN EXISTS (SELECT 1 FROM users) THEN 'user' ELSE 'admin' END
+RETURNING *;
+
+-- name: GetUser :one
+SELECT * FROM users
+WHERE name = ?;
+
+-- name: GetUsers :many
+SELECT * FROM users;
+
+-- name: DelUsers :exec
+DELETE FROM users;
+
+-- name: CountAllData :one
+SELECT
+    (SELECT COUNT(*) FROM users) AS users,
+    (SELECT COUNT(*) FROM feeds) AS feeds,
+    (SELECT COUNT(*) FROM feed_follows) AS follows,
+    (SELECT COUNT(*) FROM posts) AS posts;
+
+-- name: DeleteUser :execrows
+DELETE FROM users WHERE id = ?;
+
+-- name: CountUserData :one
+-- Counts what deleting the user cascades to: the feeds they added, those
+-- feeds' posts, and follows by or of them.
+SELECT
+    (SELECT COUNT(*) FROM feeds WHERE feeds.user_id = sqlc.arg(user_id)) AS feeds,
+    (SELECT COUNT(*) FROM feed_follows
+        WHERE feed_follows.user_id = sqlc.arg(user_id)
+            OR feed_follows.feed_id IN (SELECT id FROM feeds WHERE feeds.user_id = sqlc.arg(user_id))) AS follows,
+    (SELECT COUNT(*) FROM posts
+        JOIN feeds ON posts.feed_id = feeds.id
+        WHERE feeds.user_id = sqlc.arg(user_id)) AS posts;
+
+-- name: RenameUser :execrows
+UPDATE users
+SET name = sqlc.arg(name),
+updated_at = sqlc.arg(updated_at)
+WHERE id = sqlc.arg(id);
+
+-- name: SetUserRole :execrows
+UPDATE users
+SET role = sqlc.arg(role),
+updated_at = sqlc.arg(updated_at)
+WHERE id = sqlc.arg(id);
+
+-- name: SetUserDisabled :execrows
+-- Pass a NULL disabled_at to enable the user again.
+UPDATE users
+SET disabled_at = sqlc.narg(disabled_at),
+updated_at = sqlc.arg(updated_at)
+WHERE id = sqlc.arg(id);
+
+-- name: CountActiveAdmins :one
+SELECT COUNT(*) FROM users
+WHERE role = 'admin' AND disabled_at IS NULL;
+
+-- name: RecordUserActivity :exec
+UPDATE users
+SET last_active_at = sqlc.arg(last_active_at)
+WHERE id = sqlc.arg(id) AND disabled_at IS NULL;
+
+-- name: GetUserStats :many
+-- Every user with how many feeds they follow and have added, and how many
+-- posts in the feeds they haven't muted they have neither read nor hidden.
+SELECT
+    sqlc.embed(users),
+    (SELECT COUNT(*) FROM feed_follows WHERE feed_follows.user_id = users.id) AS follows,
+    (SELECT COUNT(*) FROM feeds WHERE feeds.user_id = users.id) AS feeds_created,
+    (SELECT COUNT(*) FROM posts
+        JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+        LEFT JOIN post_states ON post_states.post_id = posts.id
+            AND post_states.user_id = feed_follows.user_id
+        WHERE feed_follows.user_id = users.id
+            AND NOT feed_follows.muted
+            AND post_states.read_at IS NULL
+            AND post_states.hidden_at IS NULL) AS unread
+FROM users
+ORDER BY users.name;
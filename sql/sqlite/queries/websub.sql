@@ -0,0 +1,37 @@
+-- name: UpsertWebSubSubscription :one
+INSERT INTO websub_subscriptions (id, created_at, updated_at, feed_id, hub_url, topic_url, secret, pending_secret, state)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, 'pending')
+ON CONFLICT (feed_id) DO UPDATE
+SET updated_at = excluded.updated_at,
+    hub_url = excluded.hub_url,
+    topic_url = excluded.topic_url,
+    pending_secret = excluded.pending_secret,
+    state = 'pending'
+RETURNING *;
+
+-- name: GetWebSubSubscription :one
+SELECT * FROM websub_subscriptions WHERE id = ?;
+
+-- name: GetWebSubSubscriptionForFeed :one
+SELECT * FROM websub_subscriptions WHERE feed_id = ?;
+
+-- name: ActivateWebSubSubscription :exec
+UPDATE websub_subscriptions
+SET state = 'active',
+    secret = COALESCE(pending_secret, secret),
+    pending_secret = NULL,
+    lease_expires_at = sqlc.narg(lease_expires_at),
+    renew_at = sqlc.narg(renew_at),
+    updated_at = sqlc.arg(updated_at)
+WHERE id = sqlc.arg(id);
+
+-- name: SetWebSubSubscriptionState :exec
+UPDATE websub_subscriptions
+SET state = sqlc.arg(state),
+    updated_at = sqlc.arg(updated_at)
+WHERE id = sqlc.arg(id);
+
+-- name: GetWebSubSubscriptionsToRenew :many
+SELECT * FROM websub_subscriptions
+WHERE state = 'active' AND renew_at <= sqlc.arg(before)
+ORDER BY renew_at ASC;
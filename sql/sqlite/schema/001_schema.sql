@@ -0,0 +1,196 @@
+-- +goose Up
+-- The SQLite schema matches the Postgres one in sql/schema as of
+-- 015_digests.sql, with these differences:
+--   - UUIDs and timestamps are stored as text.
+--   - posts.categories is a JSON array rather than TEXT[].
+CREATE TABLE users (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    name TEXT NOT NULL UNIQUE
+);
+
+CREATE TABLE feeds (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    name TEXT NOT NULL,
+    url TEXT NOT NULL UNIQUE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    last_fetched_at TIMESTAMP,
+    redirect_url TEXT,
+    redirect_count INTEGER NOT NULL DEFAULT 0,
+    deactivated_at TIMESTAMP,
+    next_fetch_at TIMESTAMP,
+    fetch_error_count INTEGER NOT NULL DEFAULT 0,
+    retention_max_age_seconds BIGINT,
+    retention_max_posts INTEGER
+);
+
+CREATE TABLE feed_url_history (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    url TEXT NOT NULL UNIQUE,
+    feed_id UUID NOT NULL REFERENCES feeds(id) ON DELETE CASCADE
+);
+
+CREATE TABLE folders (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    UNIQUE (user_id, name)
+);
+
+CREATE TABLE feed_follows (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    feed_id UUID NOT NULL REFERENCES feeds(id) ON DELETE CASCADE,
+    folder_id UUID REFERENCES folders(id) ON DELETE SET NULL,
+    title TEXT,
+    muted BOOLEAN NOT NULL DEFAULT FALSE,
+    priority INTEGER NOT NULL DEFAULT 0,
+    notify BOOLEAN NOT NULL DEFAULT TRUE,
+    UNIQUE (user_id, feed_id)
+);
+
+CREATE TABLE feed_follow_tags (
+    feed_follow_id UUID NOT NULL REFERENCES feed_follows(id) ON DELETE CASCADE,
+    tag TEXT NOT NULL,
+    PRIMARY KEY (feed_follow_id, tag)
+);
+
+CREATE TABLE posts (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    title TEXT NOT NULL,
+    url TEXT NOT NULL UNIQUE,
+    description TEXT,
+    published_at TIMESTAMP,
+    feed_id UUID NOT NULL REFERENCES feeds(id) ON DELETE CASCADE,
+    author TEXT NOT NULL DEFAULT '',
+    -- A JSON array of strings.
+    categories TEXT NOT NULL DEFAULT '[]'
+);
+
+CREATE TABLE websub_subscriptions (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    feed_id UUID NOT NULL UNIQUE REFERENCES feeds(id) ON DELETE CASCADE,
+    hub_url TEXT NOT NULL,
+    topic_url TEXT NOT NULL,
+    secret TEXT NOT NULL,
+    -- The secret of a subscription request the hub hasn't verified yet. The
+    -- hub keeps signing with secret until it does.
+    pending_secret TEXT,
+    -- pending, active, denied, unsubscribing or unsubscribed
+    state TEXT NOT NULL,
+    lease_expires_at TIMESTAMP,
+    -- When to renew the lease, set from the lease the hub granted.
+    renew_at TIMESTAMP
+);
+
+CREATE TABLE post_states (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    post_id UUID NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    read_at TIMESTAMP,
+    starred_at TIMESTAMP,
+    hidden_at TIMESTAMP,
+    PRIMARY KEY (user_id, post_id)
+);
+
+CREATE TABLE pruned_posts (
+    url TEXT PRIMARY KEY,
+    feed_id UUID NOT NULL REFERENCES feeds(id) ON DELETE CASCADE,
+    pruned_at TIMESTAMP NOT NULL
+);
+
+CREATE TABLE post_tags (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    post_id UUID NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    tag TEXT NOT NULL,
+    PRIMARY KEY (user_id, post_id, tag)
+);
+
+CREATE TABLE filter_rules (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    -- NULL applies the rule to every feed the user follows.
+    feed_id UUID REFERENCES feeds(id) ON DELETE CASCADE,
+    -- title, description, author or category
+    field TEXT NOT NULL,
+    -- keyword or regex
+    match_type TEXT NOT NULL,
+    pattern TEXT NOT NULL,
+    -- hide, read, star or tag
+    action TEXT NOT NULL,
+    -- The tag to add when action is tag.
+    tag TEXT NOT NULL DEFAULT ''
+);
+
+CREATE TABLE notification_rules (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    -- NULL watches every feed the user follows.
+    feed_id UUID REFERENCES feeds(id) ON DELETE CASCADE,
+    -- title, description, author or category
+    field TEXT NOT NULL,
+    -- keyword or regex
+    match_type TEXT NOT NULL,
+    -- An empty pattern matches every new post.
+    pattern TEXT NOT NULL,
+    -- The name of a sink in the notifications config.
+    sink TEXT NOT NULL
+);
+
+CREATE TABLE notification_deliveries (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    post_id UUID REFERENCES posts(id) ON DELETE SET NULL,
+    post_url TEXT NOT NULL,
+    sink TEXT NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP NOT NULL,
+    delivered_at TIMESTAMP,
+    last_error TEXT NOT NULL DEFAULT '',
+    UNIQUE (user_id, post_url, sink)
+);
+
+CREATE INDEX notification_deliveries_pending_idx
+    ON notification_deliveries (next_attempt_at)
+    WHERE delivered_at IS NULL;
+
+CREATE TABLE digest_settings (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    email TEXT NOT NULL,
+    folder TEXT,
+    tag TEXT,
+    last_sent_at TIMESTAMP
+);
+
+-- +goose Down
+DROP TABLE digest_settings;
+DROP TABLE notification_deliveries;
+DROP TABLE notification_rules;
+DROP TABLE filter_rules;
+DROP TABLE post_tags;
+DROP TABLE pruned_posts;
+DROP TABLE post_states;
+DROP TABLE websub_subscriptions;
+DROP TABLE posts;
+DROP TABLE feed_follow_tags;
+DROP TABLE feed_follows;
+DROP TABLE folders;
+DROP TABLE feed_url_history;
+DROP TABLE feeds;
+DROP TABLE users;
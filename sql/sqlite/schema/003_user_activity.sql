@@ -0,0 +1,6 @@
+-- +goose Up
+-- Matches sql/schema/017_user_activity.sql.
+ALTER TABLE users ADD COLUMN last_active_at TIMESTAMP;
+
+-- +goose Down
+ALTER TABLE users DROP COLUMN last_active_at;
@@ -0,0 +1,67 @@
+-- +goose Up
+-- Matches sql/schema/018_fever.sql. SQLite can't add a column with a
+-- sequence, so existing rows are numbered by rowid and the Create queries
+-- number new ones from fever_sequences.
+ALTER TABLE feeds ADD COLUMN fever_id BIGINT NOT NULL DEFAULT 0;
+UPDATE feeds SET fever_id = rowid;
+CREATE UNIQUE INDEX feeds_fever_id_idx ON feeds (fever_id);
+
+ALTER TABLE folders ADD COLUMN fever_id BIGINT NOT NULL DEFAULT 0;
+UPDATE folders SET fever_id = rowid;
+CREATE UNIQUE INDEX folders_fever_id_idx ON folders (fever_id);
+
+ALTER TABLE posts ADD COLUMN fever_id BIGINT NOT NULL DEFAULT 0;
+UPDATE posts SET fever_id = rowid;
+CREATE UNIQUE INDEX posts_fever_id_idx ON posts (fever_id);
+
+ALTER TABLE users ADD COLUMN fever_api_key TEXT;
+CREATE UNIQUE INDEX users_fever_api_key_idx ON users (fever_api_key);
+
+CREATE TABLE favicons (
+    feed_id UUID PRIMARY KEY REFERENCES feeds(id) ON DELETE CASCADE,
+    checked_at TIMESTAMP NOT NULL,
+    content_type TEXT NOT NULL DEFAULT '',
+    data BLOB
+);
+
+-- The last fever_id given out in each table. Numbering new rows after the
+-- highest existing fever_id would reuse the IDs of deleted ones, which
+-- clients still remember, so this never goes back.
+CREATE TABLE fever_sequences (
+    name TEXT PRIMARY KEY,
+    last_id BIGINT NOT NULL
+);
+INSERT INTO fever_sequences (name, last_id)
+SELECT 'feeds', COALESCE(MAX(fever_id), 0) FROM feeds
+UNION ALL
+SELECT 'folders', COALESCE(MAX(fever_id), 0) FROM folders
+UNION ALL
+SELECT 'posts', COALESCE(MAX(fever_id), 0) FROM posts;
+
+CREATE TRIGGER feeds_fever_id_trg AFTER INSERT ON feeds
+BEGIN
+    UPDATE fever_sequences SET last_id = MAX(last_id, NEW.fever_id) WHERE name = 'feeds';
+END;
+CREATE TRIGGER folders_fever_id_trg AFTER INSERT ON folders
+BEGIN
+    UPDATE fever_sequences SET last_id = MAX(last_id, NEW.fever_id) WHERE name = 'folders';
+END;
+CREATE TRIGGER posts_fever_id_trg AFTER INSERT ON posts
+BEGIN
+    UPDATE fever_sequences SET last_id = MAX(last_id, NEW.fever_id) WHERE name = 'posts';
+END;
+
+-- +goose Down
+DROP TRIGGER posts_fever_id_trg;
+DROP TRIGGER folders_fever_id_trg;
+DROP TRIGGER feeds_fever_id_trg;
+DROP TABLE fever_sequences;
+DROP TABLE favicons;
+DROP INDEX users_fever_api_key_idx;
+ALTER TABLE users DROP COLUMN fever_api_key;
+DROP INDEX posts_fever_id_idx;
+ALTER TABLE posts DROP COLUMN fever_id;
+DROP INDEX folders_fever_id_idx;
+ALTER TABLE folders DROP COLUMN fever_id;
+DROP INDEX feeds_fever_id_idx;
+ALTER TABLE feeds DROP COLUMN fever_id;
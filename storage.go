@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/isaacjstriker/gatorapp/internal/database"
+	"github.com/isaacjstriker/gatorapp/internal/sqlitedb"
+)
+
+//go:embed sql/sqlite/schema/*.sql
+var sqliteSchema embed.FS
+
+// sqlitePath returns the file a sqlite: database URL names, accepting
+// sqlite:gator.db, sqlite://gator.db and sqlite:///abs/path/gator.db.
+// A leading ~/ is the home directory. ok is false for other URLs.
+func sqlitePath(dbURL string) (path string, ok bool) {
+	path, ok = strings.CutPrefix(dbURL, "sqlite:")
+	if !ok {
+		return "", false
+	}
+	path = strings.TrimPrefix(path, "//")
+	if rest, ok := strings.CutPrefix(path, "~/"); ok {
+		if home, err := os.UserHomeDir(); err == nil {
+			path = filepath.Join(home, rest)
+		}
+	}
+	return path, true
+}
+
+// openDB opens the database dbURL names: a SQLite file for sqlite: URLs
+// and Postgres for anything else.
+func openDB(dbURL string) (*sql.DB, error) {
+	if path, ok := sqlitePath(dbURL); ok {
+		return sqlitedb.Open(path)
+	}
+	return sql.Open("postgres", dbURL)
+}
+
+// newStore returns the Store for db, which openDB opened from dbURL, running
+// queries through dbtx. A SQLite database is brought up to date with the
+// schema first; Postgres migrations are run with goose.
+func newStore(ctx context.Context, dbURL string, db *sql.DB, dbtx database.DBTX) (database.Store, error) {
+	if _, ok := sqlitePath(dbURL); !ok {
+		return database.NewPostgresStore(db, dbtx), nil
+	}
+	schema, err := fs.Sub(sqliteSchema, "sql/sqlite/schema")
+	if err != nil {
+		return nil, err
+	}
+	if err := sqlitedb.Migrate(ctx, db, schema); err != nil {
+		return nil, err
+	}
+	return sqlitedb.NewStore(db, dbtx), nil
+}
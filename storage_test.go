@@ -0,0 +1,204 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/isaacjstriker/gatorapp/internal/database"
+)
+
+// postgresTestEnv names a Postgres database, already migrated with goose,
+// to run the tests against as well as SQLite. The store tests add users
+// with unique names and remove them again; the handler tests empty the
+// database first, so don't point it at one whose data you want.
+const postgresTestEnv = "GATOR_TEST_POSTGRES_URL"
+
+type testStore struct {
+	name  string
+	dbURL string
+	db    *sql.DB
+	store database.Store
+}
+
+// testStores opens a store on each backend: a fresh SQLite file, and
+// Postgres when postgresTestEnv is set.
+func testStores(t *testing.T) []testStore {
+	t.Helper()
+	stores := []testStore{testSQLiteStore(t)}
+	if dbURL := os.Getenv(postgresTestEnv); dbURL != "" {
+		stores = append(stores, openTestStore(t, "postgres", dbURL))
+	} else {
+		t.Logf("%s not set, testing SQLite only", postgresTestEnv)
+	}
+	return stores
+}
+
+func testSQLiteStore(t *testing.T) testStore {
+	t.Helper()
+	return openTestStore(t, "sqlite", "sqlite:"+filepath.Join(t.TempDir(), "gator.db"))
+}
+
+func openTestStore(t *testing.T, name, dbURL string) testStore {
+	t.Helper()
+	db, err := openDB(dbURL)
+	if err != nil {
+		t.Fatalf("%s: couldn't open database: %v", name, err)
+	}
+	t.Cleanup(func() { db.Close() })
+	store, err := newStore(context.Background(), dbURL, db, db)
+	if err != nil {
+		t.Fatalf("%s: couldn't set up database: %v", name, err)
+	}
+	return testStore{name, dbURL, db, store}
+}
+
+// createTestUser adds a user with a unique name, removed when the test ends.
+func createTestUser(t *testing.T, q database.Querier, at time.Time) database.User {
+	t.Helper()
+	user, err := q.CreateUser(context.Background(), database.CreateUserParams{
+		ID:        uuid.New(),
+		CreatedAt: at,
+		UpdatedAt: at,
+		Name:      "test-" + uuid.NewString(),
+		Role:      roleUser,
+	})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	return user
+}
+
+func cleanupUser(t *testing.T, s database.Store, id uuid.UUID) {
+	t.Cleanup(func() { s.DeleteUser(context.Background(), id) })
+}
+
+func TestStoreInTx(t *testing.T) {
+	errRollback := errors.New("roll back")
+	ctx := context.Background()
+	for _, ts := range testStores(t) {
+		t.Run(ts.name, func(t *testing.T) {
+			var committed, rolledBack database.User
+			err := ts.store.InTx(ctx, func(q database.Querier) error {
+				committed = createTestUser(t, q, time.Now().UTC())
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("InTx: %v", err)
+			}
+			cleanupUser(t, ts.store, committed.ID)
+			if _, err := ts.store.GetUser(ctx, committed.Name); err != nil {
+				t.Errorf("user created in a committed transaction: %v", err)
+			}
+
+			err = ts.store.InTx(ctx, func(q database.Querier) error {
+				rolledBack = createTestUser(t, q, time.Now().UTC())
+				if _, err := q.GetUser(ctx, rolledBack.Name); err != nil {
+					t.Errorf("transaction doesn't see its own user: %v", err)
+				}
+				return errRollback
+			})
+			if !errors.Is(err, errRollback) {
+				t.Errorf("InTx error = %v, want the one fn returned", err)
+			}
+			cleanupUser(t, ts.store, rolledBack.ID)
+			if _, err := ts.store.GetUser(ctx, rolledBack.Name); !errors.Is(err, sql.ErrNoRows) {
+				t.Errorf("user created in a rolled back transaction: got error %v, want sql.ErrNoRows", err)
+			}
+		})
+	}
+}
+
+func TestStoreTimes(t *testing.T) {
+	ctx := context.Background()
+	// Postgres keeps microseconds.
+	at := time.Date(2026, 3, 2, 9, 30, 15, 123456000, time.UTC)
+	for _, ts := range testStores(t) {
+		t.Run(ts.name, func(t *testing.T) {
+			user := createTestUser(t, ts.store, at)
+			cleanupUser(t, ts.store, user.ID)
+			if _, err := ts.store.SetUserDisabled(ctx, database.SetUserDisabledParams{
+				ID:         user.ID,
+				DisabledAt: sql.NullTime{Time: at.Add(time.Hour), Valid: true},
+			}); err != nil {
+				t.Fatalf("SetUserDisabled: %v", err)
+			}
+
+			got, err := ts.store.GetUser(ctx, user.Name)
+			if err != nil {
+				t.Fatalf("GetUser: %v", err)
+			}
+			if !got.CreatedAt.Equal(at) {
+				t.Errorf("created_at = %v, want %v", got.CreatedAt, at)
+			}
+			if !got.DisabledAt.Valid || !got.DisabledAt.Time.Equal(at.Add(time.Hour)) {
+				t.Errorf("disabled_at = %v, want %v", got.DisabledAt, at.Add(time.Hour))
+			}
+			if got.LastActiveAt.Valid {
+				t.Errorf("last_active_at = %v, want NULL", got.LastActiveAt)
+			}
+		})
+	}
+}
+
+// TestSQLiteTimeZones checks that times with any offset are stored in UTC,
+// so a time compares equal to the same instant given in another zone.
+// Postgres timestamp columns drop the offset instead; gatorapp passes them
+// UTC times.
+func TestSQLiteTimeZones(t *testing.T) {
+	ctx := context.Background()
+	ts := testSQLiteStore(t)
+	user := createTestUser(t, ts.store, time.Now().UTC())
+	if _, err := ts.store.UpsertDigestSettings(ctx, database.UpsertDigestSettingsParams{
+		UserID:    user.ID,
+		CreatedAt: time.Now().UTC(),
+		Email:     "test@example.com",
+	}); err != nil {
+		t.Fatalf("UpsertDigestSettings: %v", err)
+	}
+
+	sent := time.Date(2026, 3, 2, 9, 0, 0, 0, time.FixedZone("UTC+5", 5*60*60))
+	claims := []struct{ from, to sql.NullTime }{
+		{sql.NullTime{}, sql.NullTime{Time: sent, Valid: true}},
+		{sql.NullTime{Time: sent.UTC(), Valid: true}, sql.NullTime{Time: sent.Add(time.Hour), Valid: true}},
+		{sql.NullTime{Time: sent.Add(time.Hour).In(time.FixedZone("UTC-8", -8*60*60)), Valid: true}, sql.NullTime{}},
+	}
+	for i, c := range claims {
+		n, err := ts.store.ClaimDigest(ctx, database.ClaimDigestParams{UserID: user.ID, OldSentAt: c.from, NewSentAt: c.to})
+		if err != nil {
+			t.Fatalf("ClaimDigest %d: %v", i, err)
+		}
+		if n != 1 {
+			t.Errorf("ClaimDigest %d from %v claimed %d rows, want 1", i, c.from, n)
+		}
+	}
+}
+
+// TestSQLiteMigrations checks that a new database gets every SQLite
+// migration, recorded in its user_version.
+func TestSQLiteMigrations(t *testing.T) {
+	names, err := fs.Glob(sqliteSchema, "sql/sqlite/schema/*.sql")
+	if err != nil || len(names) == 0 {
+		t.Fatalf("no SQLite migrations: %v", err)
+	}
+	ts := testSQLiteStore(t)
+	var version int
+	if err := ts.db.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		t.Fatal(err)
+	}
+	if version != len(names) {
+		t.Errorf("user_version = %d, want %d, one per migration", version, len(names))
+	}
+
+	// Opening it again finds nothing left to do.
+	if _, err := newStore(context.Background(), ts.dbURL, ts.db, ts.db); err != nil {
+		t.Errorf("migrating again: %v", err)
+	}
+}
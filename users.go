@@ -0,0 +1,150 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/isaacjstriker/gatorapp/internal/database"
+)
+
+// userSorts orders the users listing. Names and sign-up dates sort oldest
+// first; the rest sort the most active users first.
+var userSorts = map[string]func(a, b database.GetUserStatsRow) int{
+	"name": func(a, b database.GetUserStatsRow) int {
+		return strings.Compare(a.User.Name, b.User.Name)
+	},
+	"created": func(a, b database.GetUserStatsRow) int {
+		return a.User.CreatedAt.Compare(b.User.CreatedAt)
+	},
+	"active": func(a, b database.GetUserStatsRow) int {
+		// Users who have never been active come last.
+		switch {
+		case a.User.LastActiveAt.Valid && b.User.LastActiveAt.Valid:
+			return b.User.LastActiveAt.Time.Compare(a.User.LastActiveAt.Time)
+		case a.User.LastActiveAt.Valid:
+			return -1
+		case b.User.LastActiveAt.Valid:
+			return 1
+		}
+		return 0
+	},
+	"follows": func(a, b database.GetUserStatsRow) int {
+		return cmp.Compare(b.Follows, a.Follows)
+	},
+	"feeds": func(a, b database.GetUserStatsRow) int {
+		return cmp.Compare(b.FeedsCreated, a.FeedsCreated)
+	},
+	"unread": func(a, b database.GetUserStatsRow) int {
+		return cmp.Compare(b.Unread, a.Unread)
+	},
+}
+
+func usersFlags(fs *flag.FlagSet) {
+	fs.String("sort", "name", "order users by name, created, active, follows, feeds or unread")
+	fs.Bool("json", false, "print the users as JSON")
+}
+
+// userJSON is how users prints a user with -json.
+type userJSON struct {
+	Name         string     `json:"name"`
+	Role         string     `json:"role"`
+	Current      bool       `json:"current"`
+	Disabled     bool       `json:"disabled"`
+	CreatedAt    time.Time  `json:"created_at"`
+	LastActiveAt *time.Time `json:"last_active_at"`
+	Follows      int64      `json:"follows"`
+	FeedsCreated int64      `json:"feeds_created"`
+	Unread       int64      `json:"unread"`
+}
+
+// handlerUsers lists every user with what they follow, the feeds they
+// added, their unread posts and when they last ran a command.
+func handlerUsers(s *State, _ database.User, cmd Command) error {
+	sortBy, ok := userSorts[cmd.flagString("sort")]
+	if !ok {
+		return &UsageError{
+			Usage:  "users [flags]",
+			Reason: fmt.Sprintf("unknown sort %q; use name, created, active, follows, feeds or unread", cmd.flagString("sort")),
+		}
+	}
+
+	users, err := s.Queries.GetUserStats(context.Background())
+	if err != nil {
+		return fmt.Errorf("failed to fetch users: %w", err)
+	}
+	// Ties keep the query's order, by name.
+	slices.SortStableFunc(users, sortBy)
+
+	if cmd.flagBool("json") {
+		out := make([]userJSON, 0, len(users))
+		for _, u := range users {
+			out = append(out, userJSON{
+				Name:         u.User.Name,
+				Role:         u.User.Role,
+				Current:      u.User.Name == s.Config.CurrentUsername,
+				Disabled:     u.User.DisabledAt.Valid,
+				CreatedAt:    u.User.CreatedAt,
+				LastActiveAt: timePtr(u.User.LastActiveAt),
+				Follows:      u.Follows,
+				FeedsCreated: u.FeedsCreated,
+				Unread:       u.Unread,
+			})
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(out)
+	}
+
+	if len(users) == 0 {
+		fmt.Println("No users found")
+		return nil
+	}
+	fmt.Println("Users:")
+	for _, u := range users {
+		var notes []string
+		if u.User.Name == s.Config.CurrentUsername {
+			notes = append(notes, "current")
+		}
+		if u.User.Role == roleAdmin {
+			notes = append(notes, roleAdmin)
+		}
+		if u.User.DisabledAt.Valid {
+			notes = append(notes, "disabled")
+		}
+		if len(notes) > 0 {
+			fmt.Printf("* %s (%s)\n", u.User.Name, strings.Join(notes, ", "))
+		} else {
+			fmt.Printf("* %s\n", u.User.Name)
+		}
+
+		lastActive := "never"
+		if u.User.LastActiveAt.Valid {
+			lastActive = u.User.LastActiveAt.Time.Format(time.RFC3339)
+		}
+		fmt.Printf("    %s, %s added, %d unread, last active: %s\n",
+			count(u.Follows, "follow"), count(u.FeedsCreated, "feed"), u.Unread, lastActive)
+	}
+	return nil
+}
+
+// recordActivity notes that user has just run a command, for the users
+// listing. It is only bookkeeping, so a failure is logged rather than
+// failing the command.
+func recordActivity(s *State, user database.User) {
+	err := s.Queries.RecordUserActivity(context.Background(), database.RecordUserActivityParams{
+		ID:           user.ID,
+		LastActiveAt: sql.NullTime{Time: time.Now().UTC(), Valid: true},
+	})
+	if err != nil {
+		slog.Warn("could not record user activity", "user_name", user.Name, "error", err)
+	}
+}
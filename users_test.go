@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+)
+
+func TestRecordActivity(t *testing.T) {
+	for _, b := range testBackends(t) {
+		t.Run(b.name, func(t *testing.T) {
+			s := newTestState(t, b)
+			ctx := context.Background()
+			lastActive := func(name string) sql.NullTime {
+				t.Helper()
+				user, err := s.Queries.GetUser(ctx, name)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return user.LastActiveAt
+			}
+
+			mustRun(t, s, []string{"register", "alice"})
+			alice := lastActive("alice")
+			if !alice.Valid {
+				t.Fatal("registering didn't record alice as active")
+			}
+
+			// Registering bob is bob's activity, not alice's.
+			mustRun(t, s, []string{"register", "bob"})
+			if !lastActive("bob").Valid {
+				t.Error("registering didn't record bob as active")
+			}
+			if got := lastActive("alice"); got != alice {
+				t.Errorf("registering bob moved alice's last activity from %v to %v", alice.Time, got.Time)
+			}
+
+			// feeds doesn't act as anyone.
+			bob := lastActive("bob")
+			mustRun(t, s, []string{"feeds"})
+			if got := lastActive("bob"); got != bob {
+				t.Errorf("feeds moved bob's last activity from %v to %v", bob.Time, got.Time)
+			}
+
+			mustRun(t, s, []string{"login", "alice"})
+			if got := lastActive("alice"); !got.Time.After(alice.Time) {
+				t.Errorf("logging in left alice's last activity at %v", got.Time)
+			}
+			alice = lastActive("alice")
+			mustRun(t, s, []string{"following"})
+			if got := lastActive("alice"); !got.Time.After(alice.Time) {
+				t.Errorf("following left alice's last activity at %v", got.Time)
+			}
+			if got := lastActive("bob"); got != bob {
+				t.Errorf("alice's commands moved bob's last activity from %v to %v", bob.Time, got.Time)
+			}
+		})
+	}
+}
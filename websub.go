@@ -0,0 +1,275 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/isaacjstriker/gatorapp/internal/database"
+	"github.com/isaacjstriker/gatorapp/internal/websub"
+)
+
+const (
+	// webSubMinRenewWindow is the least time before a lease expires that it
+	// is renewed, so a renewal isn't missed between agg rounds.
+	webSubMinRenewWindow = 15 * time.Minute
+	// webSubPendingRetry is how long to wait for a hub to verify a
+	// subscription before asking again.
+	webSubPendingRetry = time.Hour
+)
+
+// webSubRenewWindow is how long before a lease of the given length expires
+// it is renewed: a tenth of the lease, at least webSubMinRenewWindow, and
+// never more than half the lease.
+func webSubRenewWindow(lease time.Duration) time.Duration {
+	return min(max(lease/10, webSubMinRenewWindow), lease/2)
+}
+
+// webSubStore backs the websub callback handler with the subscriptions
+// table.
+type webSubStore struct {
+	s *State
+}
+
+func (ws webSubStore) Lookup(ctx context.Context, id string) (websub.Subscription, error) {
+	subID, err := uuid.Parse(id)
+	if err != nil {
+		return websub.Subscription{}, websub.ErrUnknownSubscription
+	}
+	sub, err := ws.s.Queries.GetWebSubSubscription(ctx, subID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return websub.Subscription{}, websub.ErrUnknownSubscription
+	}
+	if err != nil {
+		return websub.Subscription{}, err
+	}
+
+	var pending string
+	switch sub.State {
+	case "pending", "active":
+		pending = websub.ModeSubscribe
+	case "unsubscribing":
+		pending = websub.ModeUnsubscribe
+	default:
+		// Denied and unsubscribed callbacks are dead; a 404 tells the hub
+		// to stop.
+		return websub.Subscription{}, websub.ErrUnknownSubscription
+	}
+
+	return websub.Subscription{
+		ID:            sub.ID.String(),
+		Topic:         sub.TopicUrl,
+		Secret:        sub.Secret,
+		PendingSecret: sub.PendingSecret.String,
+		Pending:       pending,
+	}, nil
+}
+
+func (ws webSubStore) Verified(ctx context.Context, id, mode string, lease time.Duration) error {
+	subID, err := uuid.Parse(id)
+	if err != nil {
+		return err
+	}
+	switch mode {
+	case websub.ModeSubscribe:
+		slog.Info("websub subscription verified", "subscription_id", id, "lease", lease)
+		now := time.Now().UTC()
+		expires := now.Add(lease)
+		return ws.s.Queries.ActivateWebSubSubscription(ctx, database.ActivateWebSubSubscriptionParams{
+			ID:             subID,
+			LeaseExpiresAt: sql.NullTime{Time: expires, Valid: true},
+			RenewAt:        sql.NullTime{Time: expires.Add(-webSubRenewWindow(lease)), Valid: true},
+			UpdatedAt:      now,
+		})
+	case websub.ModeUnsubscribe:
+		return ws.s.Queries.SetWebSubSubscriptionState(ctx, database.SetWebSubSubscriptionStateParams{
+			ID:        subID,
+			State:     "unsubscribed",
+			UpdatedAt: time.Now().UTC(),
+		})
+	case websub.ModeDenied:
+		slog.Warn("websub subscription denied by hub", "subscription_id", id)
+		return ws.s.Queries.SetWebSubSubscriptionState(ctx, database.SetWebSubSubscriptionStateParams{
+			ID:        subID,
+			State:     "denied",
+			UpdatedAt: time.Now().UTC(),
+		})
+	}
+	return fmt.Errorf("unknown mode %q", mode)
+}
+
+func newWebSubHandler(s *State) *websub.Handler {
+	return &websub.Handler{
+		Store:   webSubStore{s: s},
+		Deliver: deliverWebSubContent(s),
+		Logger:  slog.Default(),
+	}
+}
+
+// deliverWebSubContent ingests a pushed feed document through the same path
+// as a polled one.
+func deliverWebSubContent(s *State) func(context.Context, websub.Subscription, string, []byte) error {
+	return func(ctx context.Context, sub websub.Subscription, contentType string, body []byte) error {
+		subID, err := uuid.Parse(sub.ID)
+		if err != nil {
+			return err
+		}
+		record, err := s.Queries.GetWebSubSubscription(ctx, subID)
+		if err != nil {
+			return err
+		}
+		feed, err := s.Queries.GetFeedByID(ctx, record.FeedID)
+		if err != nil {
+			return err
+		}
+		feedData, err := parseFeed(body)
+		if err != nil {
+			return fmt.Errorf("could not parse pushed content: %w", err)
+		}
+		savePosts(s, feed, feedData)
+		feedLog(feed).Info("feed pushed by hub", "subscription_id", sub.ID, "posts", len(feedData.Channel.Item))
+		return nil
+	}
+}
+
+func webSubCallbackURL(s *State, sub database.WebsubSubscription) string {
+	return strings.TrimRight(s.Config.WebSub.CallbackURL, "/") + "/websub/" + sub.ID.String()
+}
+
+// maybeSubscribe subscribes to the hub a freshly fetched feed advertises,
+// unless we already hold or are waiting on a subscription for it.
+func maybeSubscribe(s *State, feed database.Feed, feedData *RSSFeed) {
+	if !s.Config.WebSub.Enabled() {
+		return
+	}
+	hub := feedData.HubURL()
+	if hub == "" {
+		return
+	}
+	topic := feedData.SelfURL()
+	if topic == "" {
+		topic = feed.Url
+	}
+
+	ctx := context.Background()
+	existing, err := s.Queries.GetWebSubSubscriptionForFeed(ctx, feed.ID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		feedLog(feed).Error("couldn't look up websub subscription", "error", err)
+		return
+	}
+	if err == nil && existing.HubUrl == hub && existing.TopicUrl == topic {
+		switch existing.State {
+		case "active", "denied":
+			return
+		case "pending":
+			if time.Since(existing.UpdatedAt) < webSubPendingRetry {
+				return
+			}
+		}
+	}
+
+	secret, err := websub.NewSecret()
+	if err != nil {
+		feedLog(feed).Error("couldn't generate websub secret", "error", err)
+		return
+	}
+	subscribeFeed(s, feed, hub, topic, secret)
+}
+
+// subscribeFeed records a pending subscription and asks the hub for it. An
+// existing subscription keeps its current secret until the hub verifies the
+// new request, so content the hub pushes in the meantime still checks out.
+func subscribeFeed(s *State, feed database.Feed, hub, topic, secret string) {
+	ctx := context.Background()
+	now := time.Now().UTC()
+	sub, err := s.Queries.UpsertWebSubSubscription(ctx, database.UpsertWebSubSubscriptionParams{
+		ID:            uuid.New(),
+		CreatedAt:     now,
+		UpdatedAt:     now,
+		FeedID:        feed.ID,
+		HubUrl:        hub,
+		TopicUrl:      topic,
+		Secret:        secret,
+		PendingSecret: sql.NullString{String: secret, Valid: true},
+	})
+	if err != nil {
+		feedLog(feed).Error("couldn't save websub subscription", "hub_url", hub, "error", err)
+		return
+	}
+
+	err = websub.Subscribe(ctx, s.Fetcher.client, websub.Request{
+		Hub:      hub,
+		Topic:    topic,
+		Callback: webSubCallbackURL(s, sub),
+		Secret:   secret,
+		Lease:    s.Config.WebSub.Lease.Duration,
+	})
+	if err != nil {
+		feedLog(feed).Error("couldn't subscribe at hub", "hub_url", hub, "error", err)
+		return
+	}
+	feedLog(feed).Info("requested websub subscription", "hub_url", hub, "subscription_id", sub.ID)
+}
+
+// unsubscribeFeed tells the hub we no longer want pushes for feed.
+func unsubscribeFeed(s *State, feed database.Feed) {
+	if !s.Config.WebSub.Enabled() {
+		return
+	}
+	ctx := context.Background()
+	sub, err := s.Queries.GetWebSubSubscriptionForFeed(ctx, feed.ID)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			feedLog(feed).Error("couldn't look up websub subscription", "error", err)
+		}
+		return
+	}
+	if sub.State != "active" && sub.State != "pending" {
+		return
+	}
+
+	if err := s.Queries.SetWebSubSubscriptionState(ctx, database.SetWebSubSubscriptionStateParams{
+		ID:        sub.ID,
+		State:     "unsubscribing",
+		UpdatedAt: time.Now().UTC(),
+	}); err != nil {
+		feedLog(feed).Error("couldn't update websub subscription", "subscription_id", sub.ID, "error", err)
+		return
+	}
+	err = websub.Unsubscribe(ctx, s.Fetcher.client, websub.Request{
+		Hub:      sub.HubUrl,
+		Topic:    sub.TopicUrl,
+		Callback: webSubCallbackURL(s, sub),
+	})
+	if err != nil {
+		feedLog(feed).Error("couldn't unsubscribe at hub", "hub_url", sub.HubUrl, "error", err)
+	}
+}
+
+// renewWebSubSubscriptions re-subscribes once a subscription's renew_at,
+// set from the lease the hub granted, has passed. Each renewal goes back to
+// pending until the hub verifies it again.
+func renewWebSubSubscriptions(s *State) {
+	if !s.Config.WebSub.Enabled() {
+		return
+	}
+	ctx := context.Background()
+	subs, err := s.Queries.GetWebSubSubscriptionsToRenew(ctx, time.Now().UTC())
+	if err != nil {
+		slog.Error("couldn't get websub subscriptions to renew", "error", err)
+		return
+	}
+	for _, sub := range subs {
+		feed, err := s.Queries.GetFeedByID(ctx, sub.FeedID)
+		if err != nil {
+			slog.Error("couldn't get feed for websub subscription", "subscription_id", sub.ID, "error", err)
+			continue
+		}
+		subscribeFeed(s, feed, sub.HubUrl, sub.TopicUrl, sub.Secret)
+	}
+}